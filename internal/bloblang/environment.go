@@ -11,6 +11,9 @@ import (
 // features, functions and methods can be modified.
 type Environment struct {
 	pCtx parser.Context
+
+	coverage     *mapping.Coverage
+	coverageName string
 }
 
 // GlobalEnvironment returns the global default environment. Modifying this
@@ -70,9 +73,23 @@ func (e *Environment) NewMapping(blobl string) (*mapping.Executor, error) {
 	if err != nil {
 		return nil, err
 	}
+	if e.coverage != nil {
+		e.coverage.Register(e.coverageName, exec)
+	}
 	return exec, nil
 }
 
+// WithCoverage returns a copy of the environment where all mappings parsed are
+// registered with a coverage tracker under the provided name, which is usually
+// the path of the file containing the mappings.
+func (e *Environment) WithCoverage(c *mapping.Coverage, name string) *Environment {
+	return &Environment{
+		pCtx:         e.pCtx,
+		coverage:     c,
+		coverageName: name,
+	}
+}
+
 // Deactivated returns a version of the environment where constructors are
 // disabled for all functions and methods, allowing mappings to be parsed and
 // validated but not executed.
@@ -0,0 +1,180 @@
+package mapping
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+)
+
+// CoveragePointResult describes how many times a coverage point of a mapping
+// was executed, along with its position within the mapping.
+type CoveragePointResult struct {
+	Kind   query.CoverageKind
+	Line   int
+	Column int
+	Hits   int64
+}
+
+// CoverageResult describes the coverage of a single mapping.
+type CoverageResult struct {
+	// Name of the file or resource the mapping originates from.
+	Name string
+
+	// Mapping is the source of the mapping.
+	Mapping string
+
+	// Points are the coverage points of the mapping sorted by their position.
+	Points []CoveragePointResult
+}
+
+// Covered returns the number of points of a given kind that were executed at
+// least once, and the total number of points of that kind.
+func (r CoverageResult) Covered(kinds ...query.CoverageKind) (covered, total int) {
+	for _, p := range r.Points {
+		for _, k := range kinds {
+			if p.Kind != k {
+				continue
+			}
+			total++
+			if p.Hits > 0 {
+				covered++
+			}
+		}
+	}
+	return
+}
+
+//------------------------------------------------------------------------------
+
+type coverageKey struct {
+	kind   query.CoverageKind
+	offset int
+}
+
+type coverageMapping struct {
+	name  string
+	input []rune
+	hits  map[coverageKey]int64
+}
+
+type coverageRef struct {
+	mapping *coverageMapping
+	key     coverageKey
+}
+
+// Coverage records the number of times the statements, branches and named maps
+// of registered mappings are executed. Mappings that share both a name and
+// source are aggregated, and therefore the same mapping can be parsed and
+// registered any number of times.
+type Coverage struct {
+	mut      sync.Mutex
+	mappings map[string]*coverageMapping
+	points   map[*query.CoveragePoint]coverageRef
+}
+
+// NewCoverage creates a new coverage tracker.
+func NewCoverage() *Coverage {
+	return &Coverage{
+		mappings: map[string]*coverageMapping{},
+		points:   map[*query.CoveragePoint]coverageRef{},
+	}
+}
+
+// tailOffset returns the offset of a clip within an input, only if the clip is
+// a tail slice of that same input.
+func tailOffset(input, clip []rune) (int, bool) {
+	if len(clip) == 0 || len(clip) > len(input) {
+		return 0, false
+	}
+	offset := len(input) - len(clip)
+	if &input[offset] != &clip[0] {
+		return 0, false
+	}
+	return offset, true
+}
+
+// Register a mapping executor under a name, which is usually the path of the
+// file that the mapping originates from. The executor will record coverage to
+// this tracker from then on.
+//
+// Coverage points that belong to imported mappings are ignored.
+func (c *Coverage) Register(name string, e *Executor) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	id := name + "\x00" + string(e.input)
+	m, exists := c.mappings[id]
+	if !exists {
+		m = &coverageMapping{
+			name:  name,
+			input: e.input,
+			hits:  map[coverageKey]int64{},
+		}
+		c.mappings[id] = m
+	}
+
+	for _, p := range e.CoveragePoints() {
+		offset, ok := tailOffset(e.input, p.Input)
+		if !ok {
+			continue
+		}
+		key := coverageKey{kind: p.Kind, offset: offset}
+		if _, exists := m.hits[key]; !exists {
+			m.hits[key] = 0
+		}
+		c.points[p] = coverageRef{mapping: m, key: key}
+	}
+
+	e.SetCoverage(c)
+}
+
+// Hit records an execution of a coverage point.
+func (c *Coverage) Hit(p *query.CoveragePoint) {
+	c.mut.Lock()
+	if ref, exists := c.points[p]; exists {
+		ref.mapping.hits[ref.key]++
+	}
+	c.mut.Unlock()
+}
+
+// Results returns the coverage of all registered mappings sorted by name.
+func (c *Coverage) Results() []CoverageResult {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	results := make([]CoverageResult, 0, len(c.mappings))
+	for _, m := range c.mappings {
+		res := CoverageResult{
+			Name:    m.name,
+			Mapping: string(m.input),
+		}
+		for k, hits := range m.hits {
+			line, col := LineAndColOf(m.input, m.input[k.offset:])
+			res.Points = append(res.Points, CoveragePointResult{
+				Kind:   k.kind,
+				Line:   line,
+				Column: col,
+				Hits:   hits,
+			})
+		}
+		sort.Slice(res.Points, func(i, j int) bool {
+			if res.Points[i].Line != res.Points[j].Line {
+				return res.Points[i].Line < res.Points[j].Line
+			}
+			if res.Points[i].Column != res.Points[j].Column {
+				return res.Points[i].Column < res.Points[j].Column
+			}
+			return res.Points[i].Kind < res.Points[j].Kind
+		})
+		results = append(results, res)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Name != results[j].Name {
+			return results[i].Name < results[j].Name
+		}
+		return results[i].Mapping < results[j].Mapping
+	})
+	return results
+}
@@ -3,6 +3,7 @@ package mapping
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
@@ -45,14 +46,16 @@ type Statement struct {
 	input      []rune
 	assignment Assignment
 	query      query.Function
+	point      *query.CoveragePoint
 }
 
 // NewStatement initialises a new mapping statement from an Assignment and
 // query.Function. The input parameter is an optional slice pointing to the
 // parsed expression that created the statement.
-func NewStatement(input []rune, assignment Assignment, query query.Function) Statement {
+func NewStatement(input []rune, assignment Assignment, fn query.Function) Statement {
 	return Statement{
-		input, assignment, query,
+		input, assignment, fn,
+		query.NewCoveragePoint(query.CoverageStatement, input),
 	}
 }
 
@@ -65,6 +68,10 @@ type Executor struct {
 	input      []rune
 	maps       map[string]query.Function
 	statements []Statement
+
+	point        *query.CoveragePoint
+	branchPoints []*query.CoveragePoint
+	coverage     query.CoverageRecorder
 }
 
 // NewExecutor initialises a new mapping executor from a map of query functions,
@@ -72,7 +79,13 @@ type Executor struct {
 // is an optional slice pointing to the parsed expression that created the
 // executor.
 func NewExecutor(annotation string, input []rune, maps map[string]query.Function, statements ...Statement) *Executor {
-	return &Executor{annotation, input, maps, statements}
+	return &Executor{
+		annotation: annotation,
+		input:      input,
+		maps:       maps,
+		statements: statements,
+		point:      query.NewCoveragePoint(query.CoverageMap, input),
+	}
 }
 
 // Annotation returns a string annotation that describes the mapping executor.
@@ -85,6 +98,45 @@ func (e *Executor) Maps() map[string]query.Function {
 	return e.maps
 }
 
+// AddCoveragePoints adds coverage points of branches within the statements of
+// the mapping, these are obtained during parsing.
+func (e *Executor) AddCoveragePoints(points ...*query.CoveragePoint) {
+	e.branchPoints = append(e.branchPoints, points...)
+}
+
+// CoveragePoints returns all coverage points of the mapping, including the
+// statements and branches of named maps defined within it.
+func (e *Executor) CoveragePoints() []*query.CoveragePoint {
+	var points []*query.CoveragePoint
+	for _, stmt := range e.statements {
+		points = append(points, stmt.point)
+	}
+
+	mapNames := make([]string, 0, len(e.maps))
+	for k := range e.maps {
+		mapNames = append(mapNames, k)
+	}
+	sort.Strings(mapNames)
+	for _, k := range mapNames {
+		mExec, ok := e.maps[k].(*Executor)
+		if !ok || mExec == e {
+			continue
+		}
+		points = append(points, mExec.point)
+		for _, stmt := range mExec.statements {
+			points = append(points, stmt.point)
+		}
+	}
+
+	return append(points, e.branchPoints...)
+}
+
+// SetCoverage sets a recorder that is notified each time a statement, branch
+// or named map of the mapping is executed.
+func (e *Executor) SetCoverage(r query.CoverageRecorder) {
+	e.coverage = r
+}
+
 // QueryPart executes the bloblang mapping on a particular message index of a
 // batch. The message is parsed as a JSON document in order to provide the
 // mapping context. The result of the mapping is expected to be a boolean value
@@ -157,12 +209,16 @@ func (e *Executor) mapPart(appendTo types.Part, index int, reference Message) (t
 	vars := map[string]interface{}{}
 
 	for _, stmt := range e.statements {
+		if e.coverage != nil {
+			e.coverage.Hit(stmt.point)
+		}
 		res, err := stmt.query.Exec(query.FunctionContext{
 			Maps:     e.maps,
 			Vars:     vars,
 			Index:    index,
 			MsgBatch: reference,
 			NewMsg:   newPart,
+			Coverage: e.coverage,
 		}.WithValueFunc(lazyValue))
 		if err != nil {
 			var line int
@@ -247,8 +303,18 @@ func (e *Executor) Exec(ctx query.FunctionContext) (interface{}, error) {
 		return nil, fmt.Errorf("entering %v exceeded maximum allowed stacks of %v, this could be due to unbounded recursion", e.annotation, maxMapStacks)
 	}
 
+	if ctx.Coverage == nil {
+		ctx.Coverage = e.coverage
+	}
+	if ctx.Coverage != nil {
+		ctx.Coverage.Hit(e.point)
+	}
+
 	var newObj interface{} = query.Nothing(nil)
 	for _, stmt := range e.statements {
+		if ctx.Coverage != nil {
+			ctx.Coverage.Hit(stmt.point)
+		}
 		res, err := stmt.query.Exec(ctx)
 		if err != nil {
 			// TODO: Do this betterly
@@ -283,7 +349,13 @@ func (e *Executor) Exec(ctx query.FunctionContext) (interface{}, error) {
 
 // ExecOnto a provided assignment context.
 func (e *Executor) ExecOnto(ctx query.FunctionContext, onto AssignmentContext) error {
+	if ctx.Coverage == nil {
+		ctx.Coverage = e.coverage
+	}
 	for _, stmt := range e.statements {
+		if ctx.Coverage != nil {
+			ctx.Coverage.Hit(stmt.point)
+		}
 		res, err := stmt.query.Exec(ctx)
 		if err != nil {
 			var line int
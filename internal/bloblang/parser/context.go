@@ -16,6 +16,7 @@ type Context struct {
 	Methods      *query.MethodSet
 	namedContext *namedContext
 	importer     Importer
+	coverage     *coverageCollector
 }
 
 // EmptyContext returns a parser context with no functions, methods or import
@@ -121,6 +122,38 @@ func (pCtx Context) DisabledImports() Context {
 
 //------------------------------------------------------------------------------
 
+type coverageCollector struct {
+	points []*query.CoveragePoint
+}
+
+// withCoverageCollector returns a Context where branches of parsed expressions
+// are wrapped with coverage points, which are collected for the executor of
+// the mapping.
+func (pCtx Context) withCoverageCollector() Context {
+	pCtx.coverage = &coverageCollector{}
+	return pCtx
+}
+
+// wrapCoverage wraps a function with a new coverage point when the context is
+// collecting coverage points, otherwise the function is returned unchanged.
+func (pCtx Context) wrapCoverage(kind query.CoverageKind, input []rune, fn query.Function) query.Function {
+	if pCtx.coverage == nil || fn == nil {
+		return fn
+	}
+	point := query.NewCoveragePoint(kind, input)
+	pCtx.coverage.points = append(pCtx.coverage.points, point)
+	return query.NewCoverageFunction(point, fn)
+}
+
+func (pCtx Context) coveragePoints() []*query.CoveragePoint {
+	if pCtx.coverage == nil {
+		return nil
+	}
+	return pCtx.coverage.points
+}
+
+//------------------------------------------------------------------------------
+
 // Importer represents a repository of bloblang files that can be imported by
 // mappings. It's possible for mappings to import files using relative paths, if
 // the import is from a mapping which was itself imported then the path should
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappingCoverage(t *testing.T) {
+	mappingStr := `map upper {
+  root = this.uppercase()
+}
+
+root.a = match this.type {
+  "foo" => "was foo"
+  "bar" => "was bar"
+  _ => "was other"
+}
+root.b = if this.type == "foo" {
+  "yes"
+} else if this.type == "baz" {
+  "maybe"
+} else {
+  "no"
+}
+root.c = this.type.apply("upper")`
+
+	exec, perr := ParseMapping(GlobalContext(), mappingStr)
+	require.Nil(t, perr)
+
+	cov := mapping.NewCoverage()
+	cov.Register("foo.blobl", exec)
+
+	for _, v := range []string{`{"type":"foo"}`, `{"type":"foo"}`, `{"type":"buz"}`} {
+		_, err := exec.MapPart(0, message.New([][]byte{[]byte(v)}))
+		require.NoError(t, err)
+	}
+
+	// Registering the same mapping again aggregates the results.
+	exec2, perr := ParseMapping(GlobalContext(), mappingStr)
+	require.Nil(t, perr)
+	cov.Register("foo.blobl", exec2)
+
+	_, err := exec2.MapPart(0, message.New([][]byte{[]byte(`{"type":"bar"}`)}))
+	require.NoError(t, err)
+
+	results := cov.Results()
+	require.Len(t, results, 1)
+	assert.Equal(t, "foo.blobl", results[0].Name)
+
+	type point struct {
+		kind query.CoverageKind
+		line int
+		hits int64
+	}
+	var points []point
+	for _, p := range results[0].Points {
+		points = append(points, point{p.Kind, p.Line, p.Hits})
+	}
+	assert.Equal(t, []point{
+		{query.CoverageMap, 1, 4},
+		{query.CoverageStatement, 2, 4},
+		{query.CoverageStatement, 5, 4},
+		{query.CoverageMatchCase, 6, 2},
+		{query.CoverageMatchCase, 7, 1},
+		{query.CoverageMatchCase, 8, 1},
+		{query.CoverageStatement, 10, 4},
+		{query.CoverageIfBranch, 10, 2},
+		{query.CoverageIfBranch, 12, 0},
+		{query.CoverageElseBranch, 14, 2},
+		{query.CoverageStatement, 17, 4},
+	}, points)
+
+	covered, total := results[0].Covered(query.CoverageMatchCase, query.CoverageIfBranch, query.CoverageElseBranch)
+	assert.Equal(t, 5, covered)
+	assert.Equal(t, 6, total)
+}
+
+func TestMappingCoverageIgnoresImports(t *testing.T) {
+	pCtx := GlobalContext().CustomImporter(func(name string) ([]byte, error) {
+		return []byte(`map foo {
+  root = if this.v { "a" } else { "b" }
+}`), nil
+	})
+
+	exec, perr := ParseMapping(pCtx, `import "foo.blobl"
+root = this.apply("foo")`)
+	require.Nil(t, perr)
+
+	cov := mapping.NewCoverage()
+	cov.Register("bar.yaml", exec)
+
+	_, err := exec.MapPart(0, message.New([][]byte{[]byte(`{"v":true}`)}))
+	require.NoError(t, err)
+
+	results := cov.Results()
+	require.Len(t, results, 1)
+	require.Len(t, results[0].Points, 1)
+	assert.Equal(t, query.CoverageStatement, results[0].Points[0].Kind)
+	assert.Equal(t, 2, results[0].Points[0].Line)
+	assert.Equal(t, int64(1), results[0].Points[0].Hits)
+}
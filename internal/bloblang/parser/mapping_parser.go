@@ -26,17 +26,26 @@ func ParseMapping(pCtx Context, expr string) (*mapping.Executor, *Error) {
 		return resDirectImport.Payload.(*mapping.Executor), nil
 	}
 
-	resExe := parseExecutor(pCtx)(in)
+	exeCtx := pCtx.withCoverageCollector()
+	resExe := parseExecutor(exeCtx)(in)
 	if resExe.Err != nil && resExe.Err.IsFatal() {
 		return nil, resExe.Err
 	}
-	resSingle := singleRootMapping(pCtx)(in)
+	singleCtx := pCtx.withCoverageCollector()
+	resSingle := singleRootMapping(singleCtx)(in)
 
 	res := bestMatch(resExe, resSingle)
 	if res.Err != nil {
 		return nil, res.Err
 	}
-	return res.Payload.(*mapping.Executor), nil
+
+	exec := res.Payload.(*mapping.Executor)
+	if res.Payload == resExe.Payload {
+		exec.AddCoveragePoints(exeCtx.coveragePoints()...)
+	} else {
+		exec.AddCoveragePoints(singleCtx.coveragePoints()...)
+	}
+	return exec, nil
 }
 
 //------------------------------------------------------------------------------'
@@ -59,7 +59,7 @@ func matchCaseParser(pCtx Context) Func {
 		}
 
 		return Success(
-			query.NewMatchCase(caseFn, seqSlice[2].(query.Function)),
+			query.NewMatchCase(caseFn, pCtx.wrapCoverage(query.CoverageMatchCase, input, seqSlice[2].(query.Function))),
 			res.Remaining,
 		)
 	}
@@ -171,10 +171,11 @@ func ifExpressionParser(pCtx Context) Func {
 
 		seqSlice := res.Payload.([]interface{})
 		queryFn := seqSlice[2].(query.Function)
-		ifFn := seqSlice[6].(query.Function)
+		ifFn := pCtx.wrapCoverage(query.CoverageIfBranch, input, seqSlice[6].(query.Function))
 
 		var elseIfs []query.ElseIf
 		for {
+			branchInput := optionalWhitespace(res.Remaining).Remaining
 			res = elseIfParser(res.Remaining)
 			if res.Err != nil {
 				return res
@@ -185,18 +186,20 @@ func ifExpressionParser(pCtx Context) Func {
 			seqSlice = res.Payload.([]interface{})
 			elseIfs = append(elseIfs, query.ElseIf{
 				QueryFn: seqSlice[3].(query.Function),
-				MapFn:   seqSlice[7].(query.Function),
+				MapFn:   pCtx.wrapCoverage(query.CoverageIfBranch, branchInput, seqSlice[7].(query.Function)),
 			})
 		}
 
 		var elseFn query.Function
 
+		branchInput := optionalWhitespace(res.Remaining).Remaining
 		res = elseParser(res.Remaining)
 		if res.Err != nil {
 			return res
 		}
 		if res.Payload != nil {
 			elseFn, _ = res.Payload.([]interface{})[5].(query.Function)
+			elseFn = pCtx.wrapCoverage(query.CoverageElseBranch, branchInput, elseFn)
 		}
 
 		res.Payload = query.NewIfFunction(queryFn, ifFn, elseIfs, elseFn)
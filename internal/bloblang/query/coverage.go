@@ -0,0 +1,85 @@
+package query
+
+// CoverageKind describes the type of a coverage point within a mapping.
+type CoverageKind int
+
+// CoverageKinds
+const (
+	CoverageStatement CoverageKind = iota
+	CoverageMatchCase
+	CoverageIfBranch
+	CoverageElseBranch
+	CoverageMap
+)
+
+// String returns a human readable name of the coverage kind.
+func (k CoverageKind) String() string {
+	switch k {
+	case CoverageStatement:
+		return "statement"
+	case CoverageMatchCase:
+		return "match case"
+	case CoverageIfBranch:
+		return "if branch"
+	case CoverageElseBranch:
+		return "else branch"
+	case CoverageMap:
+		return "map"
+	}
+	return "unknown"
+}
+
+// IsBranch returns true if the coverage kind represents one of several
+// conditional paths of execution.
+func (k CoverageKind) IsBranch() bool {
+	return k == CoverageMatchCase || k == CoverageIfBranch || k == CoverageElseBranch
+}
+
+// CoveragePoint represents an executable location within a mapping, such as an
+// assignment statement or a branch of a match or if expression. The input is a
+// slice pointing to the parsed expression at that location.
+type CoveragePoint struct {
+	Kind  CoverageKind
+	Input []rune
+}
+
+// NewCoveragePoint creates a new coverage point.
+func NewCoveragePoint(kind CoverageKind, input []rune) *CoveragePoint {
+	return &CoveragePoint{Kind: kind, Input: input}
+}
+
+// CoverageRecorder is an interface implemented by types that record when
+// coverage points of a mapping are executed. Implementations must be safe to
+// call from concurrent executions.
+type CoverageRecorder interface {
+	Hit(p *CoveragePoint)
+}
+
+//------------------------------------------------------------------------------
+
+// NewCoverageFunction wraps a function so that, when executed with a context
+// containing a coverage recorder, a hit of the provided coverage point is
+// recorded before the wrapped function is executed.
+func NewCoverageFunction(point *CoveragePoint, fn Function) Function {
+	return &coverageFunction{point: point, fn: fn}
+}
+
+type coverageFunction struct {
+	point *CoveragePoint
+	fn    Function
+}
+
+func (c *coverageFunction) Annotation() string {
+	return c.fn.Annotation()
+}
+
+func (c *coverageFunction) Exec(ctx FunctionContext) (interface{}, error) {
+	if ctx.Coverage != nil {
+		ctx.Coverage.Hit(c.point)
+	}
+	return c.fn.Exec(ctx)
+}
+
+func (c *coverageFunction) QueryTargets(ctx TargetsContext) (TargetsContext, []TargetPath) {
+	return c.fn.QueryTargets(ctx)
+}
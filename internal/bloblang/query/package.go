@@ -61,6 +61,9 @@ type FunctionContext struct {
 	// Reference new message being mapped
 	NewMsg types.Part
 
+	// Optionally records which parts of a mapping are executed
+	Coverage CoverageRecorder
+
 	valueFn    func() *interface{}
 	value      *interface{}
 	nextValue  *interface{}
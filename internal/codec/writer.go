@@ -2,6 +2,7 @@ package codec
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"errors"
 	"fmt"
@@ -47,6 +48,15 @@ type WriterConstructor func(io.WriteCloser) (Writer, error)
 
 // GetWriter returns a constructor that creates write codecs.
 func GetWriter(codec string) (WriterConstructor, WriterConfig, error) {
+	if strings.HasPrefix(codec, "gzip/") {
+		ctor, conf, err := GetWriter(strings.TrimPrefix(codec, "gzip/"))
+		if err != nil {
+			return nil, WriterConfig{}, err
+		}
+		return func(w io.WriteCloser) (Writer, error) {
+			return ctor(&gzipWriteCloser{w: w, g: gzip.NewWriter(w)})
+		}, conf, nil
+	}
 	switch codec {
 	case "all-bytes":
 		return func(w io.WriteCloser) (Writer, error) {
@@ -73,6 +83,25 @@ func GetWriter(codec string) (WriterConstructor, WriterConfig, error) {
 
 //------------------------------------------------------------------------------
 
+type gzipWriteCloser struct {
+	w io.WriteCloser
+	g *gzip.Writer
+}
+
+func (g *gzipWriteCloser) Write(b []byte) (int, error) {
+	return g.g.Write(b)
+}
+
+func (g *gzipWriteCloser) Close() error {
+	if err := g.g.Close(); err != nil {
+		g.w.Close()
+		return err
+	}
+	return g.w.Close()
+}
+
+//------------------------------------------------------------------------------
+
 var allBytesConfig = WriterConfig{
 	Truncate:   true,
 	CloseAfter: true,
@@ -0,0 +1,76 @@
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type closeTrackingBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closeTrackingBuffer) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestWriterCodecs(t *testing.T) {
+	for _, test := range []struct {
+		codec  string
+		parts  []string
+		output string
+	}{
+		{codec: "lines", parts: []string{"foo", "bar\n", "baz"}, output: "foo\nbar\nbaz\n"},
+		{codec: "append", parts: []string{"foo", "bar"}, output: "foobar"},
+		{codec: "delim:X", parts: []string{"foo", "barX"}, output: "fooXbarX"},
+	} {
+		ctor, _, err := GetWriter(test.codec)
+		require.NoError(t, err, test.codec)
+
+		buf := &closeTrackingBuffer{}
+		w, err := ctor(buf)
+		require.NoError(t, err, test.codec)
+
+		for _, p := range test.parts {
+			require.NoError(t, w.Write(context.Background(), message.NewPart([]byte(p))), test.codec)
+		}
+		require.NoError(t, w.Close(context.Background()), test.codec)
+
+		assert.True(t, buf.closed, test.codec)
+		assert.Equal(t, test.output, buf.String(), test.codec)
+	}
+}
+
+func TestWriterGzipCodec(t *testing.T) {
+	ctor, conf, err := GetWriter("gzip/lines")
+	require.NoError(t, err)
+	assert.Equal(t, linesWriterConfig, conf)
+
+	buf := &closeTrackingBuffer{}
+	w, err := ctor(buf)
+	require.NoError(t, err)
+
+	for _, p := range []string{"foo", "bar"} {
+		require.NoError(t, w.Write(context.Background(), message.NewPart([]byte(p))))
+	}
+	require.NoError(t, w.Close(context.Background()))
+	assert.True(t, buf.closed)
+
+	g, err := gzip.NewReader(&buf.Buffer)
+	require.NoError(t, err)
+
+	b, err := io.ReadAll(g)
+	require.NoError(t, err)
+	assert.Equal(t, "foo\nbar\n", string(b))
+
+	_, _, err = GetWriter("gzip/nope")
+	require.EqualError(t, err, "codec was not recognised: nope")
+}
@@ -36,9 +36,52 @@ type V2 interface {
 	Close(ctx context.Context) error
 }
 
+// V2GetMulti is an optional interface that can be implemented by a V2 cache in
+// order to retrieve the values of multiple keys in a single operation.
+type V2GetMulti interface {
+	// GetMulti attempts to locate and return the cached values of multiple
+	// keys, where keys that do not exist are omitted from the result.
+	GetMulti(ctx context.Context, keys []string) (map[string][]byte, error)
+}
+
+// V2SetMulti is an optional interface that can be implemented by a V2 cache in
+// order to set the values of multiple keys in a single operation.
+type V2SetMulti interface {
+	// SetMulti sets the values of multiple keys, each with an optional TTL.
+	SetMulti(ctx context.Context, items map[string]types.CacheTTLItem) error
+}
+
+// V2Incr is an optional interface that can be implemented by a V2 cache in
+// order to support atomic increments of integer values.
+type V2Incr interface {
+	// Incr atomically adds a delta to the integer value of a key and returns
+	// the result. A key that does not exist is treated as having a value of
+	// zero.
+	Incr(ctx context.Context, key string, delta int64) (int64, error)
+}
+
+// V2CompareAndSwap is an optional interface that can be implemented by a V2
+// cache in order to support atomic compare-and-swap operations.
+type V2CompareAndSwap interface {
+	// CompareAndSwap sets the value of a key only if its current value matches
+	// old, where a nil old value requires that the key does not exist. Returns
+	// types.ErrKeyValueMismatch if the current value does not match.
+	CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl *time.Duration) error
+}
+
+// V2Scan is an optional interface that can be implemented by a V2 cache in
+// order to support enumerating its keys.
+type V2Scan interface {
+	// Scan returns all keys that begin with a prefix along with their remaining
+	// TTLs, where a nil TTL indicates that the key does not expire or that its
+	// expiry is unknown.
+	Scan(ctx context.Context, prefix string) ([]types.CacheScanItem, error)
+}
+
 //------------------------------------------------------------------------------
 
-// Implements types.Cache
+// Implements types.CacheWithTTL, types.CacheWithGetMulti, types.CacheWithIncr,
+// types.CacheWithCAS and types.CacheWithScan
 type v2ToV1Cache struct {
 	c   V2
 	sig *shutdown.Signaller
@@ -52,6 +95,14 @@ type v2ToV1Cache struct {
 	mSetSuccess metrics.StatCounter
 	mSetLatency metrics.StatTimer
 
+	mGetMultiFailed  metrics.StatCounter
+	mGetMultiSuccess metrics.StatCounter
+	mGetMultiLatency metrics.StatTimer
+
+	mSetMultiFailed  metrics.StatCounter
+	mSetMultiSuccess metrics.StatCounter
+	mSetMultiLatency metrics.StatTimer
+
 	mAddDupe    metrics.StatCounter
 	mAddFailed  metrics.StatCounter
 	mAddSuccess metrics.StatCounter
@@ -60,9 +111,25 @@ type v2ToV1Cache struct {
 	mDelFailed  metrics.StatCounter
 	mDelSuccess metrics.StatCounter
 	mDelLatency metrics.StatTimer
+
+	mIncrFailed  metrics.StatCounter
+	mIncrSuccess metrics.StatCounter
+	mIncrLatency metrics.StatTimer
+
+	mCASMismatch metrics.StatCounter
+	mCASFailed   metrics.StatCounter
+	mCASSuccess  metrics.StatCounter
+	mCASLatency  metrics.StatTimer
+
+	mScanFailed  metrics.StatCounter
+	mScanSuccess metrics.StatCounter
+	mScanLatency metrics.StatTimer
 }
 
 // NewV2ToV1Cache wraps a cache.V2 with a struct that implements types.Cache.
+// The optional operations of the result, such as Incr, CompareAndSwap and
+// Scan, return types.ErrCacheOpNotSupported when the underlying cache does not
+// implement them.
 func NewV2ToV1Cache(c V2, stats metrics.Type) types.Cache {
 	return &v2ToV1Cache{
 		c: c, sig: shutdown.NewSignaller(),
@@ -76,6 +143,14 @@ func NewV2ToV1Cache(c V2, stats metrics.Type) types.Cache {
 		mSetSuccess: stats.GetCounter("set.success"),
 		mSetLatency: stats.GetTimer("set.latency"),
 
+		mGetMultiFailed:  stats.GetCounter("get_multi.failed"),
+		mGetMultiSuccess: stats.GetCounter("get_multi.success"),
+		mGetMultiLatency: stats.GetTimer("get_multi.latency"),
+
+		mSetMultiFailed:  stats.GetCounter("set_multi.failed"),
+		mSetMultiSuccess: stats.GetCounter("set_multi.success"),
+		mSetMultiLatency: stats.GetTimer("set_multi.latency"),
+
 		mAddDupe:    stats.GetCounter("add.duplicate"),
 		mAddFailed:  stats.GetCounter("add.failed"),
 		mAddSuccess: stats.GetCounter("add.success"),
@@ -84,6 +159,19 @@ func NewV2ToV1Cache(c V2, stats metrics.Type) types.Cache {
 		mDelFailed:  stats.GetCounter("delete.failed"),
 		mDelSuccess: stats.GetCounter("delete.success"),
 		mDelLatency: stats.GetTimer("delete.latency"),
+
+		mIncrFailed:  stats.GetCounter("incr.failed"),
+		mIncrSuccess: stats.GetCounter("incr.success"),
+		mIncrLatency: stats.GetTimer("incr.latency"),
+
+		mCASMismatch: stats.GetCounter("cas.mismatch"),
+		mCASFailed:   stats.GetCounter("cas.failed"),
+		mCASSuccess:  stats.GetCounter("cas.success"),
+		mCASLatency:  stats.GetTimer("cas.latency"),
+
+		mScanFailed:  stats.GetCounter("scan.failed"),
+		mScanSuccess: stats.GetCounter("scan.success"),
+		mScanLatency: stats.GetTimer("scan.latency"),
 	}
 }
 
@@ -128,6 +216,13 @@ func (a *v2ToV1Cache) SetWithTTL(key string, value []byte, ttl *time.Duration) e
 }
 
 func (a *v2ToV1Cache) SetMulti(items map[string][]byte) error {
+	if _, ok := a.c.(V2SetMulti); ok {
+		ttlItems := make(map[string]types.CacheTTLItem, len(items))
+		for k, v := range items {
+			ttlItems[k] = types.CacheTTLItem{Value: v}
+		}
+		return a.SetMultiWithTTL(ttlItems)
+	}
 	for k, v := range items {
 		if err := a.Set(k, v); err != nil {
 			return err
@@ -137,6 +232,17 @@ func (a *v2ToV1Cache) SetMulti(items map[string][]byte) error {
 }
 
 func (a *v2ToV1Cache) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
+	if mc, ok := a.c.(V2SetMulti); ok {
+		started := time.Now()
+		err := mc.SetMulti(context.Background(), items)
+		a.mSetMultiLatency.Timing(int64(time.Since(started)))
+		if err != nil {
+			a.mSetMultiFailed.Incr(1)
+		} else {
+			a.mSetMultiSuccess.Incr(1)
+		}
+		return err
+	}
 	for k, v := range items {
 		if err := a.SetWithTTL(k, v.Value, v.TTL); err != nil {
 			return err
@@ -145,6 +251,34 @@ func (a *v2ToV1Cache) SetMultiWithTTL(items map[string]types.CacheTTLItem) error
 	return nil
 }
 
+// GetMulti attempts to retrieve multiple keys in a single operation when the
+// underlying cache supports it, and otherwise gets each key individually.
+func (a *v2ToV1Cache) GetMulti(keys []string) (map[string][]byte, error) {
+	if mc, ok := a.c.(V2GetMulti); ok {
+		started := time.Now()
+		res, err := mc.GetMulti(context.Background(), keys)
+		a.mGetMultiLatency.Timing(int64(time.Since(started)))
+		if err != nil {
+			a.mGetMultiFailed.Incr(1)
+		} else {
+			a.mGetMultiSuccess.Incr(1)
+		}
+		return res, err
+	}
+	res := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		v, err := a.Get(k)
+		if err != nil {
+			if errors.Is(err, types.ErrKeyNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		res[k] = v
+	}
+	return res, nil
+}
+
 func (a *v2ToV1Cache) Add(key string, value []byte) error {
 	started := time.Now()
 	err := a.c.Add(context.Background(), key, value, nil)
@@ -189,6 +323,58 @@ func (a *v2ToV1Cache) Delete(key string) error {
 	return err
 }
 
+func (a *v2ToV1Cache) Incr(key string, delta int64) (int64, error) {
+	ic, ok := a.c.(V2Incr)
+	if !ok {
+		return 0, types.ErrCacheOpNotSupported
+	}
+	started := time.Now()
+	v, err := ic.Incr(context.Background(), key, delta)
+	a.mIncrLatency.Timing(int64(time.Since(started)))
+	if err != nil {
+		a.mIncrFailed.Incr(1)
+	} else {
+		a.mIncrSuccess.Incr(1)
+	}
+	return v, err
+}
+
+func (a *v2ToV1Cache) CompareAndSwap(key string, old, value []byte, ttl *time.Duration) error {
+	cc, ok := a.c.(V2CompareAndSwap)
+	if !ok {
+		return types.ErrCacheOpNotSupported
+	}
+	started := time.Now()
+	err := cc.CompareAndSwap(context.Background(), key, old, value, ttl)
+	a.mCASLatency.Timing(int64(time.Since(started)))
+	if err != nil {
+		if errors.Is(err, types.ErrKeyValueMismatch) {
+			a.mCASMismatch.Incr(1)
+		} else {
+			a.mCASFailed.Incr(1)
+		}
+	} else {
+		a.mCASSuccess.Incr(1)
+	}
+	return err
+}
+
+func (a *v2ToV1Cache) Scan(prefix string) ([]types.CacheScanItem, error) {
+	sc, ok := a.c.(V2Scan)
+	if !ok {
+		return nil, types.ErrCacheOpNotSupported
+	}
+	started := time.Now()
+	items, err := sc.Scan(context.Background(), prefix)
+	a.mScanLatency.Timing(int64(time.Since(started)))
+	if err != nil {
+		a.mScanFailed.Incr(1)
+	} else {
+		a.mScanSuccess.Incr(1)
+	}
+	return items, err
+}
+
 func (a *v2ToV1Cache) CloseAsync() {
 	go func() {
 		if err := a.c.Close(context.Background()); err == nil {
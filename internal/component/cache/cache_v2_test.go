@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -347,3 +348,75 @@ func (c *closableCacheType) CloseAsync() {
 func (c *closableCacheType) WaitForClose(t time.Duration) error {
 	return nil
 }
+
+type incrCache struct {
+	closableCache
+}
+
+func (c *incrCache) Incr(ctx context.Context, key string, delta int64) (int64, error) {
+	return delta, c.err
+}
+
+func TestCacheAirGapIncr(t *testing.T) {
+	agrl := NewV2ToV1Cache(&closableCache{m: map[string]testCacheItem{}}, metrics.Noop())
+	_, err := agrl.(types.CacheWithIncr).Incr("foo", 5)
+	assert.Equal(t, types.ErrCacheOpNotSupported, err)
+	err = agrl.(types.CacheWithCAS).CompareAndSwap("foo", nil, []byte("bar"), nil)
+	assert.Equal(t, types.ErrCacheOpNotSupported, err)
+
+	agrl = NewV2ToV1Cache(&incrCache{closableCache{m: map[string]testCacheItem{}}}, metrics.Noop())
+	v, err := agrl.(types.CacheWithIncr).Incr("foo", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), v)
+	err = agrl.(types.CacheWithCAS).CompareAndSwap("foo", nil, []byte("bar"), nil)
+	assert.Equal(t, types.ErrCacheOpNotSupported, err)
+}
+
+type scanCache struct {
+	closableCache
+}
+
+func (c *scanCache) Scan(ctx context.Context, prefix string) ([]types.CacheScanItem, error) {
+	var items []types.CacheScanItem
+	for k := range c.m {
+		if strings.HasPrefix(k, prefix) {
+			items = append(items, types.CacheScanItem{Key: k})
+		}
+	}
+	return items, c.err
+}
+
+func TestCacheAirGapScan(t *testing.T) {
+	agrl := NewV2ToV1Cache(&closableCache{m: map[string]testCacheItem{}}, metrics.Noop())
+	_, err := agrl.(types.CacheWithScan).Scan("")
+	assert.Equal(t, types.ErrCacheOpNotSupported, err)
+
+	agrl = NewV2ToV1Cache(&scanCache{closableCache{m: map[string]testCacheItem{
+		"foo": {b: []byte("first")},
+		"bar": {b: []byte("second")},
+	}}}, metrics.Noop())
+	items, err := agrl.(types.CacheWithScan).Scan("fo")
+	assert.NoError(t, err)
+	assert.Equal(t, []types.CacheScanItem{{Key: "foo"}}, items)
+}
+
+func TestCacheAirGapGetMulti(t *testing.T) {
+	rl := &closableCache{
+		m: map[string]testCacheItem{
+			"foo": {b: []byte("bar")},
+			"baz": {b: []byte("buz")},
+		},
+	}
+	agrl := NewV2ToV1Cache(rl, metrics.Noop()).(types.CacheWithGetMulti)
+
+	res, err := agrl.GetMulti([]string{"foo", "nope", "baz"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"foo": []byte("bar"),
+		"baz": []byte("buz"),
+	}, res)
+
+	rl.err = errors.New("test err")
+	_, err = agrl.GetMulti([]string{"foo"})
+	assert.EqualError(t, err, "test err")
+}
@@ -0,0 +1,427 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// PartitionCommitFields returns a docs spec for the fields within a partition
+// commit config struct.
+func PartitionCommitFields() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldBool("enabled", "Whether to track event time partitions and commit them once they are complete."),
+		docs.FieldBloblang(
+			"timestamp_mapping", "A [Bloblang mapping](/docs/guides/bloblang/about) that provides the event timestamp of each message, which determines the partition that it belongs to. The timestamp value assigned to `root` must either be a numerical unix time in seconds (with up to nanosecond precision via decimals), or a string in ISO 8601 format.",
+			`root = this.created_at`, `root = meta("kafka_timestamp_unix").number()`,
+		),
+		docs.FieldString("period", "The period of time covered by each partition, which should match the time granularity of the `path`.", "1h", "24h"),
+		docs.FieldString("allowed_lateness", "An optional duration string describing how far the watermark lags behind the largest timestamp seen, allowing messages that arrive out of order within this duration to be written before their partition is committed.", "1m", "10m"),
+		docs.FieldString("success_marker", "The name of an empty marker file to write into each directory of a partition once it is committed. Set to an empty string in order to disable markers."),
+		docs.FieldCommon(
+			"processors", "An optional list of [processors](/docs/components/processors/about) to execute as a hook when a partition is committed, applied to a message describing the partition.",
+		).Array().HasType(docs.FieldTypeProcessor).Optional(),
+	}
+}
+
+// PartitionCommitConfig describes how event time partitions written by an
+// output are tracked and committed.
+type PartitionCommitConfig struct {
+	Enabled          bool               `json:"enabled" yaml:"enabled"`
+	TimestampMapping string             `json:"timestamp_mapping" yaml:"timestamp_mapping"`
+	Period           string             `json:"period" yaml:"period"`
+	AllowedLateness  string             `json:"allowed_lateness" yaml:"allowed_lateness"`
+	SuccessMarker    string             `json:"success_marker" yaml:"success_marker"`
+	Processors       []processor.Config `json:"processors" yaml:"processors"`
+}
+
+// NewPartitionCommitConfig returns a PartitionCommitConfig configuration struct
+// with default values.
+func NewPartitionCommitConfig() PartitionCommitConfig {
+	return PartitionCommitConfig{
+		Enabled:          false,
+		TimestampMapping: "root = now()",
+		Period:           "1h",
+		AllowedLateness:  "",
+		SuccessMarker:    "_SUCCESS",
+		Processors:       []processor.Config{},
+	}
+}
+
+// PartitionCommitDocs is a description of partition commits that can be
+// appended to the docs of outputs that support them.
+var PartitionCommitDocs = `
+### Partition Commits
+
+When writing to paths partitioned by event time, such as ` + "`dt=2021-12-01/hour=03/`" + `, downstream readers need to know when a partition is complete. When ` + "`partition_commit.enabled`" + ` is set to ` + "`true`" + ` each message is allocated a partition of ` + "`partition_commit.period`" + ` by the timestamp provided by ` + "`partition_commit.timestamp_mapping`" + `, and a watermark is tracked, which is the largest timestamp seen minus the ` + "`partition_commit.allowed_lateness`" + `.
+
+Once the watermark passes the end of a partition and all writes to it have completed, all files of the partition are flushed and closed, and the partition is committed by writing an empty ` + "`partition_commit.success_marker`" + ` file into each directory of the paths written to, and by executing the ` + "`partition_commit.processors`" + ` on a message of the form:
+
+` + "```json" + `
+{
+  "partition_start": "2021-12-01T03:00:00Z",
+  "partition_end": "2021-12-01T04:00:00Z",
+  "directories": [ "dt=2021-12-01/hour=03" ]
+}
+` + "```" + `
+
+Since the watermark only advances as new messages are written, a partition is not committed until a message beyond it has been written. Messages that arrive after their partition has been committed are still written, and cause the partition to be committed again. When a commit fails the partition is retained and the commit is attempted again after the next write, and when the output is closed.
+
+Messages for which ` + "`partition_commit.timestamp_mapping`" + ` fails are still written, but do not belong to any partition and therefore do not advance the watermark or contribute directories to a commit.`
+
+//------------------------------------------------------------------------------
+
+// PartitionMarkerWriter writes an empty marker file at a path.
+type PartitionMarkerWriter func(ctx context.Context, path string) error
+
+// PartitionFlusher flushes and closes all files of a partition, it must not
+// block on writes that are in progress.
+type PartitionFlusher func(partition time.Time)
+
+type partitionState struct {
+	pending     int
+	flushed     bool
+	directories map[string]struct{}
+
+	// Set whilst the partition is being committed, and changed is set when
+	// the partition is written to during a commit, in which case the
+	// partition is committed again.
+	committing bool
+	changed    bool
+}
+
+// PartitionCommitter tracks the event time partitions written to by an output
+// and commits them once a watermark has passed them.
+type PartitionCommitter struct {
+	tsMapping   *mapping.Executor
+	period      time.Duration
+	lateness    time.Duration
+	marker      string
+	procs       []types.Processor
+	writeMarker PartitionMarkerWriter
+
+	log        log.Modular
+	mCommitted metrics.StatCounter
+	mFailed    metrics.StatCounter
+	mLate      metrics.StatCounter
+	mTSErr     metrics.StatCounter
+
+	mut        sync.Mutex
+	watermark  time.Time
+	partitions map[time.Time]*partitionState
+}
+
+// NewPartitionCommitter creates a partition committer from a config.
+func NewPartitionCommitter(
+	conf PartitionCommitConfig,
+	writeMarker PartitionMarkerWriter,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (*PartitionCommitter, error) {
+	c := &PartitionCommitter{
+		marker:      conf.SuccessMarker,
+		writeMarker: writeMarker,
+		log:         log,
+		mCommitted:  stats.GetCounter("partition.committed"),
+		mFailed:     stats.GetCounter("partition.failed"),
+		mLate:       stats.GetCounter("partition.late"),
+		mTSErr:      stats.GetCounter("partition.timestamp_error"),
+		partitions:  map[time.Time]*partitionState{},
+	}
+
+	var err error
+	if c.tsMapping, err = interop.NewBloblangMapping(mgr, conf.TimestampMapping); err != nil {
+		return nil, fmt.Errorf("failed to parse timestamp mapping: %w", err)
+	}
+	if c.period, err = time.ParseDuration(conf.Period); err != nil {
+		return nil, fmt.Errorf("failed to parse period: %w", err)
+	}
+	if c.period <= 0 {
+		return nil, errors.New("period must be greater than zero")
+	}
+	if conf.AllowedLateness != "" {
+		if c.lateness, err = time.ParseDuration(conf.AllowedLateness); err != nil {
+			return nil, fmt.Errorf("failed to parse allowed lateness: %w", err)
+		}
+	}
+	for i, pConf := range conf.Processors {
+		iMgr, iLog, iStats := interop.LabelChild(fmt.Sprintf("partition_commit.processors.%v", i), mgr, log, stats)
+		proc, err := processor.New(pConf, iMgr, iLog, iStats)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create partition commit processor %v: %w", i, err)
+		}
+		c.procs = append(c.procs, proc)
+	}
+	return c, nil
+}
+
+// PartitionWrite tracks a write of a batch of messages to the partitions that
+// they belong to, and must be ended once the write has completed.
+type PartitionWrite struct {
+	c          *PartitionCommitter
+	partitions []time.Time
+	tracked    []bool
+}
+
+// Begin allocates the messages of a batch to partitions and advances the
+// watermark, and must be followed by a call to End once the batch has been
+// written. Messages for which the timestamp mapping fails are not allocated a
+// partition.
+func (c *PartitionCommitter) Begin(msg types.Message) *PartitionWrite {
+	w := &PartitionWrite{
+		c:          c,
+		partitions: make([]time.Time, msg.Len()),
+		tracked:    make([]bool, msg.Len()),
+	}
+
+	var maxTS time.Time
+	for i := range w.partitions {
+		ts, err := c.timestamp(i, msg)
+		if err != nil {
+			c.mTSErr.Incr(1)
+			c.log.Errorf("Timestamp mapping failed, message will be written outside of a partition: %v\n", err)
+			continue
+		}
+		w.partitions[i] = ts.Truncate(c.period)
+		w.tracked[i] = true
+		if ts.After(maxTS) {
+			maxTS = ts
+		}
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	for i, p := range w.partitions {
+		if !w.tracked[i] {
+			continue
+		}
+		state, exists := c.partitions[p]
+		if !exists {
+			if !c.watermark.Before(p.Add(c.period)) {
+				c.mLate.Incr(1)
+			}
+			state = &partitionState{directories: map[string]struct{}{}}
+			c.partitions[p] = state
+		}
+		if state.committing {
+			state.changed = true
+		}
+		state.flushed = false
+		state.pending++
+	}
+	if wm := maxTS.Add(-c.lateness); wm.After(c.watermark) {
+		c.watermark = wm
+	}
+	return w
+}
+
+func (c *PartitionCommitter) timestamp(index int, msg types.Message) (time.Time, error) {
+	p, err := c.tsMapping.MapPart(index, msg)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if p == nil {
+		return time.Time{}, errors.New("message was deleted")
+	}
+	v, err := p.JSON()
+	if err != nil {
+		v = string(p.Get())
+	}
+	ts, err := query.IGetTimestamp(v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ts.UTC(), nil
+}
+
+// Partition returns the partition that a message of the batch belongs to,
+// which is the zero time when the message does not belong to a partition.
+func (w *PartitionWrite) Partition(index int) time.Time {
+	return w.partitions[index]
+}
+
+// Key returns the key of the partition that a message of the batch belongs
+// to, which is suitable for keying rolling objects. The key is empty when the
+// message does not belong to a partition.
+func (w *PartitionWrite) Key(index int) string {
+	if !w.tracked[index] {
+		return ""
+	}
+	return PartitionKey(w.partitions[index])
+}
+
+// PartitionKey returns a key that identifies a partition.
+func PartitionKey(partition time.Time) string {
+	return partition.Format(time.RFC3339Nano)
+}
+
+// Track records the path that a message of the batch was written to.
+func (w *PartitionWrite) Track(index int, p string) {
+	if !w.tracked[index] {
+		return
+	}
+	w.c.mut.Lock()
+	if state, exists := w.c.partitions[w.partitions[index]]; exists {
+		state.directories[path.Dir(p)] = struct{}{}
+	}
+	w.c.mut.Unlock()
+}
+
+// End marks the write of the batch as complete, flushes all partitions that
+// the watermark has passed and commits those without writes in progress.
+func (w *PartitionWrite) End(ctx context.Context, flush PartitionFlusher) {
+	w.c.mut.Lock()
+	for i, p := range w.partitions {
+		if w.tracked[i] {
+			w.c.partitions[p].pending--
+		}
+	}
+	w.c.mut.Unlock()
+
+	w.c.CommitReady(ctx, flush)
+}
+
+// CommitReady flushes all partitions that the watermark has passed and commits
+// those without writes in progress. Partitions that fail to commit are retained
+// and attempted again on the next call.
+func (c *PartitionCommitter) CommitReady(ctx context.Context, flush PartitionFlusher) {
+	c.commitPartitions(ctx, flush, false)
+}
+
+// CommitAll flushes and commits all partitions regardless of the watermark,
+// and should be called once an output has stopped writing and before the
+// committer is closed, as otherwise the final partitions are never committed.
+// Partitions that fail to commit are retained and attempted again on the next
+// call.
+func (c *PartitionCommitter) CommitAll(ctx context.Context, flush PartitionFlusher) {
+	c.commitPartitions(ctx, flush, true)
+}
+
+func (c *PartitionCommitter) commitPartitions(ctx context.Context, flush PartitionFlusher, all bool) {
+	type commit struct {
+		partition   time.Time
+		directories []string
+	}
+	var toFlush []time.Time
+	var toCommit []commit
+
+	c.mut.Lock()
+	for p, state := range c.partitions {
+		if !all && c.watermark.Before(p.Add(c.period)) {
+			continue
+		}
+		if !state.flushed {
+			state.flushed = true
+			toFlush = append(toFlush, p)
+		}
+		if state.pending > 0 || state.committing {
+			continue
+		}
+		dirs := make([]string, 0, len(state.directories))
+		for d := range state.directories {
+			dirs = append(dirs, d)
+		}
+		sort.Strings(dirs)
+		toCommit = append(toCommit, commit{partition: p, directories: dirs})
+		state.committing, state.changed = true, false
+	}
+	c.mut.Unlock()
+
+	for _, p := range toFlush {
+		flush(p)
+	}
+	sort.Slice(toCommit, func(i, j int) bool {
+		return toCommit[i].partition.Before(toCommit[j].partition)
+	})
+	for _, cm := range toCommit {
+		err := c.commit(ctx, cm.partition, cm.directories)
+		if err != nil {
+			c.mFailed.Incr(1)
+			c.log.Errorf("Failed to commit partition %v, it will be attempted again: %v\n", cm.partition.Format(time.RFC3339), err)
+		} else {
+			c.mCommitted.Incr(1)
+		}
+
+		c.mut.Lock()
+		state := c.partitions[cm.partition]
+		state.committing = false
+		if err == nil && !state.changed {
+			delete(c.partitions, cm.partition)
+		}
+		c.mut.Unlock()
+	}
+}
+
+func (c *PartitionCommitter) commit(ctx context.Context, partition time.Time, directories []string) error {
+	if c.marker != "" {
+		for _, d := range directories {
+			if err := c.writeMarker(ctx, path.Join(d, c.marker)); err != nil {
+				return fmt.Errorf("failed to write marker: %w", err)
+			}
+		}
+	}
+	if len(c.procs) == 0 {
+		return nil
+	}
+
+	dirs := make([]interface{}, len(directories))
+	for i, d := range directories {
+		dirs[i] = d
+	}
+	msgBytes, err := json.Marshal(map[string]interface{}{
+		"partition_start": partition.Format(time.RFC3339Nano),
+		"partition_end":   partition.Add(c.period).Format(time.RFC3339Nano),
+		"directories":     dirs,
+	})
+	if err != nil {
+		return err
+	}
+
+	msgs, res := processor.ExecuteAll(c.procs, message.New([][]byte{msgBytes}))
+	if res != nil && res.Error() != nil {
+		return fmt.Errorf("processors failed: %w", res.Error())
+	}
+	for _, m := range msgs {
+		for i := 0; i < m.Len(); i++ {
+			if fail := processor.GetFail(m.Get(i)); fail != "" {
+				return fmt.Errorf("processors failed: %v", fail)
+			}
+		}
+	}
+	return nil
+}
+
+// CloseAsync shuts down the processors of the committer.
+func (c *PartitionCommitter) CloseAsync() {
+	for _, p := range c.procs {
+		p.CloseAsync()
+	}
+}
+
+// WaitForClose blocks until the processors of the committer have closed.
+func (c *PartitionCommitter) WaitForClose(timeout time.Duration) error {
+	stopBy := time.Now().Add(timeout)
+	for _, p := range c.procs {
+		if err := p.WaitForClose(time.Until(stopBy)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type partitionRecorder struct {
+	mut     sync.Mutex
+	markers []string
+	flushed []string
+}
+
+func (p *partitionRecorder) writeMarker(ctx context.Context, path string) error {
+	p.mut.Lock()
+	p.markers = append(p.markers, path)
+	p.mut.Unlock()
+	return nil
+}
+
+func (p *partitionRecorder) flush(partition time.Time) {
+	p.mut.Lock()
+	p.flushed = append(p.flushed, PartitionKey(partition))
+	p.mut.Unlock()
+}
+
+func newTestPartitionCommitter(t *testing.T, conf PartitionCommitConfig) (*PartitionCommitter, *partitionRecorder, *metrics.Local) {
+	t.Helper()
+
+	rec := &partitionRecorder{}
+	stats := metrics.NewLocal()
+	c, err := NewPartitionCommitter(conf, rec.writeMarker, types.NoopMgr(), log.Noop(), stats)
+	require.NoError(t, err)
+	return c, rec, stats
+}
+
+func partitionTestConf() PartitionCommitConfig {
+	conf := NewPartitionCommitConfig()
+	conf.Enabled = true
+	conf.TimestampMapping = "root = this.ts"
+	return conf
+}
+
+func writePartitioned(t *testing.T, c *PartitionCommitter, rec *partitionRecorder, docs ...string) {
+	t.Helper()
+
+	var parts [][]byte
+	for _, d := range docs {
+		parts = append(parts, []byte(d))
+	}
+	msg := message.New(parts)
+
+	pw := c.Begin(msg)
+	for i := range docs {
+		pw.Track(i, "dt="+pw.Partition(i).Format("2006-01-02")+"/hour="+pw.Partition(i).Format("15")+"/foo.log")
+	}
+	pw.End(context.Background(), rec.flush)
+}
+
+func TestPartitionCommitWatermark(t *testing.T) {
+	conf := partitionTestConf()
+	conf.AllowedLateness = "10m"
+	c, rec, stats := newTestPartitionCommitter(t, conf)
+
+	writePartitioned(t, c, rec,
+		`{"ts":"2021-12-01T03:10:00Z"}`,
+		`{"ts":"2021-12-01T03:50:00Z"}`,
+	)
+	writePartitioned(t, c, rec, `{"ts":"2021-12-01T04:05:00Z"}`)
+	assert.Empty(t, rec.markers)
+
+	// Late messages within the allowed lateness are still within the
+	// partition.
+	writePartitioned(t, c, rec, `{"ts":"2021-12-01T03:55:00Z"}`)
+	assert.Empty(t, rec.markers)
+
+	writePartitioned(t, c, rec, `{"ts":"2021-12-01T04:11:00Z"}`)
+	assert.Equal(t, []string{"dt=2021-12-01/hour=03/_SUCCESS"}, rec.markers)
+	assert.Equal(t, []string{"2021-12-01T03:00:00Z"}, rec.flushed)
+
+	// A message arriving after its partition has been committed causes it to
+	// be committed again.
+	writePartitioned(t, c, rec, `{"ts":"2021-12-01T03:20:00Z"}`)
+	assert.Equal(t, []string{
+		"dt=2021-12-01/hour=03/_SUCCESS",
+		"dt=2021-12-01/hour=03/_SUCCESS",
+	}, rec.markers)
+
+	assert.Equal(t, int64(2), stats.GetCounters()["partition.committed"])
+	assert.Equal(t, int64(1), stats.GetCounters()["partition.late"])
+}
+
+func TestPartitionCommitPendingWrites(t *testing.T) {
+	c, rec, _ := newTestPartitionCommitter(t, partitionTestConf())
+
+	pending := c.Begin(message.New([][]byte{[]byte(`{"ts":"2021-12-01T03:10:00Z"}`)}))
+
+	// The watermark passes the partition whilst a write is in progress, which
+	// flushes the partition but does not commit it.
+	writePartitioned(t, c, rec, `{"ts":"2021-12-01T05:00:00Z"}`)
+	assert.Equal(t, []string{"2021-12-01T03:00:00Z"}, rec.flushed)
+	assert.Empty(t, rec.markers)
+
+	pending.Track(0, "dt=2021-12-01/hour=03/bar.log")
+	pending.End(context.Background(), rec.flush)
+	assert.Equal(t, []string{"dt=2021-12-01/hour=03/_SUCCESS"}, rec.markers)
+	assert.Equal(t, []string{"2021-12-01T03:00:00Z"}, rec.flushed)
+}
+
+func TestPartitionCommitProcessors(t *testing.T) {
+	conf := partitionTestConf()
+	conf.SuccessMarker = ""
+
+	procConf := processor.NewConfig()
+	procConf.Type = processor.TypeBloblang
+	procConf.Bloblang = `root = if this.partition_start != "2021-12-01T03:00:00Z" || this.partition_end != "2021-12-01T04:00:00Z" || this.directories.index(0) != "dt=2021-12-01/hour=03" { throw("unexpected partition: " + this.string()) }`
+	conf.Processors = append(conf.Processors, procConf)
+
+	c, rec, stats := newTestPartitionCommitter(t, conf)
+
+	writePartitioned(t, c, rec, `{"ts":"2021-12-01T03:10:00Z"}`)
+	writePartitioned(t, c, rec, `{"ts":"2021-12-01T04:10:00Z"}`)
+	writePartitioned(t, c, rec, `{"ts":"2021-12-01T05:10:00Z"}`)
+
+	assert.Empty(t, rec.markers)
+	assert.Equal(t, int64(1), stats.GetCounters()["partition.committed"])
+	assert.Equal(t, int64(1), stats.GetCounters()["partition.failed"])
+
+	c.CloseAsync()
+	require.NoError(t, c.WaitForClose(time.Second))
+}
+
+func TestPartitionCommitTimestampErrors(t *testing.T) {
+	c, rec, stats := newTestPartitionCommitter(t, partitionTestConf())
+
+	pw := c.Begin(message.New([][]byte{
+		[]byte(`{"ts":"nope"}`),
+		[]byte(`{"ts":"2021-12-01T03:10:00Z"}`),
+	}))
+	assert.Equal(t, "", pw.Key(0))
+	assert.True(t, pw.Partition(0).IsZero())
+	assert.Equal(t, "2021-12-01T03:00:00Z", pw.Key(1))
+	pw.Track(0, "foo.log")
+	pw.Track(1, "dt=2021-12-01/hour=03/foo.log")
+	pw.End(context.Background(), rec.flush)
+
+	writePartitioned(t, c, rec, `{"ts":"2021-12-01T04:10:00Z"}`)
+	assert.Equal(t, []string{"dt=2021-12-01/hour=03/_SUCCESS"}, rec.markers)
+	assert.Equal(t, int64(1), stats.GetCounters()["partition.timestamp_error"])
+}
+
+func TestPartitionCommitRetries(t *testing.T) {
+	c, rec, stats := newTestPartitionCommitter(t, partitionTestConf())
+
+	fail := true
+	c.writeMarker = func(ctx context.Context, path string) error {
+		if fail {
+			return errors.New("nope")
+		}
+		return rec.writeMarker(ctx, path)
+	}
+
+	writePartitioned(t, c, rec, `{"ts":"2021-12-01T03:10:00Z"}`)
+	writePartitioned(t, c, rec, `{"ts":"2021-12-01T04:10:00Z"}`)
+	assert.Empty(t, rec.markers)
+	assert.Equal(t, int64(1), stats.GetCounters()["partition.failed"])
+
+	// The failed partition is retained and committed on the next write.
+	fail = false
+	writePartitioned(t, c, rec, `{"ts":"2021-12-01T04:20:00Z"}`)
+	assert.Equal(t, []string{"dt=2021-12-01/hour=03/_SUCCESS"}, rec.markers)
+
+	writePartitioned(t, c, rec, `{"ts":"2021-12-01T04:30:00Z"}`)
+	assert.Equal(t, []string{"dt=2021-12-01/hour=03/_SUCCESS"}, rec.markers)
+	assert.Equal(t, int64(1), stats.GetCounters()["partition.committed"])
+}
+
+func TestPartitionCommitAll(t *testing.T) {
+	c, rec, _ := newTestPartitionCommitter(t, partitionTestConf())
+
+	writePartitioned(t, c, rec, `{"ts":"2021-12-01T03:10:00Z"}`)
+	assert.Empty(t, rec.markers)
+
+	c.CommitAll(context.Background(), rec.flush)
+	assert.Equal(t, []string{"dt=2021-12-01/hour=03/_SUCCESS"}, rec.markers)
+	assert.Equal(t, []string{"2021-12-01T03:00:00Z"}, rec.flushed)
+}
@@ -0,0 +1,438 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/codec"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// RollingFields returns a docs spec for the fields within a rolling config
+// struct.
+func RollingFields() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldBool("enabled", "Whether to append messages to rolling objects rather than writing an object per message."),
+		docs.FieldString(
+			"codec", "The way in which the bytes of messages are written into objects.",
+			"lines", "gzip/lines", "delim:\t",
+		).HasAnnotatedOptions(
+			"append", "Append each message to the object without any delimiter.",
+			"lines", "Append each message to the object followed by a line break.",
+			"delim:x", "Append each message to the object followed by a custom delimiter.",
+			"gzip/x", "Compress the object with gzip, where x is another codec, e.g. `gzip/lines`.",
+		),
+		docs.FieldInt("max_size", "The approximate size in bytes after which an object is committed, measured after compression. Set to `0` to disable size based rolling."),
+		docs.FieldInt("max_records", "The number of messages after which an object is committed. Set to `0` to disable record based rolling."),
+		docs.FieldString("max_age", "The period of time after an object is created that it is committed. Set to an empty string to disable time based rolling.", "1m", "1h"),
+		docs.FieldInt("max_in_flight", "The maximum number of messages (or batches) to have in flight at a given time whilst rolling is enabled, which replaces the field `max_in_flight` of the output. Messages are only acknowledged once their object has been committed, and therefore an object receives at most this many writes and is committed as soon as it has received them."),
+	}
+}
+
+// RollingConfig describes how messages are appended to rolling objects by
+// outputs that write to object storage.
+type RollingConfig struct {
+	Enabled     bool   `json:"enabled" yaml:"enabled"`
+	Codec       string `json:"codec" yaml:"codec"`
+	MaxSize     int    `json:"max_size" yaml:"max_size"`
+	MaxRecords  int    `json:"max_records" yaml:"max_records"`
+	MaxAge      string `json:"max_age" yaml:"max_age"`
+	MaxInFlight int    `json:"max_in_flight" yaml:"max_in_flight"`
+}
+
+// NewRollingConfig returns a RollingConfig configuration struct with default
+// values.
+func NewRollingConfig() RollingConfig {
+	return RollingConfig{
+		Enabled:     false,
+		Codec:       "lines",
+		MaxSize:     64 * 1024 * 1024,
+		MaxRecords:  0,
+		MaxAge:      "1m",
+		MaxInFlight: 64,
+	}
+}
+
+// RollingDocs is a description of rolling objects that can be appended to the
+// docs of outputs that support them.
+var RollingDocs = `
+### Rolling Objects
+
+When ` + "`rolling.enabled`" + ` is set to ` + "`true`" + ` messages are appended to an in progress object using the ` + "`rolling.codec`" + `, which is streamed to the bucket as it is written. The object is committed once it reaches either ` + "`rolling.max_size`" + `, ` + "`rolling.max_records`" + ` or ` + "`rolling.max_age`" + `, whichever comes first, and a new object is started with the next message. The path and other object properties are resolved from the first message written to each object.
+
+Messages are only acknowledged once the object they were written to has been committed, and if an object fails to be written then all messages written to it are rejected and retried. Since each in flight write waits for its object to be committed the field ` + "`rolling.max_in_flight`" + `, which replaces ` + "`max_in_flight`" + ` whilst rolling is enabled, limits the number of messages (or batches) that can be written to a single object. An object is therefore also committed once it has received ` + "`rolling.max_in_flight`" + ` writes, and the field should be set sufficiently high for objects to reach the desired size.`
+
+//------------------------------------------------------------------------------
+
+// RollingObject is an object of a storage service that is being streamed to
+// and is only made available once it is committed.
+type RollingObject interface {
+	io.Writer
+
+	// Commit completes writing the object.
+	Commit(ctx context.Context) error
+
+	// Abort abandons the object, discarding the data written to it.
+	Abort()
+}
+
+// RollingObjectOpener opens a new object to be written to, where the message
+// and index is that of the first message to be written to it.
+type RollingObjectOpener func(ctx context.Context, msg types.Message, index int) (RollingObject, error)
+
+// Rolling appends messages to rolling objects, blocking writes until the object
+// that they are written to has been committed.
+type Rolling struct {
+	open       RollingObjectOpener
+	codecCtor  codec.WriterConstructor
+	maxSize    int
+	maxRecords int
+	maxAge     time.Duration
+	maxWrites  int
+	log        log.Modular
+
+	mut     sync.Mutex
+	current map[string]*rollingObject
+	closed  bool
+	commits sync.WaitGroup
+}
+
+// NewRolling creates a rolling object writer from a config.
+func NewRolling(conf RollingConfig, open RollingObjectOpener, log log.Modular) (*Rolling, error) {
+	codecCtor, codecConf, err := codec.GetWriter(conf.Codec)
+	if err != nil {
+		return nil, err
+	}
+	if codecConf.CloseAfter {
+		return nil, fmt.Errorf("codec %v cannot be used with rolling objects", conf.Codec)
+	}
+
+	r := &Rolling{
+		open:       open,
+		codecCtor:  codecCtor,
+		maxSize:    conf.MaxSize,
+		maxRecords: conf.MaxRecords,
+		maxWrites:  conf.MaxInFlight,
+		log:        log,
+		current:    map[string]*rollingObject{},
+	}
+	if conf.MaxAge != "" {
+		if r.maxAge, err = time.ParseDuration(conf.MaxAge); err != nil {
+			return nil, fmt.Errorf("failed to parse max age: %w", err)
+		}
+	}
+	if r.maxSize <= 0 && r.maxRecords <= 0 && r.maxAge <= 0 {
+		return nil, errors.New("at least one of max_size, max_records or max_age must be set for rolling objects")
+	}
+	if r.maxWrites < 1 {
+		return nil, errors.New("max_in_flight must be at least 1 for rolling objects")
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+type rollingCounter struct {
+	w io.Writer
+	n int
+}
+
+func (c *rollingCounter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += n
+	return n, err
+}
+
+// Closing the codec must not complete the object, which is committed
+// explicitly once the codec has been flushed.
+func (c *rollingCounter) Close() error {
+	return nil
+}
+
+type rollingObject struct {
+	key    string
+	obj    RollingObject
+	cancel func()
+	timer  *time.Timer
+
+	// Writes to an object are serialised by its own mutex so that a blocking
+	// write only holds up writes to the same object. Once rolled is set the
+	// object must no longer be written to.
+	mut     sync.Mutex
+	codec   codec.Writer
+	counter *rollingCounter
+	records int
+	writes  int
+	rolled  bool
+
+	done chan struct{}
+	err  error
+}
+
+func (r *Rolling) openObject(key string, msg types.Message, index int) (*rollingObject, error) {
+	objCtx, cancel := context.WithCancel(context.Background())
+	obj, err := r.open(objCtx, msg, index)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	counter := &rollingCounter{w: obj}
+	cw, err := r.codecCtor(counter)
+	if err != nil {
+		obj.Abort()
+		cancel()
+		return nil, err
+	}
+	o := &rollingObject{
+		key:     key,
+		obj:     obj,
+		codec:   cw,
+		counter: counter,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	if r.maxAge > 0 {
+		o.timer = time.AfterFunc(r.maxAge, func() {
+			r.mut.Lock()
+			defer r.mut.Unlock()
+			if r.current[key] == o {
+				r.rollLocked(key)
+			}
+		})
+	}
+	return o, nil
+}
+
+// detachLocked removes an object from the current objects if it is still the
+// current object of its key.
+func (r *Rolling) detachLocked(o *rollingObject) {
+	if r.current[o.key] == o {
+		delete(r.current, o.key)
+	}
+	if o.timer != nil {
+		o.timer.Stop()
+	}
+}
+
+// rollLocked detaches the current object of a key and commits it in the
+// background once writes in progress to it have completed.
+func (r *Rolling) rollLocked(key string) {
+	o := r.current[key]
+	if o == nil {
+		return
+	}
+	r.detachLocked(o)
+	r.commits.Add(1)
+	go func() {
+		defer r.commits.Done()
+
+		o.mut.Lock()
+		if o.rolled {
+			// The object was aborted by a failed write.
+			o.mut.Unlock()
+			return
+		}
+		o.rolled = true
+		o.mut.Unlock()
+
+		defer o.cancel()
+		err := o.codec.Close(context.Background())
+		if err == nil {
+			err = o.obj.Commit(context.Background())
+		}
+		if err != nil {
+			o.obj.Abort()
+			r.log.Errorf("Failed to commit object: %v\n", err)
+		}
+		o.err = err
+		close(o.done)
+	}()
+}
+
+// abort detaches an object and abandons it, and must be called whilst holding
+// the mutex of the object.
+func (r *Rolling) abort(o *rollingObject, err error) {
+	r.mut.Lock()
+	r.detachLocked(o)
+	r.mut.Unlock()
+
+	o.rolled = true
+	o.obj.Abort()
+	o.cancel()
+	o.err = err
+	close(o.done)
+}
+
+// Write appends the messages of a batch to the current object, opening a new
+// one if necessary, and blocks until the object has been committed.
+func (r *Rolling) Write(ctx context.Context, msg types.Message) error {
+	return r.WriteKeyed(ctx, msg, nil)
+}
+
+// currentObject returns the current object of a key, opening a new one if
+// necessary.
+func (r *Rolling) currentObject(key string, msg types.Message, index int) (*rollingObject, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if r.closed {
+		return nil, types.ErrTypeClosed
+	}
+	o := r.current[key]
+	if o == nil {
+		var err error
+		if o, err = r.openObject(key, msg, index); err != nil {
+			return nil, fmt.Errorf("failed to open object: %w", err)
+		}
+		r.current[key] = o
+	}
+	return o, nil
+}
+
+// writePart appends a message to an object, and returns false if the object
+// was rolled before the message could be written.
+func (r *Rolling) writePart(ctx context.Context, o *rollingObject, p types.Part) (bool, error) {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	if o.rolled {
+		return false, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return true, err
+	}
+
+	// Writes to an object may block, in which case abandoning the object
+	// unblocks the write.
+	writeDone := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			o.obj.Abort()
+		case <-writeDone:
+		}
+	}()
+	err := o.codec.Write(ctx, p)
+	close(writeDone)
+	if err != nil {
+		err = fmt.Errorf("failed to write to object: %w", err)
+		r.abort(o, err)
+		return true, err
+	}
+
+	o.records++
+	if (r.maxRecords > 0 && o.records >= r.maxRecords) ||
+		(r.maxSize > 0 && o.counter.n >= r.maxSize) {
+		r.mut.Lock()
+		if r.current[o.key] == o {
+			r.rollLocked(o.key)
+		}
+		r.mut.Unlock()
+	}
+	return true, nil
+}
+
+// addWrite counts a write that has been appended to an object. Each write
+// blocks until its objects are committed, and therefore an object that has
+// received a write from every write in flight cannot receive any more and is
+// rolled.
+func (r *Rolling) addWrite(o *rollingObject) {
+	o.mut.Lock()
+	o.writes++
+	full := !o.rolled && o.writes >= r.maxWrites
+	o.mut.Unlock()
+
+	if full {
+		r.mut.Lock()
+		if r.current[o.key] == o {
+			r.rollLocked(o.key)
+		}
+		r.mut.Unlock()
+	}
+}
+
+// WriteKeyed appends the messages of a batch to the current objects of keys
+// returned by a function for each message, where each key has its own current
+// object, and blocks until the objects have been committed.
+func (r *Rolling) WriteKeyed(ctx context.Context, msg types.Message, keyFn func(index int) string) error {
+	// A batch may span multiple objects, all of which must be committed.
+	var objs []*rollingObject
+	seen := map[*rollingObject]struct{}{}
+	err := msg.Iter(func(i int, p types.Part) error {
+		var key string
+		if keyFn != nil {
+			key = keyFn(i)
+		}
+		for {
+			o, err := r.currentObject(key, msg, i)
+			if err != nil {
+				return err
+			}
+			written, err := r.writePart(ctx, o, p)
+			if !written {
+				// The object was rolled whilst we waited to write to it.
+				continue
+			}
+			if _, exists := seen[o]; !exists {
+				seen[o] = struct{}{}
+				objs = append(objs, o)
+			}
+			return err
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, o := range objs {
+		r.addWrite(o)
+	}
+	for _, o := range objs {
+		select {
+		case <-o.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if o.err != nil {
+			return o.err
+		}
+	}
+	return nil
+}
+
+// Roll commits the current object of a key without waiting for it to be
+// committed.
+func (r *Rolling) Roll(key string) {
+	r.mut.Lock()
+	r.rollLocked(key)
+	r.mut.Unlock()
+}
+
+// Close commits the current objects, prevents further writes and waits for all
+// objects to be committed.
+func (r *Rolling) Close(ctx context.Context) error {
+	r.mut.Lock()
+	r.closed = true
+	for key := range r.current {
+		r.rollLocked(key)
+	}
+	r.mut.Unlock()
+
+	committed := make(chan struct{})
+	go func() {
+		r.commits.Wait()
+		close(committed)
+	}()
+	select {
+	case <-committed:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryObjects struct {
+	mut       sync.Mutex
+	committed map[string]string
+	aborted   []string
+	failWrite bool
+
+	// Writes to objects of this path block until the object is aborted, and
+	// signal blocked when they begin.
+	blockPath string
+	blocked   chan struct{}
+}
+
+type memoryObject struct {
+	store *memoryObjects
+	path  string
+	buf   bytes.Buffer
+
+	abortOnce   sync.Once
+	abortedChan chan struct{}
+}
+
+func (m *memoryObject) Write(b []byte) (int, error) {
+	if m.path == m.store.blockPath {
+		m.store.blocked <- struct{}{}
+		<-m.abortedChan
+		return 0, errors.New("aborted")
+	}
+	m.store.mut.Lock()
+	defer m.store.mut.Unlock()
+	if m.store.failWrite {
+		return 0, errors.New("nope")
+	}
+	return m.buf.Write(b)
+}
+
+func (m *memoryObject) Commit(ctx context.Context) error {
+	m.store.mut.Lock()
+	m.store.committed[m.path] = m.buf.String()
+	m.store.mut.Unlock()
+	return nil
+}
+
+func (m *memoryObject) Abort() {
+	m.abortOnce.Do(func() {
+		m.store.mut.Lock()
+		m.store.aborted = append(m.store.aborted, m.path)
+		m.store.mut.Unlock()
+		close(m.abortedChan)
+	})
+}
+
+func (m *memoryObjects) opener() RollingObjectOpener {
+	return func(ctx context.Context, msg types.Message, index int) (RollingObject, error) {
+		return &memoryObject{
+			store:       m,
+			path:        string(msg.Get(index).Get()),
+			abortedChan: make(chan struct{}),
+		}, nil
+	}
+}
+
+func newTestRolling(t *testing.T, conf RollingConfig) (*Rolling, *memoryObjects) {
+	t.Helper()
+
+	store := &memoryObjects{committed: map[string]string{}}
+	r, err := NewRolling(conf, store.opener(), log.Noop())
+	require.NoError(t, err)
+	return r, store
+}
+
+func TestRollingConfigErrors(t *testing.T) {
+	conf := NewRollingConfig()
+	conf.Codec = "all-bytes"
+	_, err := NewRolling(conf, nil, log.Noop())
+	require.EqualError(t, err, "codec all-bytes cannot be used with rolling objects")
+
+	conf = NewRollingConfig()
+	conf.MaxSize = 0
+	conf.MaxAge = ""
+	_, err = NewRolling(conf, nil, log.Noop())
+	require.EqualError(t, err, "at least one of max_size, max_records or max_age must be set for rolling objects")
+
+	conf = NewRollingConfig()
+	conf.MaxInFlight = 0
+	_, err = NewRolling(conf, nil, log.Noop())
+	require.EqualError(t, err, "max_in_flight must be at least 1 for rolling objects")
+}
+
+func TestRollingMaxRecords(t *testing.T) {
+	conf := NewRollingConfig()
+	conf.MaxRecords = 3
+	conf.MaxAge = ""
+	r, store := newTestRolling(t, conf)
+
+	// A batch spanning two objects is only acknowledged once both have been
+	// committed.
+	done := make(chan error)
+	go func() {
+		done <- r.Write(context.Background(), message.New([][]byte{
+			[]byte("a"), []byte("b"), []byte("c"), []byte("d"),
+		}))
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("write should block until the object is committed: %v", err)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	require.NoError(t, r.Write(context.Background(), message.New([][]byte{
+		[]byte("e"), []byte("f"),
+	})))
+	require.NoError(t, <-done)
+
+	assert.Equal(t, map[string]string{
+		"a": "a\nb\nc\n",
+		"d": "d\ne\nf\n",
+	}, store.committed)
+}
+
+func TestRollingMaxInFlight(t *testing.T) {
+	conf := NewRollingConfig()
+	conf.MaxAge = "1h"
+	conf.MaxInFlight = 2
+	r, store := newTestRolling(t, conf)
+
+	// An object that has received a write from every write in flight is
+	// committed without waiting for max_age.
+	for _, pair := range [][]string{{"a", "b"}, {"c", "d"}} {
+		var wg sync.WaitGroup
+		for _, c := range pair {
+			c := c
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, r.Write(context.Background(), message.New([][]byte{[]byte(c)})))
+			}()
+		}
+		wg.Wait()
+	}
+
+	require.Len(t, store.committed, 2)
+	for _, v := range store.committed {
+		assert.Len(t, v, 4)
+	}
+
+	// With a single write in flight each write is committed to its own object.
+	conf.MaxInFlight = 1
+	r, store = newTestRolling(t, conf)
+	require.NoError(t, r.Write(context.Background(), message.New([][]byte{[]byte("a"), []byte("b")})))
+	require.NoError(t, r.Write(context.Background(), message.New([][]byte{[]byte("c")})))
+	assert.Equal(t, map[string]string{
+		"a": "a\nb\n",
+		"c": "c\n",
+	}, store.committed)
+}
+
+func TestRollingMaxAge(t *testing.T) {
+	conf := NewRollingConfig()
+	conf.Codec = "delim:,"
+	conf.MaxAge = "50ms"
+	r, store := newTestRolling(t, conf)
+
+	var wg sync.WaitGroup
+	for _, c := range []string{"a", "b"} {
+		c := c
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, r.Write(context.Background(), message.New([][]byte{[]byte(c)})))
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, store.committed, 1)
+	for _, v := range store.committed {
+		assert.Len(t, v, 4)
+	}
+}
+
+func TestRollingWriteError(t *testing.T) {
+	conf := NewRollingConfig()
+	conf.MaxRecords = 2
+	r, store := newTestRolling(t, conf)
+
+	store.failWrite = true
+	err := r.Write(context.Background(), message.New([][]byte{[]byte("a")}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nope")
+	assert.Equal(t, []string{"a"}, store.aborted)
+
+	store.failWrite = false
+	require.NoError(t, r.Write(context.Background(), message.New([][]byte{[]byte("b"), []byte("c")})))
+	assert.Equal(t, map[string]string{"b": "b\nc\n"}, store.committed)
+}
+
+func TestRollingClose(t *testing.T) {
+	conf := NewRollingConfig()
+	r, store := newTestRolling(t, conf)
+
+	done := make(chan error)
+	go func() {
+		done <- r.Write(context.Background(), message.New([][]byte{[]byte("a")}))
+	}()
+
+	<-time.After(time.Millisecond * 50)
+	require.NoError(t, r.Close(context.Background()))
+	require.NoError(t, <-done)
+	assert.Equal(t, map[string]string{"a": "a\n"}, store.committed)
+
+	assert.Equal(t, types.ErrTypeClosed, r.Write(context.Background(), message.New([][]byte{[]byte("b")})))
+}
+
+func TestRollingBlockedWrite(t *testing.T) {
+	conf := NewRollingConfig()
+	conf.MaxRecords = 1
+	r, store := newTestRolling(t, conf)
+	store.blockPath = "a"
+	store.blocked = make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- r.WriteKeyed(ctx, message.New([][]byte{[]byte("a")}), func(int) string {
+			return "a"
+		})
+	}()
+	select {
+	case <-store.blocked:
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+
+	// A write blocked on one object does not hold up writes to other objects.
+	require.NoError(t, r.WriteKeyed(context.Background(), message.New([][]byte{[]byte("b")}), func(int) string {
+		return "b"
+	}))
+	assert.Equal(t, map[string]string{"b": "b\n"}, store.committed)
+
+	// Cancelling the blocked write abandons its object.
+	cancel()
+	select {
+	case err := <-done:
+		require.Error(t, err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	assert.Equal(t, []string{"a"}, store.aborted)
+}
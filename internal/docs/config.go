@@ -86,6 +86,12 @@ func reservedFieldsByType(t Type) map[string]FieldSpec {
 	return m
 }
 
+// ReservedFieldsByType returns the fields that are common to all components of
+// a given type, such as the type and label fields.
+func ReservedFieldsByType(t Type) map[string]FieldSpec {
+	return reservedFieldsByType(t)
+}
+
 // TODO: V4 remove this as it's not needed.
 func refreshOldPlugins() {
 	plugins.FlushNameTypes(func(nt [2]string) {
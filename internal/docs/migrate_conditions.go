@@ -0,0 +1,173 @@
+package docs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TODO: V4 Remove this along with conditions.
+
+// conditionToBloblang attempts to convert a condition config into an
+// equivalent Bloblang query that can be used within a check field.
+func conditionToBloblang(node *yaml.Node) (string, error) {
+	node = unwrapDocumentNode(node)
+	if node.Kind != yaml.MappingNode {
+		return "", errors.New("expected object value")
+	}
+
+	var name string
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		if node.Content[i].Value == "type" {
+			name = node.Content[i+1].Value
+			break
+		}
+	}
+	if name == "" {
+		for i := 0; i < len(node.Content)-1; i += 2 {
+			if node.Content[i].Value != "type" {
+				name = node.Content[i].Value
+				break
+			}
+		}
+	}
+
+	var conf *yaml.Node
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		if node.Content[i].Value == name {
+			conf = node.Content[i+1]
+			break
+		}
+	}
+	if conf == nil {
+		return "", fmt.Errorf("condition type %v has no config", name)
+	}
+
+	switch name {
+	case "bloblang":
+		return conf.Value, nil
+	case "static":
+		var b bool
+		if err := conf.Decode(&b); err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(b), nil
+	case "processor_failed":
+		var part int
+		if err := optionalField(conf, "part", &part); err != nil {
+			return "", err
+		}
+		if part != 0 {
+			return "", errors.New("condition processor_failed with a part other than 0 cannot be converted")
+		}
+		return "errored()", nil
+	case "not":
+		inner, err := conditionToBloblang(conf)
+		if err != nil {
+			return "", err
+		}
+		return "!(" + inner + ")", nil
+	case "and", "or":
+		if conf.Kind != yaml.SequenceNode || len(conf.Content) == 0 {
+			return "", fmt.Errorf("condition %v expected a non-empty array", name)
+		}
+		op := " && "
+		if name == "or" {
+			op = " || "
+		}
+		var queries []string
+		for _, child := range conf.Content {
+			inner, err := conditionToBloblang(child)
+			if err != nil {
+				return "", err
+			}
+			queries = append(queries, "("+inner+")")
+		}
+		return strings.Join(queries, op), nil
+	case "text":
+		return textConditionToBloblang(conf)
+	case "metadata":
+		return metadataConditionToBloblang(conf)
+	}
+	return "", fmt.Errorf("condition type %v cannot be converted", name)
+}
+
+func optionalField(node *yaml.Node, key string, v interface{}) error {
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1].Decode(v)
+		}
+	}
+	return nil
+}
+
+func stringOperatorToBloblang(target, operator, arg string) (string, error) {
+	quoted := strconv.Quote(arg)
+	switch operator {
+	case "equals_cs":
+		return fmt.Sprintf("%v == %v", target, quoted), nil
+	case "equals":
+		return fmt.Sprintf("%v.lowercase() == %v", target, strconv.Quote(strings.ToLower(arg))), nil
+	case "contains_cs":
+		return fmt.Sprintf("%v.contains(%v)", target, quoted), nil
+	case "contains":
+		return fmt.Sprintf("%v.lowercase().contains(%v)", target, strconv.Quote(strings.ToLower(arg))), nil
+	case "prefix_cs":
+		return fmt.Sprintf("%v.has_prefix(%v)", target, quoted), nil
+	case "prefix":
+		return fmt.Sprintf("%v.lowercase().has_prefix(%v)", target, strconv.Quote(strings.ToLower(arg))), nil
+	case "suffix_cs":
+		return fmt.Sprintf("%v.has_suffix(%v)", target, quoted), nil
+	case "suffix":
+		return fmt.Sprintf("%v.lowercase().has_suffix(%v)", target, strconv.Quote(strings.ToLower(arg))), nil
+	case "regexp_partial":
+		return fmt.Sprintf("%v.re_match(%v)", target, quoted), nil
+	case "regexp_exact":
+		return fmt.Sprintf("%v.re_match(%v)", target, strconv.Quote("^(?:"+arg+")$")), nil
+	}
+	return "", fmt.Errorf("operator %v cannot be converted", operator)
+}
+
+func textConditionToBloblang(conf *yaml.Node) (string, error) {
+	operator, arg, part := "equals_cs", "", 0
+	if err := optionalField(conf, "operator", &operator); err != nil {
+		return "", err
+	}
+	if err := optionalField(conf, "arg", &arg); err != nil {
+		return "", fmt.Errorf("condition text arg cannot be converted: %w", err)
+	}
+	if err := optionalField(conf, "part", &part); err != nil {
+		return "", err
+	}
+	if part != 0 {
+		return "", errors.New("condition text with a part other than 0 cannot be converted")
+	}
+	return stringOperatorToBloblang("content().string()", operator, arg)
+}
+
+func metadataConditionToBloblang(conf *yaml.Node) (string, error) {
+	operator, key, arg := "equals_cs", "", ""
+	if err := optionalField(conf, "operator", &operator); err != nil {
+		return "", err
+	}
+	if err := optionalField(conf, "key", &key); err != nil {
+		return "", err
+	}
+	target := fmt.Sprintf("meta(%v)", strconv.Quote(key))
+	if operator == "exists" {
+		return target + " != null", nil
+	}
+	if err := optionalField(conf, "arg", &arg); err != nil {
+		return "", fmt.Errorf("condition metadata arg cannot be converted: %w", err)
+	}
+	switch operator {
+	case "has_prefix":
+		return stringOperatorToBloblang(target+".or(\"\")", "prefix_cs", arg)
+	case "equals_cs", "equals", "regexp_partial", "regexp_exact":
+		return stringOperatorToBloblang(target+".or(\"\")", operator, arg)
+	}
+	return "", fmt.Errorf("operator %v cannot be converted", operator)
+}
@@ -0,0 +1,399 @@
+package docs
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Migration describes a change made to a config in order to replace a
+// deprecated component or field with its modern equivalent. When a deprecated
+// component or field cannot be replaced automatically then Skipped is true, the
+// config is left unchanged and What describes why.
+type Migration struct {
+	Line    int
+	What    string
+	Skipped bool
+}
+
+func newMigration(line int, format string, args ...interface{}) Migration {
+	return Migration{Line: line, What: fmt.Sprintf(format, args...)}
+}
+
+func newSkippedMigration(line int, format string, args ...interface{}) Migration {
+	return Migration{Line: line, What: fmt.Sprintf(format, args...), Skipped: true}
+}
+
+// componentReplacements maps deprecated components to modern components that
+// support the same config fields with the same behaviour.
+var componentReplacements = map[Type]map[string]string{
+	TypeCache: {
+		"dynamodb": "aws_dynamodb",
+		"s3":       "aws_s3",
+	},
+	TypeInput: {
+		"kafka_balanced": "kafka",
+	},
+	TypeMetrics: {
+		"cloudwatch": "aws_cloudwatch",
+	},
+	TypeOutput: {
+		"blob_storage":     "azure_blob_storage",
+		"dynamodb":         "aws_dynamodb",
+		"kinesis":          "aws_kinesis",
+		"kinesis_firehose": "aws_kinesis_firehose",
+		"s3":               "aws_s3",
+		"sns":              "aws_sns",
+		"sqs":              "aws_sqs",
+		"table_storage":    "azure_table_storage",
+	},
+	TypeProcessor: {
+		"lambda":        "aws_lambda",
+		"process_batch": "for_each",
+	},
+}
+
+// componentMigrationFn rewrites the config of a deprecated component into a
+// modern component, and returns the name of the new component.
+type componentMigrationFn func(conf *yaml.Node) (newName string, newConf *yaml.Node, what string, err error)
+
+// componentMigrations are custom migrations for deprecated components where
+// the config of the replacement component differs.
+var componentMigrations = map[Type]map[string]componentMigrationFn{
+	TypeProcessor: {
+		"filter":       migrateFilterProc,
+		"filter_parts": migrateFilterProc,
+		"conditional":  migrateConditionalProc,
+	},
+}
+
+// fieldMigrationFn rewrites deprecated fields of a component config.
+type fieldMigrationFn func(conf *yaml.Node) []Migration
+
+// fieldMigrations are custom migrations for deprecated fields of components
+// where the replacement field differs.
+var fieldMigrations = map[Type]map[string]fieldMigrationFn{
+	TypeInput: {
+		"kafka": migrateKafkaInputTopic,
+	},
+}
+
+//------------------------------------------------------------------------------
+
+func mappingValue(node *yaml.Node, key string) (*yaml.Node, int) {
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1], i
+		}
+	}
+	return nil, -1
+}
+
+func removeMappingKey(node *yaml.Node, key string) {
+	if _, i := mappingValue(node, key); i >= 0 {
+		node.Content = append(node.Content[:i], node.Content[i+2:]...)
+	}
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func migrateFilterProc(conf *yaml.Node) (string, *yaml.Node, string, error) {
+	check, err := conditionToBloblang(conf)
+	if err != nil {
+		return "", nil, "", err
+	}
+	return "bloblang", scalarNode(fmt.Sprintf("root = if !(%v) { deleted() }", check)), "the resulting mapping deletes individual messages rather than entire batches", nil
+}
+
+func migrateConditionalProc(conf *yaml.Node) (string, *yaml.Node, string, error) {
+	condNode, _ := mappingValue(conf, "condition")
+	if condNode == nil {
+		return "", nil, "", fmt.Errorf("field condition is required")
+	}
+	check, err := conditionToBloblang(condNode)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	firstCase := &yaml.Node{Kind: yaml.MappingNode}
+	firstCase.Content = append(firstCase.Content, scalarNode("check"), scalarNode(check))
+	if procs, _ := mappingValue(conf, "processors"); procs != nil {
+		firstCase.Content = append(firstCase.Content, scalarNode("processors"), procs)
+	}
+
+	cases := &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{firstCase}}
+	if elseProcs, _ := mappingValue(conf, "else_processors"); elseProcs != nil && len(elseProcs.Content) > 0 {
+		elseCase := &yaml.Node{Kind: yaml.MappingNode}
+		elseCase.Content = append(elseCase.Content, scalarNode("processors"), elseProcs)
+		cases.Content = append(cases.Content, elseCase)
+	}
+	return "switch", cases, "the resulting cases are checked against individual messages rather than entire batches", nil
+}
+
+func migrateKafkaInputTopic(conf *yaml.Node) []Migration {
+	topicNode, _ := mappingValue(conf, "topic")
+	if topicNode == nil || topicNode.Value == "" {
+		return nil
+	}
+	if topicsNode, _ := mappingValue(conf, "topics"); topicsNode != nil && len(topicsNode.Content) > 0 {
+		return nil
+	}
+
+	topic := topicNode.Value
+	if partitionNode, _ := mappingValue(conf, "partition"); partitionNode != nil {
+		topic += ":" + partitionNode.Value
+	} else {
+		topic += ":0"
+	}
+
+	removeMappingKey(conf, "topics")
+	line := topicNode.Line
+	_, i := mappingValue(conf, "topic")
+	conf.Content[i] = scalarNode("topics")
+	conf.Content[i+1] = &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{scalarNode(topic)}}
+	removeMappingKey(conf, "partition")
+	return []Migration{newMigration(line, "fields topic and partition replaced with topics")}
+}
+
+//------------------------------------------------------------------------------
+
+// isDefaultYAML returns true if a node is equal to the default value of a
+// field.
+func (f FieldSpec) isDefaultYAML(node *yaml.Node) bool {
+	if f.Default == nil {
+		return false
+	}
+	var defaultNode yaml.Node
+	if err := defaultNode.Encode(*f.Default); err != nil {
+		return false
+	}
+	var defaultValue, value interface{}
+	if err := defaultNode.Decode(&defaultValue); err != nil {
+		return false
+	}
+	if err := node.Decode(&value); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(defaultValue, value)
+}
+
+// MigrateYAML takes a yaml.Node of a component config and rewrites deprecated
+// components and fields to their modern equivalents where it is possible to do
+// so without changing the behaviour of the config. A list of migrations is
+// returned, including deprecated components and fields that could not be
+// migrated automatically.
+func MigrateYAML(docsProvider Provider, cType Type, node *yaml.Node) []Migration {
+	if cType == "condition" {
+		return nil
+	}
+
+	node = unwrapDocumentNode(node)
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		return nil
+	}
+
+	var name string
+	var keys []string
+	typeNode, _ := mappingValue(node, "type")
+	if typeNode != nil {
+		name = typeNode.Value
+	} else {
+		for i := 0; i < len(node.Content)-1; i += 2 {
+			keys = append(keys, node.Content[i].Value)
+		}
+		var err error
+		if name, _, err = getInferenceCandidateFromList(docsProvider, cType, "", keys); err != nil {
+			return nil
+		}
+	}
+
+	cSpec, exists := GetDocs(docsProvider, name, cType)
+	if !exists {
+		return nil
+	}
+
+	var migrations []Migration
+	confNode, confIndex := mappingValue(node, name)
+	line := node.Line
+	if confIndex >= 0 {
+		line = node.Content[confIndex].Line
+	}
+
+	rename := func(newName string, newConf *yaml.Node) {
+		if typeNode != nil {
+			typeNode.Value = newName
+		}
+		if confIndex >= 0 {
+			node.Content[confIndex].Value = newName
+			node.Content[confIndex+1] = newConf
+		}
+		name = newName
+		confNode = newConf
+		cSpec, _ = GetDocs(docsProvider, newName, cType)
+	}
+
+	if fn, exists := componentMigrations[cType][name]; exists && confNode != nil {
+		newName, newConf, caveat, err := fn(confNode)
+		if err != nil {
+			migrations = append(migrations, newSkippedMigration(line, "deprecated %v %v could not be migrated: %v", cType, name, err))
+		} else {
+			migrations = append(migrations, newMigration(line, "deprecated %v %v replaced with %v, %v", cType, name, newName, caveat))
+			rename(newName, newConf)
+		}
+	} else if newName, exists := componentReplacements[cType][name]; exists {
+		newSpec, exists := GetDocs(docsProvider, newName, cType)
+		var unsupported []string
+		if confNode != nil && confNode.Kind == yaml.MappingNode && len(newSpec.Config.Children) > 0 {
+			for i := 0; i < len(confNode.Content)-1; i += 2 {
+				if _, ok := newSpec.Config.Children.find(confNode.Content[i].Value); !ok {
+					unsupported = append(unsupported, confNode.Content[i].Value)
+				}
+			}
+		}
+		switch {
+		case !exists:
+			migrations = append(migrations, newSkippedMigration(line, "deprecated %v %v could not be migrated as %v is not available", cType, name, newName))
+		case len(unsupported) > 0:
+			migrations = append(migrations, newSkippedMigration(line, "deprecated %v %v could not be migrated as fields %v are not supported by %v", cType, name, strings.Join(unsupported, ", "), newName))
+		default:
+			migrations = append(migrations, newMigration(line, "deprecated %v %v replaced with %v", cType, name, newName))
+			rename(newName, confNode)
+		}
+	} else if cSpec.Status == StatusDeprecated {
+		migrations = append(migrations, newSkippedMigration(line, "deprecated %v %v must be migrated manually", cType, name))
+	}
+
+	if confNode != nil {
+		if fn, exists := fieldMigrations[cType][name]; exists {
+			migrations = append(migrations, fn(confNode)...)
+		}
+		migrations = append(migrations, cSpec.Config.MigrateYAML(docsProvider, confNode)...)
+	}
+
+	reservedFields := reservedFieldsByType(cType)
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		if node.Content[i].Value == name || node.Content[i].Value == "type" {
+			continue
+		}
+		if spec, exists := reservedFields[node.Content[i].Value]; exists {
+			migrations = append(migrations, spec.MigrateYAML(docsProvider, node.Content[i+1])...)
+		}
+	}
+	return migrations
+}
+
+func (f FieldSpecs) find(name string) (FieldSpec, bool) {
+	for _, spec := range f {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return FieldSpec{}, false
+}
+
+// MigrateYAML rewrites deprecated components and fields within a yaml.Node of
+// a field to their modern equivalents.
+func (f FieldSpec) MigrateYAML(docsProvider Provider, node *yaml.Node) []Migration {
+	node = unwrapDocumentNode(node)
+
+	var migrations []Migration
+	migrate := func(n *yaml.Node) {
+		if coreType, isCore := f.Type.IsCoreComponent(); isCore {
+			migrations = append(migrations, MigrateYAML(docsProvider, coreType, n)...)
+		} else if len(f.Children) > 0 {
+			migrations = append(migrations, f.Children.MigrateYAML(docsProvider, n)...)
+		}
+	}
+
+	switch f.Kind {
+	case Kind2DArray:
+		for i := 0; i < len(node.Content); i++ {
+			for j := 0; j < len(node.Content[i].Content); j++ {
+				migrate(node.Content[i].Content[j])
+			}
+		}
+	case KindArray:
+		for i := 0; i < len(node.Content); i++ {
+			migrate(node.Content[i])
+		}
+	case KindMap:
+		for i := 0; i < len(node.Content)-1; i += 2 {
+			migrate(node.Content[i+1])
+		}
+	default:
+		migrate(node)
+	}
+	return migrations
+}
+
+// MigrateYAML rewrites deprecated components and fields within a yaml.Node of
+// an object to their modern equivalents. Deprecated condition fields are
+// replaced with a Bloblang check field where one exists, and deprecated fields
+// that are set to their default value are removed.
+func (f FieldSpecs) MigrateYAML(docsProvider Provider, node *yaml.Node) []Migration {
+	node = unwrapDocumentNode(node)
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var migrations []Migration
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		if spec, exists := f.find(node.Content[i].Value); exists {
+			migrations = append(migrations, spec.MigrateYAML(docsProvider, node.Content[i+1])...)
+		}
+	}
+
+	// Deprecated conditions are replaced with a Bloblang check when the check
+	// field is supported and hasn't already been set.
+	var condKey, check string
+	var checkErr error
+	if _, hasCheck := f.find("check"); hasCheck {
+		if checkNode, _ := mappingValue(node, "check"); checkNode == nil || checkNode.Value == "" {
+			for i := 0; i < len(node.Content)-1; i += 2 {
+				if spec, exists := f.find(node.Content[i].Value); exists && spec.IsDeprecated && spec.Type == FieldTypeCondition {
+					condKey = spec.Name
+					check, checkErr = conditionToBloblang(node.Content[i+1])
+					break
+				}
+			}
+		}
+	}
+
+	var newNodes []*yaml.Node
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		if keyNode.Value == "check" && condKey != "" && checkErr == nil {
+			continue
+		}
+
+		spec, exists := f.find(keyNode.Value)
+		if !exists || !spec.IsDeprecated {
+			newNodes = append(newNodes, keyNode, valueNode)
+			continue
+		}
+
+		if keyNode.Value == condKey {
+			if checkErr != nil {
+				migrations = append(migrations, newSkippedMigration(keyNode.Line, "deprecated field %v could not be migrated: %v", keyNode.Value, checkErr))
+				newNodes = append(newNodes, keyNode, valueNode)
+				continue
+			}
+			migrations = append(migrations, newMigration(keyNode.Line, "deprecated field %v replaced with check", keyNode.Value))
+			newNodes = append(newNodes, scalarNode("check"), scalarNode(check))
+			continue
+		}
+
+		if spec.isDefaultYAML(valueNode) {
+			migrations = append(migrations, newMigration(keyNode.Line, "deprecated field %v removed as it is set to its default value", keyNode.Value))
+			continue
+		}
+		migrations = append(migrations, newSkippedMigration(keyNode.Line, "deprecated field %v must be migrated manually", keyNode.Value))
+		newNodes = append(newNodes, keyNode, valueNode)
+	}
+	node.Content = newNodes
+	return migrations
+}
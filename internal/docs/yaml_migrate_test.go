@@ -0,0 +1,258 @@
+package docs_test
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMigrateYAML(t *testing.T) {
+	mockProv := docs.NewMappedDocsProvider()
+	mockProv.RegisterDocs(docs.ComponentSpec{
+		Name:   "lambda",
+		Type:   docs.TypeProcessor,
+		Status: docs.StatusDeprecated,
+		Config: docs.FieldComponent().WithChildren(
+			docs.FieldString("function", "").HasDefault(""),
+			docs.FieldString("role", "").HasDefault(""),
+		),
+	})
+	mockProv.RegisterDocs(docs.ComponentSpec{
+		Name: "aws_lambda",
+		Type: docs.TypeProcessor,
+		Config: docs.FieldComponent().WithChildren(
+			docs.FieldString("function", "").HasDefault(""),
+		),
+	})
+	mockProv.RegisterDocs(docs.ComponentSpec{
+		Name:   "filter_parts",
+		Type:   docs.TypeProcessor,
+		Status: docs.StatusDeprecated,
+		Config: docs.FieldComponent().HasType(docs.FieldTypeCondition),
+	})
+	mockProv.RegisterDocs(docs.ComponentSpec{
+		Name:   "jmespath",
+		Type:   docs.TypeProcessor,
+		Status: docs.StatusDeprecated,
+		Config: docs.FieldComponent().WithChildren(
+			docs.FieldString("query", "").HasDefault(""),
+		),
+	})
+	mockProv.RegisterDocs(docs.ComponentSpec{
+		Name:   "bloblang",
+		Type:   docs.TypeProcessor,
+		Config: docs.FieldBloblang("", ""),
+	})
+	mockProv.RegisterDocs(docs.ComponentSpec{
+		Name: "switch",
+		Type: docs.TypeProcessor,
+		Config: docs.FieldComponent().Array().WithChildren(
+			docs.FieldDeprecated("condition").HasType(docs.FieldTypeCondition),
+			docs.FieldBloblang("check", "").HasDefault(""),
+			docs.FieldCommon("processors", "").Array().HasType(docs.FieldTypeProcessor).HasDefault([]interface{}{}),
+		),
+	})
+	mockProv.RegisterDocs(docs.ComponentSpec{
+		Name: "kafka",
+		Type: docs.TypeInput,
+		Config: docs.FieldComponent().WithChildren(
+			docs.FieldString("topics", "").Array().HasDefault([]interface{}{}),
+			docs.FieldDeprecated("topic").HasType(docs.FieldTypeString).HasDefault("benthos_stream"),
+			docs.FieldDeprecated("partition").HasType(docs.FieldTypeInt).HasDefault(0),
+			docs.FieldDeprecated("max_batch_count").HasType(docs.FieldTypeInt).HasDefault(1),
+		),
+	})
+
+	spec := docs.FieldSpecs{
+		docs.FieldCommon("input", "").HasType(docs.FieldTypeInput),
+		docs.FieldCommon("processors", "").Array().HasType(docs.FieldTypeProcessor),
+	}
+
+	tests := []struct {
+		name       string
+		input      string
+		output     string
+		migrations []docs.Migration
+	}{
+		{
+			name: "rename component",
+			input: `
+processors:
+  - lambda:
+      function: foo
+`,
+			output: `
+processors:
+  - aws_lambda:
+      function: foo
+`,
+			migrations: []docs.Migration{
+				{Line: 3, What: "deprecated processor lambda replaced with aws_lambda"},
+			},
+		},
+		{
+			name: "rename component with type",
+			input: `
+processors:
+  - type: lambda
+    lambda:
+      function: foo
+`,
+			output: `
+processors:
+  - type: aws_lambda
+    aws_lambda:
+      function: foo
+`,
+			migrations: []docs.Migration{
+				{Line: 4, What: "deprecated processor lambda replaced with aws_lambda"},
+			},
+		},
+		{
+			name: "rename component unsupported fields",
+			input: `
+processors:
+  - lambda:
+      function: foo
+      role: bar
+`,
+			output: `
+processors:
+  - lambda:
+      function: foo
+      role: bar
+`,
+			migrations: []docs.Migration{
+				{Line: 3, What: "deprecated processor lambda could not be migrated as fields role are not supported by aws_lambda", Skipped: true},
+			},
+		},
+		{
+			name: "deprecated component manual",
+			input: `
+processors:
+  - jmespath:
+      query: foo
+`,
+			output: `
+processors:
+  - jmespath:
+      query: foo
+`,
+			migrations: []docs.Migration{
+				{Line: 3, What: "deprecated processor jmespath must be migrated manually", Skipped: true},
+			},
+		},
+		{
+			name: "filter parts to bloblang",
+			input: `
+processors:
+  - filter_parts:
+      text:
+        operator: contains
+        arg: FOO
+`,
+			output: `
+processors:
+  - bloblang: root = if !(content().string().lowercase().contains("foo")) { deleted() }
+`,
+			migrations: []docs.Migration{
+				{Line: 3, What: "deprecated processor filter_parts replaced with bloblang, the resulting mapping deletes individual messages rather than entire batches"},
+			},
+		},
+		{
+			name: "switch conditions to checks",
+			input: `
+processors:
+  - switch:
+      - condition:
+          and:
+            - metadata:
+                operator: exists
+                key: foo
+            - not:
+                bloblang: this.bar > 10
+        processors:
+          - lambda:
+              function: foo
+      - condition:
+          jmespath:
+            query: foo
+`,
+			output: `
+processors:
+  - switch:
+      - check: (meta("foo") != null) && (!(this.bar > 10))
+        processors:
+          - aws_lambda:
+              function: foo
+      - condition:
+          jmespath:
+            query: foo
+`,
+			migrations: []docs.Migration{
+				{Line: 12, What: "deprecated processor lambda replaced with aws_lambda"},
+				{Line: 4, What: "deprecated field condition replaced with check"},
+				{Line: 14, What: "deprecated field condition could not be migrated: condition type jmespath cannot be converted", Skipped: true},
+			},
+		},
+		{
+			name: "kafka topic and defaults",
+			input: `
+input:
+  kafka:
+    topic: foo
+    partition: 3
+    max_batch_count: 1
+`,
+			output: `
+input:
+  kafka:
+    topics:
+      - foo:3
+`,
+			migrations: []docs.Migration{
+				{Line: 4, What: "fields topic and partition replaced with topics"},
+				{Line: 6, What: "deprecated field max_batch_count removed as it is set to its default value"},
+			},
+		},
+		{
+			name: "kafka deprecated field manual",
+			input: `
+input:
+  kafka:
+    topics: [ foo ]
+    max_batch_count: 10
+`,
+			output: `
+input:
+  kafka:
+    topics: [foo]
+    max_batch_count: 10
+`,
+			migrations: []docs.Migration{
+				{Line: 5, What: "deprecated field max_batch_count must be migrated manually", Skipped: true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			var node yaml.Node
+			require.NoError(t, yaml.Unmarshal([]byte(test.input), &node))
+
+			assert.Equal(t, test.migrations, spec.MigrateYAML(mockProv, &node))
+
+			var expected yaml.Node
+			require.NoError(t, yaml.Unmarshal([]byte(test.output), &expected))
+
+			var actualV, expectedV interface{}
+			require.NoError(t, node.Decode(&actualV))
+			require.NoError(t, expected.Decode(&expectedV))
+			assert.Equal(t, expectedV, actualV)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
@@ -17,6 +18,7 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
 	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
@@ -40,6 +42,14 @@ type Client struct {
 	headers map[string]*field.Expression
 	host    *field.Expression
 
+	requestHook func(*http.Request)
+
+	proxyURL         *field.Expression
+	retryWhen        *mapping.Executor
+	hedgeDelay       time.Duration
+	hedgeMaxRequests int
+	maxBackoff       time.Duration
+
 	conf          client.Config
 	retryThrottle *throttle.Type
 
@@ -57,6 +67,7 @@ type Client struct {
 	mLimitErr      metrics.StatCounter
 	mSucc          metrics.StatCounter
 	mLatency       metrics.StatTimer
+	mHedged        metrics.StatCounter
 
 	mCodes   map[int]metrics.StatCounter
 	codesMut sync.RWMutex
@@ -124,6 +135,23 @@ func NewClient(conf client.Config, opts ...func(*Client)) (*Client, error) {
 		}
 	}
 
+	if err := h.initTransport(); err != nil {
+		return nil, err
+	}
+
+	if tout := conf.Hedging.Delay; len(tout) > 0 {
+		var err error
+		if h.hedgeDelay, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse hedging delay string: %v", err)
+		}
+		if _, exists := idempotentVerbs[strings.ToUpper(conf.Verb)]; !exists {
+			return nil, fmt.Errorf("hedging cannot be used with non-idempotent verb: %v", conf.Verb)
+		}
+		if h.hedgeMaxRequests = conf.Hedging.MaxRequests; h.hedgeMaxRequests < 1 {
+			return nil, errors.New("hedging max_requests must be greater than zero")
+		}
+	}
+
 	for _, c := range conf.BackoffOn {
 		h.backoffOn[c] = struct{}{}
 	}
@@ -143,6 +171,17 @@ func NewClient(conf client.Config, opts ...func(*Client)) (*Client, error) {
 		return nil, fmt.Errorf("failed to parse URL expression: %v", err)
 	}
 
+	if conf.Proxy.URL != "" {
+		if h.proxyURL, err = interop.NewBloblangField(h.mgr, conf.Proxy.URL); err != nil {
+			return nil, fmt.Errorf("failed to parse proxy url expression: %v", err)
+		}
+	}
+	if conf.RetryWhen != "" {
+		if h.retryWhen, err = interop.NewBloblangMapping(h.mgr, conf.RetryWhen); err != nil {
+			return nil, fmt.Errorf("failed to parse retry_when query: %v", err)
+		}
+	}
+
 	for k, v := range conf.Headers {
 		if strings.EqualFold(k, "host") {
 			if h.host, err = interop.NewBloblangField(h.mgr, v); err != nil {
@@ -165,9 +204,10 @@ func NewClient(conf client.Config, opts ...func(*Client)) (*Client, error) {
 	h.mLimitErr = h.stats.GetCounter("rate_limit.error")
 	h.mLatency = h.stats.GetTimer("latency")
 	h.mSucc = h.stats.GetCounter("success")
+	h.mHedged = h.stats.GetCounter("hedged")
 	h.mCodes = map[int]metrics.StatCounter{}
 
-	var retry, maxBackoff time.Duration
+	var retry time.Duration
 	if tout := conf.Retry; len(tout) > 0 {
 		var err error
 		if retry, err = time.ParseDuration(tout); err != nil {
@@ -176,7 +216,7 @@ func NewClient(conf client.Config, opts ...func(*Client)) (*Client, error) {
 	}
 	if tout := conf.MaxBackoff; len(tout) > 0 {
 		var err error
-		if maxBackoff, err = time.ParseDuration(tout); err != nil {
+		if h.maxBackoff, err = time.ParseDuration(tout); err != nil {
 			return nil, fmt.Errorf("failed to parse max backoff duration string: %v", err)
 		}
 	}
@@ -190,7 +230,7 @@ func NewClient(conf client.Config, opts ...func(*Client)) (*Client, error) {
 	h.retryThrottle = throttle.New(
 		throttle.OptMaxUnthrottledRetries(0),
 		throttle.OptThrottlePeriod(retry),
-		throttle.OptMaxExponentPeriod(maxBackoff),
+		throttle.OptMaxExponentPeriod(h.maxBackoff),
 	)
 
 	return &h, nil
@@ -227,6 +267,14 @@ func OptSetRoundTripper(rt http.RoundTripper) func(*Client) {
 	}
 }
 
+// OptSetRequestHook sets a func that is called with each request before it is
+// signed and sent, allowing headers to be added dynamically.
+func OptSetRequestHook(fn func(*http.Request)) func(*Client) {
+	return func(t *Client) {
+		t.requestHook = fn
+	}
+}
+
 //------------------------------------------------------------------------------
 
 func (h *Client) incrCode(code int) {
@@ -316,8 +364,13 @@ func (h *Client) CreateRequest(sendMsg, refMsg types.Message) (req *http.Request
 		body = buf
 	}
 
+	ctx, err := h.resolveProxy(context.Background(), refMsg)
+	if err != nil {
+		return
+	}
+
 	url := h.url.String(0, refMsg)
-	if req, err = http.NewRequest(h.conf.Verb, url, body); err != nil {
+	if req, err = http.NewRequestWithContext(ctx, h.conf.Verb, url, body); err != nil {
 		return
 	}
 
@@ -331,6 +384,9 @@ func (h *Client) CreateRequest(sendMsg, refMsg types.Message) (req *http.Request
 		req.Header.Del("Content-Type")
 		req.Header.Add("Content-Type", overrideContentType)
 	}
+	if h.requestHook != nil {
+		h.requestHook(req)
+	}
 
 	err = h.conf.Config.Sign(req)
 	return
@@ -447,6 +503,108 @@ func (h *Client) checkStatus(code int) (succeeded bool, retStrat retryStrategy)
 	return true, noRetry
 }
 
+// do performs a request, hedging it when configured to do so, where the
+// messages are used to create any hedged requests.
+func (h *Client) do(ctx context.Context, req *http.Request, sendMsg, refMsg types.Message) (res *http.Response, err error) {
+	if h.hedgeDelay > 0 && h.hedgeMaxRequests > 1 {
+		res, err = h.doHedged(ctx, req, func() (*http.Request, error) {
+			return h.CreateRequest(sendMsg, refMsg)
+		})
+	} else {
+		res, err = h.client.Do(req.WithContext(withRequestProxy(ctx, req.Context())))
+	}
+	if err != nil {
+		if err, ok := err.(net.Error); ok && err.Timeout() {
+			h.mErrReqTimeout.Incr(1)
+		}
+	}
+	return
+}
+
+// checkResponse determines whether a response indicates that the request
+// succeeded, and if not returns an error along with the retry strategy and
+// the period requested by the server to wait before retrying, if any.
+func (h *Client) checkResponse(res *http.Response) (retryStrategy, time.Duration, error) {
+	h.incrCode(res.StatusCode)
+
+	retryStrat := noRetry
+	if h.retryWhen != nil {
+		retry, err := h.queryRetryWhen(res)
+		if err != nil {
+			h.log.Errorf("Failed to execute retry_when query: %v\n", err)
+		} else if retry {
+			retryStrat = retryBackoff
+		}
+	}
+	if retryStrat == noRetry {
+		var resolved bool
+		if resolved, retryStrat = h.checkStatus(res.StatusCode); resolved {
+			return noRetry, 0, nil
+		}
+	}
+
+	err := UnexpectedErr(res)
+	if res.Body != nil {
+		res.Body.Close()
+	}
+	return retryStrat, h.retryAfter(res), err
+}
+
+// queryRetryWhen executes the retry_when query on a response, which is read in
+// full and replaced so that it can be consumed again.
+func (h *Client) queryRetryWhen(res *http.Response) (bool, error) {
+	var body []byte
+	if res.Body != nil {
+		var err error
+		body, err = io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return false, err
+		}
+		res.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	part := message.NewPart(body)
+	meta := part.Metadata()
+	for k, values := range res.Header {
+		if len(values) > 0 {
+			meta.Set(strings.ToLower(k), values[0])
+		}
+	}
+	meta.Set("http_status_code", strconv.Itoa(res.StatusCode))
+
+	msg := message.New(nil)
+	msg.Append(part)
+	return h.retryWhen.QueryPart(0, msg)
+}
+
+// retryAfter returns the period specified by the Retry-After header of a
+// response, limited to the maximum retry backoff, or zero when the header
+// should not be respected.
+func (h *Client) retryAfter(res *http.Response) time.Duration {
+	if !h.conf.RespectRetryAfter {
+		return 0
+	}
+	v := strings.TrimSpace(res.Header.Get("Retry-After"))
+	if v == "" {
+		return 0
+	}
+
+	var period time.Duration
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		period = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil {
+		period = time.Until(t)
+	}
+	if period <= 0 {
+		return 0
+	}
+	if h.maxBackoff > 0 && period > h.maxBackoff {
+		period = h.maxBackoff
+	}
+	return period
+}
+
 // SendToResponse attempts to create an HTTP request from a provided message,
 // performs it, and then returns the *http.Response, allowing the raw response
 // to be consumed.
@@ -495,25 +653,13 @@ func (h *Client) SendToResponse(ctx context.Context, sendMsg, refMsg types.Messa
 		return nil, types.ErrTypeClosed
 	}
 
-	rateLimited := false
+	var retryStrat retryStrategy
+	var retryAfter time.Duration
 	numRetries := h.conf.NumRetries
 
-	res, err = h.client.Do(req.WithContext(ctx))
-	if err != nil {
-		if err, ok := err.(net.Error); ok && err.Timeout() {
-			h.mErrReqTimeout.Incr(1)
-		}
-	} else {
-		h.incrCode(res.StatusCode)
-		if resolved, retryStrat := h.checkStatus(res.StatusCode); !resolved {
-			rateLimited = retryStrat == retryBackoff
-			if retryStrat == noRetry {
-				numRetries = 0
-			}
-			err = UnexpectedErr(res)
-			if res.Body != nil {
-				res.Body.Close()
-			}
+	if res, err = h.do(ctx, req, sendMsg, refMsg); err == nil {
+		if retryStrat, retryAfter, err = h.checkResponse(res); retryStrat == noRetry {
+			numRetries = 0
 		}
 	}
 
@@ -523,7 +669,13 @@ func (h *Client) SendToResponse(ctx context.Context, sendMsg, refMsg types.Messa
 		if req, err = h.CreateRequest(sendMsg, refMsg); err != nil {
 			continue
 		}
-		if rateLimited {
+		if retryAfter > 0 {
+			select {
+			case <-time.After(retryAfter):
+			case <-ctx.Done():
+				return nil, types.ErrTypeClosed
+			}
+		} else if retryStrat == retryBackoff {
 			if !h.retryThrottle.ExponentialRetryWithContext(ctx) {
 				return nil, types.ErrTypeClosed
 			}
@@ -535,21 +687,11 @@ func (h *Client) SendToResponse(ctx context.Context, sendMsg, refMsg types.Messa
 		if !h.waitForAccess(ctx) {
 			return nil, types.ErrTypeClosed
 		}
-		rateLimited = false
-		if res, err = h.client.Do(req.WithContext(ctx)); err == nil {
-			h.incrCode(res.StatusCode)
-			if resolved, retryStrat := h.checkStatus(res.StatusCode); !resolved {
-				rateLimited = retryStrat == retryBackoff
-				if retryStrat == noRetry {
-					j = 0
-				}
-				err = UnexpectedErr(res)
-				if res.Body != nil {
-					res.Body.Close()
-				}
+		retryStrat, retryAfter = retryLinear, 0
+		if res, err = h.do(ctx, req, sendMsg, refMsg); err == nil {
+			if retryStrat, retryAfter, err = h.checkResponse(res); retryStrat == noRetry {
+				j = 0
 			}
-		} else if err, ok := err.(net.Error); ok && err.Timeout() {
-			h.mErrReqTimeout.Incr(1)
 		}
 		i++
 	}
@@ -23,6 +23,8 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/util/http/client"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 //------------------------------------------------------------------------------
@@ -455,3 +457,258 @@ func TestHTTPClientReceiveMultipartWithHeaders(t *testing.T) {
 		assert.Equal(t, "201", resMsg.Get(1).Metadata().Get("http_status_code"))
 	}
 }
+
+func TestHTTPClientRetryWhen(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddUint32(&reqCount, 1) < 3 {
+			w.Header().Set("X-Status", "pending")
+			_, _ = w.Write([]byte(`{"status":"pending"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"done"}`))
+	}))
+	defer ts.Close()
+
+	conf := client.NewConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.Retry = "1ms"
+	conf.NumRetries = 3
+	conf.RetryWhen = `this.status == "pending" && meta("x-status") == "pending" && meta("http_status_code") == "200"`
+
+	h, err := NewClient(conf)
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	out := message.New([][]byte{[]byte("test")})
+	resMsg, err := h.Send(context.Background(), out, out)
+	require.NoError(t, err)
+	assert.Equal(t, `{"status":"done"}`, string(resMsg.Get(0).Get()))
+	assert.Equal(t, uint32(3), atomic.LoadUint32(&reqCount))
+
+	conf.NumRetries = 1
+	atomic.StoreUint32(&reqCount, 0)
+
+	h, err = NewClient(conf)
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	_, err = h.Send(context.Background(), out, out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "pending")
+	assert.Equal(t, uint32(2), atomic.LoadUint32(&reqCount))
+}
+
+func TestHTTPClientRetryAfter(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddUint32(&reqCount, 1) == 1 {
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "slow down", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	conf := client.NewConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.Retry = "1ms"
+	conf.MaxBackoff = "200ms"
+	conf.NumRetries = 1
+	conf.RespectRetryAfter = true
+
+	h, err := NewClient(conf)
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	startedAt := time.Now()
+	out := message.New([][]byte{[]byte("test")})
+	resMsg, err := h.Send(context.Background(), out, out)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(resMsg.Get(0).Get()))
+	assert.GreaterOrEqual(t, int64(time.Since(startedAt)), int64(200*time.Millisecond))
+	assert.Less(t, int64(time.Since(startedAt)), int64(10*time.Second))
+}
+
+func TestHTTPClientHedging(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddUint32(&reqCount, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second * 10):
+			}
+			return
+		}
+		_, _ = w.Write([]byte("hedged"))
+	}))
+	defer ts.Close()
+
+	conf := client.NewConfig()
+	conf.URL = ts.URL + "/testget"
+	conf.Verb = "GET"
+	conf.Hedging.Delay = "10ms"
+	conf.Hedging.MaxRequests = 2
+
+	h, err := NewClient(conf)
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	startedAt := time.Now()
+	resMsg, err := h.Send(context.Background(), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "hedged", string(resMsg.Get(0).Get()))
+	assert.Less(t, int64(time.Since(startedAt)), int64(5*time.Second))
+	assert.Equal(t, uint32(2), atomic.LoadUint32(&reqCount))
+
+	conf.Verb = "POST"
+	_, err = NewClient(conf)
+	require.Error(t, err)
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	var proxied []string
+	var proxyAuth []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		proxyAuth = append(proxyAuth, r.Header.Get("Proxy-Authorization"))
+		_, _ = w.Write([]byte("from proxy"))
+	}))
+	defer proxy.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("direct"))
+	}))
+	defer ts.Close()
+
+	conf := client.NewConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.Proxy.URL = `${! meta("proxy") }`
+	conf.Proxy.Username = "foo"
+	conf.Proxy.Password = "bar"
+
+	h, err := NewClient(conf)
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	out := message.New([][]byte{[]byte("test")})
+	out.Get(0).Metadata().Set("proxy", proxy.URL)
+	resMsg, err := h.Send(context.Background(), out, out)
+	require.NoError(t, err)
+	assert.Equal(t, "from proxy", string(resMsg.Get(0).Get()))
+
+	out = message.New([][]byte{[]byte("test")})
+	resMsg, err = h.Send(context.Background(), out, out)
+	require.NoError(t, err)
+	assert.Equal(t, "direct", string(resMsg.Get(0).Get()))
+
+	assert.Equal(t, []string{ts.URL + "/testpost"}, proxied)
+	assert.Equal(t, []string{"Basic Zm9vOmJhcg=="}, proxyAuth)
+
+	conf.ProxyURL = proxy.URL
+	_, err = NewClient(conf)
+	require.Error(t, err)
+}
+
+func TestHTTPClientHTTP2(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	conf := client.NewConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.TLS.Enabled = true
+	conf.TLS.InsecureSkipVerify = true
+	conf.HTTP2.Enabled = true
+
+	h, err := NewClient(conf)
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	resMsg, err := h.Send(context.Background(), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/2.0", string(resMsg.Get(0).Get()))
+}
+
+func TestHTTPClientHTTP2Default(t *testing.T) {
+	h, err := NewClient(client.NewConfig())
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	// The transport is left as it is unless HTTP/2 is enabled.
+	assert.Nil(t, h.client.Transport)
+
+	conf := client.NewConfig()
+	conf.ProxyURL = "http://localhost:3128"
+	h, err = NewClient(conf)
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+	assert.False(t, h.client.Transport.(*http.Transport).ForceAttemptHTTP2)
+
+	conf.HTTP2.Enabled = true
+	h, err = NewClient(conf)
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+	assert.True(t, h.client.Transport.(*http.Transport).ForceAttemptHTTP2)
+}
+
+func TestHTTPClientHostTLS(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	tsA := httptest.NewTLSServer(handler)
+	defer tsA.Close()
+	tsB := httptest.NewTLSServer(handler)
+	defer tsB.Close()
+
+	// Only the host of server A skips verification of its self signed
+	// certificate.
+	conf := client.NewConfig()
+	conf.URL = `${! meta("url") }`
+	conf.NumRetries = 0
+	hostConf := client.NewHostTLSConfig()
+	hostConf.Host = strings.TrimPrefix(tsA.URL, "https://")
+	hostConf.TLS.Enabled = true
+	hostConf.TLS.InsecureSkipVerify = true
+	conf.HostTLS = append(conf.HostTLS, hostConf)
+
+	h, err := NewClient(conf)
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	send := func(url string) error {
+		msg := message.New([][]byte{[]byte("hello")})
+		msg.Get(0).Metadata().Set("url", url)
+		_, err := h.Send(context.Background(), msg, msg)
+		return err
+	}
+	require.NoError(t, send(tsA.URL))
+	require.Error(t, send(tsB.URL))
+
+	conf.HostTLS = []client.HostTLSConfig{client.NewHostTLSConfig()}
+	_, err = NewClient(conf)
+	require.EqualError(t, err, "host_tls 0: host must not be empty")
+}
+
+func TestHTTPClientH2C(t *testing.T) {
+	ts := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}), &http2.Server{}))
+	defer ts.Close()
+
+	conf := client.NewConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.HTTP2.H2C = true
+
+	h, err := NewClient(conf)
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	resMsg, err := h.Send(context.Background(), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/2.0", string(resMsg.Get(0).Get()))
+}
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// idempotentVerbs are the verbs for which requests may be hedged, as sending a
+// request more than once has the same effect as sending it once.
+var idempotentVerbs = map[string]struct{}{
+	"GET":     {},
+	"HEAD":    {},
+	"OPTIONS": {},
+	"TRACE":   {},
+	"PUT":     {},
+	"DELETE":  {},
+}
+
+// cancelOnClose cancels the context of a request once its response body has
+// been closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel func()
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+type hedgeResult struct {
+	index int
+	res   *http.Response
+	err   error
+}
+
+// doHedged sends a request and, each time a response is not received within
+// the hedging delay, sends another created with newReq until the maximum
+// number of requests is reached. The first response received is returned and
+// all other requests are cancelled. An error is returned only when all
+// requests fail.
+func (h *Client) doHedged(ctx context.Context, req *http.Request, newReq func() (*http.Request, error)) (*http.Response, error) {
+	results := make(chan hedgeResult, h.hedgeMaxRequests)
+	var cancels []func()
+	send := func(req *http.Request) {
+		rctx, cancel := context.WithCancel(withRequestProxy(ctx, req.Context()))
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			res, err := h.client.Do(req.WithContext(rctx))
+			results <- hedgeResult{index: index, res: res, err: err}
+		}()
+	}
+
+	send(req)
+	sent, pending := 1, 1
+
+	timer := time.NewTimer(h.hedgeDelay)
+	defer timer.Stop()
+
+	var err error
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if r, rerr := newReq(); rerr != nil {
+				h.log.Errorf("Failed to create hedged request: %v\n", rerr)
+			} else {
+				h.mHedged.Incr(1)
+				send(r)
+				pending++
+			}
+			if sent++; sent < h.hedgeMaxRequests {
+				timer.Reset(h.hedgeDelay)
+			}
+		case result := <-results:
+			pending--
+			if result.err != nil {
+				cancels[result.index]()
+				err = result.err
+				continue
+			}
+			for i, cancel := range cancels {
+				if i != result.index {
+					cancel()
+				}
+			}
+			go func(pending int) {
+				for ; pending > 0; pending-- {
+					if loser := <-results; loser.err == nil && loser.res.Body != nil {
+						loser.res.Body.Close()
+					}
+				}
+			}(pending)
+			result.res.Body = &cancelOnClose{ReadCloser: result.res.Body, cancel: cancels[result.index]}
+			return result.res, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,230 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"golang.org/x/net/http2"
+)
+
+type proxyCtxKey struct{}
+
+// requestProxy holds the proxy URL determined for a request within the context
+// of the request, where a nil URL indicates that no proxy should be used.
+type requestProxy struct {
+	url *url.URL
+}
+
+// withRequestProxy returns a copy of a context that carries the proxy of a
+// request from another context, if any, as the proxy is determined whilst the
+// request is created but the request is sent with a different context.
+func withRequestProxy(ctx, from context.Context) context.Context {
+	if p, ok := from.Value(proxyCtxKey{}).(requestProxy); ok {
+		return context.WithValue(ctx, proxyCtxKey{}, p)
+	}
+	return ctx
+}
+
+// proxyFunc returns a func that selects the proxy of a request from its
+// context, falling back to another proxy func for requests without one.
+func proxyFunc(fallback func(*http.Request) (*url.URL, error)) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if p, ok := req.Context().Value(proxyCtxKey{}).(requestProxy); ok {
+			return p.url, nil
+		}
+		if fallback == nil {
+			return nil, nil
+		}
+		return fallback(req)
+	}
+}
+
+// baseTransport returns the *http.Transport of the client, creating a clone of
+// the default transport if the client does not yet have one.
+func (h *Client) baseTransport() (*http.Transport, error) {
+	if h.client.Transport == nil {
+		if c, ok := http.DefaultTransport.(*http.Transport); ok {
+			h.client.Transport = c.Clone()
+		} else {
+			h.client.Transport = &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+			}
+		}
+	}
+	tr, ok := h.client.Transport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("unexpected transport type %T", h.client.Transport)
+	}
+	return tr, nil
+}
+
+// initTransport configures the transport of the client for the proxy, HTTP/2
+// and host TLS fields.
+func (h *Client) initTransport() error {
+	if h.conf.Proxy.URL != "" {
+		if h.conf.ProxyURL != "" {
+			return errors.New("cannot specify both proxy_url and proxy.url")
+		}
+		tr, err := h.baseTransport()
+		if err != nil {
+			return fmt.Errorf("unable to apply proxy to transport: %w", err)
+		}
+		tr.Proxy = proxyFunc(tr.Proxy)
+	}
+	if err := h.initHTTP2(); err != nil {
+		return err
+	}
+	if err := h.initHostTLS(); err != nil {
+		return err
+	}
+	return h.initH2C()
+}
+
+// resolveProxy determines the proxy to route a request through from the
+// reference message, and returns a context for the request that carries it.
+func (h *Client) resolveProxy(ctx context.Context, refMsg types.Message) (context.Context, error) {
+	if h.proxyURL == nil {
+		return ctx, nil
+	}
+	urlStr := h.proxyURL.String(0, refMsg)
+	if urlStr == "" {
+		return context.WithValue(ctx, proxyCtxKey{}, requestProxy{}), nil
+	}
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proxy url: %w", err)
+	}
+	if h.conf.Proxy.Username != "" || h.conf.Proxy.Password != "" {
+		u.User = url.UserPassword(h.conf.Proxy.Username, h.conf.Proxy.Password)
+	}
+	return context.WithValue(ctx, proxyCtxKey{}, requestProxy{url: u}), nil
+}
+
+// initHTTP2 configures the transport of the client to always attempt HTTP/2,
+// otherwise the transport is left as it is.
+func (h *Client) initHTTP2() error {
+	if !h.conf.HTTP2.Enabled {
+		return nil
+	}
+	tr, err := h.baseTransport()
+	if err != nil {
+		return fmt.Errorf("unable to enable http2 for transport: %w", err)
+	}
+	tr.ForceAttemptHTTP2 = true
+	return nil
+}
+
+// initHostTLS wraps the transport of the client in order to apply custom TLS
+// settings to requests of specific hosts.
+func (h *Client) initHostTLS() error {
+	if len(h.conf.HostTLS) == 0 {
+		return nil
+	}
+	tr, err := h.baseTransport()
+	if err != nil {
+		return fmt.Errorf("unable to apply host_tls to transport: %w", err)
+	}
+
+	hosts := map[string]http.RoundTripper{}
+	for i, hConf := range h.conf.HostTLS {
+		if hConf.Host == "" {
+			return fmt.Errorf("host_tls %v: host must not be empty", i)
+		}
+		if _, exists := hosts[hConf.Host]; exists {
+			return fmt.Errorf("host_tls %v: duplicate host %v", i, hConf.Host)
+		}
+		hostTr := tr.Clone()
+		if hConf.TLS.Enabled {
+			if hostTr.TLSClientConfig, err = hConf.TLS.Get(); err != nil {
+				return fmt.Errorf("host_tls %v: %w", i, err)
+			}
+		} else {
+			hostTr.TLSClientConfig = nil
+		}
+		hosts[hConf.Host] = hostTr
+	}
+	h.client.Transport = &hostTLSRoundTripper{
+		hosts: hosts,
+		base:  tr,
+	}
+	return nil
+}
+
+// hostTLSRoundTripper sends requests with the round tripper of their host,
+// matched first by the host and port and then by the hostname, and all other
+// requests with a base round tripper.
+type hostTLSRoundTripper struct {
+	hosts map[string]http.RoundTripper
+	base  http.RoundTripper
+}
+
+func (t *hostTLSRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rt, exists := t.hosts[req.URL.Host]; exists {
+		return rt.RoundTrip(req)
+	}
+	if rt, exists := t.hosts[req.URL.Hostname()]; exists {
+		return rt.RoundTrip(req)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of all round trippers.
+func (t *hostTLSRoundTripper) CloseIdleConnections() {
+	type closeIdler interface {
+		CloseIdleConnections()
+	}
+	for _, rt := range t.hosts {
+		if c, ok := rt.(closeIdler); ok {
+			c.CloseIdleConnections()
+		}
+	}
+	if c, ok := t.base.(closeIdler); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// initH2C wraps the transport of the client in order to send requests to http
+// URLs with HTTP/2 over cleartext.
+func (h *Client) initH2C() error {
+	if !h.conf.HTTP2.H2C {
+		return nil
+	}
+	if h.conf.ProxyURL != "" || h.conf.Proxy.URL != "" {
+		return errors.New("http2.h2c cannot be combined with a proxy")
+	}
+
+	base := h.client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	h.client.Transport = &h2cRoundTripper{
+		h2c: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			},
+		},
+		base: base,
+	}
+	return nil
+}
+
+// h2cRoundTripper sends requests to http URLs with HTTP/2 over cleartext, and
+// all other requests with a base round tripper.
+type h2cRoundTripper struct {
+	h2c  http.RoundTripper
+	base http.RoundTripper
+}
+
+func (t *h2cRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" {
+		return t.h2c.RoundTrip(req)
+	}
+	return t.base.RoundTrip(req)
+}
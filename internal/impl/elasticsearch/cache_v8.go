@@ -0,0 +1,176 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+func elasticsearchV8CacheConfig() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("3.60.0").
+		Summary("Use an Elasticsearch 8 or OpenSearch index as a cache, where keys are document IDs.").
+		Description(`
+This cache is mostly useful for enriching messages with documents from an index, for example with the ` + "[`cached` processor](/docs/components/processors/cached)" + ` or a ` + "[`cache` processor](/docs/components/processors/cache)" + ` ` + "`get`" + ` operation.
+
+When a ` + "`value_field`" + ` is specified cache values are stored within that field of each document, and a ` + "`get`" + ` operation returns the contents of the field. A string field is returned as raw bytes and any other type is returned as JSON. Otherwise values are stored as the entire document, and must therefore be JSON objects, and a ` + "`get`" + ` operation returns the full source of the document.
+
+Elasticsearch has no notion of document expiry and therefore TTLs are ignored by this cache.`).
+		Field(service.NewStringField("index").
+			Description("The index to store documents in.").
+			Example("benthos_cache")).
+		Field(service.NewStringField("value_field").
+			Description("An optional field of each document to store cache values in. When empty the entire document is used as the value.").
+			Example("value").
+			Default(""))
+
+	for _, f := range clientFields() {
+		spec = spec.Field(f)
+	}
+
+	return spec.Example("Enrichment", `
+Here we enrich messages with the document of a user from an index, where the user ID of each message is the document ID:`,
+		`
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - cache:
+              resource: users
+              operator: get
+              key: ${! json("user.id") }
+        result_map: root.user = this
+
+cache_resources:
+  - label: users
+    elasticsearch_v8:
+      urls: [ http://localhost:9200 ]
+      index: users
+`,
+	)
+}
+
+func init() {
+	err := service.RegisterCache(
+		"elasticsearch_v8", elasticsearchV8CacheConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Cache, error) {
+			return newElasticsearchV8CacheFromConfig(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type elasticsearchV8Cache struct {
+	client     *restClient
+	index      string
+	valueField string
+}
+
+func newElasticsearchV8CacheFromConfig(conf *service.ParsedConfig) (*elasticsearchV8Cache, error) {
+	e := &elasticsearchV8Cache{}
+
+	var err error
+	if e.client, err = newRESTClientFromConfig(conf); err != nil {
+		return nil, err
+	}
+	if e.index, err = conf.FieldString("index"); err != nil {
+		return nil, err
+	}
+	if e.index == "" {
+		return nil, errors.New("an index must be specified")
+	}
+	if e.valueField, err = conf.FieldString("value_field"); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *elasticsearchV8Cache) docPath(endpoint, key string) string {
+	return "/" + url.PathEscape(e.index) + "/" + endpoint + "/" + url.PathEscape(key)
+}
+
+func isStatus(err error, status int) bool {
+	var rErr *responseError
+	return errors.As(err, &rErr) && rErr.Status == status
+}
+
+func (e *elasticsearchV8Cache) Get(ctx context.Context, key string) ([]byte, error) {
+	var res struct {
+		Source map[string]json.RawMessage `json:"_source"`
+	}
+	if err := e.client.do(ctx, http.MethodGet, e.docPath("_doc", key), nil, "", nil, &res); err != nil {
+		if isStatus(err, http.StatusNotFound) {
+			return nil, service.ErrKeyNotFound
+		}
+		return nil, err
+	}
+
+	if e.valueField == "" {
+		return json.Marshal(res.Source)
+	}
+
+	raw, exists := res.Source[e.valueField]
+	if !exists {
+		return nil, service.ErrKeyNotFound
+	}
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		return []byte(str), nil
+	}
+	return raw, nil
+}
+
+func (e *elasticsearchV8Cache) document(value []byte) ([]byte, error) {
+	if e.valueField == "" {
+		var doc map[string]interface{}
+		if err := json.Unmarshal(value, &doc); err != nil {
+			return nil, fmt.Errorf("value must be a JSON object when a value_field is not specified: %w", err)
+		}
+		return value, nil
+	}
+	return json.Marshal(map[string]string{e.valueField: string(value)})
+}
+
+func (e *elasticsearchV8Cache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	doc, err := e.document(value)
+	if err != nil {
+		return err
+	}
+	return e.client.do(ctx, http.MethodPut, e.docPath("_doc", key), nil, "application/json", doc, nil)
+}
+
+func (e *elasticsearchV8Cache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	doc, err := e.document(value)
+	if err != nil {
+		return err
+	}
+	if err = e.client.do(ctx, http.MethodPut, e.docPath("_create", key), nil, "application/json", doc, nil); err != nil {
+		if isStatus(err, http.StatusConflict) {
+			return service.ErrKeyAlreadyExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (e *elasticsearchV8Cache) Delete(ctx context.Context, key string) error {
+	err := e.client.do(ctx, http.MethodDelete, e.docPath("_doc", key), nil, "", nil, nil)
+	if isStatus(err, http.StatusNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (e *elasticsearchV8Cache) Close(ctx context.Context) error {
+	return nil
+}
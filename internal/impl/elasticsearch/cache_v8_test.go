@@ -0,0 +1,133 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type docTestServer struct {
+	mut  sync.Mutex
+	docs map[string]string
+}
+
+func (s *docTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "foo" {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+	endpoint, id := parts[1], parts[2]
+
+	w.Header().Set("Content-Type", "application/json")
+	doc, exists := s.docs[id]
+	switch {
+	case r.Method == http.MethodGet && endpoint == "_doc":
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprintf(w, `{"_index":"foo","_id":%q,"found":false}`, id)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"_index":"foo","_id":%q,"found":true,"_source":%v}`, id, doc)
+	case r.Method == http.MethodPut && (endpoint == "_doc" || endpoint == "_create"):
+		if exists && endpoint == "_create" {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error":{"type":"version_conflict_engine_exception","reason":"document already exists"},"status":409}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		s.docs[id] = string(body)
+		_, _ = w.Write([]byte(`{"result":"created"}`))
+	case r.Method == http.MethodDelete && endpoint == "_doc":
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"result":"not_found"}`))
+			return
+		}
+		delete(s.docs, id)
+		_, _ = w.Write([]byte(`{"result":"deleted"}`))
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func testElasticsearchV8Cache(t *testing.T, extraConf string) (*elasticsearchV8Cache, *docTestServer) {
+	t.Helper()
+
+	srv := &docTestServer{docs: map[string]string{}}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	conf, err := elasticsearchV8CacheConfig().ParseYAML(fmt.Sprintf(`
+urls: [ %v ]
+index: foo
+%v
+`, ts.URL, extraConf), service.NewEnvironment())
+	require.NoError(t, err)
+
+	c, err := newElasticsearchV8CacheFromConfig(conf)
+	require.NoError(t, err)
+	return c, srv
+}
+
+func TestElasticsearchV8CacheDocuments(t *testing.T) {
+	c, srv := testElasticsearchV8Cache(t, "")
+	ctx := context.Background()
+
+	_, err := c.Get(ctx, "a")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	require.NoError(t, c.Set(ctx, "a", []byte(`{"name":"foo"}`), nil))
+	assert.Equal(t, `{"name":"foo"}`, srv.docs["a"])
+
+	v, err := c.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"foo"}`, string(v))
+
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "a", []byte(`{"name":"bar"}`), nil))
+	require.NoError(t, c.Add(ctx, "b", []byte(`{"name":"bar"}`), nil))
+
+	require.NoError(t, c.Delete(ctx, "a"))
+
+	// Deleting a key that does not exist is not an error.
+	require.NoError(t, c.Delete(ctx, "a"))
+
+	assert.Error(t, c.Set(ctx, "c", []byte(`not a document`), nil))
+}
+
+func TestElasticsearchV8CacheValueField(t *testing.T) {
+	c, srv := testElasticsearchV8Cache(t, "value_field: value")
+	ctx := context.Background()
+
+	require.NoError(t, c.Set(ctx, "a", []byte(`hello world`), nil))
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(srv.docs["a"]), &doc))
+	assert.Equal(t, map[string]interface{}{"value": "hello world"}, doc)
+
+	v, err := c.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(v))
+
+	srv.docs["b"] = `{"value":{"nested":true}}`
+	v, err = c.Get(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, `{"nested":true}`, string(v))
+
+	srv.docs["c"] = `{"other":"field"}`
+	_, err = c.Get(ctx, "c")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+}
@@ -0,0 +1,189 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+// clientFields returns the config fields shared by components that talk to a
+// cluster over the REST API directly, which is compatible with Elasticsearch 8
+// and OpenSearch.
+func clientFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringListField("urls").
+			Description("A list of URLs to connect to. If an item of the list contains commas it will be expanded into multiple URLs. Requests are distributed across the URLs in a round robin fashion.").
+			Example([]string{"http://localhost:9200"}),
+		service.NewStringField("api_key").
+			Description("An optional Elasticsearch API key to authenticate with, encoded as the base64 of the key ID and key joined by a colon, which is the `encoded` value returned when creating a key. API keys are not supported by OpenSearch.").
+			Default(""),
+		service.NewInternalField(auth.BasicAuthFieldSpec()),
+		service.NewTLSToggledField("tls"),
+		service.NewStringField("timeout").
+			Description("The maximum time to wait before abandoning a request.").
+			Advanced().
+			Default("5s"),
+	}
+}
+
+// restClient performs requests against the REST API of an Elasticsearch or
+// OpenSearch cluster.
+type restClient struct {
+	urls       []string
+	next       uint64
+	apiKey     string
+	authConf   auth.BasicAuthConfig
+	httpClient *http.Client
+}
+
+func newRESTClientFromConfig(conf *service.ParsedConfig) (*restClient, error) {
+	c := &restClient{}
+
+	urlList, err := conf.FieldStringList("urls")
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range urlList {
+		for _, splitURL := range strings.Split(u, ",") {
+			if len(splitURL) > 0 {
+				c.urls = append(c.urls, strings.TrimSuffix(splitURL, "/"))
+			}
+		}
+	}
+	if len(c.urls) == 0 {
+		return nil, fmt.Errorf("at least one url must be specified")
+	}
+
+	if c.apiKey, err = conf.FieldString("api_key"); err != nil {
+		return nil, err
+	}
+	if c.authConf.Enabled, err = conf.FieldBool("basic_auth", "enabled"); err != nil {
+		return nil, err
+	}
+	if c.authConf.Enabled {
+		if c.authConf.Username, err = conf.FieldString("basic_auth", "username"); err != nil {
+			return nil, err
+		}
+		if c.authConf.Password, err = conf.FieldString("basic_auth", "password"); err != nil {
+			return nil, err
+		}
+	}
+	if c.apiKey != "" && c.authConf.Enabled {
+		return nil, fmt.Errorf("cannot use both api_key and basic_auth")
+	}
+
+	var timeout time.Duration
+	timeoutStr, err := conf.FieldString("timeout")
+	if err != nil {
+		return nil, err
+	}
+	if timeoutStr != "" {
+		if timeout, err = time.ParseDuration(timeoutStr); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %w", err)
+		}
+	}
+
+	c.httpClient = &http.Client{Timeout: timeout}
+
+	var tlsConf *tls.Config
+	var tlsEnabled bool
+	if tlsConf, tlsEnabled, err = conf.FieldTLSToggled("tls"); err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		c.httpClient.Transport = &http.Transport{
+			TLSClientConfig: tlsConf,
+		}
+	}
+	return c, nil
+}
+
+// responseError is returned when a request results in an unexpected status.
+type responseError struct {
+	Status int
+	Type   string
+	Reason string
+}
+
+func (r *responseError) Error() string {
+	if r.Type == "" {
+		return fmt.Sprintf("request failed with status [%v]", r.Status)
+	}
+	return fmt.Sprintf("request failed with status [%v]: %v: %v", r.Status, r.Type, r.Reason)
+}
+
+// do performs a request with an optional body, decoding the response body into
+// out when the status is successful and out is non-nil.
+func (c *restClient) do(ctx context.Context, method, path string, query url.Values, contentType string, body []byte, out interface{}) error {
+	base := c.urls[int(atomic.AddUint64(&c.next, 1)-1)%len(c.urls)]
+	target := base + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bodyReader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+c.apiKey)
+	} else if c.authConf.Enabled {
+		req.SetBasicAuth(c.authConf.Username, c.authConf.Password)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		rErr := &responseError{Status: res.StatusCode}
+		var errBody struct {
+			Error json.RawMessage `json:"error"`
+		}
+		if json.Unmarshal(resBytes, &errBody) == nil && len(errBody.Error) > 0 {
+			var details struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			}
+			if json.Unmarshal(errBody.Error, &details) == nil {
+				rErr.Type, rErr.Reason = details.Type, details.Reason
+			} else {
+				rErr.Reason = string(errBody.Error)
+			}
+		}
+		return rErr
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(resBytes, out); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,416 @@
+// Package elasticsearch contains component implementations for Elasticsearch.
+package elasticsearch
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/olivere/elastic/v7"
+)
+
+func elasticsearchInputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Categories("Services").
+		Version("3.60.0").
+		Summary("Reads all documents matching a query from an Elasticsearch index.").
+		Description(`
+Documents are read in pages of `+"`batch_size`"+` documents, where each page is emitted as a batch of messages containing the source of each document. Once all documents matching the query have been read this input shuts down, allowing the pipeline to gracefully terminate (or the next input in a [sequence](/docs/components/inputs/sequence) to execute).
+
+### Pagination
+
+The `+"`pagination`"+` field determines how results are paged through:
+
+- `+"`scroll`"+` uses the [scroll API](https://www.elastic.co/guide/en/elasticsearch/reference/current/paginate-search-results.html#scroll-search-results), which keeps a search context open on the cluster for the duration of `+"`keep_alive`"+` between each page.
+- `+"`search_after`"+` opens a [point in time](https://www.elastic.co/guide/en/elasticsearch/reference/current/point-in-time-api.html) and pages through results using the sort values of the last document of each page, which requires Elasticsearch 7.10 or newer.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- elasticsearch_index
+- elasticsearch_id
+`+"```"+`
+
+You can access these metadata fields using
+[function interpolation](/docs/configuration/interpolation#metadata).`).
+		Field(service.NewStringListField("urls").
+			Description("A list of URLs to connect to. If an item of the list contains commas it will be expanded into multiple URLs.").
+			Example([]string{"http://localhost:9200"})).
+		Field(service.NewStringField("index").
+			Description("The index to read documents from, which can also be a comma separated list or a wildcard pattern.").
+			Example("foo").Example("logs-*")).
+		Field(service.NewStringField("query").
+			Description("A JSON object containing the [query](https://www.elastic.co/guide/en/elasticsearch/reference/current/query-dsl.html) that documents must match.").
+			Example(`{"range":{"created_at":{"gte":"now-1d/d"}}}`).
+			Default(`{"match_all":{}}`)).
+		Field(service.NewStringAnnotatedEnumField("pagination", map[string]string{
+			"scroll":       "Page through results with the scroll API.",
+			"search_after": "Page through results of a point in time with search after.",
+		}).
+			Description("The method used in order to page through results.").
+			Default("scroll")).
+		Field(service.NewStringListField("sort").
+			Description("An optional list of fields to sort documents by, where a field can be suffixed with `:desc` in order to sort in descending order. When empty documents are sorted by `_doc` for the `scroll` pagination method and `_shard_doc` for the `search_after` method, which are the most efficient orders.").
+			Example([]string{"created_at", "id:desc"}).
+			Advanced().
+			Default([]string{})).
+		Field(service.NewIntField("batch_size").
+			Description("The maximum number of documents to read in each page.").
+			Default(100)).
+		Field(service.NewStringField("keep_alive").
+			Description("The period of time to keep the search context of a scroll or point in time alive between each page.").
+			Advanced().
+			Default("1m")).
+		Field(service.NewBoolField("sniff").
+			Description("Prompts Benthos to sniff for brokers to connect to when establishing a connection.").
+			Advanced().
+			Default(true)).
+		Field(service.NewBoolField("healthcheck").
+			Description("Whether to enable healthchecks.").
+			Advanced().
+			Default(true)).
+		Field(service.NewStringField("timeout").
+			Description("The maximum time to wait before abandoning a request.").
+			Advanced().
+			Default("5s")).
+		Field(service.NewTLSToggledField("tls")).
+		Field(service.NewInternalField(auth.BasicAuthFieldSpec())).
+		Example("Export an Index", `
+Here we read all documents of an index created within the last day and write them to a file:`,
+			`
+input:
+  elasticsearch:
+    urls: [ http://localhost:9200 ]
+    index: events
+    query: '{"range":{"created_at":{"gte":"now-1d/d"}}}'
+    pagination: search_after
+
+output:
+  file:
+    path: ./events.jsonl
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchInput(
+		"elasticsearch", elasticsearchInputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			i, err := newElasticsearchInputFromConfig(conf, mgr.Logger())
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksBatched(i), nil
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type elasticsearchInput struct {
+	urls        []string
+	index       string
+	query       interface{}
+	pagination  string
+	sort        []interface{}
+	batchSize   int
+	keepAlive   string
+	sniff       bool
+	healthcheck bool
+	timeout     time.Duration
+	tlsConf     *tls.Config
+
+	authEnabled        bool
+	username, password string
+
+	log *service.Logger
+
+	clientMut   sync.Mutex
+	client      *elastic.Client
+	scroll      *elastic.ScrollService
+	pitID       string
+	searchAfter []interface{}
+	done        bool
+}
+
+func newElasticsearchInputFromConfig(conf *service.ParsedConfig, log *service.Logger) (*elasticsearchInput, error) {
+	e := &elasticsearchInput{
+		log: log,
+	}
+
+	urlList, err := conf.FieldStringList("urls")
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range urlList {
+		for _, splitURL := range strings.Split(u, ",") {
+			if len(splitURL) > 0 {
+				e.urls = append(e.urls, splitURL)
+			}
+		}
+	}
+
+	if e.index, err = conf.FieldString("index"); err != nil {
+		return nil, err
+	}
+
+	queryStr, err := conf.FieldString("query")
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(queryStr), &e.query); err != nil {
+		return nil, fmt.Errorf("failed to parse query: %w", err)
+	}
+
+	if e.pagination, err = conf.FieldString("pagination"); err != nil {
+		return nil, err
+	}
+	switch e.pagination {
+	case "scroll", "search_after":
+	default:
+		return nil, fmt.Errorf("pagination method %v was not recognised", e.pagination)
+	}
+
+	sortFields, err := conf.FieldStringList("sort")
+	if err != nil {
+		return nil, err
+	}
+	if e.sort = parseSortFields(sortFields); len(e.sort) == 0 {
+		if e.pagination == "scroll" {
+			e.sort = []interface{}{"_doc"}
+		} else {
+			e.sort = []interface{}{"_shard_doc"}
+		}
+	}
+
+	if e.batchSize, err = conf.FieldInt("batch_size"); err != nil {
+		return nil, err
+	}
+	if e.batchSize < 1 {
+		return nil, errors.New("batch_size must be greater than zero")
+	}
+	if e.keepAlive, err = conf.FieldString("keep_alive"); err != nil {
+		return nil, err
+	}
+	if e.sniff, err = conf.FieldBool("sniff"); err != nil {
+		return nil, err
+	}
+	if e.healthcheck, err = conf.FieldBool("healthcheck"); err != nil {
+		return nil, err
+	}
+
+	timeoutStr, err := conf.FieldString("timeout")
+	if err != nil {
+		return nil, err
+	}
+	if timeoutStr != "" {
+		if e.timeout, err = time.ParseDuration(timeoutStr); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %w", err)
+		}
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		e.tlsConf = tlsConf
+	}
+
+	if e.authEnabled, err = conf.FieldBool("basic_auth", "enabled"); err != nil {
+		return nil, err
+	}
+	if e.authEnabled {
+		if e.username, err = conf.FieldString("basic_auth", "username"); err != nil {
+			return nil, err
+		}
+		if e.password, err = conf.FieldString("basic_auth", "password"); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// parseSortFields converts a list of fields, optionally suffixed with an order,
+// into sort values of a search request.
+func parseSortFields(fields []string) []interface{} {
+	var sort []interface{}
+	for _, f := range fields {
+		if i := strings.LastIndex(f, ":"); i > 0 {
+			if order := f[i+1:]; order == "asc" || order == "desc" {
+				sort = append(sort, map[string]interface{}{f[:i]: order})
+				continue
+			}
+		}
+		sort = append(sort, f)
+	}
+	return sort
+}
+
+func (e *elasticsearchInput) Connect(ctx context.Context) error {
+	e.clientMut.Lock()
+	defer e.clientMut.Unlock()
+
+	if e.client != nil || e.done {
+		return nil
+	}
+
+	httpClient := &http.Client{
+		Timeout: e.timeout,
+	}
+	if e.tlsConf != nil {
+		httpClient.Transport = &http.Transport{
+			TLSClientConfig: e.tlsConf,
+		}
+	}
+
+	opts := []elastic.ClientOptionFunc{
+		elastic.SetURL(e.urls...),
+		elastic.SetSniff(e.sniff),
+		elastic.SetHealthcheck(e.healthcheck),
+		elastic.SetHttpClient(httpClient),
+	}
+	if e.authEnabled {
+		opts = append(opts, elastic.SetBasicAuth(e.username, e.password))
+	}
+
+	client, err := elastic.NewClient(opts...)
+	if err != nil {
+		return err
+	}
+
+	if e.pagination == "scroll" {
+		e.scroll = client.Scroll(e.index).
+			Body(map[string]interface{}{
+				"query": e.query,
+				"sort":  e.sort,
+			}).
+			Size(e.batchSize).
+			KeepAlive(e.keepAlive)
+	} else {
+		res, err := client.OpenPointInTime(e.index).KeepAlive(e.keepAlive).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to open point in time: %w", err)
+		}
+		e.pitID = res.Id
+	}
+
+	e.client = client
+	e.log.Infof("Reading documents from Elasticsearch index %v at urls: %s", e.index, e.urls)
+	return nil
+}
+
+func (e *elasticsearchInput) nextPage(ctx context.Context) (*elastic.SearchResult, error) {
+	if e.scroll != nil {
+		return e.scroll.Do(ctx)
+	}
+
+	body := map[string]interface{}{
+		"query": e.query,
+		"size":  e.batchSize,
+		"sort":  e.sort,
+		"pit": map[string]interface{}{
+			"id":         e.pitID,
+			"keep_alive": e.keepAlive,
+		},
+	}
+	if e.searchAfter != nil {
+		body["search_after"] = e.searchAfter
+	}
+
+	res, err := e.client.Search().Source(body).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if res.PitId != "" {
+		e.pitID = res.PitId
+	}
+	if res.Hits == nil || len(res.Hits.Hits) == 0 {
+		return nil, io.EOF
+	}
+	e.searchAfter = res.Hits.Hits[len(res.Hits.Hits)-1].Sort
+	return res, nil
+}
+
+func (e *elasticsearchInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	e.clientMut.Lock()
+	defer e.clientMut.Unlock()
+
+	if e.done {
+		return nil, nil, service.ErrEndOfInput
+	}
+	if e.client == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	res, err := e.nextPage(ctx)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			e.done = true
+			e.release(ctx)
+			return nil, nil, service.ErrEndOfInput
+		}
+		return nil, nil, err
+	}
+
+	var batch service.MessageBatch
+	if res.Hits != nil {
+		for _, hit := range res.Hits.Hits {
+			msg := service.NewMessage(hit.Source)
+			msg.MetaSet("elasticsearch_index", hit.Index)
+			msg.MetaSet("elasticsearch_id", hit.Id)
+			batch = append(batch, msg)
+		}
+	}
+	if len(batch) == 0 {
+		e.done = true
+		e.release(ctx)
+		return nil, nil, service.ErrEndOfInput
+	}
+
+	return batch, func(ctx context.Context, err error) error {
+		// Nacks are handled by AutoRetryNacksBatched because we don't have an
+		// explicit ack mechanism.
+		return nil
+	}, nil
+}
+
+// release frees the search context held on the cluster, if any.
+func (e *elasticsearchInput) release(ctx context.Context) {
+	if e.scroll != nil {
+		if err := e.scroll.Clear(ctx); err != nil {
+			e.log.Debugf("Failed to clear scroll: %v", err)
+		}
+		e.scroll = nil
+	}
+	if e.pitID != "" && e.client != nil {
+		if _, err := e.client.ClosePointInTime(e.pitID).Do(ctx); err != nil {
+			e.log.Debugf("Failed to close point in time: %v", err)
+		}
+		e.pitID = ""
+	}
+}
+
+func (e *elasticsearchInput) Close(ctx context.Context) error {
+	e.clientMut.Lock()
+	defer e.clientMut.Unlock()
+
+	e.release(ctx)
+	e.client = nil
+	return nil
+}
@@ -0,0 +1,160 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type searchTestServer struct {
+	mut      sync.Mutex
+	docs     []string
+	requests []string
+	closed   []string
+}
+
+func (s *searchTestServer) hits(from, size int) []interface{} {
+	hits := []interface{}{}
+	for i := from; i < from+size && i < len(s.docs); i++ {
+		hits = append(hits, map[string]interface{}{
+			"_index":  "foo",
+			"_id":     fmt.Sprintf("id%v", i),
+			"_source": json.RawMessage(s.docs[i]),
+			"sort":    []interface{}{i},
+		})
+	}
+	return hits
+}
+
+func (s *searchTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	bodyBytes, _ := json.Marshal(body)
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path+" "+string(bodyBytes))
+
+	var res interface{}
+	switch {
+	case r.Method == http.MethodDelete:
+		s.closed = append(s.closed, r.URL.Path)
+		res = map[string]interface{}{"succeeded": true}
+	case r.URL.Path == "/foo/_pit":
+		res = map[string]interface{}{"id": "pit0"}
+	case r.URL.Path == "/foo/_search":
+		res = map[string]interface{}{
+			"_scroll_id": "scroll0",
+			"hits":       map[string]interface{}{"hits": s.hits(0, 2)},
+		}
+	case r.URL.Path == "/_search/scroll":
+		var from int
+		_, _ = fmt.Sscanf(body["scroll_id"].(string), "scroll%d", &from)
+		from += 2
+		res = map[string]interface{}{
+			"_scroll_id": fmt.Sprintf("scroll%v", from),
+			"hits":       map[string]interface{}{"hits": s.hits(from, 2)},
+		}
+	case r.URL.Path == "/_search":
+		from := 0
+		if after, ok := body["search_after"].([]interface{}); ok {
+			from = int(after[0].(float64)) + 1
+		}
+		res = map[string]interface{}{
+			"pit_id": "pit1",
+			"hits":   map[string]interface{}{"hits": s.hits(from, 2)},
+		}
+	default:
+		http.Error(w, "unexpected request", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func testElasticsearchInput(t *testing.T, pagination string) *searchTestServer {
+	t.Helper()
+
+	srv := &searchTestServer{
+		docs: []string{`{"n":0}`, `{"n":1}`, `{"n":2}`},
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	conf, err := elasticsearchInputConfig().ParseYAML(fmt.Sprintf(`
+urls: [ %v ]
+index: foo
+query: '{"term":{"type":"bar"}}'
+pagination: %v
+batch_size: 2
+sniff: false
+healthcheck: false
+`, ts.URL, pagination), service.NewEnvironment())
+	require.NoError(t, err)
+
+	i, err := newElasticsearchInputFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, i.Connect(ctx))
+
+	var docs, ids []string
+	for {
+		batch, ackFn, err := i.ReadBatch(ctx)
+		if err == service.ErrEndOfInput {
+			break
+		}
+		require.NoError(t, err)
+		for _, msg := range batch {
+			b, err := msg.AsBytes()
+			require.NoError(t, err)
+			docs = append(docs, string(b))
+
+			id, _ := msg.MetaGet("elasticsearch_id")
+			ids = append(ids, id)
+		}
+		require.NoError(t, ackFn(ctx, nil))
+	}
+	require.NoError(t, i.Close(ctx))
+
+	assert.Equal(t, []string{`{"n":0}`, `{"n":1}`, `{"n":2}`}, docs)
+	assert.Equal(t, []string{"id0", "id1", "id2"}, ids)
+	return srv
+}
+
+func TestElasticsearchInputScroll(t *testing.T) {
+	srv := testElasticsearchInput(t, "scroll")
+
+	require.NotEmpty(t, srv.requests)
+	assert.Equal(t, `POST /foo/_search {"query":{"term":{"type":"bar"}},"sort":["_doc"]}`, srv.requests[0])
+	assert.Equal(t, []string{"/_search/scroll"}, srv.closed)
+}
+
+func TestElasticsearchInputSearchAfter(t *testing.T) {
+	srv := testElasticsearchInput(t, "search_after")
+
+	require.Len(t, srv.requests, 5)
+	assert.True(t, strings.HasPrefix(srv.requests[0], "POST /foo/_pit "), srv.requests[0])
+	assert.Equal(t, `POST /_search {"pit":{"id":"pit0","keep_alive":"1m"},"query":{"term":{"type":"bar"}},"size":2,"sort":["_shard_doc"]}`, srv.requests[1])
+	assert.Equal(t, `POST /_search {"pit":{"id":"pit1","keep_alive":"1m"},"query":{"term":{"type":"bar"}},"search_after":[1],"size":2,"sort":["_shard_doc"]}`, srv.requests[2])
+	assert.Equal(t, []string{"/_pit"}, srv.closed)
+}
+
+func TestElasticsearchInputSortFields(t *testing.T) {
+	assert.Equal(t, []interface{}{
+		"created_at",
+		map[string]interface{}{"id": "desc"},
+		map[string]interface{}{"foo:bar": "asc"},
+		"baz:qux",
+	}, parseSortFields([]string{"created_at", "id:desc", "foo:bar:asc", "baz:qux"}))
+}
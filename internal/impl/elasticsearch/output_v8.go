@@ -0,0 +1,389 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/util/retries"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/cenkalti/backoff/v4"
+)
+
+func elasticsearchV8OutputConfig() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("3.60.0").
+		Summary("Writes messages to an Elasticsearch 8 or OpenSearch index or data stream with the bulk API.").
+		Description(`
+Unlike the ` + "[`elasticsearch`](/docs/components/outputs/elasticsearch)" + ` output this output talks to the REST API of a cluster directly, and is therefore compatible with Elasticsearch 8 and OpenSearch 1 and 2. Authentication is supported with either an ` + "`api_key`" + ` (Elasticsearch only) or ` + "`basic_auth`" + `.
+
+The ` + "`index`, `id`, `action`, `pipeline` and `routing`" + ` fields can be dynamically set using [function interpolations](/docs/configuration/interpolation#bloblang-queries), which are resolved per message of a batch.
+
+### Data Streams
+
+Documents can only be added to a [data stream](https://www.elastic.co/guide/en/elasticsearch/reference/current/data-streams.html) with the ` + "`create`" + ` action, and each document must contain an ` + "`@timestamp`" + ` field. When writing to data streams it's usually best to leave the ` + "`id`" + ` empty, in which case an ID is generated by the cluster.
+
+### Error Handling
+
+The response of each document within a bulk request is checked individually. Documents that fail with a status that is likely to be temporary (` + "`429`" + ` or ` + "`5xx`" + `) are retried according to the backoff fields, and documents that fail with any other status, such as a mapping error or a version conflict, are rejected without being retried. Rejected messages of a batch can be routed elsewhere with outputs such as ` + "[`fallback`](/docs/components/outputs/fallback)" + `, where only the rejected messages are passed on.`).
+		Field(service.NewInterpolatedStringField("index").
+			Description("The index or data stream to write documents to.").
+			Example("benthos_index").
+			Example(`logs-${! meta("service") }-default`)).
+		Field(service.NewInterpolatedStringField("id").
+			Description("The ID of each document. When empty an ID is generated by the cluster, which is only supported by the `index` and `create` actions.").
+			Example(`${! json("id") }`).
+			Default("")).
+		Field(service.NewInterpolatedStringField("action").
+			Description("The action to take on each document, one of `index`, `create`, `update`, `upsert` or `delete`. The `update` action performs a partial update of an existing document with the message, and the `upsert` action does the same except that the message is indexed as the document when it does not already exist.").
+			Example("create").
+			Example(`${! if meta("deleted") == "true" { "delete" } else { "index" } }`).
+			Advanced().
+			Default("index")).
+		Field(service.NewInterpolatedStringField("pipeline").
+			Description("An optional [ingest pipeline](https://www.elastic.co/guide/en/elasticsearch/reference/current/ingest.html) to preprocess documents with. When empty the default pipeline of the index, if any, is used.").
+			Example("my-pipeline").
+			Default("")).
+		Field(service.NewInterpolatedStringField("routing").
+			Description("An optional routing key for each document.").
+			Advanced().
+			Default(""))
+
+	for _, f := range clientFields() {
+		spec = spec.Field(f)
+	}
+
+	return spec.
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of batches to have in flight at a given time. Increase this to improve throughput.").
+			Default(64)).
+		Field(service.NewIntField("max_retries").
+			Description("The maximum number of retries of documents that failed with a temporary error. If set to zero there is no discrete limit.").
+			Advanced().
+			Default(0)).
+		Field(service.NewObjectField("backoff",
+			service.NewStringField("initial_interval").
+				Description("The initial period to wait between retry attempts.").
+				Default("1s"),
+			service.NewStringField("max_interval").
+				Description("The maximum period to wait between retry attempts.").
+				Default("5s"),
+			service.NewStringField("max_elapsed_time").
+				Description("The maximum period to wait before retry attempts are abandoned. If zero then no limit is used.").
+				Default("30s"),
+		).
+			Description("Control time intervals between retry attempts.").
+			Advanced()).
+		Field(service.NewBatchPolicyField("batching")).
+		Example("Writing to a Data Stream", `
+Here we write logs to an Elasticsearch 8 data stream named after the service of each message, authenticating with an API key and preprocessing documents with an ingest pipeline:`,
+			`
+output:
+  elasticsearch_v8:
+    urls: [ https://localhost:9200 ]
+    index: logs-${! meta("service") }-default
+    action: create
+    pipeline: parse-logs
+    api_key: ${ES_API_KEY}
+    tls:
+      enabled: true
+    batching:
+      count: 100
+      period: 1s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"elasticsearch_v8", elasticsearchV8OutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy("batching"); err != nil {
+				return
+			}
+			out, err = newElasticsearchV8OutputFromConfig(conf, mgr.Logger())
+			return
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type elasticsearchV8Output struct {
+	client *restClient
+
+	index    *service.InterpolatedString
+	id       *service.InterpolatedString
+	action   *service.InterpolatedString
+	pipeline *service.InterpolatedString
+	routing  *service.InterpolatedString
+
+	backoffCtor func() backoff.BackOff
+
+	log *service.Logger
+}
+
+func newElasticsearchV8OutputFromConfig(conf *service.ParsedConfig, log *service.Logger) (*elasticsearchV8Output, error) {
+	e := &elasticsearchV8Output{
+		log: log,
+	}
+
+	var err error
+	if e.client, err = newRESTClientFromConfig(conf); err != nil {
+		return nil, err
+	}
+	if e.index, err = conf.FieldInterpolatedString("index"); err != nil {
+		return nil, err
+	}
+	if e.id, err = conf.FieldInterpolatedString("id"); err != nil {
+		return nil, err
+	}
+	if e.action, err = conf.FieldInterpolatedString("action"); err != nil {
+		return nil, err
+	}
+	if e.pipeline, err = conf.FieldInterpolatedString("pipeline"); err != nil {
+		return nil, err
+	}
+	if e.routing, err = conf.FieldInterpolatedString("routing"); err != nil {
+		return nil, err
+	}
+
+	rConf := retries.NewConfig()
+	if rConf.MaxRetries, err = uint64Field(conf, "max_retries"); err != nil {
+		return nil, err
+	}
+	if rConf.Backoff.InitialInterval, err = conf.FieldString("backoff", "initial_interval"); err != nil {
+		return nil, err
+	}
+	if rConf.Backoff.MaxInterval, err = conf.FieldString("backoff", "max_interval"); err != nil {
+		return nil, err
+	}
+	if rConf.Backoff.MaxElapsedTime, err = conf.FieldString("backoff", "max_elapsed_time"); err != nil {
+		return nil, err
+	}
+	if e.backoffCtor, err = rConf.GetCtor(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func uint64Field(conf *service.ParsedConfig, name string) (uint64, error) {
+	i, err := conf.FieldInt(name)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		return 0, fmt.Errorf("field %v must not be negative", name)
+	}
+	return uint64(i), nil
+}
+
+func (e *elasticsearchV8Output) Connect(ctx context.Context) error {
+	return nil
+}
+
+type bulkAction struct {
+	action string
+	meta   map[string]string
+	doc    []byte
+}
+
+// writeTo appends the action and source lines of the request to a bulk body.
+func (b *bulkAction) writeTo(buf *bytes.Buffer) error {
+	action := b.action
+	if action == "upsert" {
+		action = "update"
+	}
+	actionBytes, err := json.Marshal(map[string]interface{}{action: b.meta})
+	if err != nil {
+		return err
+	}
+	buf.Write(actionBytes)
+	buf.WriteByte('\n')
+
+	switch b.action {
+	case "delete":
+		return nil
+	case "update", "upsert":
+		buf.WriteString(`{"doc":`)
+		buf.Write(b.doc)
+		if b.action == "upsert" {
+			buf.WriteString(`,"doc_as_upsert":true`)
+		}
+		buf.WriteByte('}')
+	default:
+		buf.Write(b.doc)
+	}
+	buf.WriteByte('\n')
+	return nil
+}
+
+func (e *elasticsearchV8Output) buildAction(i int, batch service.MessageBatch) (*bulkAction, error) {
+	b := &bulkAction{
+		action: batch.InterpolatedString(i, e.action),
+		meta: map[string]string{
+			"_index": batch.InterpolatedString(i, e.index),
+		},
+	}
+	id := batch.InterpolatedString(i, e.id)
+	if id != "" {
+		b.meta["_id"] = id
+	}
+	if routing := batch.InterpolatedString(i, e.routing); routing != "" {
+		b.meta["routing"] = routing
+	}
+
+	switch b.action {
+	case "index", "create":
+		if pipeline := batch.InterpolatedString(i, e.pipeline); pipeline != "" {
+			b.meta["pipeline"] = pipeline
+		}
+	case "update", "upsert", "delete":
+		if id == "" {
+			return nil, fmt.Errorf("an id is required for the %v action", b.action)
+		}
+	default:
+		return nil, fmt.Errorf("elasticsearch action '%s' is not allowed", b.action)
+	}
+	if b.action == "delete" {
+		return b, nil
+	}
+
+	structured, err := batch[i].AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as a JSON document: %w", err)
+	}
+	if b.doc, err = json.Marshal(structured); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+type bulkResponse struct {
+	Errors bool                              `json:"errors"`
+	Items  []map[string]bulkResponseItemBody `json:"items"`
+}
+
+type bulkResponseItemBody struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+func (e *elasticsearchV8Output) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	boff := e.backoffCtor()
+
+	var batchErr *service.BatchError
+	failMsg := func(i int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(err)
+		}
+		batchErr.Failed(i, err)
+	}
+
+	actions := make([]*bulkAction, len(batch))
+	pending := make([]int, 0, len(batch))
+	for i := range batch {
+		var err error
+		if actions[i], err = e.buildAction(i, batch); err != nil {
+			e.log.Errorf("Failed to build bulk request for message: %v", err)
+			failMsg(i, err)
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	for len(pending) > 0 {
+		var body bytes.Buffer
+		for _, i := range pending {
+			if err := actions[i].writeTo(&body); err != nil {
+				return err
+			}
+		}
+
+		var res bulkResponse
+		var retry []int
+		var retryErr error
+		err := e.client.do(ctx, http.MethodPost, "/_bulk", nil, "application/x-ndjson", body.Bytes(), &res)
+		if err != nil {
+			var rErr *responseError
+			if !errors.As(err, &rErr) || !shouldRetryStatus(rErr.Status) {
+				for _, i := range pending {
+					failMsg(i, err)
+				}
+				break
+			}
+			e.log.Errorf("Bulk request failed: %v", err)
+			retry, retryErr = pending, err
+		} else {
+			if len(res.Items) != len(pending) {
+				return fmt.Errorf("expected %v items in bulk response, received %v", len(pending), len(res.Items))
+			}
+			for j, item := range res.Items {
+				i := pending[j]
+				for _, r := range item {
+					if r.Status >= 200 && r.Status <= 299 {
+						continue
+					}
+					itemErr := &responseError{Status: r.Status}
+					if r.Error != nil {
+						itemErr.Type, itemErr.Reason = r.Error.Type, r.Error.Reason
+					}
+					if !shouldRetryStatus(r.Status) {
+						e.log.Errorf("Document rejected: %v", itemErr)
+						failMsg(i, itemErr)
+						continue
+					}
+					e.log.Errorf("Document failed: %v", itemErr)
+					retry, retryErr = append(retry, i), itemErr
+				}
+			}
+		}
+		if len(retry) == 0 {
+			break
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			for _, i := range retry {
+				failMsg(i, retryErr)
+			}
+			break
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		pending = retry
+	}
+
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+// shouldRetryStatus returns whether a request or document that failed with a
+// given status should be attempted again.
+func shouldRetryStatus(s int) bool {
+	return s == http.StatusTooManyRequests || (s >= 500 && s <= 599)
+}
+
+func (e *elasticsearchV8Output) Close(ctx context.Context) error {
+	return nil
+}
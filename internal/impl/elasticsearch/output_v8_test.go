@@ -0,0 +1,185 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bulkTestServer struct {
+	mut      sync.Mutex
+	bodies   []string
+	auth     []string
+	statuses [][]int
+}
+
+func (s *bulkTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if r.Method != http.MethodPost || r.URL.Path != "/_bulk" {
+		http.Error(w, "unexpected request", http.StatusNotFound)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	s.bodies = append(s.bodies, string(body))
+	s.auth = append(s.auth, r.Header.Get("Authorization"))
+
+	var statuses []int
+	if len(s.statuses) > 0 {
+		statuses, s.statuses = s.statuses[0], s.statuses[1:]
+	}
+
+	var items []string
+	for i, line := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+		if !strings.HasPrefix(line, `{"create"`) && !strings.HasPrefix(line, `{"index"`) &&
+			!strings.HasPrefix(line, `{"update"`) && !strings.HasPrefix(line, `{"delete"`) {
+			continue
+		}
+		status := 201
+		if len(statuses) > len(items) {
+			status = statuses[len(items)]
+		}
+		if status >= 200 && status <= 299 {
+			items = append(items, fmt.Sprintf(`{"index":{"status":%v}}`, status))
+		} else {
+			items = append(items, fmt.Sprintf(`{"index":{"status":%v,"error":{"type":"test_exception","reason":"item %v"}}}`, status, i))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = fmt.Fprintf(w, `{"errors":true,"items":[%v]}`, strings.Join(items, ","))
+}
+
+func testElasticsearchV8Output(t *testing.T, extraConf string) (*elasticsearchV8Output, *bulkTestServer) {
+	t.Helper()
+
+	srv := &bulkTestServer{}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	conf, err := elasticsearchV8OutputConfig().ParseYAML(fmt.Sprintf(`
+urls: [ %v ]
+index: ${! meta("index") }
+max_retries: 2
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+%v
+`, ts.URL, extraConf), service.NewEnvironment())
+	require.NoError(t, err)
+
+	o, err := newElasticsearchV8OutputFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, o.Connect(context.Background()))
+	return o, srv
+}
+
+func testBatch(docs ...string) service.MessageBatch {
+	var batch service.MessageBatch
+	for _, d := range docs {
+		msg := service.NewMessage([]byte(d))
+		msg.MetaSet("index", "logs-foo")
+		batch = append(batch, msg)
+	}
+	return batch
+}
+
+func TestElasticsearchV8OutputDataStream(t *testing.T) {
+	o, srv := testElasticsearchV8Output(t, `
+action: create
+pipeline: parse-logs
+api_key: Zm9vOmJhcg==
+`)
+
+	require.NoError(t, o.WriteBatch(context.Background(), testBatch(
+		`{"@timestamp":"2021-01-01T00:00:00Z","msg":"foo"}`,
+		`{"@timestamp":"2021-01-01T00:00:01Z","msg":"bar"}`,
+	)))
+
+	assert.Equal(t, []string{`{"create":{"_index":"logs-foo","pipeline":"parse-logs"}}
+{"@timestamp":"2021-01-01T00:00:00Z","msg":"foo"}
+{"create":{"_index":"logs-foo","pipeline":"parse-logs"}}
+{"@timestamp":"2021-01-01T00:00:01Z","msg":"bar"}
+`}, srv.bodies)
+	assert.Equal(t, []string{"ApiKey Zm9vOmJhcg=="}, srv.auth)
+}
+
+func TestElasticsearchV8OutputActions(t *testing.T) {
+	o, srv := testElasticsearchV8Output(t, `
+id: ${! json("id") }
+action: ${! meta("action") }
+routing: bar
+`)
+
+	batch := testBatch(`{"id":"1"}`, `{"id":"2"}`, `{"id":"3"}`)
+	batch[0].MetaSet("action", "update")
+	batch[1].MetaSet("action", "upsert")
+	batch[2].MetaSet("action", "delete")
+
+	require.NoError(t, o.WriteBatch(context.Background(), batch))
+	assert.Equal(t, []string{`{"update":{"_id":"1","_index":"logs-foo","routing":"bar"}}
+{"doc":{"id":"1"}}
+{"update":{"_id":"2","_index":"logs-foo","routing":"bar"}}
+{"doc":{"id":"2"},"doc_as_upsert":true}
+{"delete":{"_id":"3","_index":"logs-foo","routing":"bar"}}
+`}, srv.bodies)
+}
+
+func TestElasticsearchV8OutputItemErrors(t *testing.T) {
+	o, srv := testElasticsearchV8Output(t, "")
+	srv.statuses = [][]int{
+		{201, 429, 400, 503},
+		{201, 503},
+		{503},
+		{503},
+	}
+
+	err := o.WriteBatch(context.Background(), testBatch(`{"n":0}`, `{"n":1}`, `{"n":2}`, `{"n":3}`))
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr), err)
+
+	failed := map[int]string{}
+	bErr.WalkIndexedErrors(func(i int, err error) bool {
+		failed[i] = err.Error()
+		return true
+	})
+	assert.Equal(t, map[int]string{
+		2: "request failed with status [400]: test_exception: item 4",
+		3: "request failed with status [503]: test_exception: item 0",
+	}, failed)
+
+	require.Len(t, srv.bodies, 3)
+	assert.Equal(t, `{"index":{"_index":"logs-foo"}}
+{"n":1}
+{"index":{"_index":"logs-foo"}}
+{"n":3}
+`, srv.bodies[1])
+	assert.Equal(t, `{"index":{"_index":"logs-foo"}}
+{"n":3}
+`, srv.bodies[2])
+}
+
+func TestElasticsearchV8OutputMissingID(t *testing.T) {
+	o, srv := testElasticsearchV8Output(t, `
+action: update
+`)
+
+	err := o.WriteBatch(context.Background(), testBatch(`{"n":0}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "an id is required for the update action")
+	assert.Empty(t, srv.bodies)
+}
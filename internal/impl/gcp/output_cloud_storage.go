@@ -12,6 +12,7 @@ import (
 	"github.com/Jeffail/benthos/v3/internal/bundle"
 	ioutput "github.com/Jeffail/benthos/v3/internal/component/output"
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/shutdown"
 	"github.com/Jeffail/benthos/v3/lib/input"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
@@ -28,7 +29,11 @@ func init() {
 		if err != nil {
 			return nil, err
 		}
-		w, err := output.NewAsyncWriter(output.TypeGCPCloudStorage, c.GCPCloudStorage.MaxInFlight, g, nm.Logger(), nm.Metrics())
+		maxInFlight := c.GCPCloudStorage.MaxInFlight
+		if c.GCPCloudStorage.Rolling.Enabled {
+			maxInFlight = c.GCPCloudStorage.Rolling.MaxInFlight
+		}
+		w, err := output.NewAsyncWriter(output.TypeGCPCloudStorage, maxInFlight, g, nm.Logger(), nm.Metrics())
 		if err != nil {
 			return nil, err
 		}
@@ -99,7 +104,24 @@ output:
       processors:
         - archive:
             format: json_array
-`+"```"+``),
+`+"```"+`
+`+ioutput.RollingDocs+` The `+"`collision_mode`"+` is not applied to rolling objects, which always overwrite existing objects.
+
+For example, in order to upload gzip compressed objects of newline delimited messages, with each object containing up to 100MB of data or an hour of messages, we could use the following config:
+
+`+"```yaml"+`
+output:
+  gcp_cloud_storage:
+    bucket: TODO
+    path: logs/${!timestamp_unix_nano()}.log.gz
+    rolling:
+      enabled: true
+      max_in_flight: 1024
+      codec: gzip/lines
+      max_size: 104857600
+      max_age: 1h
+`+"```"+`
+`+ioutput.PartitionCommitDocs),
 		Config: docs.FieldComponent().WithChildren(
 			docs.FieldCommon("bucket", "The bucket to upload messages to."),
 			docs.FieldCommon(
@@ -121,6 +143,8 @@ output:
 			docs.FieldAdvanced("chunk_size", "An optional chunk size which controls the maximum number of bytes of the object that the Writer will attempt to send to the server in a single request. If ChunkSize is set to zero, chunking will be disabled."),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
 			batch.FieldSpec(),
+			docs.FieldAdvanced("rolling", "Optionally append messages to rolling objects that are streamed to the bucket as they are written. Messages are only acknowledged once their object has been committed, and therefore whilst rolling is enabled `rolling.max_in_flight` is used instead of `max_in_flight` and also limits the number of messages (or batches) written to each object.").WithChildren(ioutput.RollingFields()...).AtVersion("3.60.0"),
+			docs.FieldAdvanced("partition_commit", "Optionally track partitions of event time and commit them once complete.").WithChildren(ioutput.PartitionCommitFields()...).AtVersion("3.60.0"),
 		).ChildDefaultAndTypesFromStruct(output.NewGCPCloudStorageConfig()),
 	})
 }
@@ -134,6 +158,9 @@ type gcpCloudStorageOutput struct {
 	contentType     *field.Expression
 	contentEncoding *field.Expression
 
+	rolling    *ioutput.Rolling
+	partitions *ioutput.PartitionCommitter
+
 	client  *storage.Client
 	connMut sync.RWMutex
 
@@ -167,6 +194,16 @@ func newGCPCloudStorageOutput(
 		return nil, fmt.Errorf("failed to parse content encoding expression: %v", err)
 	}
 
+	if conf.Rolling.Enabled {
+		if g.rolling, err = ioutput.NewRolling(conf.Rolling, g.openRollingObject, log); err != nil {
+			return nil, fmt.Errorf("failed to create rolling objects: %w", err)
+		}
+	}
+	if conf.PartitionCommit.Enabled {
+		if g.partitions, err = ioutput.NewPartitionCommitter(conf.PartitionCommit, g.writeMarker, mgr, log, stats); err != nil {
+			return nil, fmt.Errorf("failed to create partition committer: %w", err)
+		}
+	}
 	return g, nil
 }
 
@@ -197,6 +234,27 @@ func (g *gcpCloudStorageOutput) WriteWithContext(ctx context.Context, msg types.
 		return types.ErrNotConnected
 	}
 
+	if g.partitions == nil {
+		return g.writeBatch(ctx, client, msg, nil)
+	}
+
+	pw := g.partitions.Begin(msg)
+	defer pw.End(ctx, g.flushPartition)
+
+	if err := g.writeBatch(ctx, client, msg, pw.Key); err != nil {
+		return err
+	}
+	for i := 0; i < msg.Len(); i++ {
+		pw.Track(i, g.path.String(i, msg))
+	}
+	return nil
+}
+
+func (g *gcpCloudStorageOutput) writeBatch(ctx context.Context, client *storage.Client, msg types.Message, keyFn func(int) string) error {
+	if g.rolling != nil {
+		return g.rolling.WriteKeyed(ctx, msg, keyFn)
+	}
+
 	return writer.IterateBatchedSend(msg, func(i int, p types.Part) error {
 		metadata := map[string]string{}
 		p.Metadata().Iter(func(k, v string) error {
@@ -257,9 +315,100 @@ func (g *gcpCloudStorageOutput) WriteWithContext(ctx context.Context, msg types.
 	})
 }
 
+// flushPartition commits the rolling objects of a partition.
+func (g *gcpCloudStorageOutput) flushPartition(partition time.Time) {
+	if g.rolling != nil {
+		g.rolling.Roll(ioutput.PartitionKey(partition))
+	}
+}
+
+func (g *gcpCloudStorageOutput) writeMarker(ctx context.Context, path string) error {
+	g.connMut.RLock()
+	client := g.client
+	g.connMut.RUnlock()
+
+	if client == nil {
+		return types.ErrNotConnected
+	}
+	return client.Bucket(g.conf.Bucket).Object(path).NewWriter(ctx).Close()
+}
+
+// gcsRollingObject streams an object to GCP Cloud Storage, which becomes
+// available once the writer is closed.
+type gcsRollingObject struct {
+	w      *storage.Writer
+	cancel func()
+}
+
+func (g *gcpCloudStorageOutput) openRollingObject(ctx context.Context, msg types.Message, index int) (ioutput.RollingObject, error) {
+	g.connMut.RLock()
+	client := g.client
+	g.connMut.RUnlock()
+
+	if client == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	metadata := map[string]string{}
+	_ = msg.Get(index).Metadata().Iter(func(k, v string) error {
+		metadata[k] = v
+		return nil
+	})
+
+	// Cancelling the context of a writer abandons the upload.
+	ctx, cancel := context.WithCancel(ctx)
+	w := client.Bucket(g.conf.Bucket).Object(g.path.String(index, msg)).NewWriter(ctx)
+	w.ChunkSize = g.conf.ChunkSize
+	w.ContentType = g.contentType.String(index, msg)
+	w.ContentEncoding = g.contentEncoding.String(index, msg)
+	w.Metadata = metadata
+	return &gcsRollingObject{w: w, cancel: cancel}, nil
+}
+
+func (o *gcsRollingObject) Write(b []byte) (int, error) {
+	return o.w.Write(b)
+}
+
+func (o *gcsRollingObject) Commit(ctx context.Context) error {
+	defer o.cancel()
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- o.w.Close()
+	}()
+	select {
+	case err := <-closed:
+		return err
+	case <-ctx.Done():
+		// Cancelling the writer abandons the upload and unblocks the close.
+		o.cancel()
+		<-closed
+		return ctx.Err()
+	}
+}
+
+func (o *gcsRollingObject) Abort() {
+	o.cancel()
+}
+
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
 func (g *gcpCloudStorageOutput) CloseAsync() {
 	go func() {
+		if g.rolling != nil {
+			ctx, done := context.WithTimeout(context.Background(), shutdown.MaximumShutdownWait())
+			if err := g.rolling.Close(ctx); err != nil {
+				g.log.Errorf("Failed to commit rolling objects: %v\n", err)
+			}
+			done()
+		}
+		if g.partitions != nil {
+			ctx, done := context.WithTimeout(context.Background(), shutdown.MaximumShutdownWait())
+			g.partitions.CommitAll(ctx, g.flushPartition)
+			done()
+			g.partitions.CloseAsync()
+			_ = g.partitions.WaitForClose(shutdown.MaximumShutdownWait())
+		}
+
 		g.connMut.Lock()
 		if g.client != nil {
 			g.client.Close()
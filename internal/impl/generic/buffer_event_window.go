@@ -0,0 +1,747 @@
+package generic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/internal/shutdown"
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+func eventWindowBufferConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.60.0").
+		Categories("Windowing").
+		Summary("Groups messages by a key into tumbling, sliding or session windows following the event time of messages, where windows are triggered by a watermark.").
+		Description(`
+Each message is assigned a timestamp via the `+"[`timestamp_mapping` field](#timestamp_mapping)"+` and a key via the `+"[`key_mapping` field](#key_mapping)"+`, and is then added to the windows of that key that its timestamp falls within. Windows are flushed as a batch of messages once they are triggered, where each message of the batch has the metadata fields `+"`window_key`"+`, `+"`window_start_timestamp`"+` and `+"`window_end_timestamp`"+` added to it, the timestamps being RFC3339 strings.
+
+## Window Types
+
+- `+"`tumbling`"+`: Windows of a fixed `+"`size`"+` aligned to the zeroth unix epoch, where the beginning of a window immediately follows the end of the prior window.
+- `+"`sliding`"+`: Windows of a fixed `+"`size`"+` where each window begins at an offset of `+"`slide`"+` from the beginning of the prior window, and therefore messages may belong to multiple windows.
+- `+"`session`"+`: Windows that begin with the first message of a key and are extended by each following message of that key, ending once no messages of that key have been seen for the `+"`gap`"+` duration.
+
+## Watermarks
+
+Rather than following the system clock this buffer tracks a watermark, which is the largest timestamp seen across all messages minus the `+"`allowed_lateness`"+`. A window is triggered once the watermark reaches its end, and therefore messages arriving out of order are added to their window as long as they arrive within the allowed lateness.
+
+Messages that arrive after all of their windows have been triggered are considered late, and are written to the `+"`late_output`"+` when one is configured, and are otherwise dropped.
+
+Since the watermark only advances when new messages arrive an `+"`idle_timeout`"+` can be specified, which triggers all open windows when no messages have been written to the buffer for that duration.
+
+## Delivery Guarantees
+
+Messages are not acknowledged until the windows they belong to have been successfully delivered, and when a window fails to be delivered it is triggered again.
+
+When a `+"`checkpoint_path`"+` is specified the contents of open windows are periodically written to that path, and are restored from it when the buffer is created, which means open windows are not lost when Benthos is restarted. In this case messages are acknowledged once they have been written to a checkpoint rather than when their windows are delivered.
+
+When the input has ended all open windows are triggered regardless of the watermark.`).
+		Field(service.NewStringAnnotatedEnumField("type", map[string]string{
+			"tumbling": "Fixed size windows that do not overlap.",
+			"sliding":  "Fixed size windows that overlap at an offset of the slide duration.",
+			"session":  "Windows of activity per key that are separated by a gap of inactivity.",
+		}).
+			Description("The type of windows to create.").
+			Default("tumbling")).
+		Field(service.NewBloblangField("timestamp_mapping").
+			Description(`
+A [Bloblang mapping](/docs/guides/bloblang/about) applied to each message during ingestion that provides the event timestamp to use for allocating it a window.
+
+The timestamp value assigned to `+"`root`"+` must either be a numerical unix time in seconds (with up to nanosecond precision via decimals), or a string in ISO 8601 format. If the mapping fails or provides an invalid result the message will be dropped (with logging to describe the problem).
+`).
+			Default("root = now()").
+			Example("root = this.created_at").Example(`root = meta("kafka_timestamp_unix").number()`)).
+		Field(service.NewBloblangField("key_mapping").
+			Description("A [Bloblang mapping](/docs/guides/bloblang/about) applied to each message during ingestion that provides a key to group it by, the result is converted into a string. By default all messages share the same key.").
+			Default(`root = ""`).
+			Example("root = this.user_id").Example(`root = meta("kafka_key")`)).
+		Field(service.NewStringField("size").
+			Description("A duration string describing the size of each window, required for `tumbling` and `sliding` windows.").
+			Default("").
+			Example("30s").Example("10m")).
+		Field(service.NewStringField("slide").
+			Description("A duration string describing by how much time the beginning of each window should be offset from the beginning of the previous, required for `sliding` windows and must be smaller than the `size`.").
+			Default("").
+			Example("30s").Example("10m")).
+		Field(service.NewStringField("gap").
+			Description("A duration string describing the period of inactivity after which a `session` window ends, required for `session` windows.").
+			Default("").
+			Example("30s").Example("10m")).
+		Field(service.NewStringField("allowed_lateness").
+			Description("An optional duration string describing how far the watermark lags behind the largest timestamp seen, allowing messages that arrive out of order within this duration to be added to their windows.").
+			Default("").
+			Example("10s").Example("1m")).
+		Field(service.NewStringField("idle_timeout").
+			Description("An optional duration string, which when specified causes all open windows to be triggered when no messages have been written to the buffer for that duration.").
+			Default("").
+			Example("1m")).
+		Field(service.NewOutputField("late_output").
+			Description("An optional output to write late messages to, which are messages that arrive after all of their windows have been triggered.").
+			Optional()).
+		Field(service.NewStringField("checkpoint_path").
+			Description("An optional file path to periodically write the contents of open windows to, which are restored when the buffer is created.").
+			Default("").
+			Example("/var/lib/benthos/windows.json")).
+		Field(service.NewStringField("checkpoint_interval").
+			Description("A duration string describing how often to write checkpoints when a `checkpoint_path` is specified.").
+			Default("1s").
+			Advanced()).
+		Example("Per-User Sessions", `Given a stream of user activity events of the form:
+
+`+"```json"+`
+{
+  "user_id": "ash",
+  "created_at": "2021-08-07T09:49:35Z",
+  "action": "click"
+}
+`+"```"+`
+
+We can group the events of each user into sessions that end after ten minutes of inactivity, and emit a summary of each session of the form:
+
+`+"```json"+`
+{
+  "user_id": "ash",
+  "started_at": "2021-08-07T09:49:35Z",
+  "ended_at": "2021-08-07T10:04:12Z",
+  "actions": 12
+}
+`+"```"+`
+
+With the following config:`,
+			`
+buffer:
+  event_window:
+    type: session
+    gap: 10m
+    allowed_lateness: 1m
+    timestamp_mapping: root = this.created_at
+    key_mapping: root = this.user_id
+    checkpoint_path: ./sessions.json
+
+pipeline:
+  processors:
+    - bloblang: |
+        root = if batch_index() == 0 {
+          {
+            "user_id": meta("window_key"),
+            "started_at": json("created_at").from_all().sort().index(0),
+            "ended_at": json("created_at").from_all().sort().index(-1),
+            "actions": batch_size(),
+          }
+        } else { deleted() }
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchBuffer(
+		"event_window", eventWindowBufferConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchBuffer, error) {
+			return newEventWindowBufferFromConfig(conf, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type eventWindowMessage struct {
+	ts    time.Time
+	m     *service.Message
+	ackFn service.AckFunc
+}
+
+type eventWindow struct {
+	key        string
+	start, end time.Time
+	msgs       []*eventWindowMessage
+
+	// Whether the window has been flushed and is awaiting acknowledgement.
+	flushed bool
+}
+
+type eventWindowBuffer struct {
+	logger *service.Logger
+
+	windowType      string
+	tsMapping       *bloblang.Executor
+	keyMapping      *bloblang.Executor
+	size, slide     time.Duration
+	gap             time.Duration
+	allowedLateness time.Duration
+	idleTimeout     time.Duration
+	late            *service.OwnedOutput
+
+	checkpointPath     string
+	checkpointInterval time.Duration
+
+	mut            sync.Mutex
+	windows        []*eventWindow
+	maxTS          time.Time
+	lastWrite      time.Time
+	pendingAcks    []service.AckFunc
+	checkpointDirt bool
+
+	notifyChan          chan struct{}
+	endOfInputChan      chan struct{}
+	closeEndOfInputOnce sync.Once
+	shutSig             *shutdown.Signaller
+}
+
+func newEventWindowBufferFromConfig(conf *service.ParsedConfig, logger *service.Logger) (*eventWindowBuffer, error) {
+	w := &eventWindowBuffer{
+		logger:         logger,
+		lastWrite:      time.Now(),
+		notifyChan:     make(chan struct{}, 1),
+		endOfInputChan: make(chan struct{}),
+		shutSig:        shutdown.NewSignaller(),
+	}
+
+	var err error
+	if w.windowType, err = conf.FieldString("type"); err != nil {
+		return nil, err
+	}
+	if w.tsMapping, err = conf.FieldBloblang("timestamp_mapping"); err != nil {
+		return nil, err
+	}
+	if w.keyMapping, err = conf.FieldBloblang("key_mapping"); err != nil {
+		return nil, err
+	}
+	if w.size, err = getDuration(conf, false, "size"); err != nil {
+		return nil, err
+	}
+	if w.slide, err = getDuration(conf, false, "slide"); err != nil {
+		return nil, err
+	}
+	if w.gap, err = getDuration(conf, false, "gap"); err != nil {
+		return nil, err
+	}
+
+	switch w.windowType {
+	case "tumbling":
+		if w.size <= 0 {
+			return nil, errors.New("a window size must be specified for tumbling windows")
+		}
+	case "sliding":
+		if w.size <= 0 || w.slide <= 0 {
+			return nil, errors.New("a window size and slide must be specified for sliding windows")
+		}
+		if w.slide >= w.size {
+			return nil, fmt.Errorf("invalid window slide '%v' must be lower than the size '%v'", w.slide, w.size)
+		}
+	case "session":
+		if w.gap <= 0 {
+			return nil, errors.New("a gap must be specified for session windows")
+		}
+	default:
+		return nil, fmt.Errorf("window type '%v' was not recognised", w.windowType)
+	}
+
+	if w.allowedLateness, err = getDuration(conf, false, "allowed_lateness"); err != nil {
+		return nil, err
+	}
+	if w.idleTimeout, err = getDuration(conf, false, "idle_timeout"); err != nil {
+		return nil, err
+	}
+	if conf.Contains("late_output") {
+		if w.late, err = conf.FieldOutput("late_output"); err != nil {
+			return nil, err
+		}
+	}
+
+	if w.checkpointPath, err = conf.FieldString("checkpoint_path"); err != nil {
+		return nil, err
+	}
+	if w.checkpointInterval, err = getDuration(conf, true, "checkpoint_interval"); err != nil {
+		return nil, err
+	}
+	if w.checkpointPath != "" {
+		if err := w.readCheckpoint(); err != nil {
+			return nil, err
+		}
+		go w.checkpointLoop()
+	} else {
+		w.shutSig.ShutdownComplete()
+	}
+	return w, nil
+}
+
+//------------------------------------------------------------------------------
+
+type eventWindowCheckpointMessage struct {
+	Timestamp int64             `json:"ts"`
+	Content   []byte            `json:"content"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+type eventWindowCheckpointWindow struct {
+	Key      string                         `json:"key"`
+	Start    int64                          `json:"start"`
+	End      int64                          `json:"end"`
+	Messages []eventWindowCheckpointMessage `json:"messages"`
+}
+
+type eventWindowCheckpoint struct {
+	MaxTimestamp int64                         `json:"max_ts"`
+	Windows      []eventWindowCheckpointWindow `json:"windows"`
+}
+
+func (w *eventWindowBuffer) readCheckpoint() error {
+	cBytes, err := os.ReadFile(w.checkpointPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var c eventWindowCheckpoint
+	if err := json.Unmarshal(cBytes, &c); err != nil {
+		return fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+
+	if c.MaxTimestamp > 0 {
+		w.maxTS = time.Unix(0, c.MaxTimestamp)
+	}
+	for _, cw := range c.Windows {
+		win := &eventWindow{
+			key:   cw.Key,
+			start: time.Unix(0, cw.Start).UTC(),
+			end:   time.Unix(0, cw.End).UTC(),
+		}
+		for _, cm := range cw.Messages {
+			msg := service.NewMessage(cm.Content)
+			for k, v := range cm.Metadata {
+				msg.MetaSet(k, v)
+			}
+			win.msgs = append(win.msgs, &eventWindowMessage{
+				ts: time.Unix(0, cm.Timestamp).UTC(),
+				m:  msg,
+			})
+		}
+		w.windows = append(w.windows, win)
+	}
+	return nil
+}
+
+// writeCheckpoint writes the current state of windows to the checkpoint path,
+// and then acknowledges all messages written since the last checkpoint.
+func (w *eventWindowBuffer) writeCheckpoint(ctx context.Context) error {
+	w.mut.Lock()
+	if !w.checkpointDirt {
+		w.mut.Unlock()
+		return nil
+	}
+
+	var c eventWindowCheckpoint
+	if !w.maxTS.IsZero() {
+		c.MaxTimestamp = w.maxTS.UnixNano()
+	}
+	for _, win := range w.windows {
+		cw := eventWindowCheckpointWindow{
+			Key:   win.key,
+			Start: win.start.UnixNano(),
+			End:   win.end.UnixNano(),
+		}
+		for _, m := range win.msgs {
+			content, err := m.m.AsBytes()
+			if err != nil {
+				w.mut.Unlock()
+				return err
+			}
+			cm := eventWindowCheckpointMessage{
+				Timestamp: m.ts.UnixNano(),
+				Content:   content,
+			}
+			_ = m.m.MetaWalk(func(k, v string) error {
+				if cm.Metadata == nil {
+					cm.Metadata = map[string]string{}
+				}
+				cm.Metadata[k] = v
+				return nil
+			})
+			cw.Messages = append(cw.Messages, cm)
+		}
+		c.Windows = append(c.Windows, cw)
+	}
+	acks := w.pendingAcks
+	w.pendingAcks = nil
+	w.checkpointDirt = false
+	w.mut.Unlock()
+
+	err := func() error {
+		cBytes, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		tmpPath := w.checkpointPath + ".tmp"
+		if err := os.MkdirAll(filepath.Dir(tmpPath), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(tmpPath, cBytes, 0o644); err != nil {
+			return err
+		}
+		return os.Rename(tmpPath, w.checkpointPath)
+	}()
+	if err != nil {
+		w.mut.Lock()
+		w.pendingAcks = append(acks, w.pendingAcks...)
+		w.checkpointDirt = true
+		w.mut.Unlock()
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	for _, aFn := range acks {
+		_ = aFn(ctx, nil)
+	}
+	return nil
+}
+
+func (w *eventWindowBuffer) checkpointLoop() {
+	defer w.shutSig.ShutdownComplete()
+
+	ticker := time.NewTicker(w.checkpointInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.shutSig.CloseAtLeisureChan():
+			if err := w.writeCheckpoint(context.Background()); err != nil {
+				w.logger.Errorf("Failed to write final window checkpoint: %v", err)
+			}
+			return
+		}
+		if err := w.writeCheckpoint(context.Background()); err != nil {
+			w.logger.Errorf("Failed to write window checkpoint: %v", err)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// watermark returns the current watermark, which must be called whilst holding
+// the mutex.
+func (w *eventWindowBuffer) watermark() time.Time {
+	if w.maxTS.IsZero() {
+		return w.maxTS
+	}
+	return w.maxTS.Add(-w.allowedLateness)
+}
+
+// assignWindows returns the start and end times of the fixed size windows
+// that a timestamp belongs to.
+func (w *eventWindowBuffer) assignWindows(ts time.Time) (starts, ends []time.Time) {
+	if w.windowType == "tumbling" {
+		start := ts.Truncate(w.size)
+		return []time.Time{start}, []time.Time{start.Add(w.size)}
+	}
+	for start := ts.Truncate(w.slide); start.Add(w.size).After(ts); start = start.Add(-w.slide) {
+		starts = append(starts, start)
+		ends = append(ends, start.Add(w.size))
+	}
+	return
+}
+
+func (w *eventWindowBuffer) findWindow(key string, start time.Time) *eventWindow {
+	for _, win := range w.windows {
+		if !win.flushed && win.key == key && win.start.Equal(start) {
+			return win
+		}
+	}
+	return nil
+}
+
+// isLate returns whether all of the windows that a message of a key and
+// timestamp belongs to have already been triggered. Must be called whilst
+// holding the mutex.
+func (w *eventWindowBuffer) isLate(key string, ts time.Time) bool {
+	watermark := w.watermark()
+	if w.windowType == "session" {
+		if ts.Add(w.gap).After(watermark) {
+			return false
+		}
+		// The message might still extend an open session of the key.
+		for _, win := range w.windows {
+			if !win.flushed && win.key == key && !win.start.After(ts.Add(w.gap)) && !ts.After(win.end) {
+				return false
+			}
+		}
+		return true
+	}
+	_, ends := w.assignWindows(ts)
+	for _, end := range ends {
+		if end.After(watermark) {
+			return false
+		}
+	}
+	return true
+}
+
+// addMessage adds a message to the windows it belongs to. Must be called
+// whilst holding the mutex.
+func (w *eventWindowBuffer) addMessage(key string, ts time.Time, msg *service.Message, ackFn func() service.AckFunc) {
+	if w.windowType == "session" {
+		start, end := ts, ts.Add(w.gap)
+
+		// Merge all open sessions of the key that overlap with the new one.
+		var msgs []*eventWindowMessage
+		remaining := make([]*eventWindow, 0, len(w.windows))
+		for _, win := range w.windows {
+			if !win.flushed && win.key == key && !win.start.After(end) && !start.After(win.end) {
+				if win.start.Before(start) {
+					start = win.start
+				}
+				if win.end.After(end) {
+					end = win.end
+				}
+				msgs = append(msgs, win.msgs...)
+				continue
+			}
+			remaining = append(remaining, win)
+		}
+		w.windows = append(remaining, &eventWindow{
+			key:   key,
+			start: start,
+			end:   end,
+			msgs: append(msgs, &eventWindowMessage{
+				ts: ts, m: msg, ackFn: ackFn(),
+			}),
+		})
+		return
+	}
+
+	watermark := w.watermark()
+	starts, ends := w.assignWindows(ts)
+	for i, start := range starts {
+		if !ends[i].After(watermark) {
+			continue
+		}
+		win := w.findWindow(key, start)
+		if win == nil {
+			win = &eventWindow{key: key, start: start, end: ends[i]}
+			w.windows = append(w.windows, win)
+		}
+		win.msgs = append(win.msgs, &eventWindowMessage{
+			ts: ts, m: msg, ackFn: ackFn(),
+		})
+	}
+}
+
+func (w *eventWindowBuffer) notify() {
+	select {
+	case w.notifyChan <- struct{}{}:
+	default:
+	}
+}
+
+type eventWindowPending struct {
+	key string
+	ts  time.Time
+	msg *service.Message
+}
+
+func (w *eventWindowBuffer) WriteBatch(ctx context.Context, msgBatch service.MessageBatch, aFn service.AckFunc) error {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	w.lastWrite = time.Now()
+
+	var added []eventWindowPending
+	var lateBatch service.MessageBatch
+	for i, msg := range msgBatch {
+		ts, err := mapBatchTimestamp(w.logger, w.tsMapping, i, msgBatch)
+		if err != nil {
+			continue
+		}
+		ts = ts.UTC()
+
+		keyMsg, err := msgBatch.BloblangQuery(i, w.keyMapping)
+		if err != nil {
+			w.logger.Errorf("Key mapping failed for message: %v", err)
+			continue
+		}
+		var keyBytes []byte
+		if keyMsg != nil {
+			if keyBytes, err = keyMsg.AsBytes(); err != nil {
+				w.logger.Errorf("Key mapping failed for message: %v", err)
+				continue
+			}
+		}
+
+		key := string(keyBytes)
+		if w.isLate(key, ts) {
+			w.logger.Debugf("Message with timestamp '%v' arrived after the watermark and is late", ts.Format(time.RFC3339Nano))
+			if w.late != nil {
+				lateBatch = append(lateBatch, msg)
+			}
+			continue
+		}
+		added = append(added, eventWindowPending{key: key, ts: ts, msg: msg})
+	}
+
+	// Late messages are written before adding the remaining messages to
+	// windows so that the whole batch can be rejected on failure.
+	if len(lateBatch) > 0 {
+		if err := w.late.WriteBatch(ctx, lateBatch); err != nil {
+			return fmt.Errorf("failed to write late messages: %w", err)
+		}
+	}
+
+	if len(added) == 0 {
+		// If none of the messages have fit into a window we reject them by
+		// acknowledging the batch.
+		_ = aFn(ctx, nil)
+		return nil
+	}
+
+	// When checkpointing messages are acknowledged once written to a
+	// checkpoint, otherwise they're acknowledged once all of their windows are
+	// delivered.
+	deriveAck := func() service.AckFunc { return nil }
+	if w.checkpointPath == "" {
+		aggregatedAck := batch.NewCombinedAcker(batch.AckFunc(aFn))
+		deriveAck = func() service.AckFunc {
+			return service.AckFunc(aggregatedAck.Derive())
+		}
+	} else {
+		w.pendingAcks = append(w.pendingAcks, aFn)
+		w.checkpointDirt = true
+	}
+
+	for _, p := range added {
+		w.addMessage(p.key, p.ts, p.msg, deriveAck)
+	}
+	for _, p := range added {
+		if p.ts.After(w.maxTS) {
+			w.maxTS = p.ts
+		}
+	}
+	w.notify()
+	return nil
+}
+
+// nextFlushable returns the next window ready to be flushed, or nil if no
+// windows are ready. Must be called whilst holding the mutex.
+func (w *eventWindowBuffer) nextFlushable(force bool) *eventWindow {
+	watermark := w.watermark()
+
+	var next *eventWindow
+	for _, win := range w.windows {
+		if win.flushed || (!force && win.end.After(watermark)) {
+			continue
+		}
+		if next == nil || win.end.Before(next.end) || (win.end.Equal(next.end) && win.key < next.key) {
+			next = win
+		}
+	}
+	return next
+}
+
+func (w *eventWindowBuffer) flushWindow(win *eventWindow) (service.MessageBatch, service.AckFunc) {
+	win.flushed = true
+
+	sort.SliceStable(win.msgs, func(i, j int) bool {
+		return win.msgs[i].ts.Before(win.msgs[j].ts)
+	})
+
+	flushBatch := make(service.MessageBatch, 0, len(win.msgs))
+	for _, m := range win.msgs {
+		tmpMsg := m.m.Copy()
+		tmpMsg.MetaSet("window_key", win.key)
+		tmpMsg.MetaSet("window_start_timestamp", win.start.Format(time.RFC3339Nano))
+		tmpMsg.MetaSet("window_end_timestamp", win.end.Format(time.RFC3339Nano))
+		flushBatch = append(flushBatch, tmpMsg)
+	}
+
+	return flushBatch, func(ctx context.Context, err error) error {
+		w.mut.Lock()
+		if err != nil {
+			// Trigger the window again.
+			win.flushed = false
+			w.mut.Unlock()
+			w.notify()
+			return nil
+		}
+		for i, other := range w.windows {
+			if other == win {
+				w.windows = append(w.windows[:i], w.windows[i+1:]...)
+				break
+			}
+		}
+		w.checkpointDirt = true
+		w.mut.Unlock()
+
+		for _, m := range win.msgs {
+			if m.ackFn != nil {
+				_ = m.ackFn(ctx, nil)
+			}
+		}
+		return nil
+	}
+}
+
+func (w *eventWindowBuffer) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	endOfInput := false
+	endOfInputChan := w.endOfInputChan
+	for {
+		w.mut.Lock()
+		force := endOfInput || (w.idleTimeout > 0 && time.Since(w.lastWrite) >= w.idleTimeout)
+		if win := w.nextFlushable(force); win != nil {
+			msgBatch, aFn := w.flushWindow(win)
+			w.mut.Unlock()
+			return msgBatch, aFn, nil
+		}
+		remaining := len(w.windows)
+		idleChan := (<-chan time.Time)(nil)
+		if w.idleTimeout > 0 && remaining > 0 {
+			idleChan = time.After(time.Until(w.lastWrite.Add(w.idleTimeout)))
+		}
+		w.mut.Unlock()
+
+		// When the input has ended we wait for all windows to be delivered.
+		if endOfInput && remaining == 0 {
+			return nil, nil, service.ErrEndOfBuffer
+		}
+
+		select {
+		case <-w.notifyChan:
+		case <-idleChan:
+		case <-endOfInputChan:
+			endOfInput, endOfInputChan = true, nil
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+func (w *eventWindowBuffer) EndOfInput() {
+	w.closeEndOfInputOnce.Do(func() {
+		close(w.endOfInputChan)
+	})
+}
+
+func (w *eventWindowBuffer) Close(ctx context.Context) error {
+	w.shutSig.CloseAtLeisure()
+	select {
+	case <-w.shutSig.HasClosedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if w.late != nil {
+		return w.late.Close(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,345 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEventWindowBufferFromYAML(t *testing.T, confStr string) *eventWindowBuffer {
+	t.Helper()
+
+	conf, err := eventWindowBufferConfig().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	w, err := newEventWindowBufferFromConfig(conf, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = w.Close(context.Background())
+	})
+	return w
+}
+
+type eventWindowResult struct {
+	key     string
+	start   string
+	end     string
+	content []string
+}
+
+func readEventWindow(t *testing.T, w *eventWindowBuffer, ackErr error) (eventWindowResult, bool) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer done()
+
+	msgBatch, aFn, err := w.ReadBatch(ctx)
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, service.ErrEndOfBuffer) {
+		return eventWindowResult{}, false
+	}
+	require.NoError(t, err)
+	require.NotEmpty(t, msgBatch)
+
+	var res eventWindowResult
+	for _, m := range msgBatch {
+		b, err := m.AsBytes()
+		require.NoError(t, err)
+		res.content = append(res.content, string(b))
+		res.key, _ = m.MetaGet("window_key")
+		res.start, _ = m.MetaGet("window_start_timestamp")
+		res.end, _ = m.MetaGet("window_end_timestamp")
+	}
+	require.NoError(t, aFn(context.Background(), ackErr))
+	return res, true
+}
+
+type eventWindowAcks struct {
+	mut   sync.Mutex
+	acked int
+}
+
+func (e *eventWindowAcks) fn(ctx context.Context, err error) error {
+	e.mut.Lock()
+	if err == nil {
+		e.acked++
+	}
+	e.mut.Unlock()
+	return nil
+}
+
+func (e *eventWindowAcks) count() int {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	return e.acked
+}
+
+func writeEventWindow(t *testing.T, w *eventWindowBuffer, acks *eventWindowAcks, docs ...string) {
+	t.Helper()
+
+	var b service.MessageBatch
+	for _, d := range docs {
+		b = append(b, service.NewMessage([]byte(d)))
+	}
+	require.NoError(t, w.WriteBatch(context.Background(), b, acks.fn))
+}
+
+func TestEventWindowBufferConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		config      string
+		errContains string
+	}{
+		{config: `type: tumbling`, errContains: "a window size must be specified"},
+		{config: `{ type: sliding, size: 10s }`, errContains: "a window size and slide must be specified"},
+		{config: `{ type: sliding, size: 10s, slide: 10s }`, errContains: "must be lower than the size"},
+		{config: `type: session`, errContains: "a gap must be specified"},
+	} {
+		conf, err := eventWindowBufferConfig().ParseYAML(test.config, nil)
+		require.NoError(t, err, test.config)
+
+		_, err = newEventWindowBufferFromConfig(conf, nil)
+		require.Error(t, err, test.config)
+		assert.Contains(t, err.Error(), test.errContains, test.config)
+	}
+}
+
+func TestEventWindowBufferTumbling(t *testing.T) {
+	w := newEventWindowBufferFromYAML(t, `
+type: tumbling
+size: 10s
+timestamp_mapping: root = this.ts
+key_mapping: root = this.key
+`)
+
+	acks := &eventWindowAcks{}
+	writeEventWindow(t, w, acks,
+		`{"key":"a","ts":1}`,
+		`{"key":"b","ts":2}`,
+		`{"key":"a","ts":5}`,
+	)
+
+	_, ok := readEventWindow(t, w, nil)
+	assert.False(t, ok, "window should not trigger before the watermark")
+
+	writeEventWindow(t, w, acks, `{"key":"a","ts":12}`)
+
+	res, ok := readEventWindow(t, w, nil)
+	require.True(t, ok)
+	assert.Equal(t, eventWindowResult{
+		key:     "a",
+		start:   "1970-01-01T00:00:00Z",
+		end:     "1970-01-01T00:00:10Z",
+		content: []string{`{"key":"a","ts":1}`, `{"key":"a","ts":5}`},
+	}, res)
+
+	res, ok = readEventWindow(t, w, nil)
+	require.True(t, ok)
+	assert.Equal(t, eventWindowResult{
+		key:     "b",
+		start:   "1970-01-01T00:00:00Z",
+		end:     "1970-01-01T00:00:10Z",
+		content: []string{`{"key":"b","ts":2}`},
+	}, res)
+
+	_, ok = readEventWindow(t, w, nil)
+	assert.False(t, ok)
+	assert.Equal(t, 1, acks.count())
+
+	w.EndOfInput()
+	res, ok = readEventWindow(t, w, nil)
+	require.True(t, ok)
+	assert.Equal(t, []string{`{"key":"a","ts":12}`}, res.content)
+	assert.Equal(t, 2, acks.count())
+
+	_, ok = readEventWindow(t, w, nil)
+	assert.False(t, ok)
+}
+
+func TestEventWindowBufferSliding(t *testing.T) {
+	w := newEventWindowBufferFromYAML(t, `
+type: sliding
+size: 10s
+slide: 5s
+timestamp_mapping: root = this.ts
+`)
+
+	acks := &eventWindowAcks{}
+	writeEventWindow(t, w, acks, `{"ts":7}`, `{"ts":12}`, `{"ts":21}`)
+
+	var results []eventWindowResult
+	for {
+		res, ok := readEventWindow(t, w, nil)
+		if !ok {
+			break
+		}
+		results = append(results, res)
+	}
+	assert.Equal(t, []eventWindowResult{
+		{start: "1970-01-01T00:00:00Z", end: "1970-01-01T00:00:10Z", content: []string{`{"ts":7}`}},
+		{start: "1970-01-01T00:00:05Z", end: "1970-01-01T00:00:15Z", content: []string{`{"ts":7}`, `{"ts":12}`}},
+		{start: "1970-01-01T00:00:10Z", end: "1970-01-01T00:00:20Z", content: []string{`{"ts":12}`}},
+	}, results)
+	assert.Equal(t, 0, acks.count(), "batch should not be acked until all windows are delivered")
+}
+
+func TestEventWindowBufferSession(t *testing.T) {
+	w := newEventWindowBufferFromYAML(t, `
+type: session
+gap: 5s
+timestamp_mapping: root = this.ts
+key_mapping: root = this.key
+`)
+
+	acks := &eventWindowAcks{}
+	writeEventWindow(t, w, acks, `{"key":"a","ts":1}`, `{"key":"a","ts":4}`, `{"key":"b","ts":3}`)
+	writeEventWindow(t, w, acks, `{"key":"a","ts":8}`, `{"key":"a","ts":20}`)
+
+	res, ok := readEventWindow(t, w, nil)
+	require.True(t, ok)
+	assert.Equal(t, eventWindowResult{
+		key:     "b",
+		start:   "1970-01-01T00:00:03Z",
+		end:     "1970-01-01T00:00:08Z",
+		content: []string{`{"key":"b","ts":3}`},
+	}, res)
+
+	res, ok = readEventWindow(t, w, nil)
+	require.True(t, ok)
+	assert.Equal(t, eventWindowResult{
+		key:     "a",
+		start:   "1970-01-01T00:00:01Z",
+		end:     "1970-01-01T00:00:13Z",
+		content: []string{`{"key":"a","ts":1}`, `{"key":"a","ts":4}`, `{"key":"a","ts":8}`},
+	}, res)
+
+	_, ok = readEventWindow(t, w, nil)
+	assert.False(t, ok)
+}
+
+func TestEventWindowBufferLateness(t *testing.T) {
+	latePath := filepath.Join(t.TempDir(), "late.jsonl")
+	w := newEventWindowBufferFromYAML(t, `
+type: tumbling
+size: 10s
+allowed_lateness: 5s
+timestamp_mapping: root = this.ts
+late_output:
+  file:
+    path: `+latePath+`
+    codec: lines
+`)
+
+	acks := &eventWindowAcks{}
+	writeEventWindow(t, w, acks, `{"ts":1}`, `{"ts":12}`)
+
+	// Within the allowed lateness.
+	writeEventWindow(t, w, acks, `{"ts":2}`)
+	_, ok := readEventWindow(t, w, nil)
+	assert.False(t, ok)
+
+	writeEventWindow(t, w, acks, `{"ts":16}`)
+	res, ok := readEventWindow(t, w, nil)
+	require.True(t, ok)
+	assert.Equal(t, []string{`{"ts":1}`, `{"ts":2}`}, res.content)
+
+	// After the window has triggered.
+	writeEventWindow(t, w, acks, `{"ts":3}`)
+	assert.Equal(t, 2, acks.count())
+
+	require.NoError(t, w.Close(context.Background()))
+
+	lateBytes, err := os.ReadFile(latePath)
+	require.NoError(t, err)
+	assert.Equal(t, `{"ts":3}`, strings.TrimSpace(string(lateBytes)))
+}
+
+func TestEventWindowBufferNackRetriggers(t *testing.T) {
+	w := newEventWindowBufferFromYAML(t, `
+size: 10s
+timestamp_mapping: root = this.ts
+`)
+
+	acks := &eventWindowAcks{}
+	writeEventWindow(t, w, acks, `{"ts":1}`, `{"ts":11}`)
+
+	res, ok := readEventWindow(t, w, errors.New("nope"))
+	require.True(t, ok)
+	assert.Equal(t, []string{`{"ts":1}`}, res.content)
+	assert.Equal(t, 0, acks.count())
+
+	res, ok = readEventWindow(t, w, nil)
+	require.True(t, ok)
+	assert.Equal(t, []string{`{"ts":1}`}, res.content)
+}
+
+func TestEventWindowBufferIdleTimeout(t *testing.T) {
+	w := newEventWindowBufferFromYAML(t, `
+size: 10s
+idle_timeout: 50ms
+timestamp_mapping: root = this.ts
+`)
+
+	acks := &eventWindowAcks{}
+	writeEventWindow(t, w, acks, `{"ts":1}`)
+
+	res, ok := readEventWindow(t, w, nil)
+	require.True(t, ok)
+	assert.Equal(t, []string{`{"ts":1}`}, res.content)
+	assert.Equal(t, 1, acks.count())
+}
+
+func TestEventWindowBufferCheckpoint(t *testing.T) {
+	checkpointPath := filepath.Join(t.TempDir(), "windows.json")
+	confStr := `
+size: 10s
+timestamp_mapping: root = this.ts
+key_mapping: root = this.key
+checkpoint_path: ` + checkpointPath + `
+checkpoint_interval: 1h
+`
+
+	w := newEventWindowBufferFromYAML(t, confStr)
+
+	acks := &eventWindowAcks{}
+	writeEventWindow(t, w, acks, `{"key":"a","ts":1}`, `{"key":"b","ts":2}`)
+	writeEventWindow(t, w, acks, `{"key":"a","ts":12}`)
+	assert.Equal(t, 0, acks.count())
+
+	res, ok := readEventWindow(t, w, nil)
+	require.True(t, ok)
+	assert.Equal(t, []string{`{"key":"a","ts":1}`}, res.content)
+
+	require.NoError(t, w.Close(context.Background()))
+	assert.Equal(t, 2, acks.count(), "messages should be acked once checkpointed")
+
+	w = newEventWindowBufferFromYAML(t, confStr)
+
+	// The watermark is restored and so late messages are rejected.
+	writeEventWindow(t, w, acks, `{"key":"a","ts":3}`)
+	assert.Equal(t, 3, acks.count())
+
+	res, ok = readEventWindow(t, w, nil)
+	require.True(t, ok)
+	assert.Equal(t, eventWindowResult{
+		key:     "b",
+		start:   "1970-01-01T00:00:00Z",
+		end:     "1970-01-01T00:00:10Z",
+		content: []string{`{"key":"b","ts":2}`},
+	}, res)
+
+	w.EndOfInput()
+	res, ok = readEventWindow(t, w, nil)
+	require.True(t, ok)
+	assert.Equal(t, []string{`{"key":"a","ts":12}`}, res.content)
+
+	_, ok = readEventWindow(t, w, nil)
+	assert.False(t, ok)
+}
@@ -0,0 +1,450 @@
+package generic
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/OneOfOne/xxhash"
+)
+
+func bloomCacheConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("3.60.0").
+		Summary("Stores keys in a bloom filter, a probabilistic data structure that uses a fixed amount of memory regardless of the number of keys added, but may report that a key exists when it does not.").
+		Description(`
+This cache is intended for deduplicating very large numbers of keys where storing each key in a regular cache would be prohibitively expensive, and is compatible with the `+"[`dedupe` processor](/docs/components/processors/dedupe)"+`. The size of the filter is calculated from the expected number of keys `+"`capacity`"+` and the desired `+"`false_positive_rate`"+`, which is the probability that a key that was never added is reported as a duplicate.
+
+Bloom filters do not store values and therefore a `+"`get`"+` operation returns an empty value when a key might exist. It is also not possible to delete keys from a bloom filter, and delete operations therefore fail.
+
+### Generations
+
+When a `+"`rotation_period`"+` is specified keys are added to the newest of several filters, called generations, and once the period has elapsed the oldest generation is discarded and a new empty one is created. Keys are considered to exist when they are found in any generation, which means keys are remembered for at least `+"`generations - 1`"+` rotation periods and no longer than `+"`generations`"+` rotation periods. This allows deduplication within a sliding window of time without the filter becoming saturated.
+
+Note that the `+"`capacity`"+` applies to each generation and therefore the memory usage of this cache is multiplied by the number of generations.
+
+### Snapshots
+
+When a `+"`snapshot_path`"+` is specified the filters are written to that path when the cache is closed and periodically according to the `+"`snapshot_interval`"+`, and are loaded from it when the cache is created. This allows deduplication state to survive restarts. Snapshots are ignored when the `+"`capacity`"+` or `+"`false_positive_rate`"+` of the cache have changed since the snapshot was written.`).
+		Field(service.NewIntField("capacity").
+			Description("The expected number of distinct keys to be added to each generation of the filter. Adding more keys than this increases the false positive rate beyond what is configured.").
+			Default(1000000)).
+		Field(service.NewFloatField("false_positive_rate").
+			Description("The desired probability of a key that has not been added being reported as existing, from 0 to 1 exclusive.").
+			Default(0.001)).
+		Field(service.NewStringField("rotation_period").
+			Description("An optional duration string describing how often to discard the oldest generation of the filter, which allows keys to expire. By default keys never expire.").
+			Default("").
+			Example("1h").Example("24h")).
+		Field(service.NewIntField("generations").
+			Description("The number of generations to keep when a `rotation_period` is specified.").
+			Default(2).
+			Advanced()).
+		Field(service.NewStringField("snapshot_path").
+			Description("An optional file path to persist snapshots of the filter to, and to restore them from on start up.").
+			Default("").
+			Example("/var/lib/benthos/dedupe.bloom")).
+		Field(service.NewStringField("snapshot_interval").
+			Description("An optional duration string describing how often to write a snapshot of the filter when a `snapshot_path` is specified, otherwise a snapshot is only written when the cache is closed.").
+			Default("").
+			Example("5m").
+			Advanced()).
+		Example("Windowed Deduplication", `
+Deduplicate billions of message IDs over a sliding window of roughly a day, keeping the filter state across restarts:`,
+			`
+pipeline:
+  processors:
+    - dedupe:
+        cache: ids
+        key: ${! json("id") }
+
+cache_resources:
+  - label: ids
+    bloom:
+      capacity: 500000000
+      false_positive_rate: 0.0001
+      rotation_period: 12h
+      generations: 3
+      snapshot_path: /var/lib/benthos/ids.bloom
+      snapshot_interval: 10m
+`)
+}
+
+func init() {
+	err := service.RegisterCache(
+		"bloom", bloomCacheConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Cache, error) {
+			return newBloomCacheFromConfig(conf, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newBloomCacheFromConfig(conf *service.ParsedConfig, log *service.Logger) (*bloomCache, error) {
+	capacity, err := conf.FieldInt("capacity")
+	if err != nil {
+		return nil, err
+	}
+	if capacity <= 0 {
+		return nil, errors.New("capacity must be greater than zero")
+	}
+	fpRate, err := conf.FieldFloat("false_positive_rate")
+	if err != nil {
+		return nil, err
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, errors.New("false_positive_rate must be between 0 and 1 exclusive")
+	}
+	rotationPeriod, err := getDuration(conf, false, "rotation_period")
+	if err != nil {
+		return nil, err
+	}
+	generations := 1
+	if rotationPeriod > 0 {
+		if generations, err = conf.FieldInt("generations"); err != nil {
+			return nil, err
+		}
+		if generations < 2 {
+			return nil, errors.New("generations must be at least 2 when a rotation_period is specified")
+		}
+	}
+	snapshotPath, err := conf.FieldString("snapshot_path")
+	if err != nil {
+		return nil, err
+	}
+	snapshotInterval, err := getDuration(conf, false, "snapshot_interval")
+	if err != nil {
+		return nil, err
+	}
+	return newBloomCache(bloomCacheOptions{
+		capacity:         uint64(capacity),
+		fpRate:           fpRate,
+		rotationPeriod:   rotationPeriod,
+		generations:      generations,
+		snapshotPath:     snapshotPath,
+		snapshotInterval: snapshotInterval,
+	}, log)
+}
+
+//------------------------------------------------------------------------------
+
+// bloomFilter is a fixed size bloom filter where the bit positions of a key are
+// derived from two hashes using the Kirsch-Mitzenmacher technique.
+type bloomFilter []uint64
+
+func bloomFilterSize(capacity uint64, fpRate float64) (m, k uint64) {
+	n := float64(capacity)
+	m = uint64(math.Ceil(-n * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k = uint64(math.Round(float64(m) / n * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return
+}
+
+func bloomHashes(key string) (h1, h2 uint64) {
+	b := []byte(key)
+	h1 = xxhash.Checksum64(b)
+	h2 = xxhash.Checksum64S(b, h1) | 1
+	return
+}
+
+func (f bloomFilter) contains(m, k, h1, h2 uint64) bool {
+	for i := uint64(0); i < k; i++ {
+		pos := (h1 + i*h2) % m
+		if f[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f bloomFilter) add(m, k, h1, h2 uint64) {
+	for i := uint64(0); i < k; i++ {
+		pos := (h1 + i*h2) % m
+		f[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type bloomCacheOptions struct {
+	capacity         uint64
+	fpRate           float64
+	rotationPeriod   time.Duration
+	generations      int
+	snapshotPath     string
+	snapshotInterval time.Duration
+}
+
+// bloomSnapshot is the persisted form of a bloom cache.
+type bloomSnapshot struct {
+	M, K        uint64
+	Rotated     time.Time
+	Generations []bloomFilter
+}
+
+type bloomCache struct {
+	opts bloomCacheOptions
+	log  *service.Logger
+	m, k uint64
+
+	// Generations are ordered from newest to oldest, and only the newest is
+	// ever modified. When frozen is set the newest generation is referenced by
+	// a snapshot being written and must be copied before it is modified.
+	generations []bloomFilter
+	frozen      bool
+	rotated     time.Time
+	mut         sync.Mutex
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+func newBloomCache(opts bloomCacheOptions, log *service.Logger) (*bloomCache, error) {
+	b := &bloomCache{
+		opts:       opts,
+		log:        log,
+		rotated:    time.Now(),
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+	b.m, b.k = bloomFilterSize(opts.capacity, opts.fpRate)
+	for i := 0; i < opts.generations; i++ {
+		b.generations = append(b.generations, b.newFilter())
+	}
+
+	if opts.snapshotPath != "" {
+		if err := b.loadSnapshot(); err != nil {
+			return nil, fmt.Errorf("failed to load snapshot: %w", err)
+		}
+	}
+
+	if opts.snapshotPath != "" && opts.snapshotInterval > 0 {
+		go b.snapshotLoop()
+	} else {
+		close(b.closedChan)
+	}
+	return b, nil
+}
+
+func (b *bloomCache) newFilter() bloomFilter {
+	return make(bloomFilter, (b.m+63)/64)
+}
+
+// rotate discards generations that have expired, must be called with the mutex
+// held.
+func (b *bloomCache) rotate() {
+	if b.opts.rotationPeriod <= 0 {
+		return
+	}
+	elapsed := time.Since(b.rotated)
+	if elapsed < b.opts.rotationPeriod {
+		return
+	}
+	periods := int(elapsed / b.opts.rotationPeriod)
+	b.rotated = b.rotated.Add(time.Duration(periods) * b.opts.rotationPeriod)
+	if periods > len(b.generations) {
+		periods = len(b.generations)
+	}
+	for i := 0; i < periods; i++ {
+		copy(b.generations[1:], b.generations[:len(b.generations)-1])
+		b.generations[0] = b.newFilter()
+	}
+	b.frozen = false
+}
+
+// addToNewest adds a key to the newest generation, copying it first if it is
+// referenced by a snapshot, must be called with the mutex held.
+func (b *bloomCache) addToNewest(h1, h2 uint64) {
+	if b.frozen {
+		b.generations[0] = append(bloomFilter(nil), b.generations[0]...)
+		b.frozen = false
+	}
+	b.generations[0].add(b.m, b.k, h1, h2)
+}
+
+// exists returns whether a key is possibly held by any generation, must be
+// called with the mutex held.
+func (b *bloomCache) exists(h1, h2 uint64) bool {
+	for _, g := range b.generations {
+		if g.contains(b.m, b.k, h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *bloomCache) Get(ctx context.Context, key string) ([]byte, error) {
+	h1, h2 := bloomHashes(key)
+
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.rotate()
+	if !b.exists(h1, h2) {
+		return nil, service.ErrKeyNotFound
+	}
+	return []byte{}, nil
+}
+
+func (b *bloomCache) Set(ctx context.Context, key string, value []byte, _ *time.Duration) error {
+	h1, h2 := bloomHashes(key)
+
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.rotate()
+	b.addToNewest(h1, h2)
+	return nil
+}
+
+func (b *bloomCache) Add(ctx context.Context, key string, value []byte, _ *time.Duration) error {
+	h1, h2 := bloomHashes(key)
+
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.rotate()
+	if b.exists(h1, h2) {
+		return service.ErrKeyAlreadyExists
+	}
+	b.addToNewest(h1, h2)
+	return nil
+}
+
+func (b *bloomCache) Delete(ctx context.Context, key string) error {
+	return errors.New("keys cannot be deleted from a bloom filter")
+}
+
+//------------------------------------------------------------------------------
+
+func (b *bloomCache) loadSnapshot() error {
+	f, err := os.Open(b.opts.snapshotPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	var snap bloomSnapshot
+	if err := gob.NewDecoder(f).Decode(&snap); err != nil {
+		return err
+	}
+	if snap.M != b.m || snap.K != b.k {
+		b.log.Warnf("Ignoring bloom filter snapshot '%v' as it was written with a different capacity or false positive rate\n", b.opts.snapshotPath)
+		return nil
+	}
+
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	for i := range b.generations {
+		if i < len(snap.Generations) && uint64(len(snap.Generations[i])) == (b.m+63)/64 {
+			b.generations[i] = snap.Generations[i]
+		}
+	}
+	if b.opts.rotationPeriod > 0 {
+		b.rotated = snap.Rotated
+		b.rotate()
+	}
+	return nil
+}
+
+// snapshot returns the current state of the cache without copying the
+// generations, which remain unmodified until thaw is called as writes copy the
+// newest generation instead.
+func (b *bloomCache) snapshot() bloomSnapshot {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.frozen = true
+	return bloomSnapshot{
+		M:           b.m,
+		K:           b.k,
+		Rotated:     b.rotated,
+		Generations: append([]bloomFilter(nil), b.generations...),
+	}
+}
+
+// thaw allows the newest generation to be modified in place again once a
+// snapshot has been written, unless it has since been replaced.
+func (b *bloomCache) thaw(snap bloomSnapshot) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if &snap.Generations[0][0] == &b.generations[0][0] {
+		b.frozen = false
+	}
+}
+
+func (b *bloomCache) writeSnapshot() error {
+	snap := b.snapshot()
+	defer b.thaw(snap)
+
+	// Write to a temporary file first so that a failed write never corrupts an
+	// existing snapshot.
+	tmpPath := b.opts.snapshotPath + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(tmpPath), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(snap); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, b.opts.snapshotPath)
+}
+
+func (b *bloomCache) snapshotLoop() {
+	defer close(b.closedChan)
+
+	ticker := time.NewTicker(b.opts.snapshotInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := b.writeSnapshot(); err != nil {
+				b.log.Errorf("Failed to write bloom filter snapshot: %v\n", err)
+			}
+		case <-b.closeChan:
+			return
+		}
+	}
+}
+
+func (b *bloomCache) Close(ctx context.Context) error {
+	b.closeOnce.Do(func() {
+		close(b.closeChan)
+	})
+	select {
+	case <-b.closedChan:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if b.opts.snapshotPath != "" {
+		return b.writeSnapshot()
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package generic
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBloomCacheConfigs(t *testing.T) {
+	tests := []struct {
+		config           string
+		buildErrContains string
+	}{
+		{
+			config: `{}`,
+		},
+		{
+			config: `
+capacity: 1000
+false_positive_rate: 0.01
+rotation_period: 1h
+generations: 3
+`,
+		},
+		{
+			config: `
+capacity: 0
+`,
+			buildErrContains: "capacity must be greater than zero",
+		},
+		{
+			config: `
+false_positive_rate: 1.5
+`,
+			buildErrContains: "false_positive_rate must be between 0 and 1 exclusive",
+		},
+		{
+			config: `
+rotation_period: 1h
+generations: 1
+`,
+			buildErrContains: "generations must be at least 2",
+		},
+		{
+			config: `
+rotation_period: nope
+`,
+			buildErrContains: "failed to parse field 'rotation_period' as duration",
+		},
+	}
+
+	for i, test := range tests {
+		test := test
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			conf, err := bloomCacheConfig().ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			c, err := newBloomCacheFromConfig(conf, nil)
+			if test.buildErrContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.buildErrContains)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, c.Close(context.Background()))
+		})
+	}
+}
+
+func TestBloomCacheAddGet(t *testing.T) {
+	ctx := context.Background()
+	c, err := newBloomCache(bloomCacheOptions{
+		capacity:    1000,
+		fpRate:      0.001,
+		generations: 1,
+	}, nil)
+	require.NoError(t, err)
+
+	_, err = c.Get(ctx, "foo")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	require.NoError(t, c.Add(ctx, "foo", []byte("t"), nil))
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "foo", []byte("t"), nil))
+
+	_, err = c.Get(ctx, "foo")
+	assert.NoError(t, err)
+
+	require.NoError(t, c.Set(ctx, "bar", []byte("t"), nil))
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "bar", []byte("t"), nil))
+
+	assert.Error(t, c.Delete(ctx, "foo"))
+}
+
+func TestBloomCacheFalsePositiveRate(t *testing.T) {
+	ctx := context.Background()
+	c, err := newBloomCache(bloomCacheOptions{
+		capacity:    10000,
+		fpRate:      0.01,
+		generations: 1,
+	}, nil)
+	require.NoError(t, err)
+
+	for i := 0; i < 10000; i++ {
+		require.NoError(t, c.Set(ctx, "added"+strconv.Itoa(i), nil, nil))
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if _, err := c.Get(ctx, "notadded"+strconv.Itoa(i)); err == nil {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 300)
+}
+
+func TestBloomCacheRotation(t *testing.T) {
+	ctx := context.Background()
+	c, err := newBloomCache(bloomCacheOptions{
+		capacity:       1000,
+		fpRate:         0.001,
+		rotationPeriod: time.Hour,
+		generations:    2,
+	}, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, c.Add(ctx, "foo", nil, nil))
+
+	// Simulate a single rotation, where foo is still in the older generation.
+	c.rotated = c.rotated.Add(-time.Hour)
+	require.NoError(t, c.Add(ctx, "bar", nil, nil))
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "foo", nil, nil))
+
+	// Simulate another rotation, where foo is discarded.
+	c.rotated = c.rotated.Add(-time.Hour)
+	require.NoError(t, c.Add(ctx, "foo", nil, nil))
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "bar", nil, nil))
+
+	// Simulate many missed rotations, where everything is discarded.
+	c.rotated = c.rotated.Add(-time.Hour * 10)
+	require.NoError(t, c.Add(ctx, "foo", nil, nil))
+	require.NoError(t, c.Add(ctx, "bar", nil, nil))
+}
+
+func TestBloomCacheSnapshots(t *testing.T) {
+	ctx := context.Background()
+	opts := bloomCacheOptions{
+		capacity:       1000,
+		fpRate:         0.001,
+		rotationPeriod: time.Hour,
+		generations:    2,
+		snapshotPath:   filepath.Join(t.TempDir(), "nested", "snapshot.bloom"),
+	}
+
+	c, err := newBloomCache(opts, nil)
+	require.NoError(t, err)
+	require.NoError(t, c.Add(ctx, "foo", nil, nil))
+	require.NoError(t, c.Close(ctx))
+
+	c, err = newBloomCache(opts, nil)
+	require.NoError(t, err)
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "foo", nil, nil))
+	require.NoError(t, c.Add(ctx, "bar", nil, nil))
+	require.NoError(t, c.Close(ctx))
+
+	// A snapshot from a filter of a different size is ignored.
+	opts.capacity = 2000
+	c, err = newBloomCache(opts, nil)
+	require.NoError(t, err)
+	require.NoError(t, c.Add(ctx, "foo", nil, nil))
+	require.NoError(t, c.Close(ctx))
+}
+
+func TestBloomCacheSnapshotInterval(t *testing.T) {
+	ctx := context.Background()
+	opts := bloomCacheOptions{
+		capacity:         1000,
+		fpRate:           0.001,
+		generations:      1,
+		snapshotPath:     filepath.Join(t.TempDir(), "snapshot.bloom"),
+		snapshotInterval: time.Millisecond * 10,
+	}
+
+	c, err := newBloomCache(opts, nil)
+	require.NoError(t, err)
+	require.NoError(t, c.Add(ctx, "foo", nil, nil))
+
+	assert.Eventually(t, func() bool {
+		c2, err := newBloomCache(bloomCacheOptions{
+			capacity:     opts.capacity,
+			fpRate:       opts.fpRate,
+			generations:  1,
+			snapshotPath: opts.snapshotPath,
+		}, nil)
+		if err != nil {
+			return false
+		}
+		_, err = c2.Get(ctx, "foo")
+		return err == nil
+	}, time.Second, time.Millisecond*10)
+
+	require.NoError(t, c.Close(ctx))
+}
+
+func TestBloomCacheSnapshotCopyOnWrite(t *testing.T) {
+	ctx := context.Background()
+	opts := bloomCacheOptions{
+		capacity:     1000000,
+		fpRate:       0.001,
+		generations:  1,
+		snapshotPath: filepath.Join(t.TempDir(), "snapshot.bloom"),
+	}
+
+	c, err := newBloomCache(opts, nil)
+	require.NoError(t, err)
+	require.NoError(t, c.Add(ctx, "foo", nil, nil))
+
+	// Taking a snapshot references the generations rather than copying them,
+	// and writes made whilst it is held do not modify it.
+	snap := c.snapshot()
+	assert.True(t, &snap.Generations[0][0] == &c.generations[0][0])
+
+	require.NoError(t, c.Add(ctx, "bar", nil, nil))
+	assert.False(t, &snap.Generations[0][0] == &c.generations[0][0])
+
+	h1, h2 := bloomHashes("bar")
+	assert.False(t, snap.Generations[0].contains(c.m, c.k, h1, h2))
+	assert.True(t, c.generations[0].contains(c.m, c.k, h1, h2))
+	c.thaw(snap)
+
+	// Once a snapshot has been written the newest generation is modified in
+	// place again.
+	require.NoError(t, c.writeSnapshot())
+	gen := &c.generations[0][0]
+	require.NoError(t, c.Add(ctx, "baz", nil, nil))
+	assert.True(t, gen == &c.generations[0][0])
+
+	// The snapshot is no larger than the varint encoded filter words.
+	info, err := os.Stat(opts.snapshotPath)
+	require.NoError(t, err)
+	assert.Less(t, info.Size(), int64(len(c.generations[0])*9+1024))
+
+	require.NoError(t, c.Close(ctx))
+}
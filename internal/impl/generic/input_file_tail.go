@@ -0,0 +1,641 @@
+package generic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/shutdown"
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+func fileTailInputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Version("3.60.0").
+		Categories("Local").
+		Summary("Follows files matching glob patterns and consumes lines as they are appended, handling rotations and persisting the offsets of consumed lines.").
+		Description(`
+Each line of a file is consumed as a message once it is terminated with a newline, and the file is then followed for newly appended lines. The paths are checked for new files, rotations and truncations every `+"`poll_interval`"+`, and new files that match the patterns are consumed as they appear.
+
+### Rotation
+
+Files are identified by their device and inode, and therefore when a file is rotated by renaming it and creating a new file at the same path the new file is consumed from the beginning, whilst the old file continues to be followed for the duration `+"`rotate_wait`"+` in order to consume lines written to it by processes that still have it open, after which its remaining lines are consumed and it is closed. When a file is truncated it is consumed again from the beginning.
+
+### Offsets
+
+When a `+"`state_path`"+` is set the offset of each file is written to it once the lines before that offset have been acknowledged, and when the input is restarted files are resumed from the offsets stored within. Offsets are written at most every `+"`poll_interval`"+`, and therefore lines acknowledged just before the input stops may be consumed again after a restart.
+
+### Multiline
+
+When a `+"`multiline.start_pattern`"+` regular expression is set only lines that match it begin a new message, and all following lines that do not match it are appended to the message with newline delimiters. This is useful for consuming logs containing stack traces. Since the end of a message is only known once the next one begins, a message is also flushed after no lines have been appended to it for the duration `+"`multiline.timeout`"+`.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- path
+- file_tail_offset
+`+"```"+`
+
+Where `+"`file_tail_offset`"+` is the offset of the end of the message within the file.
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`).
+		Field(service.NewStringListField("paths").
+			Description("A list of paths to consume, which may contain glob patterns.").
+			Example([]string{"/var/log/app/*.log"})).
+		Field(service.NewStringEnumField("start_position", "beginning", "end").
+			Description("Where to begin consuming files found when the input starts that do not have a stored offset. Files created after the input has started are always consumed from the beginning.").
+			Default("beginning")).
+		Field(service.NewStringField("state_path").
+			Description("An optional path of a file to persist the offsets of consumed files within. When empty the offsets are not persisted and files are consumed according to `start_position` each time the input starts.").
+			Default("").
+			Example("/var/lib/benthos/file_tail_state.json")).
+		Field(service.NewStringField("poll_interval").
+			Description("The period of time between each check of the paths for new data, new files and rotations.").
+			Default("1s").
+			Advanced()).
+		Field(service.NewStringField("rotate_wait").
+			Description("The period of time to continue following a file after it no longer matches the paths, such as when it has been rotated to a name outside of the patterns or deleted, before it is closed.").
+			Default("5s").
+			Advanced()).
+		Field(service.NewObjectField("multiline",
+			service.NewStringField("start_pattern").
+				Description("A regular expression that matches lines beginning a new message, when empty each line is a message.").
+				Default("").
+				Example(`^\d{4}-\d{2}-\d{2}`).
+				Example(`^[^\s]`),
+			service.NewStringField("timeout").
+				Description("The maximum period of time to wait for further lines of a message before it is flushed.").
+				Default("1s"),
+		).Description("Optionally join multiple lines into messages.").Advanced()).
+		Example("Shipping Logs", `
+Follow all logs of an application, joining the lines of stack traces with the log lines that precede them, and resume from where we left off when restarted:`,
+			`
+input:
+  file_tail:
+    paths: [ /var/log/app/*.log ]
+    start_position: end
+    state_path: /var/lib/benthos/app_logs.json
+    multiline:
+      start_pattern: '^\d{4}-\d{2}-\d{2}'
+`)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"file_tail", fileTailInputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newFileTailInputFromConfig(conf, mgr.Logger())
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacks(i), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type fileTailStateEntry struct {
+	Path   string `json:"path"`
+	Offset int64  `json:"offset"`
+}
+
+type fileTailState struct {
+	Files map[string]fileTailStateEntry `json:"files"`
+}
+
+// fileTailAcks tracks the messages of a file that are in flight in order to
+// determine the offset before which all lines have been acknowledged.
+type fileTailAcks struct {
+	generation int
+	committed  int64
+	inflight   []*fileTailInflight
+}
+
+type fileTailInflight struct {
+	end  int64
+	done bool
+}
+
+type fileTailFile struct {
+	id      string
+	path    string
+	f       *os.File
+	removed time.Time
+
+	// The offset of the data read from the file, and any trailing data that
+	// is not yet terminated by a newline.
+	readOffset int64
+	partial    []byte
+
+	// A multiline message that is pending further lines.
+	pending    []byte
+	pendingEnd int64
+	pendingAt  time.Time
+
+	acks fileTailAcks
+}
+
+type fileTailMessage struct {
+	msg   *service.Message
+	ackFn service.AckFunc
+}
+
+type fileTailInput struct {
+	log *service.Logger
+
+	patterns         []string
+	startAtEnd       bool
+	statePath        string
+	pollInterval     time.Duration
+	rotateWait       time.Duration
+	startPattern     *regexp.Regexp
+	multilineTimeout time.Duration
+
+	// Protects the acks of files along with the dirty flag.
+	mut       sync.Mutex
+	files     map[string]*fileTailFile
+	stateDirt bool
+
+	stored      map[string]fileTailStateEntry
+	initialised bool
+
+	msgChan   chan fileTailMessage
+	startOnce sync.Once
+	shutSig   *shutdown.Signaller
+}
+
+func newFileTailInputFromConfig(conf *service.ParsedConfig, logger *service.Logger) (*fileTailInput, error) {
+	t := &fileTailInput{
+		log:     logger,
+		files:   map[string]*fileTailFile{},
+		stored:  map[string]fileTailStateEntry{},
+		msgChan: make(chan fileTailMessage),
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	var err error
+	if t.patterns, err = conf.FieldStringList("paths"); err != nil {
+		return nil, err
+	}
+	if len(t.patterns) == 0 {
+		return nil, errors.New("at least one path must be specified")
+	}
+	for _, p := range t.patterns {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("failed to parse path '%v': %w", p, err)
+		}
+	}
+
+	startPosition, err := conf.FieldString("start_position")
+	if err != nil {
+		return nil, err
+	}
+	t.startAtEnd = startPosition == "end"
+
+	if t.statePath, err = conf.FieldString("state_path"); err != nil {
+		return nil, err
+	}
+	if t.pollInterval, err = getDuration(conf, true, "poll_interval"); err != nil {
+		return nil, err
+	}
+	if t.rotateWait, err = getDuration(conf, false, "rotate_wait"); err != nil {
+		return nil, err
+	}
+
+	startPattern, err := conf.FieldString("multiline", "start_pattern")
+	if err != nil {
+		return nil, err
+	}
+	if startPattern != "" {
+		if t.startPattern, err = regexp.Compile(startPattern); err != nil {
+			return nil, fmt.Errorf("failed to compile multiline start pattern: %w", err)
+		}
+	}
+	if t.multilineTimeout, err = getDuration(conf.Namespace("multiline"), true, "timeout"); err != nil {
+		return nil, err
+	}
+
+	if err := t.readState(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (t *fileTailInput) readState() error {
+	if t.statePath == "" {
+		return nil
+	}
+	stateBytes, err := os.ReadFile(t.statePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	var state fileTailState
+	if err := json.Unmarshal(stateBytes, &state); err != nil {
+		return fmt.Errorf("failed to parse state file: %w", err)
+	}
+	if state.Files != nil {
+		t.stored = state.Files
+	}
+	return nil
+}
+
+func (t *fileTailInput) writeState() error {
+	if t.statePath == "" {
+		return nil
+	}
+
+	t.mut.Lock()
+	if !t.stateDirt {
+		t.mut.Unlock()
+		return nil
+	}
+	state := fileTailState{Files: map[string]fileTailStateEntry{}}
+	for id, f := range t.files {
+		state.Files[id] = fileTailStateEntry{
+			Path:   f.path,
+			Offset: f.acks.committed,
+		}
+	}
+	t.stateDirt = false
+	t.mut.Unlock()
+
+	err := func() error {
+		stateBytes, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		tmpPath := t.statePath + ".tmp"
+		if err := os.MkdirAll(filepath.Dir(tmpPath), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(tmpPath, stateBytes, 0o644); err != nil {
+			return err
+		}
+		return os.Rename(tmpPath, t.statePath)
+	}()
+	if err != nil {
+		t.mut.Lock()
+		t.stateDirt = true
+		t.mut.Unlock()
+	}
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+// discover finds the files matching the patterns, opening new files and
+// detecting files that have been rotated away or removed.
+func (t *fileTailInput) discover() {
+	var paths []string
+	for _, p := range t.patterns {
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			t.log.Errorf("Failed to expand path '%v': %v", p, err)
+			continue
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	seen := map[string]struct{}{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		id := fileTailIdentity(path, info)
+		if _, exists := seen[id]; exists {
+			continue
+		}
+		seen[id] = struct{}{}
+
+		t.mut.Lock()
+		f, exists := t.files[id]
+		if exists {
+			// The file may have been renamed by a rotation but still matches
+			// the patterns.
+			f.path = path
+			f.removed = time.Time{}
+			t.mut.Unlock()
+			continue
+		}
+		t.mut.Unlock()
+
+		if f, err = t.open(id, path, info); err != nil {
+			t.log.Errorf("Failed to open file '%v': %v", path, err)
+			continue
+		}
+		t.mut.Lock()
+		t.files[id] = f
+		t.stateDirt = true
+		t.mut.Unlock()
+	}
+
+	t.mut.Lock()
+	for id, f := range t.files {
+		if _, exists := seen[id]; !exists && f.removed.IsZero() {
+			f.removed = time.Now()
+		}
+	}
+	t.mut.Unlock()
+
+	t.initialised = true
+}
+
+func (t *fileTailInput) open(id, path string, info os.FileInfo) (*fileTailFile, error) {
+	var offset int64
+	if entry, exists := t.stored[id]; exists {
+		if entry.Offset <= info.Size() {
+			offset = entry.Offset
+		}
+	} else if t.startAtEnd && !t.initialised {
+		offset = info.Size()
+	}
+
+	osFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := osFile.Seek(offset, io.SeekStart); err != nil {
+		osFile.Close()
+		return nil, err
+	}
+	return &fileTailFile{
+		id:         id,
+		path:       path,
+		f:          osFile,
+		readOffset: offset,
+		acks: fileTailAcks{
+			committed: offset,
+		},
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (t *fileTailInput) ackFn(f *fileTailFile, generation int, end int64) service.AckFunc {
+	inflight := &fileTailInflight{end: end}
+
+	t.mut.Lock()
+	f.acks.inflight = append(f.acks.inflight, inflight)
+	t.mut.Unlock()
+
+	return func(ctx context.Context, err error) error {
+		t.mut.Lock()
+		defer t.mut.Unlock()
+		if f.acks.generation != generation {
+			return nil
+		}
+		inflight.done = true
+		for len(f.acks.inflight) > 0 && f.acks.inflight[0].done {
+			f.acks.committed = f.acks.inflight[0].end
+			f.acks.inflight = f.acks.inflight[1:]
+			t.stateDirt = true
+		}
+		return nil
+	}
+}
+
+func (t *fileTailInput) emit(ctx context.Context, f *fileTailFile, content []byte, end int64) error {
+	t.mut.Lock()
+	generation := f.acks.generation
+	t.mut.Unlock()
+
+	msg := service.NewMessage(content)
+	msg.MetaSet("path", f.path)
+	msg.MetaSet("file_tail_offset", strconv.FormatInt(end, 10))
+
+	select {
+	case t.msgChan <- fileTailMessage{msg: msg, ackFn: t.ackFn(f, generation, end)}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (t *fileTailInput) flushPending(ctx context.Context, f *fileTailFile) error {
+	if f.pending == nil {
+		return nil
+	}
+	content, end := f.pending, f.pendingEnd
+	f.pending = nil
+	return t.emit(ctx, f, content, end)
+}
+
+func (t *fileTailInput) addLine(ctx context.Context, f *fileTailFile, line []byte, end int64) error {
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if t.startPattern == nil {
+		return t.emit(ctx, f, line, end)
+	}
+	if f.pending != nil && !t.startPattern.Match(line) {
+		f.pending = append(f.pending, '\n')
+		f.pending = append(f.pending, line...)
+		f.pendingEnd = end
+		f.pendingAt = time.Now()
+		return nil
+	}
+	if err := t.flushPending(ctx, f); err != nil {
+		return err
+	}
+	f.pending = append([]byte(nil), line...)
+	f.pendingEnd = end
+	f.pendingAt = time.Now()
+	return nil
+}
+
+// consume reads all available data from a file, returns true if the file was
+// closed as it has been fully consumed after being removed for longer than the
+// rotate wait.
+func (t *fileTailInput) consume(ctx context.Context, f *fileTailFile) (bool, error) {
+	info, err := f.f.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.Size() < f.readOffset {
+		// The file has been truncated and is consumed from the beginning,
+		// acknowledgements of previous messages are ignored.
+		if err := t.flushPending(ctx, f); err != nil {
+			return false, err
+		}
+		if _, err := f.f.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		f.readOffset = 0
+		f.partial = nil
+		t.mut.Lock()
+		f.acks.generation++
+		f.acks.committed = 0
+		f.acks.inflight = nil
+		t.stateDirt = true
+		t.mut.Unlock()
+	}
+
+	chunk := make([]byte, 32*1024)
+	for {
+		n, rerr := f.f.Read(chunk)
+		if n > 0 {
+			data := chunk[:n]
+			for {
+				i := bytes.IndexByte(data, '\n')
+				if i < 0 {
+					f.partial = append(f.partial, data...)
+					f.readOffset += int64(len(data))
+					break
+				}
+				line := make([]byte, 0, len(f.partial)+i)
+				line = append(line, f.partial...)
+				line = append(line, data[:i]...)
+				f.partial = nil
+				f.readOffset += int64(i + 1)
+				data = data[i+1:]
+				if err := t.addLine(ctx, f, line, f.readOffset); err != nil {
+					return false, err
+				}
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return false, rerr
+		}
+	}
+
+	if !f.removed.IsZero() && time.Since(f.removed) >= t.rotateWait {
+		// No more data is expected to be appended, so flush everything we
+		// have.
+		if len(f.partial) > 0 {
+			line := f.partial
+			f.partial = nil
+			if err := t.addLine(ctx, f, line, f.readOffset); err != nil {
+				return false, err
+			}
+		}
+		if err := t.flushPending(ctx, f); err != nil {
+			return false, err
+		}
+		f.f.Close()
+		return true, nil
+	}
+
+	if f.pending != nil && time.Since(f.pendingAt) >= t.multilineTimeout {
+		if err := t.flushPending(ctx, f); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+func (t *fileTailInput) poll(ctx context.Context) {
+	t.discover()
+
+	t.mut.Lock()
+	files := make([]*fileTailFile, 0, len(t.files))
+	for _, f := range t.files {
+		files = append(files, f)
+	}
+	t.mut.Unlock()
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].path < files[j].path
+	})
+
+	for _, f := range files {
+		closed, err := t.consume(ctx, f)
+		if err != nil {
+			if ctx.Err() == nil {
+				t.log.Errorf("Failed to consume file '%v': %v", f.path, err)
+			}
+			continue
+		}
+		if closed {
+			t.mut.Lock()
+			delete(t.files, f.id)
+			t.stateDirt = true
+			t.mut.Unlock()
+		}
+	}
+
+	if err := t.writeState(); err != nil {
+		t.log.Errorf("Failed to write state file: %v", err)
+	}
+}
+
+func (t *fileTailInput) loop() {
+	defer func() {
+		if err := t.writeState(); err != nil {
+			t.log.Errorf("Failed to write state file: %v", err)
+		}
+		t.mut.Lock()
+		for _, f := range t.files {
+			f.f.Close()
+		}
+		t.mut.Unlock()
+		t.shutSig.ShutdownComplete()
+	}()
+
+	ctx, done := t.shutSig.CloseAtLeisureCtx(context.Background())
+	defer done()
+
+	ticker := time.NewTicker(t.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		t.poll(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (t *fileTailInput) Connect(ctx context.Context) error {
+	t.startOnce.Do(func() {
+		go t.loop()
+	})
+	return nil
+}
+
+func (t *fileTailInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	select {
+	case m := <-t.msgChan:
+		return m.msg, m.ackFn, nil
+	case <-t.shutSig.CloseAtLeisureChan():
+		return nil, nil, service.ErrEndOfInput
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (t *fileTailInput) Close(ctx context.Context) error {
+	t.shutSig.CloseAtLeisure()
+	t.startOnce.Do(func() {
+		t.shutSig.ShutdownComplete()
+	})
+	select {
+	case <-t.shutSig.HasClosedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
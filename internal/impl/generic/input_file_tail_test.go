@@ -0,0 +1,254 @@
+package generic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFileTailInputFromYAML(t *testing.T, confStr string) *fileTailInput {
+	t.Helper()
+
+	conf, err := fileTailInputConfig().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	i, err := newFileTailInputFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, i.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, i.Close(ctx))
+	})
+	return i
+}
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func readFileTail(t *testing.T, i *fileTailInput, n int, ack bool) []string {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	var results []string
+	for len(results) < n {
+		msg, ackFn, err := i.Read(ctx)
+		require.NoError(t, err)
+
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		path, _ := msg.MetaGet("path")
+		results = append(results, fmt.Sprintf("%v: %s", filepath.Base(path), b))
+
+		if ack {
+			require.NoError(t, ackFn(ctx, nil))
+		}
+	}
+	return results
+}
+
+func assertNoFileTailMessages(t *testing.T, i *fileTailInput) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer done()
+
+	msg, _, err := i.Read(ctx)
+	if msg != nil {
+		b, _ := msg.AsBytes()
+		t.Errorf("unexpected message: %s", b)
+	}
+	assert.Error(t, err)
+}
+
+func TestFileTailBasic(t *testing.T) {
+	dir := t.TempDir()
+
+	appendFile(t, filepath.Join(dir, "a.log"), "a1\na2\r\n")
+	appendFile(t, filepath.Join(dir, "b.txt"), "b1\n")
+
+	i := newFileTailInputFromYAML(t, fmt.Sprintf(`
+paths: [ "%v" ]
+poll_interval: 10ms
+`, filepath.Join(dir, "*.log")))
+
+	assert.Equal(t, []string{"a.log: a1", "a.log: a2"}, readFileTail(t, i, 2, true))
+
+	appendFile(t, filepath.Join(dir, "a.log"), "a3\na4")
+	assert.Equal(t, []string{"a.log: a3"}, readFileTail(t, i, 1, true))
+	assertNoFileTailMessages(t, i)
+
+	appendFile(t, filepath.Join(dir, "a.log"), "\n")
+	appendFile(t, filepath.Join(dir, "c.log"), "c1\n")
+	assert.ElementsMatch(t, []string{"a.log: a4", "c.log: c1"}, readFileTail(t, i, 2, true))
+}
+
+func TestFileTailStartAtEnd(t *testing.T) {
+	dir := t.TempDir()
+
+	appendFile(t, filepath.Join(dir, "a.log"), "a1\n")
+
+	i := newFileTailInputFromYAML(t, fmt.Sprintf(`
+paths: [ "%v" ]
+start_position: end
+poll_interval: 10ms
+`, filepath.Join(dir, "*.log")))
+
+	// Wait for the initial poll in order to ensure b.log is a new file.
+	assertNoFileTailMessages(t, i)
+
+	appendFile(t, filepath.Join(dir, "a.log"), "a2\n")
+	appendFile(t, filepath.Join(dir, "b.log"), "b1\n")
+	assert.ElementsMatch(t, []string{"a.log: a2", "b.log: b1"}, readFileTail(t, i, 2, true))
+}
+
+func TestFileTailRotation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("rotations by rename are not detected on windows")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	appendFile(t, path, "first1\n")
+
+	i := newFileTailInputFromYAML(t, fmt.Sprintf(`
+paths: [ "%v" ]
+poll_interval: 10ms
+rotate_wait: 300ms
+`, path))
+
+	assert.Equal(t, []string{"app.log: first1"}, readFileTail(t, i, 1, true))
+
+	// Lines written to the old file after the rotation are still consumed
+	// during the rotate wait, including the final unterminated line once the
+	// file is closed.
+	require.NoError(t, os.Rename(path, filepath.Join(dir, "app.log.1")))
+	appendFile(t, path, "second1\n")
+	assert.Equal(t, []string{"app.log: second1"}, readFileTail(t, i, 1, true))
+
+	appendFile(t, filepath.Join(dir, "app.log.1"), "first2\nfirst3")
+	assert.Equal(t, []string{
+		"app.log: first2",
+		"app.log: first3",
+	}, readFileTail(t, i, 2, true))
+
+	appendFile(t, path, "second2\n")
+	assert.Equal(t, []string{"app.log: second2"}, readFileTail(t, i, 1, true))
+}
+
+func TestFileTailTruncation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	appendFile(t, path, "foo1\nfoo2\n")
+
+	i := newFileTailInputFromYAML(t, fmt.Sprintf(`
+paths: [ "%v" ]
+poll_interval: 10ms
+`, path))
+
+	assert.Equal(t, []string{"app.log: foo1", "app.log: foo2"}, readFileTail(t, i, 2, true))
+
+	require.NoError(t, os.Truncate(path, 0))
+	assertNoFileTailMessages(t, i)
+
+	appendFile(t, path, "bar1\n")
+	assert.Equal(t, []string{"app.log: bar1"}, readFileTail(t, i, 1, true))
+}
+
+func TestFileTailMultiline(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	appendFile(t, path, "continued from nothing\n2021-01-01 first\n  at foo\n  at bar\n2021-01-01 second\n")
+
+	i := newFileTailInputFromYAML(t, fmt.Sprintf(`
+paths: [ "%v" ]
+poll_interval: 10ms
+multiline:
+  start_pattern: '^\d{4}-\d{2}-\d{2}'
+  timeout: 200ms
+`, path))
+
+	assert.Equal(t, []string{
+		"app.log: continued from nothing",
+		"app.log: 2021-01-01 first\n  at foo\n  at bar",
+	}, readFileTail(t, i, 2, true))
+
+	appendFile(t, path, "  at baz\n")
+	assert.Equal(t, []string{"app.log: 2021-01-01 second\n  at baz"}, readFileTail(t, i, 1, true))
+}
+
+func TestFileTailState(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	statePath := filepath.Join(dir, "state", "offsets.json")
+
+	appendFile(t, path, "foo1\nfoo2\nfoo3\n")
+
+	confStr := fmt.Sprintf(`
+paths: [ "%v" ]
+state_path: "%v"
+poll_interval: 10ms
+`, path, statePath)
+
+	conf, err := fileTailInputConfig().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	i, err := newFileTailInputFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, i.Connect(context.Background()))
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	// Acknowledge the messages out of order, leaving the second unacked.
+	_, ackFn1, err := i.Read(ctx)
+	require.NoError(t, err)
+	_, _, err = i.Read(ctx)
+	require.NoError(t, err)
+	_, ackFn3, err := i.Read(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, ackFn3(ctx, nil))
+	require.NoError(t, ackFn1(ctx, nil))
+	require.NoError(t, i.Close(ctx))
+
+	stateBytes, err := os.ReadFile(statePath)
+	require.NoError(t, err)
+
+	var state fileTailState
+	require.NoError(t, json.Unmarshal(stateBytes, &state))
+	require.Len(t, state.Files, 1)
+	for _, v := range state.Files {
+		assert.Equal(t, fileTailStateEntry{Path: path, Offset: 5}, v)
+	}
+
+	appendFile(t, path, "foo4\n")
+
+	i = newFileTailInputFromYAML(t, confStr)
+	assert.Equal(t, []string{
+		"app.log: foo2",
+		"app.log: foo3",
+		"app.log: foo4",
+	}, readFileTail(t, i, 3, true))
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package generic
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fileTailIdentity returns an identifier of the underlying file that remains
+// stable when the file is renamed, which allows rotations to be detected.
+func fileTailIdentity(path string, info os.FileInfo) string {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return fmt.Sprintf("%v:%v", uint64(stat.Dev), uint64(stat.Ino))
+	}
+	return path
+}
@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package generic
+
+import (
+	"os"
+)
+
+// fileTailIdentity returns an identifier of the underlying file, inodes are not
+// available on windows and therefore files are identified by their path, and
+// rotations are detected only by truncation.
+func fileTailIdentity(path string, info os.FileInfo) string {
+	return path
+}
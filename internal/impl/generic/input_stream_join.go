@@ -0,0 +1,856 @@
+package generic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/shutdown"
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+func streamJoinInputConfig() *service.ConfigSpec {
+	sideFields := func(name string) *service.ConfigField {
+		return service.NewObjectField(name,
+			service.NewInputField("input").
+				Description("The input to consume "+name+" side records from."),
+			service.NewBloblangField("key").
+				Description("A [Bloblang mapping](/docs/guides/bloblang/about) that provides the key used to join records, the result of the mapping is converted into a string.").
+				Example("root = this.id").Example(`root = meta("kafka_key")`),
+		).Description("Configures the " + name + " side of the join.")
+	}
+
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.60.0").
+		Categories("Utility").
+		Summary("Consumes records from two child inputs and joins them by a common key within a window of time, with the state of pending records stored within a cache resource.").
+		Description(`
+Each record consumed from either the `+"`left`"+` or `+"`right`"+` input is assigned a key by executing its `+"`key`"+` mapping, and is then stored within the cache for the duration of the `+"`window`"+`. When a record arrives it is joined with every record of the other side that shares its key and is still within the window, producing a message of the form:
+
+`+"```json"+`
+{"left":{"the":"left record"},"right":{"the":"right record"}}
+`+"```"+`
+
+Records that are not valid JSON documents are added as strings. The metadata of the resulting message is a combination of the metadata of both records, with the metadata of the left record taking precedence, and the key is added to the metadata field `+"`stream_join_key`"+`.
+
+### Join Types
+
+The `+"`type`"+` of the join determines what happens to records that were not matched by the time their window expires:
+
+- `+"`inner`"+`: Unmatched records are not emitted.
+- `+"`left`"+`: Unmatched left records are emitted on their own, e.g. `+"`{\"left\":{\"the\":\"left record\"}}`"+`.
+- `+"`outer`"+`: Unmatched records from either side are emitted on their own.
+
+Expired unmatched records that are not emitted according to the join type are written to the `+"`expired_output`"+` when one is configured, with the metadata field `+"`stream_join_side`"+` set to the side they were consumed from, and are otherwise dropped.
+
+### Delivery Guarantees
+
+Records consumed from the child inputs are stored within the cache, and a batch consumed from a child input is acknowledged once every joined message produced from it has been acknowledged. When a joined message is rejected the records of its batch are withdrawn and the batch is rejected, and therefore consumed again, which means joined messages are delivered at least once and may be duplicated. Records are only flagged as matched once a message joining them has been acknowledged, and unmatched records emitted by `+"`left`"+` and `+"`outer`"+` joins are only removed once acknowledged, and are otherwise emitted again.
+
+The delivery guarantees of this input therefore also depend on the durability of the cache resource used. Pending records are checked for expiry periodically according to `+"`check_interval`"+`, and are stored in the cache with a TTL of twice the window in order to prevent orphaned records from accumulating after a restart. The deadlines of pending records are tracked in memory, and so after a restart the records stored before it are only checked for expiry once a record with the same key is consumed again. Unmatched records of keys that are not consumed again after a restart are therefore never emitted, meaning unmatched records are emitted at most once across restarts.
+
+When both child inputs have been consumed entirely the remaining pending records are expired immediately and this input terminates.`).
+		Field(service.NewStringAnnotatedEnumField("type", map[string]string{
+			"inner": "Emit only records that were matched with records from the other side.",
+			"left":  "Emit matched records and also unmatched records from the left side once their window expires.",
+			"outer": "Emit matched records and also unmatched records from either side once their window expires.",
+		}).
+			Description("The type of join to perform.").
+			Default("inner")).
+		Field(service.NewStringField("cache").
+			Description("A [`cache` resource](/docs/components/caches/about) used to store pending records.")).
+		Field(service.NewStringField("window").
+			Description("A duration string describing the length of time that records remain eligible for joining after they are consumed.").
+			Example("30s").Example("10m")).
+		Field(sideFields("left")).
+		Field(sideFields("right")).
+		Field(service.NewOutputField("expired_output").
+			Description("An optional output to send expired records that were not matched and are not emitted according to the join type.").
+			Optional()).
+		Field(service.NewStringField("check_interval").
+			Description("A duration string describing how often pending records should be checked for expiry.").
+			Default("1s").
+			Advanced()).
+		Example("Joining Orders With Payments", `
+Given a stream of orders and a stream of payments that reference those orders by an `+"`order_id`"+` field we can join each order with payments made within five minutes of it, and emit orders that were never paid:`,
+			`
+input:
+  stream_join:
+    type: left
+    cache: joins
+    window: 5m
+    left:
+      input:
+        kafka:
+          addresses: [ localhost:9092 ]
+          topics: [ orders ]
+          consumer_group: benthos_joins
+      key: root = this.id
+    right:
+      input:
+        kafka:
+          addresses: [ localhost:9092 ]
+          topics: [ payments ]
+          consumer_group: benthos_joins
+      key: root = this.order_id
+    expired_output:
+      file:
+        path: ./unmatched_payments.jsonl
+
+cache_resources:
+  - label: joins
+    memory: {}
+`)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"stream_join", streamJoinInputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			return newStreamJoinInputFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	streamJoinLeft  = "left"
+	streamJoinRight = "right"
+)
+
+func streamJoinOtherSide(side string) string {
+	if side == streamJoinLeft {
+		return streamJoinRight
+	}
+	return streamJoinLeft
+}
+
+type streamJoinSide struct {
+	name  string
+	input *service.OwnedInput
+	key   *bloblang.Executor
+}
+
+// streamJoinRecord is the form in which pending records are stored within the
+// cache.
+type streamJoinRecord struct {
+	Timestamp int64             `json:"ts"`
+	Matched   bool              `json:"matched,omitempty"`
+	Content   []byte            `json:"content"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// streamJoinHead is stored within the cache for each side and key with pending
+// records, where the records are stored individually under the indexes from
+// First (inclusive) to Next (exclusive) in the order they were consumed.
+type streamJoinHead struct {
+	First int64 `json:"first"`
+	Next  int64 `json:"next"`
+}
+
+type streamJoinPendingKey struct {
+	side string
+	key  string
+}
+
+// streamJoinRef identifies a pending record.
+type streamJoinRef struct {
+	side  string
+	key   string
+	index int64
+}
+
+type streamJoinResult struct {
+	msg   *service.Message
+	ackFn service.AckFunc
+}
+
+// streamJoinBatchAck acknowledges a batch consumed from a child input once
+// all messages emitted as a result of consuming it have been acknowledged.
+type streamJoinBatchAck struct {
+	mut     sync.Mutex
+	pending int
+	err     error
+	refs    []streamJoinRef
+	fn      func(refs []streamJoinRef, err error)
+}
+
+func newStreamJoinBatchAck(fn func(refs []streamJoinRef, err error)) *streamJoinBatchAck {
+	// The batch holds a reference of its own until it has been consumed.
+	return &streamJoinBatchAck{pending: 1, fn: fn}
+}
+
+func (b *streamJoinBatchAck) addRecord(ref streamJoinRef) {
+	b.mut.Lock()
+	b.refs = append(b.refs, ref)
+	b.mut.Unlock()
+}
+
+func (b *streamJoinBatchAck) track() {
+	b.mut.Lock()
+	b.pending++
+	b.mut.Unlock()
+}
+
+func (b *streamJoinBatchAck) done(err error) {
+	b.mut.Lock()
+	if err != nil && b.err == nil {
+		b.err = err
+	}
+	b.pending--
+	resolved := b.pending == 0
+	b.mut.Unlock()
+	if resolved {
+		b.fn(b.refs, b.err)
+	}
+}
+
+type streamJoinInput struct {
+	joinType      string
+	cacheName     string
+	window        time.Duration
+	checkInterval time.Duration
+
+	sides   []*streamJoinSide
+	expired *service.OwnedOutput
+
+	mgr *service.Resources
+	log *service.Logger
+
+	// Guards the state of pending records, both within the cache and the
+	// index of expiry deadlines.
+	stateMut sync.Mutex
+	pending  map[streamJoinPendingKey]time.Time
+
+	// Counts the emitted messages awaiting acknowledgement for each record,
+	// which cannot be expired until they are resolved.
+	inFlight map[streamJoinRef]int
+
+	startOnce sync.Once
+	resChan   chan streamJoinResult
+	shutSig   *shutdown.Signaller
+}
+
+func newStreamJoinInputFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*streamJoinInput, error) {
+	j := &streamJoinInput{
+		mgr:      mgr,
+		log:      mgr.Logger(),
+		pending:  map[streamJoinPendingKey]time.Time{},
+		inFlight: map[streamJoinRef]int{},
+		resChan:  make(chan streamJoinResult),
+		shutSig:  shutdown.NewSignaller(),
+	}
+
+	var err error
+	if j.joinType, err = conf.FieldString("type"); err != nil {
+		return nil, err
+	}
+	switch j.joinType {
+	case "inner", "left", "outer":
+	default:
+		return nil, fmt.Errorf("join type '%v' was not recognised", j.joinType)
+	}
+	if j.cacheName, err = conf.FieldString("cache"); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(j.cacheName) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", j.cacheName)
+	}
+	if j.window, err = getDuration(conf, true, "window"); err != nil {
+		return nil, err
+	}
+	if j.checkInterval, err = getDuration(conf, true, "check_interval"); err != nil {
+		return nil, err
+	}
+
+	for _, name := range []string{streamJoinLeft, streamJoinRight} {
+		side := &streamJoinSide{name: name}
+		if side.key, err = conf.FieldBloblang(name, "key"); err != nil {
+			return nil, err
+		}
+		if side.input, err = conf.FieldInput(name, "input"); err != nil {
+			return nil, err
+		}
+		j.sides = append(j.sides, side)
+	}
+
+	if conf.Contains("expired_output") {
+		if j.expired, err = conf.FieldOutput("expired_output"); err != nil {
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+//------------------------------------------------------------------------------
+
+func streamJoinHeadKey(side, key string) string {
+	return side + ":h:" + key
+}
+
+func streamJoinRecordKey(ref streamJoinRef) string {
+	return ref.side + ":r:" + strconv.FormatInt(ref.index, 10) + ":" + ref.key
+}
+
+func (j *streamJoinInput) cacheGet(ctx context.Context, key string, v interface{}) (found bool, err error) {
+	var value []byte
+	if cerr := j.mgr.AccessCache(ctx, j.cacheName, func(c service.Cache) {
+		value, err = c.Get(ctx, key)
+	}); cerr != nil {
+		return false, cerr
+	}
+	if err != nil {
+		if errors.Is(err, service.ErrKeyNotFound) {
+			err = nil
+		}
+		return false, err
+	}
+	if err = json.Unmarshal(value, v); err != nil {
+		return false, fmt.Errorf("failed to parse pending state: %w", err)
+	}
+	return true, nil
+}
+
+func (j *streamJoinInput) cacheSet(ctx context.Context, key string, v interface{}) (err error) {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	ttl := j.window * 2
+	if cerr := j.mgr.AccessCache(ctx, j.cacheName, func(c service.Cache) {
+		err = c.Set(ctx, key, value, &ttl)
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+func (j *streamJoinInput) cacheDelete(ctx context.Context, key string) (err error) {
+	if cerr := j.mgr.AccessCache(ctx, j.cacheName, func(c service.Cache) {
+		err = c.Delete(ctx, key)
+	}); cerr != nil {
+		return cerr
+	}
+	if errors.Is(err, service.ErrKeyNotFound) {
+		err = nil
+	}
+	return err
+}
+
+func (j *streamJoinInput) getHead(ctx context.Context, side, key string) (head streamJoinHead, found bool, err error) {
+	found, err = j.cacheGet(ctx, streamJoinHeadKey(side, key), &head)
+	return
+}
+
+// setHead stores the head of a side and key, or removes it when there are no
+// longer any pending records.
+func (j *streamJoinInput) setHead(ctx context.Context, side, key string, head streamJoinHead) error {
+	if head.First >= head.Next {
+		delete(j.pending, streamJoinPendingKey{side: side, key: key})
+		return j.cacheDelete(ctx, streamJoinHeadKey(side, key))
+	}
+	return j.cacheSet(ctx, streamJoinHeadKey(side, key), head)
+}
+
+func (j *streamJoinInput) getRecord(ctx context.Context, ref streamJoinRef) (record streamJoinRecord, found bool, err error) {
+	found, err = j.cacheGet(ctx, streamJoinRecordKey(ref), &record)
+	return
+}
+
+// trackPending ensures that a side and key with pending records is checked for
+// expiry, which recovers pending records stored before a restart once their
+// key is consumed again.
+func (j *streamJoinInput) trackPending(side, key string, deadline time.Time) {
+	pKey := streamJoinPendingKey{side: side, key: key}
+	if existing, exists := j.pending[pKey]; !exists || deadline.Before(existing) {
+		j.pending[pKey] = deadline
+	}
+}
+
+func (j *streamJoinInput) isExpired(r streamJoinRecord, now time.Time) bool {
+	return !now.Before(j.deadline(r))
+}
+
+func (j *streamJoinInput) deadline(r streamJoinRecord) time.Time {
+	return time.Unix(0, r.Timestamp).Add(j.window)
+}
+
+func streamJoinRecordValue(r streamJoinRecord) interface{} {
+	var v interface{}
+	if err := json.Unmarshal(r.Content, &v); err != nil {
+		return string(r.Content)
+	}
+	return v
+}
+
+func (j *streamJoinInput) newJoinedMessage(key string, left, right *streamJoinRecord) *service.Message {
+	msg := service.NewMessage(nil)
+	obj := map[string]interface{}{}
+	for _, r := range []struct {
+		name   string
+		record *streamJoinRecord
+	}{{streamJoinRight, right}, {streamJoinLeft, left}} {
+		if r.record == nil {
+			continue
+		}
+		obj[r.name] = streamJoinRecordValue(*r.record)
+		for k, v := range r.record.Metadata {
+			msg.MetaSet(k, v)
+		}
+	}
+	msg.SetStructured(obj)
+	msg.MetaSet("stream_join_key", key)
+	return msg
+}
+
+// emit sends a message to be read from the input, and resolves its ack with
+// an error if it could not be sent.
+func (j *streamJoinInput) emit(ctx context.Context, res streamJoinResult) error {
+	select {
+	case j.resChan <- res:
+	case <-ctx.Done():
+		_ = res.ackFn(context.Background(), ctx.Err())
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (j *streamJoinInput) retainsUnmatched(side string) bool {
+	switch j.joinType {
+	case "left":
+		return side == streamJoinLeft
+	case "outer":
+		return true
+	}
+	return false
+}
+
+func (j *streamJoinInput) releaseInFlight(ref streamJoinRef) {
+	if j.inFlight[ref]--; j.inFlight[ref] <= 0 {
+		delete(j.inFlight, ref)
+	}
+}
+
+// markMatched flags a pending record as having been matched, which is done
+// once a message joining it has been acknowledged.
+func (j *streamJoinInput) markMatched(ctx context.Context, ref streamJoinRef) error {
+	record, found, err := j.getRecord(ctx, ref)
+	if err != nil || !found || record.Matched {
+		return err
+	}
+	record.Matched = true
+	return j.cacheSet(ctx, streamJoinRecordKey(ref), record)
+}
+
+// resolveJoin is called once a joined message has been acknowledged, where the
+// records that were joined are only flagged as matched when it was successful.
+func (j *streamJoinInput) resolveJoin(refs []streamJoinRef, ackErr error) {
+	j.stateMut.Lock()
+	defer j.stateMut.Unlock()
+
+	for _, ref := range refs {
+		j.releaseInFlight(ref)
+		if ackErr != nil {
+			continue
+		}
+		if err := j.markMatched(context.Background(), ref); err != nil {
+			j.log.Errorf("Failed to flag %v record as matched: %v", ref.side, err)
+		}
+	}
+}
+
+// withdraw removes records that were consumed as part of a batch that was
+// rejected, as the batch will be consumed again.
+func (j *streamJoinInput) withdraw(refs []streamJoinRef) {
+	j.stateMut.Lock()
+	defer j.stateMut.Unlock()
+
+	for _, ref := range refs {
+		if err := j.cacheDelete(context.Background(), streamJoinRecordKey(ref)); err != nil {
+			j.log.Errorf("Failed to withdraw rejected %v record: %v", ref.side, err)
+		}
+	}
+}
+
+// addRecord joins a newly consumed record with pending records from the other
+// side, and then stores it as pending. The joined messages are returned with
+// acks that are tracked by the batch the record was consumed from.
+func (j *streamJoinInput) addRecord(ctx context.Context, side *streamJoinSide, msg *service.Message, bAck *streamJoinBatchAck) ([]streamJoinResult, error) {
+	keyMsg, err := msg.BloblangQuery(side.key)
+	if err != nil {
+		return nil, fmt.Errorf("key mapping failed: %w", err)
+	}
+	if keyMsg == nil {
+		return nil, errors.New("key mapping resulted in a deleted message")
+	}
+	keyBytes, err := keyMsg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	key := string(keyBytes)
+
+	content, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	record := streamJoinRecord{Content: content}
+	_ = msg.MetaWalk(func(k, v string) error {
+		if record.Metadata == nil {
+			record.Metadata = map[string]string{}
+		}
+		record.Metadata[k] = v
+		return nil
+	})
+
+	j.stateMut.Lock()
+	defer j.stateMut.Unlock()
+
+	// The timestamp is taken whilst the state is locked so that the records of
+	// each key are stored in the order of their timestamps.
+	now := time.Now()
+	record.Timestamp = now.UnixNano()
+
+	head, _, err := j.getHead(ctx, side.name, key)
+	if err != nil {
+		return nil, err
+	}
+	ref := streamJoinRef{side: side.name, key: key, index: head.Next}
+	if err := j.cacheSet(ctx, streamJoinRecordKey(ref), record); err != nil {
+		return nil, err
+	}
+	head.Next++
+	if err := j.setHead(ctx, side.name, key, head); err != nil {
+		return nil, err
+	}
+	j.trackPending(side.name, key, j.deadline(record))
+	bAck.addRecord(ref)
+
+	otherSide := streamJoinOtherSide(side.name)
+	otherHead, found, err := j.getHead(ctx, otherSide, key)
+	if err != nil || !found {
+		return nil, err
+	}
+	j.trackPending(otherSide, key, now)
+
+	var joined []streamJoinResult
+	for i := otherHead.First; i < otherHead.Next; i++ {
+		otherRef := streamJoinRef{side: otherSide, key: key, index: i}
+		other, found, err := j.getRecord(ctx, otherRef)
+		if err != nil {
+			return nil, err
+		}
+		if !found || j.isExpired(other, now) {
+			continue
+		}
+
+		var joinedMsg *service.Message
+		if side.name == streamJoinLeft {
+			joinedMsg = j.newJoinedMessage(key, &record, &other)
+		} else {
+			joinedMsg = j.newJoinedMessage(key, &other, &record)
+		}
+
+		refs := []streamJoinRef{ref, otherRef}
+		for _, r := range refs {
+			j.inFlight[r]++
+		}
+		bAck.track()
+		joined = append(joined, streamJoinResult{
+			msg: joinedMsg,
+			ackFn: func(_ context.Context, err error) error {
+				j.resolveJoin(refs, err)
+				bAck.done(err)
+				return nil
+			},
+		})
+	}
+	return joined, nil
+}
+
+// expire checks pending records for expiry, emitting unmatched records
+// according to the join type. When flush is true all pending records are
+// considered expired. Unmatched records that are emitted are only removed once
+// the emitted message is acknowledged, and are otherwise emitted again by a
+// later check.
+func (j *streamJoinInput) expire(ctx context.Context, flush bool) ([]streamJoinResult, error) {
+	j.stateMut.Lock()
+	defer j.stateMut.Unlock()
+
+	var emitted []streamJoinResult
+
+	now := time.Now()
+	for pKey, deadline := range j.pending {
+		if !flush && now.Before(deadline) {
+			continue
+		}
+
+		head, found, err := j.getHead(ctx, pKey.side, pKey.key)
+		if err != nil {
+			return emitted, err
+		}
+		if !found {
+			delete(j.pending, pKey)
+			continue
+		}
+
+		// Records can only be dropped from the front of the head whilst every
+		// record before them has been removed.
+		advancing, nextDeadline := true, now
+		for i := head.First; i < head.Next; i++ {
+			ref := streamJoinRef{side: pKey.side, key: pKey.key, index: i}
+			record, found, err := j.getRecord(ctx, ref)
+			if err != nil {
+				return emitted, err
+			}
+			if !found {
+				if advancing {
+					head.First = i + 1
+				}
+				continue
+			}
+			if !flush && !j.isExpired(record, now) {
+				nextDeadline = j.deadline(record)
+				break
+			}
+			if j.inFlight[ref] > 0 {
+				advancing = false
+				continue
+			}
+
+			if !record.Matched && j.retainsUnmatched(pKey.side) {
+				var msg *service.Message
+				if pKey.side == streamJoinLeft {
+					msg = j.newJoinedMessage(pKey.key, &record, nil)
+				} else {
+					msg = j.newJoinedMessage(pKey.key, nil, &record)
+				}
+				j.inFlight[ref]++
+				emitted = append(emitted, streamJoinResult{
+					msg: msg,
+					ackFn: func(_ context.Context, err error) error {
+						j.stateMut.Lock()
+						defer j.stateMut.Unlock()
+						j.releaseInFlight(ref)
+						if err == nil {
+							if derr := j.cacheDelete(context.Background(), streamJoinRecordKey(ref)); derr != nil {
+								j.log.Errorf("Failed to remove emitted %v record: %v", ref.side, derr)
+							}
+						}
+						return nil
+					},
+				})
+				advancing = false
+				continue
+			}
+
+			if !record.Matched && j.expired != nil {
+				msg := service.NewMessage(record.Content)
+				for k, v := range record.Metadata {
+					msg.MetaSet(k, v)
+				}
+				msg.MetaSet("stream_join_side", pKey.side)
+				if err := j.expired.Write(ctx, msg); err != nil {
+					return emitted, fmt.Errorf("failed to write expired record: %w", err)
+				}
+			}
+			if err := j.cacheDelete(ctx, streamJoinRecordKey(ref)); err != nil {
+				return emitted, err
+			}
+			if advancing {
+				head.First = i + 1
+			}
+		}
+
+		if err := j.setHead(ctx, pKey.side, pKey.key, head); err != nil {
+			return emitted, err
+		}
+		if head.First < head.Next {
+			j.pending[pKey] = nextDeadline
+		}
+	}
+	return emitted, nil
+}
+
+// expireAndEmit expires pending records and emits the unmatched records that
+// result.
+func (j *streamJoinInput) expireAndEmit(ctx context.Context, flush bool) error {
+	emitted, err := j.expire(ctx, flush)
+	for i, res := range emitted {
+		if eErr := j.emit(ctx, res); eErr != nil {
+			for _, unsent := range emitted[i+1:] {
+				_ = unsent.ackFn(context.Background(), eErr)
+			}
+			return eErr
+		}
+	}
+	return err
+}
+
+// pendingCount returns the number of keys with pending records.
+func (j *streamJoinInput) pendingCount() int {
+	j.stateMut.Lock()
+	defer j.stateMut.Unlock()
+	return len(j.pending)
+}
+
+//------------------------------------------------------------------------------
+
+func (j *streamJoinInput) consumeSide(ctx context.Context, side *streamJoinSide) {
+	for {
+		batch, ackFn, err := side.input.ReadBatch(ctx)
+		if err != nil {
+			if !errors.Is(err, service.ErrEndOfInput) && ctx.Err() == nil {
+				j.log.Errorf("Failed to read %v input: %v", side.name, err)
+			}
+			return
+		}
+
+		bAck := newStreamJoinBatchAck(func(refs []streamJoinRef, err error) {
+			if err != nil {
+				j.withdraw(refs)
+			}
+			_ = ackFn(context.Background(), err)
+		})
+
+		var ackErr error
+		for _, msg := range batch {
+			results, err := j.addRecord(ctx, side, msg, bAck)
+			if err != nil {
+				if ctx.Err() != nil {
+					ackErr = err
+					break
+				}
+				j.log.Errorf("Failed to join %v record: %v", side.name, err)
+				msg.SetError(err)
+				bAck.track()
+				results = append(results, streamJoinResult{
+					msg: msg,
+					ackFn: func(_ context.Context, err error) error {
+						bAck.done(err)
+						return nil
+					},
+				})
+			}
+			for _, res := range results {
+				if eErr := j.emit(ctx, res); eErr != nil {
+					ackErr = eErr
+				}
+			}
+			if ackErr != nil {
+				break
+			}
+		}
+		bAck.done(ackErr)
+	}
+}
+
+func (j *streamJoinInput) closeChildren() {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+	for _, side := range j.sides {
+		if err := side.input.Close(ctx); err != nil {
+			j.log.Errorf("Failed to close %v input: %v", side.name, err)
+		}
+	}
+	if j.expired != nil {
+		if err := j.expired.Close(ctx); err != nil {
+			j.log.Errorf("Failed to close expired output: %v", err)
+		}
+	}
+	j.shutSig.ShutdownComplete()
+}
+
+func (j *streamJoinInput) loop() {
+	defer j.closeChildren()
+
+	ctx, done := j.shutSig.CloseAtLeisureCtx(context.Background())
+	defer done()
+
+	var wg sync.WaitGroup
+	for _, side := range j.sides {
+		wg.Add(1)
+		go func(side *streamJoinSide) {
+			defer wg.Done()
+			j.consumeSide(ctx, side)
+		}(side)
+	}
+
+	consumedChan := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(consumedChan)
+	}()
+
+	ticker := time.NewTicker(j.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := j.expireAndEmit(ctx, false); err != nil && ctx.Err() == nil {
+				j.log.Errorf("Failed to expire pending records: %v", err)
+			}
+		case <-consumedChan:
+			// Once both inputs are consumed all pending records are expired,
+			// and expiry is repeated until every emitted record has been
+			// acknowledged.
+			for ctx.Err() == nil {
+				if err := j.expireAndEmit(ctx, true); err != nil && ctx.Err() == nil {
+					j.log.Errorf("Failed to expire pending records: %v", err)
+				}
+				if j.pendingCount() == 0 {
+					close(j.resChan)
+					return
+				}
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+				}
+			}
+			return
+		}
+	}
+}
+
+func (j *streamJoinInput) Connect(ctx context.Context) error {
+	j.startOnce.Do(func() {
+		go j.loop()
+	})
+	return nil
+}
+
+func (j *streamJoinInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	select {
+	case res, open := <-j.resChan:
+		if !open {
+			return nil, nil, service.ErrEndOfInput
+		}
+		return res.msg, res.ackFn, nil
+	case <-j.shutSig.HasClosedChan():
+		return nil, nil, service.ErrEndOfInput
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (j *streamJoinInput) Close(ctx context.Context) error {
+	j.shutSig.CloseAtLeisure()
+	j.startOnce.Do(func() {
+		go j.closeChildren()
+	})
+	select {
+	case <-j.shutSig.HasClosedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runStreamJoin(t *testing.T, inputConf string) []string {
+	t.Helper()
+	return runStreamJoinRejecting(t, inputConf, false)
+}
+
+// runStreamJoinRejecting runs a stream join and returns the messages consumed,
+// where when rejectFirst is true the first delivery of each distinct message is
+// rejected.
+func runStreamJoinRejecting(t *testing.T, inputConf string, rejectFirst bool) []string {
+	t.Helper()
+
+	builder := service.NewStreamBuilder()
+	require.NoError(t, builder.SetLoggerYAML(`level: OFF`))
+	require.NoError(t, builder.AddCacheYAML(`
+label: joincache
+memory: {}
+`))
+	require.NoError(t, builder.AddInputYAML(inputConf))
+
+	var resMut sync.Mutex
+	var results []string
+	rejected := map[string]bool{}
+	require.NoError(t, builder.AddConsumerFunc(func(_ context.Context, m *service.Message) error {
+		b, err := m.AsBytes()
+		require.NoError(t, err)
+
+		resMut.Lock()
+		defer resMut.Unlock()
+		if rejectFirst && !rejected[string(b)] {
+			rejected[string(b)] = true
+			return errors.New("rejected")
+		}
+		results = append(results, string(b))
+		return nil
+	}))
+
+	strm, err := builder.Build()
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+	require.NoError(t, strm.Run(ctx))
+
+	sort.Strings(results)
+	return results
+}
+
+func streamJoinSidesConf(leftIDs, rightIDs []string) string {
+	return fmt.Sprintf(`
+  left:
+    input:
+      generate:
+        count: %v
+        interval: ""
+        mapping: 'root.id = %v.index(count("%v") - 1)'
+    key: root = this.id
+  right:
+    input:
+      generate:
+        count: %v
+        interval: ""
+        mapping: 'root.uid = %v.index(count("%v") - 1)'
+    key: root = this.uid
+`,
+		len(leftIDs), bloblArray(leftIDs), testCounterName(),
+		len(rightIDs), bloblArray(rightIDs), testCounterName())
+}
+
+func bloblArray(ids []string) string {
+	quoted := make([]string, len(ids))
+	for i, id := range ids {
+		quoted[i] = `"` + id + `"`
+	}
+	return "[" + strings.Join(quoted, ",") + "]"
+}
+
+func TestStreamJoinTypes(t *testing.T) {
+	leftIDs, rightIDs := []string{"a", "b", "c"}, []string{"b", "c", "d"}
+
+	for _, test := range []struct {
+		joinType string
+		expected []string
+	}{
+		{
+			joinType: "inner",
+			expected: []string{
+				`{"left":{"id":"b"},"right":{"uid":"b"}}`,
+				`{"left":{"id":"c"},"right":{"uid":"c"}}`,
+			},
+		},
+		{
+			joinType: "left",
+			expected: []string{
+				`{"left":{"id":"a"}}`,
+				`{"left":{"id":"b"},"right":{"uid":"b"}}`,
+				`{"left":{"id":"c"},"right":{"uid":"c"}}`,
+			},
+		},
+		{
+			joinType: "outer",
+			expected: []string{
+				`{"left":{"id":"a"}}`,
+				`{"left":{"id":"b"},"right":{"uid":"b"}}`,
+				`{"left":{"id":"c"},"right":{"uid":"c"}}`,
+				`{"right":{"uid":"d"}}`,
+			},
+		},
+	} {
+		test := test
+		t.Run(test.joinType, func(t *testing.T) {
+			results := runStreamJoin(t, fmt.Sprintf(`
+stream_join:
+  type: %v
+  cache: joincache
+  window: 1h
+%v`, test.joinType, streamJoinSidesConf(leftIDs, rightIDs)))
+			assert.Equal(t, test.expected, results)
+		})
+	}
+}
+
+func TestStreamJoinRejectedResults(t *testing.T) {
+	results := runStreamJoinRejecting(t, fmt.Sprintf(`
+stream_join:
+  type: outer
+  cache: joincache
+  window: 1h
+  check_interval: 10ms
+%v`, streamJoinSidesConf([]string{"a", "b"}, []string{"b", "c"})), true)
+
+	assert.Equal(t, []string{
+		`{"left":{"id":"a"}}`,
+		`{"left":{"id":"b"},"right":{"uid":"b"}}`,
+		`{"right":{"uid":"c"}}`,
+	}, results)
+}
+
+func TestStreamJoinManyToMany(t *testing.T) {
+	results := runStreamJoin(t, fmt.Sprintf(`
+stream_join:
+  cache: joincache
+  window: 1h
+%v`, streamJoinSidesConf([]string{"a", "a"}, []string{"a", "a"})))
+
+	assert.Len(t, results, 4)
+	for _, res := range results {
+		assert.Equal(t, `{"left":{"id":"a"},"right":{"uid":"a"}}`, res)
+	}
+}
+
+func TestStreamJoinWindowExpiry(t *testing.T) {
+	tmpDir := t.TempDir()
+	expiredPath := filepath.Join(tmpDir, "expired.jsonl")
+
+	results := runStreamJoin(t, fmt.Sprintf(`
+stream_join:
+  cache: joincache
+  window: 100ms
+  check_interval: 10ms
+  left:
+    input:
+      generate:
+        count: 1
+        interval: ""
+        mapping: 'root.id = "a"'
+    key: root = this.id
+  right:
+    input:
+      generate:
+        count: 3
+        interval: 300ms
+        mapping: 'root.uid = ["b","a","c"].index(count("%v") - 1)'
+    key: root = this.uid
+  expired_output:
+    file:
+      path: %v
+      codec: lines
+`, testCounterName(), expiredPath))
+
+	assert.Empty(t, results)
+
+	expiredBytes, err := os.ReadFile(expiredPath)
+	require.NoError(t, err)
+
+	expired := strings.Split(strings.TrimSpace(string(expiredBytes)), "\n")
+	sort.Strings(expired)
+	assert.Equal(t, []string{
+		`{"id":"a"}`,
+		`{"uid":"a"}`,
+		`{"uid":"b"}`,
+		`{"uid":"c"}`,
+	}, expired)
+}
+
+func TestStreamJoinMissingCache(t *testing.T) {
+	builder := service.NewStreamBuilder()
+	require.NoError(t, builder.SetLoggerYAML(`level: OFF`))
+	require.NoError(t, builder.AddInputYAML(fmt.Sprintf(`
+stream_join:
+  cache: nope
+  window: 1m
+%v`, streamJoinSidesConf([]string{"a"}, []string{"a"}))))
+	require.NoError(t, builder.AddConsumerFunc(func(context.Context, *service.Message) error {
+		return nil
+	}))
+
+	strm, err := builder.Build()
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	err = strm.Run(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cache resource 'nope' was not found")
+}
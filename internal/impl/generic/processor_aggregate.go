@@ -0,0 +1,456 @@
+package generic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/Jeffail/gabs/v2"
+)
+
+func aggregateProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Version("3.60.0").
+		Categories("Composition").
+		Summary("Groups the messages of a batch by a key and calculates a list of aggregations for each group, emitting one message per group.").
+		Description(`
+Each message of a batch is assigned to a group by executing the `+"[Bloblang mapping](/docs/guides/bloblang/about) `group_by`"+`, which when omitted places all messages into a single group. Each group then results in a single message containing the value of each aggregation at its `+"`field`"+`, along with the group key at the field `+"`group_field`"+`. The resulting messages retain the metadata of the first message of their group, and are ordered by the first appearance of their group within the batch.
+
+The values aggregated are obtained by executing the `+"`value`"+` mapping of each aggregation against each message, where mappings that result in `+"`deleted()`"+` or `+"`null`"+` exclude the message from that aggregation only.
+
+### Aggregation Types
+
+| Type | Result |
+|---|---|
+| `+"`count`"+` | The number of values. |
+| `+"`sum`"+` | The sum of numeric values. |
+| `+"`min`"+` | The lowest numeric value. |
+| `+"`max`"+` | The highest numeric value. |
+| `+"`avg`"+` | The mean of numeric values. |
+| `+"`count_distinct`"+` | The number of unique values. |
+| `+"`percentile`"+` | The value at the `+"`percentile`"+` of numeric values, interpolated linearly between the closest ranks. |
+| `+"`collect`"+` | An array of all values. |
+
+When an aggregation has no values the result is `+"`0`"+` for counts and sums, an empty array for collections, and `+"`null`"+` for all other types.
+
+### Error Handling
+
+If either the `+"`group_by`"+` or a `+"`value`"+` mapping fails for a message, or a value is not a number when a numeric aggregation requires one, then the message is excluded from all aggregations and is instead added unchanged to the end of the resulting batch flagged with the error, where it can be handled with [error handling patterns](/docs/configuration/error_handling).`).
+		Field(service.NewBloblangField("group_by").
+			Description("An optional Bloblang mapping that results in the key of the group that each message belongs to. When omitted all messages of a batch belong to a single group.").
+			Example(`root = this.user.id`).
+			Example(`root = meta("kafka_key")`).
+			Optional()).
+		Field(service.NewStringField("group_field").
+			Description("A [dot path](/docs/configuration/field_paths) of the resulting messages to store the key of the group within. When empty or when `group_by` is omitted the key is not stored.").
+			Default("group")).
+		Field(service.NewObjectListField("aggregates",
+			service.NewStringField("field").
+				Description("A [dot path](/docs/configuration/field_paths) of the resulting messages to store the result of the aggregation within."),
+			service.NewStringEnumField("type", "count", "sum", "min", "max", "avg", "count_distinct", "percentile", "collect").
+				Description("The type of aggregation to calculate."),
+			service.NewBloblangField("value").
+				Description("A Bloblang mapping that results in the value of each message to aggregate.").
+				Default("root = this"),
+			service.NewFloatField("percentile").
+				Description("The percentile to calculate, between 0 and 100, when the type is `percentile`.").
+				Default(50.0),
+		).Description("A list of aggregations to calculate for each group.")).
+		Example("Totals per User", `
+Given batches of purchase events, emit a summary of the purchases of each user within the batch:`,
+			`
+pipeline:
+  processors:
+    - aggregate:
+        group_by: root = this.user
+        group_field: user
+        aggregates:
+          - field: purchases
+            type: count
+          - field: total
+            type: sum
+            value: root = this.price
+          - field: p95_price
+            type: percentile
+            value: root = this.price
+            percentile: 95
+          - field: items
+            type: collect
+            value: root = this.item
+`).
+		Example("Windowed Aggregations", `
+Aggregations are most useful when combined with batching or windowing mechanisms. Here we count the unique visitors of each page over tumbling windows of one minute:`,
+			`
+buffer:
+  system_window:
+    size: 1m
+
+pipeline:
+  processors:
+    - aggregate:
+        group_by: root = this.page
+        group_field: page
+        aggregates:
+          - field: unique_visitors
+            type: count_distinct
+            value: root = this.visitor_id
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"aggregate", aggregateProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newAggregateProcessorFromConfig(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type aggregateSpec struct {
+	field      []string
+	typeStr    string
+	value      *bloblang.Executor
+	percentile float64
+}
+
+type aggregateProcessor struct {
+	groupBy    *bloblang.Executor
+	groupField []string
+	aggregates []aggregateSpec
+}
+
+func newAggregateProcessorFromConfig(conf *service.ParsedConfig) (*aggregateProcessor, error) {
+	p := &aggregateProcessor{}
+
+	var err error
+	if conf.Contains("group_by") {
+		if p.groupBy, err = conf.FieldBloblang("group_by"); err != nil {
+			return nil, err
+		}
+	}
+
+	groupField, err := conf.FieldString("group_field")
+	if err != nil {
+		return nil, err
+	}
+	if groupField != "" {
+		p.groupField = gabs.DotPathToSlice(groupField)
+	}
+
+	aggConfs, err := conf.FieldObjectList("aggregates")
+	if err != nil {
+		return nil, err
+	}
+	if len(aggConfs) == 0 {
+		return nil, errors.New("at least one aggregate must be specified")
+	}
+
+	for i, aConf := range aggConfs {
+		var spec aggregateSpec
+
+		field, err := aConf.FieldString("field")
+		if err != nil {
+			return nil, err
+		}
+		if field == "" {
+			return nil, fmt.Errorf("aggregate %v: a field must be specified", i)
+		}
+		spec.field = gabs.DotPathToSlice(field)
+
+		if spec.typeStr, err = aConf.FieldString("type"); err != nil {
+			return nil, err
+		}
+		if _, err := newAggregator(spec.typeStr, 0); err != nil {
+			return nil, fmt.Errorf("aggregate %v: %w", i, err)
+		}
+		if spec.value, err = aConf.FieldBloblang("value"); err != nil {
+			return nil, err
+		}
+		if spec.percentile, err = aConf.FieldFloat("percentile"); err != nil {
+			return nil, err
+		}
+		if spec.percentile < 0 || spec.percentile > 100 {
+			return nil, fmt.Errorf("aggregate %v: percentile must be between 0 and 100, got %v", i, spec.percentile)
+		}
+		p.aggregates = append(p.aggregates, spec)
+	}
+	return p, nil
+}
+
+type aggregateGroup struct {
+	key         interface{}
+	first       *service.Message
+	aggregators []aggregator
+}
+
+func (p *aggregateProcessor) newGroup(key interface{}, first *service.Message) *aggregateGroup {
+	g := &aggregateGroup{
+		key:         key,
+		first:       first,
+		aggregators: make([]aggregator, len(p.aggregates)),
+	}
+	for i, spec := range p.aggregates {
+		g.aggregators[i], _ = newAggregator(spec.typeStr, spec.percentile)
+	}
+	return g
+}
+
+// aggregateMappingValue returns the value resulting from a mapping, where
+// mappings resulting in strings are raw and therefore not structured.
+func aggregateMappingValue(res *service.Message) interface{} {
+	if v, err := res.AsStructured(); err == nil {
+		return v
+	}
+	b, _ := res.AsBytes()
+	return string(b)
+}
+
+func (p *aggregateProcessor) groupKey(batch service.MessageBatch, i int) (key interface{}, keyStr string, err error) {
+	if p.groupBy == nil {
+		return nil, "", nil
+	}
+	res, err := batch.BloblangQuery(i, p.groupBy)
+	if err != nil {
+		return nil, "", fmt.Errorf("group_by mapping failed: %w", err)
+	}
+	if res == nil {
+		return nil, "", errors.New("group_by mapping resulted in a deleted message")
+	}
+	keyBytes, err := res.AsBytes()
+	if err != nil {
+		return nil, "", err
+	}
+	return aggregateMappingValue(res), string(keyBytes), nil
+}
+
+func (p *aggregateProcessor) values(batch service.MessageBatch, i int) ([]interface{}, error) {
+	values := make([]interface{}, len(p.aggregates))
+	for j, spec := range p.aggregates {
+		res, err := batch.BloblangQuery(i, spec.value)
+		if err != nil {
+			return nil, fmt.Errorf("aggregate %v value mapping failed: %w", j, err)
+		}
+		if res == nil {
+			continue
+		}
+		values[j] = aggregateMappingValue(res)
+		if values[j] == nil || !aggregatorIsNumeric(spec.typeStr) {
+			continue
+		}
+		if values[j], err = query.IGetNumber(values[j]); err != nil {
+			return nil, fmt.Errorf("aggregate %v: %w", j, err)
+		}
+	}
+	return values, nil
+}
+
+func (p *aggregateProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	groups := map[string]*aggregateGroup{}
+	var groupOrder []*aggregateGroup
+	var failed service.MessageBatch
+
+	for i, msg := range batch {
+		key, keyStr, err := p.groupKey(batch, i)
+		if err != nil {
+			msg = msg.Copy()
+			msg.SetError(err)
+			failed = append(failed, msg)
+			continue
+		}
+
+		values, err := p.values(batch, i)
+		if err != nil {
+			msg = msg.Copy()
+			msg.SetError(err)
+			failed = append(failed, msg)
+			continue
+		}
+
+		group, exists := groups[keyStr]
+		if !exists {
+			group = p.newGroup(key, msg)
+			groups[keyStr] = group
+			groupOrder = append(groupOrder, group)
+		}
+		for j, v := range values {
+			if v != nil {
+				group.aggregators[j].add(v)
+			}
+		}
+	}
+
+	resBatch := make(service.MessageBatch, 0, len(groupOrder)+len(failed))
+	for _, group := range groupOrder {
+		gObj := gabs.New()
+		if p.groupBy != nil && len(p.groupField) > 0 {
+			_, _ = gObj.Set(group.key, p.groupField...)
+		}
+		for j, spec := range p.aggregates {
+			_, _ = gObj.Set(group.aggregators[j].result(), spec.field...)
+		}
+		msg := group.first.Copy()
+		msg.SetStructured(gObj.Data())
+		resBatch = append(resBatch, msg)
+	}
+	resBatch = append(resBatch, failed...)
+	if len(resBatch) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{resBatch}, nil
+}
+
+func (p *aggregateProcessor) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type aggregator interface {
+	add(v interface{})
+	result() interface{}
+}
+
+func aggregatorIsNumeric(typeStr string) bool {
+	switch typeStr {
+	case "sum", "min", "max", "avg", "percentile":
+		return true
+	}
+	return false
+}
+
+func newAggregator(typeStr string, percentile float64) (aggregator, error) {
+	switch typeStr {
+	case "count":
+		return &countAggregator{}, nil
+	case "sum":
+		return &sumAggregator{}, nil
+	case "min":
+		return &minMaxAggregator{min: true}, nil
+	case "max":
+		return &minMaxAggregator{}, nil
+	case "avg":
+		return &avgAggregator{}, nil
+	case "count_distinct":
+		return &countDistinctAggregator{seen: map[string]struct{}{}}, nil
+	case "percentile":
+		return &percentileAggregator{percentile: percentile}, nil
+	case "collect":
+		return &collectAggregator{values: []interface{}{}}, nil
+	}
+	return nil, fmt.Errorf("aggregate type not recognised: %v", typeStr)
+}
+
+type countAggregator struct {
+	count int64
+}
+
+func (a *countAggregator) add(v interface{}) {
+	a.count++
+}
+
+func (a *countAggregator) result() interface{} {
+	return a.count
+}
+
+type sumAggregator struct {
+	sum float64
+}
+
+func (a *sumAggregator) add(v interface{}) {
+	a.sum += v.(float64)
+}
+
+func (a *sumAggregator) result() interface{} {
+	return a.sum
+}
+
+type minMaxAggregator struct {
+	min   bool
+	value *float64
+}
+
+func (a *minMaxAggregator) add(v interface{}) {
+	f := v.(float64)
+	if a.value == nil || (a.min && f < *a.value) || (!a.min && f > *a.value) {
+		a.value = &f
+	}
+}
+
+func (a *minMaxAggregator) result() interface{} {
+	if a.value == nil {
+		return nil
+	}
+	return *a.value
+}
+
+type avgAggregator struct {
+	sum   float64
+	count int64
+}
+
+func (a *avgAggregator) add(v interface{}) {
+	a.sum += v.(float64)
+	a.count++
+}
+
+func (a *avgAggregator) result() interface{} {
+	if a.count == 0 {
+		return nil
+	}
+	return a.sum / float64(a.count)
+}
+
+type countDistinctAggregator struct {
+	seen map[string]struct{}
+}
+
+func (a *countDistinctAggregator) add(v interface{}) {
+	b, _ := json.Marshal(v)
+	a.seen[string(b)] = struct{}{}
+}
+
+func (a *countDistinctAggregator) result() interface{} {
+	return int64(len(a.seen))
+}
+
+type percentileAggregator struct {
+	percentile float64
+	values     []float64
+}
+
+func (a *percentileAggregator) add(v interface{}) {
+	a.values = append(a.values, v.(float64))
+}
+
+func (a *percentileAggregator) result() interface{} {
+	if len(a.values) == 0 {
+		return nil
+	}
+	sort.Float64s(a.values)
+	rank := a.percentile / 100 * float64(len(a.values)-1)
+	lower, upper := math.Floor(rank), math.Ceil(rank)
+	lowerV, upperV := a.values[int(lower)], a.values[int(upper)]
+	return lowerV + (upperV-lowerV)*(rank-lower)
+}
+
+type collectAggregator struct {
+	values []interface{}
+}
+
+func (a *collectAggregator) add(v interface{}) {
+	a.values = append(a.values, v)
+}
+
+func (a *collectAggregator) result() interface{} {
+	return a.values
+}
@@ -0,0 +1,242 @@
+package generic
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAggregateProcessorFromYAML(t *testing.T, confStr string) *aggregateProcessor {
+	t.Helper()
+
+	conf, err := aggregateProcessorConfig().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	p, err := newAggregateProcessorFromConfig(conf)
+	require.NoError(t, err)
+	return p
+}
+
+type aggregateResult struct {
+	content string
+	err     string
+}
+
+func processAggregate(t *testing.T, p *aggregateProcessor, docs ...string) []aggregateResult {
+	t.Helper()
+
+	var batch service.MessageBatch
+	for _, d := range docs {
+		batch = append(batch, service.NewMessage([]byte(d)))
+	}
+
+	batches, err := p.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	if len(batches) == 0 {
+		return nil
+	}
+	require.Len(t, batches, 1)
+
+	var results []aggregateResult
+	for _, m := range batches[0] {
+		b, err := m.AsBytes()
+		require.NoError(t, err)
+		res := aggregateResult{content: string(b)}
+		if err := m.GetError(); err != nil {
+			res.err = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+func TestAggregateConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		config      string
+		errContains string
+	}{
+		{config: `aggregates: []`, errContains: "at least one aggregate must be specified"},
+		{config: `aggregates: [ { field: "", type: count } ]`, errContains: "a field must be specified"},
+		{config: `aggregates: [ { field: foo, type: nope } ]`, errContains: "aggregate type not recognised: nope"},
+		{config: `aggregates: [ { field: foo, type: percentile, percentile: 101 } ]`, errContains: "percentile must be between 0 and 100"},
+	} {
+		conf, err := aggregateProcessorConfig().ParseYAML(test.config, nil)
+		require.NoError(t, err, test.config)
+
+		_, err = newAggregateProcessorFromConfig(conf)
+		require.Error(t, err, test.config)
+		assert.Contains(t, err.Error(), test.errContains, test.config)
+	}
+}
+
+func TestAggregateGrouped(t *testing.T) {
+	p := newAggregateProcessorFromYAML(t, `
+group_by: root = this.user
+group_field: meta.user
+aggregates:
+  - field: count
+    type: count
+  - field: total
+    type: sum
+    value: root = this.price
+  - field: min
+    type: min
+    value: root = this.price
+  - field: max
+    type: max
+    value: root = this.price
+  - field: avg
+    type: avg
+    value: root = this.price
+  - field: items
+    type: count_distinct
+    value: root = this.item
+  - field: median
+    type: percentile
+    value: root = this.price
+  - field: p90
+    type: percentile
+    value: root = this.price
+    percentile: 90
+  - field: all.items
+    type: collect
+    value: root = this.item
+`)
+
+	results := processAggregate(t, p,
+		`{"user":"a","item":"x","price":10}`,
+		`{"user":"b","item":"x","price":5}`,
+		`{"user":"a","item":"y","price":20}`,
+		`{"user":"a","item":"x","price":30}`,
+		`{"user":"a","item":"z","price":40}`,
+	)
+
+	assert.Equal(t, []aggregateResult{
+		{content: `{"all":{"items":["x","y","x","z"]},"avg":25,"count":4,"items":3,"max":40,"median":25,"meta":{"user":"a"},"min":10,"p90":37,"total":100}`},
+		{content: `{"all":{"items":["x"]},"avg":5,"count":1,"items":1,"max":5,"median":5,"meta":{"user":"b"},"min":5,"p90":5,"total":5}`},
+	}, results)
+}
+
+func TestAggregateSingleGroup(t *testing.T) {
+	p := newAggregateProcessorFromYAML(t, `
+aggregates:
+  - field: count
+    type: count
+  - field: avg
+    type: avg
+    value: root = this.price
+  - field: docs
+    type: collect
+`)
+
+	var batch service.MessageBatch
+	for _, d := range []string{`{"id":1}`, `{"id":2,"price":3}`} {
+		msg := service.NewMessage([]byte(d))
+		msg.MetaSet("id", d)
+		batch = append(batch, msg)
+	}
+
+	batches, err := p.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], 1)
+
+	b, err := batches[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"avg":3,"count":2,"docs":[{"id":1},{"id":2,"price":3}]}`, string(b))
+
+	v, _ := batches[0][0].MetaGet("id")
+	assert.Equal(t, `{"id":1}`, v, "metadata of the first message should be retained")
+}
+
+func TestAggregateEmptyGroups(t *testing.T) {
+	p := newAggregateProcessorFromYAML(t, `
+group_by: root = this.user
+aggregates:
+  - field: total
+    type: sum
+    value: root = this.price
+  - field: max
+    type: max
+    value: root = this.price
+  - field: prices
+    type: collect
+    value: root = this.price
+`)
+
+	results := processAggregate(t, p, `{"user":"a"}`)
+	assert.Equal(t, []aggregateResult{
+		{content: `{"group":"a","max":null,"prices":[],"total":0}`},
+	}, results)
+}
+
+func TestAggregateErrors(t *testing.T) {
+	p := newAggregateProcessorFromYAML(t, `
+group_by: root = this.user.not_null()
+aggregates:
+  - field: total
+    type: sum
+    value: root = this.price
+`)
+
+	results := processAggregate(t, p,
+		`{"user":"a","price":10}`,
+		`{"price":5}`,
+		`{"user":"a","price":"nope"}`,
+		`{"user":"a","price":15}`,
+	)
+
+	require.Len(t, results, 3)
+	assert.Equal(t, aggregateResult{content: `{"group":"a","total":25}`}, results[0])
+
+	assert.Equal(t, `{"price":5}`, results[1].content)
+	assert.Contains(t, results[1].err, "group_by mapping failed")
+
+	assert.Equal(t, `{"user":"a","price":"nope"}`, results[2].content)
+	assert.Contains(t, results[2].err, "aggregate 0")
+}
+
+func TestAggregateStream(t *testing.T) {
+	builder := service.NewStreamBuilder()
+	require.NoError(t, builder.SetLoggerYAML(`level: OFF`))
+	require.NoError(t, builder.AddInputYAML(`
+broker:
+  inputs:
+    - generate:
+        count: 6
+        interval: ""
+        mapping: |
+          root.user = ["a","b"].index(count("`+testCounterName()+`") % 2)
+          root.price = 10
+  batching:
+    count: 6
+`))
+	require.NoError(t, builder.AddProcessorYAML(`
+aggregate:
+  group_by: root = this.user
+  aggregates:
+    - field: total
+      type: sum
+      value: root = this.price
+`))
+
+	var results []string
+	require.NoError(t, builder.AddConsumerFunc(func(_ context.Context, m *service.Message) error {
+		b, err := m.AsBytes()
+		require.NoError(t, err)
+		results = append(results, string(b))
+		return nil
+	}))
+
+	strm, err := builder.Build()
+	require.NoError(t, err)
+	require.NoError(t, strm.Run(context.Background()))
+
+	assert.ElementsMatch(t, []string{
+		`{"group":"a","total":30}`,
+		`{"group":"b","total":30}`,
+	}, results)
+}
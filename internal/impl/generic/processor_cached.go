@@ -0,0 +1,238 @@
+package generic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"golang.org/x/sync/singleflight"
+)
+
+// cachedNotFoundValue is stored in the cache in order to record that a key was
+// not found by the loader, allowing negative caching.
+var cachedNotFoundValue = []byte("\x00benthos_cached_not_found\x00")
+
+var errCachedNotFound = errors.New("key not found by loader")
+
+func cachedProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Version("3.60.0").
+		Categories("Integration").
+		Summary("Obtains the value of a key from a cache, and when the key is missing executes a list of child processors in order to load the value and stores it in the cache.").
+		Description(`
+This processor replaces the contents of each message with the value of a key obtained from a `+"[cache resource](/docs/components/caches/about)"+`. When the key does not exist the child `+"`processors`"+` are executed on a copy of the message, and the contents of the resulting message are stored in the cache with an optional `+"`ttl`"+` and then used as the value. This makes it simple to cache the results of expensive lookups such as HTTP requests or database queries, and is often combined with a `+"[`branch` processor](/docs/components/processors/branch)"+` in order to merge the value into the original message.
+
+When multiple messages processed by the same processor miss the same key of a cache concurrently only one of them executes the child processors and the resulting value is shared with the others. Each pipeline thread has its own processor, and therefore in order to coalesce misses across threads the processor should be defined as a `+"[processor resource](/docs/configuration/resources)"+`.
+
+### Not Found Results
+
+If the child processors result in zero messages, for example when a `+"`bloblang`"+` processor maps the result to `+"`deleted()`"+`, then the key is considered not found and the message is flagged as failed, which can be handled with [error handling patterns](/docs/configuration/error_handling). When a `+"`negative_ttl`"+` is specified not found results are also stored in the cache for that duration, which prevents repeated lookups of missing keys.
+
+If the child processors fail then the message is flagged as failed and nothing is stored in the cache.`).
+		Field(service.NewStringField("cache").
+			Description("The [`cache` resource](/docs/components/caches/about) to target with this processor.")).
+		Field(service.NewInterpolatedStringField("key").
+			Description("A key to look up and store values with.").
+			Example(`${! json("user_id") }`)).
+		Field(service.NewStringField("ttl").
+			Description("An optional duration string describing the TTL of values stored in the cache, otherwise the default TTL of the cache is used.").
+			Default("").
+			Example("60s").Example("5m")).
+		Field(service.NewStringField("negative_ttl").
+			Description("An optional duration string, which when specified causes keys that were not found by the child processors to be stored in the cache for that duration. By default not found results are not cached.").
+			Default("").
+			Example("30s")).
+		Field(service.NewProcessorListField("processors").
+			Description("A list of child processors to execute on a copy of a message when its key is missing from the cache, the contents of the resulting message are used as the value.")).
+		Example("Cached Enrichment", `
+Enrich documents with user details obtained via HTTP, caching the details of each user for five minutes and missing users for thirty seconds:`,
+			`
+pipeline:
+  processors:
+    - branch:
+        request_map: 'root = ""'
+        processors:
+          - cached:
+              cache: users
+              key: ${! json("user_id") }
+              ttl: 5m
+              negative_ttl: 30s
+              processors:
+                - http:
+                    url: http://example.com/users/${! json("user_id") }
+                    verb: GET
+                - bloblang: 'root = if this.found == false { deleted() }'
+        result_map: root.user = this
+
+cache_resources:
+  - label: users
+    memory: {}
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"cached", cachedProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newCachedProcessorFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type cachedProcessor struct {
+	mgr       *service.Resources
+	cacheName string
+	key       *service.InterpolatedString
+
+	ttl         *time.Duration
+	negativeTTL *time.Duration
+
+	loader []*service.OwnedProcessor
+
+	// Concurrent loads of the same key are coalesced, and are executed with a
+	// context that belongs to the processor rather than any single caller
+	// so that one caller giving up does not fail the load for the others.
+	loadGroup singleflight.Group
+	loadCtx   context.Context
+	loadDone  func()
+}
+
+func newCachedProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*cachedProcessor, error) {
+	cacheName, err := conf.FieldString("cache")
+	if err != nil {
+		return nil, err
+	}
+	key, err := conf.FieldInterpolatedString("key")
+	if err != nil {
+		return nil, err
+	}
+	p := &cachedProcessor{
+		mgr:       mgr,
+		cacheName: cacheName,
+		key:       key,
+	}
+	if ttl, err := getDuration(conf, false, "ttl"); err != nil {
+		return nil, err
+	} else if ttl > 0 {
+		p.ttl = &ttl
+	}
+	if ttl, err := getDuration(conf, false, "negative_ttl"); err != nil {
+		return nil, err
+	} else if ttl > 0 {
+		p.negativeTTL = &ttl
+	}
+	if p.loader, err = conf.FieldProcessorList("processors"); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(cacheName) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", cacheName)
+	}
+	p.loadCtx, p.loadDone = context.WithCancel(context.Background())
+	return p, nil
+}
+
+func (p *cachedProcessor) get(ctx context.Context, key string) (value []byte, err error) {
+	if cerr := p.mgr.AccessCache(ctx, p.cacheName, func(c service.Cache) {
+		value, err = c.Get(ctx, key)
+	}); cerr != nil {
+		err = cerr
+	}
+	return
+}
+
+func (p *cachedProcessor) set(ctx context.Context, key string, value []byte, ttl *time.Duration) (err error) {
+	if cerr := p.mgr.AccessCache(ctx, p.cacheName, func(c service.Cache) {
+		err = c.Set(ctx, key, value, ttl)
+	}); cerr != nil {
+		err = cerr
+	}
+	return
+}
+
+// load executes the child processors on a copy of a message and stores the
+// result in the cache.
+func (p *cachedProcessor) load(ctx context.Context, key string, msg *service.Message) ([]byte, error) {
+	batch := service.MessageBatch{msg.Copy()}
+	for _, proc := range p.loader {
+		var nextBatch service.MessageBatch
+		for _, m := range batch {
+			res, err := proc.Process(ctx, m)
+			if err != nil {
+				return nil, err
+			}
+			nextBatch = append(nextBatch, res...)
+		}
+		if batch = nextBatch; len(batch) == 0 {
+			break
+		}
+	}
+
+	if len(batch) == 0 {
+		if p.negativeTTL != nil {
+			if err := p.set(ctx, key, cachedNotFoundValue, p.negativeTTL); err != nil {
+				return nil, err
+			}
+		}
+		return nil, errCachedNotFound
+	}
+	if len(batch) > 1 {
+		return nil, fmt.Errorf("child processors resulted in %v messages, expected one", len(batch))
+	}
+	if err := batch[0].GetError(); err != nil {
+		return nil, err
+	}
+
+	value, err := batch[0].AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.set(ctx, key, value, p.ttl); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (p *cachedProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	key := p.key.String(msg)
+
+	value, err := p.get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, service.ErrKeyNotFound) {
+			return nil, err
+		}
+		resChan := p.loadGroup.DoChan(key, func() (interface{}, error) {
+			return p.load(p.loadCtx, key, msg)
+		})
+		select {
+		case res := <-resChan:
+			if res.Err != nil {
+				return nil, res.Err
+			}
+			value = res.Val.([]byte)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	} else if bytes.Equal(value, cachedNotFoundValue) {
+		return nil, errCachedNotFound
+	}
+
+	msg.SetBytes(value)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *cachedProcessor) Close(ctx context.Context) error {
+	p.loadDone()
+	for _, proc := range p.loader {
+		if err := proc.Close(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
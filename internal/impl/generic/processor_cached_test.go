@@ -0,0 +1,208 @@
+package generic
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type cachedTestResult struct {
+	content string
+	err     string
+}
+
+var testCounterNameID int64
+
+// testCounterName returns a unique name for the Bloblang count function so
+// that loads can be counted across repeated test runs.
+func testCounterName() string {
+	return fmt.Sprintf("test_counter_%v", atomic.AddInt64(&testCounterNameID, 1))
+}
+
+func runCachedProcessor(t *testing.T, procConf string, threads int, inputs ...string) []cachedTestResult {
+	t.Helper()
+	return runCachedProcessorWithResources(t, "", procConf, threads, inputs...)
+}
+
+func runCachedProcessorWithResources(t *testing.T, resourcesConf, procConf string, threads int, inputs ...string) []cachedTestResult {
+	t.Helper()
+
+	builder := service.NewStreamBuilder()
+	require.NoError(t, builder.SetLoggerYAML(`level: OFF`))
+	require.NoError(t, builder.AddCacheYAML(`
+label: foocache
+memory: {}
+`))
+	if resourcesConf != "" {
+		require.NoError(t, builder.AddResourcesYAML(resourcesConf))
+	}
+	require.NoError(t, builder.AddProcessorYAML(procConf))
+	builder.SetThreads(threads)
+
+	produce, err := builder.AddProducerFunc()
+	require.NoError(t, err)
+
+	var resMut sync.Mutex
+	var results []cachedTestResult
+	require.NoError(t, builder.AddConsumerFunc(func(_ context.Context, m *service.Message) error {
+		b, err := m.AsBytes()
+		require.NoError(t, err)
+
+		res := cachedTestResult{content: string(b)}
+		if err := m.GetError(); err != nil {
+			res.err = err.Error()
+		}
+
+		resMut.Lock()
+		results = append(results, res)
+		resMut.Unlock()
+		return nil
+	}))
+
+	strm, err := builder.Build()
+	require.NoError(t, err)
+
+	go func() {
+		_ = strm.Run(context.Background())
+	}()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	var wg sync.WaitGroup
+	for _, input := range inputs {
+		if threads > 1 {
+			wg.Add(1)
+			go func(input string) {
+				defer wg.Done()
+				require.NoError(t, produce(ctx, service.NewMessage([]byte(input))))
+			}(input)
+		} else {
+			require.NoError(t, produce(ctx, service.NewMessage([]byte(input))))
+		}
+	}
+	wg.Wait()
+
+	require.NoError(t, strm.StopWithin(time.Second*5))
+	return results
+}
+
+func TestCachedProcessorHitsAndMisses(t *testing.T) {
+	results := runCachedProcessor(t, fmt.Sprintf(`
+cached:
+  cache: foocache
+  key: ${! json("id") }
+  processors:
+    - bloblang: 'root = this.id.uppercase() + "-" + count("%v").string()'
+`, testCounterName()), 1, `{"id":"a"}`, `{"id":"a"}`, `{"id":"b"}`, `{"id":"a"}`)
+
+	assert.Equal(t, []cachedTestResult{
+		{content: "A-1"},
+		{content: "A-1"},
+		{content: "B-2"},
+		{content: "A-1"},
+	}, results)
+}
+
+func TestCachedProcessorLoaderErrors(t *testing.T) {
+	results := runCachedProcessor(t, fmt.Sprintf(`
+cached:
+  cache: foocache
+  key: ${! json("id") }
+  processors:
+    - bloblang: 'root = if count("%v") == 1 { throw("nope") } else { this.id }'
+`, testCounterName()), 1, `{"id":"a"}`, `{"id":"a"}`)
+
+	require.Len(t, results, 2)
+	assert.Equal(t, `{"id":"a"}`, results[0].content)
+	assert.Contains(t, results[0].err, "nope")
+	assert.Equal(t, cachedTestResult{content: "a"}, results[1])
+}
+
+func TestCachedProcessorNotFound(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		negTTL    string
+		expSecond cachedTestResult
+	}{
+		{
+			name:      "without negative ttl",
+			negTTL:    "",
+			expSecond: cachedTestResult{content: "found"},
+		},
+		{
+			name:      "with negative ttl",
+			negTTL:    "1m",
+			expSecond: cachedTestResult{content: `{"id":"a"}`, err: "key not found by loader"},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			results := runCachedProcessor(t, fmt.Sprintf(`
+cached:
+  cache: foocache
+  key: ${! json("id") }
+  negative_ttl: "%v"
+  processors:
+    - bloblang: 'root = if count("%v") == 1 { deleted() } else { "found" }'
+`, test.negTTL, testCounterName()), 1, `{"id":"a"}`, `{"id":"a"}`)
+
+			require.Len(t, results, 2)
+			assert.Equal(t, cachedTestResult{content: `{"id":"a"}`, err: "key not found by loader"}, results[0])
+			assert.Equal(t, test.expSecond, results[1])
+		})
+	}
+}
+
+func TestCachedProcessorCoalescesMisses(t *testing.T) {
+	inputs := make([]string, 10)
+	for i := range inputs {
+		inputs[i] = `{"id":"a"}`
+	}
+
+	// Misses are only coalesced within a processor, and so the processor is
+	// a resource shared by all pipeline threads.
+	results := runCachedProcessorWithResources(t, fmt.Sprintf(`
+processor_resources:
+  - label: cachedproc
+    cached:
+      cache: foocache
+      key: ${! json("id") }
+      processors:
+        - sleep:
+            duration: 100ms
+        - bloblang: 'root = "A-" + count("%v").string()'
+`, testCounterName()), `resource: cachedproc`, 10, inputs...)
+
+	require.Len(t, results, 10)
+	for _, res := range results {
+		assert.Equal(t, cachedTestResult{content: "A-1"}, res)
+	}
+}
+
+func TestCachedProcessorMissingCache(t *testing.T) {
+	builder := service.NewStreamBuilder()
+	require.NoError(t, builder.SetLoggerYAML(`level: OFF`))
+	require.NoError(t, builder.AddProcessorYAML(`
+cached:
+  cache: nope
+  key: foo
+  processors: []
+`))
+	strm, err := builder.Build()
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	err = strm.Run(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cache resource 'nope' was not found")
+}
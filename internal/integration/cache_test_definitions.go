@@ -131,3 +131,98 @@ func CacheTestGetAndSet(n int) CacheTestDefinition {
 		},
 	)
 }
+
+// CacheTestIncr checks that counters can be atomically incremented and
+// decremented.
+func CacheTestIncr() CacheTestDefinition {
+	return namedCacheTest(
+		"can incr and decr",
+		func(t *testing.T, env *cacheTestEnvironment) {
+			t.Parallel()
+
+			cache := initCache(t, env)
+			t.Cleanup(func() {
+				closeCache(t, cache)
+			})
+
+			icache, ok := cache.(types.CacheWithIncr)
+			require.True(t, ok, "cache does not support incr")
+
+			res, err := icache.Incr("incrkey", 5)
+			require.NoError(t, err)
+			assert.Equal(t, int64(5), res)
+
+			res, err = icache.Incr("incrkey", 10)
+			require.NoError(t, err)
+			assert.Equal(t, int64(15), res)
+
+			res, err = icache.Incr("incrkey", -3)
+			require.NoError(t, err)
+			assert.Equal(t, int64(12), res)
+
+			value, err := cache.Get("incrkey")
+			require.NoError(t, err)
+			assert.Equal(t, "12", string(value))
+		},
+	)
+}
+
+// CacheTestCompareAndSwap checks that compare-and-swap operations only succeed
+// when the current value matches.
+func CacheTestCompareAndSwap() CacheTestDefinition {
+	return namedCacheTest(
+		"can compare and swap",
+		func(t *testing.T, env *cacheTestEnvironment) {
+			t.Parallel()
+
+			cache := initCache(t, env)
+			t.Cleanup(func() {
+				closeCache(t, cache)
+			})
+
+			ccache, ok := cache.(types.CacheWithCAS)
+			require.True(t, ok, "cache does not support compare and swap")
+
+			require.NoError(t, ccache.CompareAndSwap("caskey", nil, []byte("first"), nil))
+			assert.True(t, errors.Is(ccache.CompareAndSwap("caskey", nil, []byte("second"), nil), types.ErrKeyValueMismatch))
+			assert.True(t, errors.Is(ccache.CompareAndSwap("caskey", []byte("nope"), []byte("second"), nil), types.ErrKeyValueMismatch))
+			require.NoError(t, ccache.CompareAndSwap("caskey", []byte("first"), []byte("second"), nil))
+
+			res, err := cache.Get("caskey")
+			require.NoError(t, err)
+			assert.Equal(t, "second", string(res))
+		},
+	)
+}
+
+// CacheTestGetAndSetMulti checks that we can set and then get n items with
+// multi-key operations.
+func CacheTestGetAndSetMulti(n int) CacheTestDefinition {
+	return namedCacheTest(
+		"can get and set multiple keys",
+		func(t *testing.T, env *cacheTestEnvironment) {
+			t.Parallel()
+
+			cache := initCache(t, env)
+			t.Cleanup(func() {
+				closeCache(t, cache)
+			})
+
+			mcache, ok := cache.(types.CacheWithGetMulti)
+			require.True(t, ok, "cache does not support get multi")
+
+			items := map[string][]byte{}
+			keys := []string{"multikey:missing"}
+			for i := 0; i < n; i++ {
+				key := fmt.Sprintf("multikey:%v", i)
+				items[key] = []byte(fmt.Sprintf("value:%v", i))
+				keys = append(keys, key)
+			}
+			require.NoError(t, cache.SetMulti(items))
+
+			res, err := mcache.GetMulti(keys)
+			require.NoError(t, err)
+			assert.Equal(t, items, res)
+		},
+	)
+}
@@ -34,6 +34,7 @@ type Config struct {
 	ReadTimeout    string `json:"read_timeout" yaml:"read_timeout"`
 	RootPath       string `json:"root_path" yaml:"root_path"`
 	DebugEndpoints bool   `json:"debug_endpoints" yaml:"debug_endpoints"`
+	CacheWrites    bool   `json:"cache_writes" yaml:"cache_writes"`
 	CertFile       string `json:"cert_file" yaml:"cert_file"`
 	KeyFile        string `json:"key_file" yaml:"key_file"`
 	CORS           CORS   `json:"cors" yaml:"cors"`
@@ -47,6 +48,7 @@ func NewConfig() Config {
 		ReadTimeout:    "5s",
 		RootPath:       "/benthos",
 		DebugEndpoints: false,
+		CacheWrites:    false,
 		CertFile:       "",
 		KeyFile:        "",
 		CORS: CORS{
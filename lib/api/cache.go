@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/gorilla/mux"
+)
+
+//------------------------------------------------------------------------------
+
+// CacheAccessFunc provides access to a cache resource by its name for the
+// duration of a closure, and returns an error if the cache does not exist.
+type CacheAccessFunc func(ctx context.Context, name string, fn func(types.Cache)) error
+
+// Cache is a type for exposing the keys of cache resources via HTTP endpoints,
+// which is useful for inspecting and modifying the state of caches at runtime.
+type Cache struct {
+	access      CacheAccessFunc
+	allowWrites bool
+}
+
+// NewCache creates a new Cache API type that accesses caches with the provided
+// function. Setting and deleting keys is only permitted when allowWrites is
+// true, otherwise the endpoints are read-only.
+func NewCache(access CacheAccessFunc, allowWrites bool) *Cache {
+	return &Cache{
+		access:      access,
+		allowWrites: allowWrites,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type cacheKeyInfo struct {
+	Key string `json:"key"`
+	TTL string `json:"ttl,omitempty"`
+}
+
+// HandleList is an http.HandleFunc for listing the keys of a cache resource
+// along with their remaining TTLs, keys can be filtered by a prefix with the
+// query parameter `prefix`.
+func (c *Cache) HandleList(w http.ResponseWriter, r *http.Request) {
+	var httpErr error
+	defer func() {
+		if httpErr != nil {
+			http.Error(w, fmt.Sprintf("Error: %v", httpErr), http.StatusBadGateway)
+			return
+		}
+	}()
+
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		http.Error(w, "Var `id` must be set", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method != "GET" {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, fmt.Sprintf("Verb not supported: %v", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var items []types.CacheScanItem
+	var supported bool
+	if err := c.access(r.Context(), id, func(cache types.Cache) {
+		var sc types.CacheWithScan
+		if sc, supported = cache.(types.CacheWithScan); supported {
+			items, httpErr = sc.Scan(r.URL.Query().Get("prefix"))
+		}
+	}); err != nil {
+		http.Error(w, fmt.Sprintf("Cache '%v' does not exist", id), http.StatusNotFound)
+		return
+	}
+	if !supported || errors.Is(httpErr, types.ErrCacheOpNotSupported) {
+		httpErr = nil
+		http.Error(w, fmt.Sprintf("Cache '%v' does not support listing keys", id), http.StatusNotImplemented)
+		return
+	}
+	if httpErr != nil {
+		return
+	}
+
+	keys := make([]cacheKeyInfo, 0, len(items))
+	for _, item := range items {
+		info := cacheKeyInfo{Key: item.Key}
+		if item.TTL != nil {
+			info.TTL = item.TTL.String()
+		}
+		keys = append(keys, info)
+	}
+
+	var resBytes []byte
+	if resBytes, httpErr = json.Marshal(keys); httpErr == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resBytes)
+	}
+}
+
+func (c *Cache) handleGETKey(w http.ResponseWriter, r *http.Request, cache types.Cache) error {
+	key := mux.Vars(r)["key"]
+
+	value, err := cache.Get(key)
+	if errors.Is(err, types.ErrKeyNotFound) {
+		http.Error(w, fmt.Sprintf("Key '%v' does not exist", key), http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	w.Write(value)
+	return nil
+}
+
+func (c *Cache) handlePOSTKey(w http.ResponseWriter, r *http.Request, cache types.Cache) error {
+	key := mux.Vars(r)["key"]
+
+	var ttl *time.Duration
+	if ttlStr := r.URL.Query().Get("ttl"); ttlStr != "" {
+		t, err := time.ParseDuration(ttlStr)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to parse ttl: %v", err), http.StatusBadRequest)
+			return nil
+		}
+		ttl = &t
+	}
+
+	value, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+
+	if ttl != nil {
+		tc, ok := cache.(types.CacheWithTTL)
+		if !ok {
+			http.Error(w, "Cache does not support per key TTLs", http.StatusBadRequest)
+			return nil
+		}
+		return tc.SetWithTTL(key, value, ttl)
+	}
+	return cache.Set(key, value)
+}
+
+func (c *Cache) handleDELKey(w http.ResponseWriter, r *http.Request, cache types.Cache) error {
+	return cache.Delete(mux.Vars(r)["key"])
+}
+
+// HandleCRUD is an http.HandleFunc for getting, setting and deleting the keys
+// of a cache resource. A TTL can be specified when setting a key with the
+// query parameter `ttl`.
+func (c *Cache) HandleCRUD(w http.ResponseWriter, r *http.Request) {
+	var httpErr error
+	defer func() {
+		if r.Body != nil {
+			r.Body.Close()
+		}
+		if httpErr != nil {
+			http.Error(w, fmt.Sprintf("Error: %v", httpErr), http.StatusBadGateway)
+			return
+		}
+	}()
+
+	id, key := mux.Vars(r)["id"], mux.Vars(r)["key"]
+	if id == "" || key == "" {
+		http.Error(w, "Vars `id` and `key` must be set", http.StatusBadRequest)
+		return
+	}
+
+	if !c.allowWrites && r.Method != "GET" {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "Cache endpoints are read-only, set http.cache_writes to true in order to enable writes", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch r.Method {
+	case "GET", "POST", "PUT", "DELETE":
+	default:
+		w.Header().Set("Allow", "GET, POST, PUT, DELETE")
+		http.Error(w, fmt.Sprintf("Verb not supported: %v", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := c.access(r.Context(), id, func(cache types.Cache) {
+		switch r.Method {
+		case "POST", "PUT":
+			httpErr = c.handlePOSTKey(w, r, cache)
+		case "GET":
+			httpErr = c.handleGETKey(w, r, cache)
+		case "DELETE":
+			httpErr = c.handleDELKey(w, r, cache)
+		}
+	}); err != nil {
+		http.Error(w, fmt.Sprintf("Cache '%v' does not exist", id), http.StatusNotFound)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//------------------------------------------------------------------------------
+
+type mockCacheItem struct {
+	value []byte
+	ttl   *time.Duration
+}
+
+type mockCache struct {
+	items map[string]mockCacheItem
+}
+
+func (m *mockCache) Get(key string) ([]byte, error) {
+	i, exists := m.items[key]
+	if !exists {
+		return nil, types.ErrKeyNotFound
+	}
+	return i.value, nil
+}
+
+func (m *mockCache) Set(key string, value []byte) error {
+	return m.SetWithTTL(key, value, nil)
+}
+
+func (m *mockCache) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
+	m.items[key] = mockCacheItem{value: value, ttl: ttl}
+	return nil
+}
+
+func (m *mockCache) SetMulti(items map[string][]byte) error {
+	for k, v := range items {
+		m.items[k] = mockCacheItem{value: v}
+	}
+	return nil
+}
+
+func (m *mockCache) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
+	for k, v := range items {
+		m.items[k] = mockCacheItem{value: v.Value, ttl: v.TTL}
+	}
+	return nil
+}
+
+func (m *mockCache) Add(key string, value []byte) error {
+	return m.AddWithTTL(key, value, nil)
+}
+
+func (m *mockCache) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
+	if _, exists := m.items[key]; exists {
+		return types.ErrKeyAlreadyExists
+	}
+	return m.SetWithTTL(key, value, ttl)
+}
+
+func (m *mockCache) Delete(key string) error {
+	delete(m.items, key)
+	return nil
+}
+
+func (m *mockCache) CloseAsync() {}
+
+func (m *mockCache) WaitForClose(time.Duration) error {
+	return nil
+}
+
+type mockScanCache struct {
+	mockCache
+}
+
+func (m *mockScanCache) Scan(prefix string) ([]types.CacheScanItem, error) {
+	var items []types.CacheScanItem
+	for k, v := range m.items {
+		if strings.HasPrefix(k, prefix) {
+			items = append(items, types.CacheScanItem{Key: k, TTL: v.ttl})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Key < items[j].Key
+	})
+	return items, nil
+}
+
+func cacheRouter(caches map[string]types.Cache, allowWrites bool) *mux.Router {
+	cAPI := NewCache(func(ctx context.Context, name string, fn func(types.Cache)) error {
+		c, exists := caches[name]
+		if !exists {
+			return errors.New("not found")
+		}
+		fn(c)
+		return nil
+	}, allowWrites)
+	router := mux.NewRouter()
+	router.HandleFunc("/resources/cache/{id}/keys", cAPI.HandleList)
+	router.HandleFunc("/resources/cache/{id}/keys/{key:.+}", cAPI.HandleCRUD)
+	return router
+}
+
+func doCacheReq(t *testing.T, r *mux.Router, verb, path string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	request, err := http.NewRequest(verb, path, bytes.NewReader(body))
+	require.NoError(t, err)
+	response := httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	return response
+}
+
+func TestCacheAPICRUD(t *testing.T) {
+	r := cacheRouter(map[string]types.Cache{
+		"foo": &mockScanCache{mockCache{items: map[string]mockCacheItem{}}},
+	}, true)
+
+	res := doCacheReq(t, r, "GET", "/resources/cache/foo/keys/a/b", nil)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+
+	res = doCacheReq(t, r, "POST", "/resources/cache/foo/keys/a/b", []byte("hello world"))
+	assert.Equal(t, http.StatusOK, res.Code, res.Body.String())
+
+	res = doCacheReq(t, r, "POST", "/resources/cache/foo/keys/c?ttl=1m", []byte("another"))
+	assert.Equal(t, http.StatusOK, res.Code, res.Body.String())
+
+	res = doCacheReq(t, r, "POST", "/resources/cache/foo/keys/c?ttl=nope", []byte("another"))
+	assert.Equal(t, http.StatusBadRequest, res.Code, res.Body.String())
+
+	res = doCacheReq(t, r, "GET", "/resources/cache/foo/keys/a/b", nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "hello world", res.Body.String())
+
+	res = doCacheReq(t, r, "GET", "/resources/cache/foo/keys", nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `[{"key":"a/b"},{"key":"c","ttl":"1m0s"}]`, res.Body.String())
+
+	res = doCacheReq(t, r, "GET", "/resources/cache/foo/keys?prefix=a", nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `[{"key":"a/b"}]`, res.Body.String())
+
+	res = doCacheReq(t, r, "DELETE", "/resources/cache/foo/keys/a/b", nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	res = doCacheReq(t, r, "GET", "/resources/cache/foo/keys/a/b", nil)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+
+	res = doCacheReq(t, r, "DERP", "/resources/cache/foo/keys/a/b", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, res.Code)
+	assert.Equal(t, "GET, POST, PUT, DELETE", res.Header().Get("Allow"))
+
+	res = doCacheReq(t, r, "POST", "/resources/cache/foo/keys", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, res.Code)
+	assert.Equal(t, "GET", res.Header().Get("Allow"))
+}
+
+func TestCacheAPIReadOnly(t *testing.T) {
+	r := cacheRouter(map[string]types.Cache{
+		"foo": &mockScanCache{mockCache{items: map[string]mockCacheItem{
+			"a": {value: []byte("hello world")},
+		}}},
+	}, false)
+
+	res := doCacheReq(t, r, "GET", "/resources/cache/foo/keys/a", nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "hello world", res.Body.String())
+
+	res = doCacheReq(t, r, "GET", "/resources/cache/foo/keys", nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `[{"key":"a"}]`, res.Body.String())
+
+	for _, verb := range []string{"POST", "PUT", "DELETE"} {
+		res = doCacheReq(t, r, verb, "/resources/cache/foo/keys/a", []byte("changed"))
+		assert.Equal(t, http.StatusMethodNotAllowed, res.Code, verb)
+		assert.Equal(t, "GET", res.Header().Get("Allow"), verb)
+	}
+
+	res = doCacheReq(t, r, "GET", "/resources/cache/foo/keys/a", nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "hello world", res.Body.String())
+}
+
+func TestCacheAPIBadCaches(t *testing.T) {
+	r := cacheRouter(map[string]types.Cache{
+		"foo": &mockCache{items: map[string]mockCacheItem{}},
+	}, true)
+
+	res := doCacheReq(t, r, "GET", "/resources/cache/bar/keys/a", nil)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+
+	res = doCacheReq(t, r, "GET", "/resources/cache/bar/keys", nil)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+
+	res = doCacheReq(t, r, "GET", "/resources/cache/foo/keys", nil)
+	assert.Equal(t, http.StatusNotImplemented, res.Code)
+}
@@ -13,6 +13,9 @@ func Spec() docs.FieldSpecs {
 		docs.FieldBool(
 			"debug_endpoints", "Whether to register a few extra endpoints that can be useful for debugging performance or behavioral problems.",
 		).HasDefault(false),
+		docs.FieldBool(
+			"cache_writes", "Whether the endpoints for inspecting the keys of cache resources should also allow setting and deleting keys. When disabled these endpoints are read-only.",
+		).Advanced().HasDefault(false).AtVersion("3.60.0"),
 		docs.FieldString("cert_file", "An optional certificate file for enabling TLS.").Advanced().HasDefault(""),
 		docs.FieldString("key_file", "An optional key file for enabling TLS.").Advanced().HasDefault(""),
 		docs.FieldAdvanced("cors", "Adds Cross-Origin Resource Sharing headers.").WithChildren(
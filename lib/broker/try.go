@@ -7,8 +7,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/internal/component/output"
+	imessage "github.com/Jeffail/benthos/v3/internal/message"
+	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
@@ -148,9 +152,14 @@ func (t *Try) loop() {
 			}
 			mMsgsRcvd.Incr(1)
 
+			// Parts are tagged so that when an output reports which
+			// messages of a batch failed only those are passed on.
+			group, sortMsg := imessage.NewSortGroup(tran.Payload)
+			msg := sortMsg
+
 			rChan := make(chan types.Response)
 			select {
-			case t.outputTSChans[0] <- types.NewTransaction(tran.Payload, rChan):
+			case t.outputTSChans[0] <- types.NewTransaction(msg, rChan):
 			case <-t.ctx.Done():
 				return
 			}
@@ -175,13 +184,19 @@ func (t *Try) loop() {
 				}
 
 				if i < len(t.outputTSChans) {
+					if failed := tryFailedParts(group, res.Error()); failed != nil {
+						msg = failed
+					}
 					select {
-					case t.outputTSChans[i] <- types.NewTransaction(tran.Payload, rChan):
+					case t.outputTSChans[i] <- types.NewTransaction(msg, rChan):
 					case <-t.ctx.Done():
 						return
 					}
 				}
 			}
+			if res.Error() != nil && msg != sortMsg {
+				res = response.NewError(tryBatchError(group, tran.Payload, msg, res.Error()))
+			}
 			select {
 			case tran.ResponseChan <- res:
 			case <-t.ctx.Done():
@@ -197,6 +212,72 @@ func (t *Try) loop() {
 	}
 }
 
+// tryFailedParts returns a message containing only the parts of a batch that
+// failed according to a batch error, or nil if the error does not identify
+// the failed parts of the group.
+func tryFailedParts(group *imessage.SortGroup, err error) types.Message {
+	bErr, ok := err.(*batch.Error)
+	if !ok || bErr.IndexedErrors() == 0 {
+		return nil
+	}
+	var parts []types.Part
+	known := true
+	bErr.WalkParts(func(_ int, p types.Part, pErr error) bool {
+		if pErr == nil {
+			return true
+		}
+		if group.GetIndex(p) == -1 {
+			known = false
+			return false
+		}
+		parts = append(parts, p)
+		return true
+	})
+	if !known || len(parts) == 0 {
+		return nil
+	}
+	msg := message.New(nil)
+	msg.SetAll(parts)
+	return msg
+}
+
+// tryBatchError converts an error from sending a subset of the parts of a
+// source batch into a batch error indexed against the source batch.
+func tryBatchError(group *imessage.SortGroup, source, sent types.Message, err error) error {
+	bErr := batch.NewError(source, err)
+	var failed int
+	fail := func(p types.Part, pErr error) bool {
+		index := group.GetIndex(p)
+		if index == -1 {
+			return false
+		}
+		bErr.Failed(index, pErr)
+		failed++
+		return true
+	}
+	known := true
+	if sentErr, ok := err.(*batch.Error); ok && sentErr.IndexedErrors() > 0 {
+		sentErr.WalkParts(func(_ int, p types.Part, pErr error) bool {
+			if pErr == nil {
+				return true
+			}
+			known = fail(p, pErr)
+			return known
+		})
+	} else {
+		_ = sent.Iter(func(_ int, p types.Part) error {
+			if known = fail(p, err); !known {
+				return errors.New("unknown part")
+			}
+			return nil
+		})
+	}
+	if !known || failed == 0 {
+		return err
+	}
+	return bErr
+}
+
 // CloseAsync shuts down the Try broker and stops processing requests.
 func (t *Try) CloseAsync() {
 	t.close()
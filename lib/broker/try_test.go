@@ -9,10 +9,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var _ types.Consumer = &Try{}
@@ -208,6 +211,92 @@ func TestTryHappyishPath(t *testing.T) {
 	}
 }
 
+func TestTryBatchErrorPassesFailedParts(t *testing.T) {
+	mockOutputs := []*MockOutputType{{}, {}, {}}
+	outputs := []types.Output{}
+	for _, o := range mockOutputs {
+		outputs = append(outputs, o)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	oTM, err := NewTry(outputs, metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, oTM.Consume(readChan))
+
+	recv := func(o *MockOutputType) types.Transaction {
+		t.Helper()
+		select {
+		case ts := <-o.TChan:
+			return ts
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for broker propagate")
+		}
+		return types.Transaction{}
+	}
+	respond := func(ts types.Transaction, res types.Response) {
+		t.Helper()
+		select {
+		case ts.ResponseChan <- res:
+		case <-time.After(time.Second):
+			t.Fatal("timed out responding to broker")
+		}
+	}
+
+	select {
+	case readChan <- types.NewTransaction(message.New([][]byte{
+		[]byte("a"), []byte("b"), []byte("c"), []byte("d"),
+	}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for broker send")
+	}
+
+	// The first output rejects the second and fourth messages.
+	ts := recv(mockOutputs[0])
+	require.Equal(t, 4, ts.Payload.Len())
+	failed := ts.Payload.Copy()
+	failed.Get(1).Metadata().Set("reason", "nope")
+	respond(ts, response.NewError(batch.NewError(failed, errors.New("first failed")).
+		Failed(1, errors.New("b failed")).
+		Failed(3, errors.New("d failed"))))
+
+	// The second output only receives the rejected messages, including changes
+	// made to them by the first output, and rejects all of them.
+	ts = recv(mockOutputs[1])
+	assert.Equal(t, [][]byte{[]byte("b"), []byte("d")}, message.GetAllBytes(ts.Payload))
+	assert.Equal(t, "nope", ts.Payload.Get(0).Metadata().Get("reason"))
+	respond(ts, response.NewError(errors.New("second failed")))
+
+	// The third output receives the same messages and rejects only one.
+	ts = recv(mockOutputs[2])
+	assert.Equal(t, [][]byte{[]byte("b"), []byte("d")}, message.GetAllBytes(ts.Payload))
+	respond(ts, response.NewError(batch.NewError(ts.Payload, errors.New("third failed")).
+		Failed(1, errors.New("d failed again"))))
+
+	var res types.Response
+	select {
+	case res = <-resChan:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for response")
+	}
+
+	// The final error is indexed against the original batch.
+	bErr, ok := res.Error().(*batch.Error)
+	require.True(t, ok, "%T", res.Error())
+	failedIndexes := map[int]string{}
+	bErr.WalkParts(func(i int, p types.Part, err error) bool {
+		if err != nil {
+			failedIndexes[i] = err.Error()
+		}
+		return true
+	})
+	assert.Equal(t, map[int]string{3: "d failed again"}, failedIndexes)
+
+	oTM.CloseAsync()
+	require.NoError(t, oTM.WaitForClose(time.Second*10))
+}
+
 func TestTryAllFail(t *testing.T) {
 	outputs := []types.Output{}
 	mockOutputs := []*MockOutputType{
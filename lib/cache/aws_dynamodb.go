@@ -1,7 +1,9 @@
 package cache
 
 import (
+	"errors"
 	"fmt"
+	"math/rand"
 	"strconv"
 	"sync"
 	"time"
@@ -154,6 +156,11 @@ type DynamoDB struct {
 	mSetFailed       metrics.StatCounter
 	mSetSuccess      metrics.StatCounter
 	mSetLatency      metrics.StatTimer
+	mGetMultiCount   metrics.StatCounter
+	mGetMultiRetry   metrics.StatCounter
+	mGetMultiFailed  metrics.StatCounter
+	mGetMultiSuccess metrics.StatCounter
+	mGetMultiLatency metrics.StatTimer
 	mSetMultiCount   metrics.StatCounter
 	mSetMultiRetry   metrics.StatCounter
 	mSetMultiFailed  metrics.StatCounter
@@ -171,6 +178,17 @@ type DynamoDB struct {
 	mDelFailedErr    metrics.StatCounter
 	mDelSuccess      metrics.StatCounter
 	mDelLatency      metrics.StatTimer
+	mIncrCount       metrics.StatCounter
+	mIncrRetry       metrics.StatCounter
+	mIncrFailed      metrics.StatCounter
+	mIncrSuccess     metrics.StatCounter
+	mIncrLatency     metrics.StatTimer
+	mCASCount        metrics.StatCounter
+	mCASRetry        metrics.StatCounter
+	mCASMismatch     metrics.StatCounter
+	mCASFailed       metrics.StatCounter
+	mCASSuccess      metrics.StatCounter
+	mCASLatency      metrics.StatTimer
 }
 
 // NewAWSDynamoDB creates a new DynamoDB cache type.
@@ -184,11 +202,20 @@ func NewDynamoDB(conf Config, mgr types.Manager, log log.Modular, stats metrics.
 }
 
 func newDynamoDB(conf DynamoDBConfig, mgr types.Manager, log log.Modular, stats metrics.Type) (types.Cache, error) {
+	sess, err := conf.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	return newDynamoDBFromClient(dynamodb.New(sess), conf, log, stats)
+}
+
+func newDynamoDBFromClient(client dynamodbiface.DynamoDBAPI, conf DynamoDBConfig, log log.Modular, stats metrics.Type) (*DynamoDB, error) {
 	d := DynamoDB{
-		conf:  conf,
-		log:   log,
-		stats: stats,
-		table: aws.String(conf.Table),
+		client: client,
+		conf:   conf,
+		log:    log,
+		stats:  stats,
+		table:  aws.String(conf.Table),
 
 		mLatency:         stats.GetTimer("latency"),
 		mGetCount:        stats.GetCounter("get.count"),
@@ -202,6 +229,11 @@ func newDynamoDB(conf DynamoDBConfig, mgr types.Manager, log log.Modular, stats
 		mSetFailed:       stats.GetCounter("set.failed.error"),
 		mSetSuccess:      stats.GetCounter("set.success"),
 		mSetLatency:      stats.GetTimer("set.latency"),
+		mGetMultiCount:   stats.GetCounter("get_multi.count"),
+		mGetMultiRetry:   stats.GetCounter("get_multi.retry"),
+		mGetMultiFailed:  stats.GetCounter("get_multi.failed.error"),
+		mGetMultiSuccess: stats.GetCounter("get_multi.success"),
+		mGetMultiLatency: stats.GetTimer("get_multi.latency"),
 		mSetMultiCount:   stats.GetCounter("set_multi.count"),
 		mSetMultiRetry:   stats.GetCounter("set_multi.retry"),
 		mSetMultiFailed:  stats.GetCounter("set_multi.failed.error"),
@@ -219,6 +251,17 @@ func newDynamoDB(conf DynamoDBConfig, mgr types.Manager, log log.Modular, stats
 		mDelFailedErr:    stats.GetCounter("delete.failed.error"),
 		mDelSuccess:      stats.GetCounter("delete.success"),
 		mDelLatency:      stats.GetTimer("delete.latency"),
+		mIncrCount:       stats.GetCounter("incr.count"),
+		mIncrRetry:       stats.GetCounter("incr.retry"),
+		mIncrFailed:      stats.GetCounter("incr.failed.error"),
+		mIncrSuccess:     stats.GetCounter("incr.success"),
+		mIncrLatency:     stats.GetTimer("incr.latency"),
+		mCASCount:        stats.GetCounter("cas.count"),
+		mCASRetry:        stats.GetCounter("cas.retry"),
+		mCASMismatch:     stats.GetCounter("cas.failed.mismatch"),
+		mCASFailed:       stats.GetCounter("cas.failed.error"),
+		mCASSuccess:      stats.GetCounter("cas.success"),
+		mCASLatency:      stats.GetTimer("cas.latency"),
 	}
 
 	if d.conf.TTL != "" {
@@ -229,12 +272,6 @@ func newDynamoDB(conf DynamoDBConfig, mgr types.Manager, log log.Modular, stats
 		d.ttl = ttl
 	}
 
-	sess, err := d.conf.GetSession()
-	if err != nil {
-		return nil, err
-	}
-
-	d.client = dynamodb.New(sess)
 	out, err := d.client.DescribeTable(&dynamodb.DescribeTableInput{
 		TableName: d.table,
 	})
@@ -320,6 +357,100 @@ func (d *DynamoDB) get(key string) ([]byte, error) {
 	return val.B, nil
 }
 
+// The maximum number of keys that can be retrieved by a single BatchGetItem
+// request and written by a single BatchWriteItem request.
+const (
+	dynamoDBBatchGetLimit   = 100
+	dynamoDBBatchWriteLimit = 25
+)
+
+// GetMulti attempts to locate and return the cached values of multiple keys
+// using BatchGetItem requests, keys that do not exist are omitted from the
+// result.
+func (d *DynamoDB) GetMulti(keys []string) (map[string][]byte, error) {
+	d.mGetMultiCount.Incr(1)
+
+	tStarted := time.Now()
+	boff := d.boffPool.Get().(backoff.BackOff)
+	defer func() {
+		boff.Reset()
+		d.boffPool.Put(boff)
+	}()
+
+	res := make(map[string][]byte, len(keys))
+
+	var err error
+	for len(keys) > 0 && err == nil {
+		chunk := keys
+		if len(chunk) > dynamoDBBatchGetLimit {
+			chunk = chunk[:dynamoDBBatchGetLimit]
+		}
+		keys = keys[len(chunk):]
+
+		reqKeys := make([]map[string]*dynamodb.AttributeValue, 0, len(chunk))
+		for _, k := range chunk {
+			reqKeys = append(reqKeys, map[string]*dynamodb.AttributeValue{
+				d.conf.HashKey: {
+					S: aws.String(k),
+				},
+			})
+		}
+
+		for len(reqKeys) > 0 {
+			var batchResult *dynamodb.BatchGetItemOutput
+			if batchResult, err = d.client.BatchGetItem(&dynamodb.BatchGetItemInput{
+				RequestItems: map[string]*dynamodb.KeysAndAttributes{
+					*d.table: {
+						Keys:           reqKeys,
+						ConsistentRead: aws.Bool(d.conf.ConsistentRead),
+					},
+				},
+			}); err == nil {
+				for _, item := range batchResult.Responses[*d.table] {
+					key, val := item[d.conf.HashKey], item[d.conf.DataKey]
+					if key == nil || key.S == nil || val == nil || val.B == nil {
+						continue
+					}
+					res[*key.S] = val.B
+				}
+				reqKeys = nil
+				if unproc := batchResult.UnprocessedKeys[*d.table]; unproc != nil && len(unproc.Keys) > 0 {
+					reqKeys = unproc.Keys
+				}
+			} else {
+				d.log.Errorf("Get multi error: %v\n", err)
+			}
+			if err != nil || len(reqKeys) > 0 {
+				wait := boff.NextBackOff()
+				if wait == backoff.Stop {
+					if err == nil {
+						err = fmt.Errorf("failed to get %v items", len(reqKeys))
+					}
+					break
+				}
+				time.Sleep(wait)
+				d.mGetMultiRetry.Incr(1)
+				err = nil
+			}
+		}
+	}
+
+	if err == nil {
+		d.mGetMultiSuccess.Incr(1)
+	} else {
+		d.mGetMultiFailed.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	d.mGetMultiLatency.Timing(latency)
+	d.mLatency.Timing(latency)
+
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 // Set attempts to set the value of a key.
 func (d *DynamoDB) Set(key string, value []byte) error {
 	d.mSetCount.Incr(1)
@@ -355,7 +486,7 @@ func (d *DynamoDB) Set(key string, value []byte) error {
 }
 
 // SetMulti attempts to set the value of multiple keys, if any keys fail to be
-// set an error is returned.
+// set a *types.KeysError is returned identifying the keys that failed.
 func (d *DynamoDB) SetMulti(items map[string][]byte) error {
 	d.mSetMultiCount.Incr(1)
 
@@ -378,26 +509,30 @@ func (d *DynamoDB) SetMulti(items map[string][]byte) error {
 	var err error
 	for len(writeReqs) > 0 {
 		wait := boff.NextBackOff()
+		chunk := writeReqs
+		if len(chunk) > dynamoDBBatchWriteLimit {
+			chunk = chunk[:dynamoDBBatchWriteLimit]
+		}
 		var batchResult *dynamodb.BatchWriteItemOutput
 		batchResult, err = d.client.BatchWriteItem(&dynamodb.BatchWriteItemInput{
 			RequestItems: map[string][]*dynamodb.WriteRequest{
-				*d.table: writeReqs,
+				*d.table: chunk,
 			},
 		})
 		if err != nil {
 			d.log.Errorf("Write multi error: %v\n", err)
 		} else if unproc := batchResult.UnprocessedItems[*d.table]; len(unproc) > 0 {
-			writeReqs = unproc
+			writeReqs = append(unproc, writeReqs[len(chunk):]...)
 			err = fmt.Errorf("failed to set %v items", len(unproc))
 		} else {
-			writeReqs = nil
+			writeReqs = writeReqs[len(chunk):]
 		}
 
 		if err != nil {
 			if wait == backoff.Stop {
 				break
 			}
-			time.After(wait)
+			time.Sleep(wait)
 			d.mSetMultiRetry.Incr(1)
 		}
 	}
@@ -406,6 +541,16 @@ func (d *DynamoDB) SetMulti(items map[string][]byte) error {
 		d.mSetMultiSuccess.Incr(1)
 	} else {
 		d.mSetMultiFailed.Incr(1)
+
+		// Items that remain to be written are those that failed, which allows
+		// callers to only retry the keys that were not set.
+		kerr := types.NewKeysError(err)
+		for _, req := range writeReqs {
+			if k := req.PutRequest.Item[d.conf.HashKey]; k != nil && k.S != nil {
+				kerr.AddErrFor(*k.S, err)
+			}
+		}
+		err = kerr
 	}
 
 	latency := int64(time.Since(tStarted))
@@ -521,6 +666,148 @@ func (d *DynamoDB) delete(key string) error {
 	return err
 }
 
+// dynamoDBIncrMaxConflicts is the maximum number of times that Incr attempts
+// to write a value after finding that the key was modified concurrently.
+const dynamoDBIncrMaxConflicts = 8
+
+// dynamoDBIncrConflictWait is the base period to wait before reattempting an
+// increment after a concurrent modification, which doubles with each conflict.
+const dynamoDBIncrConflictWait = 10 * time.Millisecond
+
+// errDynamoDBIncrNotInteger is returned when the existing value of a key being
+// incremented isn't an integer, which is not retried as it cannot succeed.
+var errDynamoDBIncrNotInteger = errors.New("value of key is not an integer")
+
+// Incr atomically adds a delta to the integer value of a key and returns the
+// result. The value is read and then written with a conditional put, which is
+// attempted again with a jittered backoff whenever the value is modified
+// concurrently, up to a maximum number of attempts.
+func (d *DynamoDB) Incr(key string, delta int64) (int64, error) {
+	d.mIncrCount.Incr(1)
+
+	tStarted := time.Now()
+	boff := d.boffPool.Get().(backoff.BackOff)
+	defer func() {
+		boff.Reset()
+		d.boffPool.Put(boff)
+	}()
+
+	conflicts := 0
+	result, err := d.incr(key, delta)
+	for err != nil && !errors.Is(err, errDynamoDBIncrNotInteger) {
+		if err == types.ErrKeyValueMismatch {
+			if conflicts++; conflicts >= dynamoDBIncrMaxConflicts {
+				err = fmt.Errorf("key was modified concurrently %v times: %w", conflicts, err)
+				break
+			}
+			wait := dynamoDBIncrConflictWait << conflicts
+			time.Sleep(wait/2 + time.Duration(rand.Int63n(int64(wait/2))))
+			d.mIncrRetry.Incr(1)
+			result, err = d.incr(key, delta)
+			continue
+		}
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			break
+		}
+		time.Sleep(wait)
+		d.mIncrRetry.Incr(1)
+		result, err = d.incr(key, delta)
+	}
+	if err == nil {
+		d.mIncrSuccess.Incr(1)
+	} else {
+		d.mIncrFailed.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	d.mIncrLatency.Timing(latency)
+	d.mLatency.Timing(latency)
+
+	return result, err
+}
+
+func (d *DynamoDB) incr(key string, delta int64) (int64, error) {
+	var current int64
+	old, err := d.get(key)
+	if err == nil {
+		if current, err = strconv.ParseInt(string(old), 10, 64); err != nil {
+			return 0, fmt.Errorf("%w: %v", errDynamoDBIncrNotInteger, err)
+		}
+	} else if err != types.ErrKeyNotFound {
+		return 0, err
+	}
+	current += delta
+	if err = d.compareAndSwap(key, old, []byte(strconv.FormatInt(current, 10))); err != nil {
+		return 0, err
+	}
+	return current, nil
+}
+
+// CompareAndSwap attempts to set the value of a key only if its current value
+// matches old, where a nil old value requires that the key does not exist.
+// Per-key TTLs are not supported and the configured TTL is used instead.
+func (d *DynamoDB) CompareAndSwap(key string, old, value []byte, _ *time.Duration) error {
+	d.mCASCount.Incr(1)
+
+	tStarted := time.Now()
+	boff := d.boffPool.Get().(backoff.BackOff)
+	defer func() {
+		boff.Reset()
+		d.boffPool.Put(boff)
+	}()
+
+	err := d.compareAndSwap(key, old, value)
+	for err != nil && err != types.ErrKeyValueMismatch {
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			break
+		}
+		time.Sleep(wait)
+		d.mCASRetry.Incr(1)
+		err = d.compareAndSwap(key, old, value)
+	}
+	if err == nil {
+		d.mCASSuccess.Incr(1)
+	} else if err == types.ErrKeyValueMismatch {
+		d.mCASMismatch.Incr(1)
+	} else {
+		d.mCASFailed.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	d.mCASLatency.Timing(latency)
+	d.mLatency.Timing(latency)
+
+	return err
+}
+
+func (d *DynamoDB) compareAndSwap(key string, old, value []byte) error {
+	input := d.putItemInput(key, value)
+
+	cond := expression.AttributeNotExists(expression.Name(d.conf.HashKey))
+	if old != nil {
+		cond = expression.Name(d.conf.DataKey).Equal(expression.Value(old))
+	}
+	expr, err := expression.NewBuilder().WithCondition(cond).Build()
+	if err != nil {
+		return err
+	}
+	input.ExpressionAttributeNames = expr.Names()
+	input.ExpressionAttributeValues = expr.Values()
+	input.ConditionExpression = expr.Condition()
+
+	if _, err = d.client.PutItem(input); err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			if aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+				return types.ErrKeyValueMismatch
+			}
+		}
+		return err
+	}
+	return nil
+}
+
 // putItemInput creates a generic put item input for use in Set and Add operations
 func (d *DynamoDB) putItemInput(key string, value []byte) *dynamodb.PutItemInput {
 	input := dynamodb.PutItemInput{
@@ -0,0 +1,308 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+
+	mut   sync.Mutex
+	items map[string][]byte
+
+	putFn func(input *dynamodb.PutItemInput) error
+	puts  int
+	gets  int
+
+	batchFn func(reqs []*dynamodb.WriteRequest) []*dynamodb.WriteRequest
+	batches []int
+}
+
+func (m *mockDynamoDB) DescribeTable(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{
+		Table: &dynamodb.TableDescription{
+			TableStatus: aws.String(dynamodb.TableStatusActive),
+		},
+	}, nil
+}
+
+func (m *mockDynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.gets++
+	out := &dynamodb.GetItemOutput{}
+	if v, exists := m.items[*input.Key["id"].S]; exists {
+		out.Item = map[string]*dynamodb.AttributeValue{
+			"id":   input.Key["id"],
+			"data": {B: v},
+		}
+	}
+	return out, nil
+}
+
+func (m *mockDynamoDB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.puts++
+	if m.putFn != nil {
+		if err := m.putFn(input); err != nil {
+			return nil, err
+		}
+	}
+	m.items[*input.Item["id"].S] = input.Item["data"].B
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (m *mockDynamoDB) BatchWriteItem(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	out := &dynamodb.BatchWriteItemOutput{
+		UnprocessedItems: map[string][]*dynamodb.WriteRequest{},
+	}
+	for table, reqs := range input.RequestItems {
+		m.batches = append(m.batches, len(reqs))
+		var unproc []*dynamodb.WriteRequest
+		if m.batchFn != nil {
+			unproc = m.batchFn(reqs)
+		}
+		for _, req := range reqs {
+			isUnproc := false
+			for _, u := range unproc {
+				if u == req {
+					isUnproc = true
+					break
+				}
+			}
+			if !isUnproc {
+				m.items[*req.PutRequest.Item["id"].S] = req.PutRequest.Item["data"].B
+			}
+		}
+		if len(unproc) > 0 {
+			out.UnprocessedItems[table] = unproc
+		}
+	}
+	return out, nil
+}
+
+func newMockDynamoDBCache(t *testing.T, client *mockDynamoDB) *DynamoDB {
+	t.Helper()
+
+	conf := NewDynamoDBConfig()
+	conf.Table = "foo"
+	conf.HashKey = "id"
+	conf.DataKey = "data"
+	conf.Config.Backoff.InitialInterval = "1ms"
+	conf.Config.Backoff.MaxInterval = "1ms"
+	conf.Config.Backoff.MaxElapsedTime = "10ms"
+
+	if client.items == nil {
+		client.items = map[string][]byte{}
+	}
+	d, err := newDynamoDBFromClient(client, conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	return d
+}
+
+func TestDynamoDBIncr(t *testing.T) {
+	client := &mockDynamoDB{}
+	d := newMockDynamoDBCache(t, client)
+
+	v, err := d.Incr("foo", 5)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), v)
+
+	v, err = d.Incr("foo", -2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), v)
+
+	assert.Equal(t, []byte("3"), client.items["foo"])
+}
+
+func TestDynamoDBIncrNotInteger(t *testing.T) {
+	client := &mockDynamoDB{
+		items: map[string][]byte{"foo": []byte("nope")},
+	}
+
+	conf := NewDynamoDBConfig()
+	conf.Table = "foo"
+	conf.HashKey = "id"
+	conf.DataKey = "data"
+	conf.Config.Backoff.InitialInterval = "1ms"
+	conf.Config.Backoff.MaxInterval = "1ms"
+	conf.Config.Backoff.MaxElapsedTime = "1s"
+
+	d, err := newDynamoDBFromClient(client, conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	_, err = d.Incr("foo", 5)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "value of key is not an integer")
+
+	// The value can never be parsed and therefore isn't attempted again.
+	assert.Equal(t, 1, client.gets)
+	assert.Equal(t, 0, client.puts)
+}
+
+func TestDynamoDBIncrConflicts(t *testing.T) {
+	conflicts := 0
+	client := &mockDynamoDB{
+		putFn: func(input *dynamodb.PutItemInput) error {
+			if conflicts < 3 {
+				conflicts++
+				return awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "nope", nil)
+			}
+			return nil
+		},
+	}
+	d := newMockDynamoDBCache(t, client)
+
+	v, err := d.Incr("foo", 5)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), v)
+	assert.Equal(t, 4, client.puts)
+}
+
+func TestDynamoDBIncrConflictsExhausted(t *testing.T) {
+	client := &mockDynamoDB{
+		putFn: func(input *dynamodb.PutItemInput) error {
+			return awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "nope", nil)
+		},
+	}
+	d := newMockDynamoDBCache(t, client)
+
+	_, err := d.Incr("foo", 5)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.ErrKeyValueMismatch), err)
+	assert.Equal(t, dynamoDBIncrMaxConflicts, client.puts)
+}
+
+func TestDynamoDBSetMultiChunks(t *testing.T) {
+	client := &mockDynamoDB{}
+	d := newMockDynamoDBCache(t, client)
+
+	items := map[string][]byte{}
+	for i := 0; i < 60; i++ {
+		items[fmt.Sprintf("key%v", i)] = []byte(fmt.Sprintf("value%v", i))
+	}
+	require.NoError(t, d.SetMulti(items))
+
+	assert.Equal(t, []int{25, 25, 10}, client.batches)
+	assert.Equal(t, items, client.items)
+}
+
+func TestDynamoDBSetMultiUnprocessed(t *testing.T) {
+	var lastBatch time.Time
+	var waits []time.Duration
+	client := &mockDynamoDB{
+		batchFn: func(reqs []*dynamodb.WriteRequest) []*dynamodb.WriteRequest {
+			if !lastBatch.IsZero() {
+				waits = append(waits, time.Since(lastBatch))
+			}
+			lastBatch = time.Now()
+			if len(waits) < 2 {
+				return reqs[:1]
+			}
+			return nil
+		},
+	}
+
+	conf := NewDynamoDBConfig()
+	conf.Table = "foo"
+	conf.HashKey = "id"
+	conf.DataKey = "data"
+	conf.Config.Backoff.InitialInterval = "20ms"
+	conf.Config.Backoff.MaxInterval = "20ms"
+	conf.Config.Backoff.MaxElapsedTime = "1s"
+	client.items = map[string][]byte{}
+
+	d, err := newDynamoDBFromClient(client, conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	items := map[string][]byte{
+		"foo": []byte("foo value"),
+		"bar": []byte("bar value"),
+	}
+	require.NoError(t, d.SetMulti(items))
+
+	// Unprocessed items are retried after waiting on the backoff.
+	assert.Equal(t, []int{2, 1, 1}, client.batches)
+	require.Len(t, waits, 2)
+	for _, w := range waits {
+		assert.GreaterOrEqual(t, int64(w), int64(time.Millisecond*10))
+	}
+	assert.Equal(t, items, client.items)
+}
+
+func TestDynamoDBSetMultiUnprocessedExhausted(t *testing.T) {
+	client := &mockDynamoDB{
+		batchFn: func(reqs []*dynamodb.WriteRequest) []*dynamodb.WriteRequest {
+			return reqs
+		},
+	}
+	d := newMockDynamoDBCache(t, client)
+
+	err := d.SetMulti(map[string][]byte{"foo": []byte("foo value")})
+	require.EqualError(t, err, "failed to set 1 items")
+	assert.Empty(t, client.items)
+
+	var kerr *types.KeysError
+	require.True(t, errors.As(err, &kerr), err)
+	assert.Equal(t, []string{"foo"}, keysOf(kerr.KeyErrors()))
+}
+
+func TestDynamoDBSetMultiPartialFailure(t *testing.T) {
+	client := &mockDynamoDB{
+		batchFn: func(reqs []*dynamodb.WriteRequest) []*dynamodb.WriteRequest {
+			var unproc []*dynamodb.WriteRequest
+			for _, req := range reqs {
+				if *req.PutRequest.Item["id"].S == "bar" {
+					unproc = append(unproc, req)
+				}
+			}
+			return unproc
+		},
+	}
+	d := newMockDynamoDBCache(t, client)
+
+	err := d.SetMulti(map[string][]byte{
+		"foo": []byte("foo value"),
+		"bar": []byte("bar value"),
+		"baz": []byte("baz value"),
+	})
+	require.Error(t, err)
+
+	var kerr *types.KeysError
+	require.True(t, errors.As(err, &kerr), err)
+	assert.Equal(t, []string{"bar"}, keysOf(kerr.KeyErrors()))
+	assert.Equal(t, map[string][]byte{
+		"foo": []byte("foo value"),
+		"baz": []byte("baz value"),
+	}, client.items)
+}
+
+func keysOf(m map[string]error) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
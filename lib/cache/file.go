@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/component/cache"
@@ -86,6 +87,27 @@ func (f *fileV2) Delete(_ context.Context, key string) error {
 	return os.Remove(filepath.Join(f.dir, key))
 }
 
+func (f *fileV2) Scan(_ context.Context, prefix string) ([]types.CacheScanItem, error) {
+	var items []types.CacheScanItem
+	err := filepath.Walk(f.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		key, err := filepath.Rel(f.dir, path)
+		if err != nil {
+			return err
+		}
+		if key = filepath.ToSlash(key); strings.HasPrefix(key, prefix) {
+			items = append(items, types.CacheScanItem{Key: key})
+		}
+		return nil
+	})
+	return items, err
+}
+
 func (f *fileV2) Close(context.Context) error {
 	return nil
 }
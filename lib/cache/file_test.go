@@ -2,6 +2,8 @@ package cache
 
 import (
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -78,4 +80,34 @@ func TestFileCache(t *testing.T) {
 	}
 }
 
+func TestFileCacheScan(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeFile
+	dir := t.TempDir()
+	conf.File.Directory = dir
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.Mkdir(filepath.Join(dir, "foo"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"foo/bar", "foo/baz", "buz"} {
+		if err = c.Set(k, []byte("1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items, err := c.(types.CacheWithScan).Scan("foo/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []types.CacheScanItem{{Key: "foo/bar"}, {Key: "foo/baz"}}
+	if !reflect.DeepEqual(exp, items) {
+		t.Errorf("Wrong result: %v != %v", items, exp)
+	}
+}
+
 //------------------------------------------------------------------------------
@@ -1,8 +1,10 @@
 package cache
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -62,29 +64,44 @@ type Memcached struct {
 	log   log.Modular
 	stats metrics.Type
 
-	mLatency       metrics.StatTimer
-	mGetCount      metrics.StatCounter
-	mGetRetry      metrics.StatCounter
-	mGetFailed     metrics.StatCounter
-	mGetSuccess    metrics.StatCounter
-	mGetLatency    metrics.StatTimer
-	mSetCount      metrics.StatCounter
-	mSetRetry      metrics.StatCounter
-	mSetFailed     metrics.StatCounter
-	mSetSuccess    metrics.StatCounter
-	mSetLatency    metrics.StatTimer
-	mAddCount      metrics.StatCounter
-	mAddDupe       metrics.StatCounter
-	mAddRetry      metrics.StatCounter
-	mAddFailedDupe metrics.StatCounter
-	mAddFailedErr  metrics.StatCounter
-	mAddSuccess    metrics.StatCounter
-	mAddLatency    metrics.StatTimer
-	mDelCount      metrics.StatCounter
-	mDelRetry      metrics.StatCounter
-	mDelFailedErr  metrics.StatCounter
-	mDelSuccess    metrics.StatCounter
-	mDelLatency    metrics.StatTimer
+	mLatency         metrics.StatTimer
+	mGetCount        metrics.StatCounter
+	mGetRetry        metrics.StatCounter
+	mGetFailed       metrics.StatCounter
+	mGetSuccess      metrics.StatCounter
+	mGetLatency      metrics.StatTimer
+	mSetCount        metrics.StatCounter
+	mSetRetry        metrics.StatCounter
+	mSetFailed       metrics.StatCounter
+	mSetSuccess      metrics.StatCounter
+	mSetLatency      metrics.StatTimer
+	mGetMultiCount   metrics.StatCounter
+	mGetMultiRetry   metrics.StatCounter
+	mGetMultiFailed  metrics.StatCounter
+	mGetMultiSuccess metrics.StatCounter
+	mGetMultiLatency metrics.StatTimer
+	mAddCount        metrics.StatCounter
+	mAddDupe         metrics.StatCounter
+	mAddRetry        metrics.StatCounter
+	mAddFailedDupe   metrics.StatCounter
+	mAddFailedErr    metrics.StatCounter
+	mAddSuccess      metrics.StatCounter
+	mAddLatency      metrics.StatTimer
+	mDelCount        metrics.StatCounter
+	mDelRetry        metrics.StatCounter
+	mDelFailedErr    metrics.StatCounter
+	mDelSuccess      metrics.StatCounter
+	mDelLatency      metrics.StatTimer
+	mIncrCount       metrics.StatCounter
+	mIncrFailed      metrics.StatCounter
+	mIncrSuccess     metrics.StatCounter
+	mIncrLatency     metrics.StatTimer
+	mCASCount        metrics.StatCounter
+	mCASRetry        metrics.StatCounter
+	mCASMismatch     metrics.StatCounter
+	mCASFailed       metrics.StatCounter
+	mCASSuccess      metrics.StatCounter
+	mCASLatency      metrics.StatTimer
 
 	mc          *memcache.Client
 	retryPeriod time.Duration
@@ -114,29 +131,44 @@ func NewMemcached(
 		log:   log,
 		stats: stats,
 
-		mLatency:       stats.GetTimer("latency"),
-		mGetCount:      stats.GetCounter("get.count"),
-		mGetRetry:      stats.GetCounter("get.retry"),
-		mGetFailed:     stats.GetCounter("get.failed.error"),
-		mGetSuccess:    stats.GetCounter("get.success"),
-		mGetLatency:    stats.GetTimer("get.latency"),
-		mSetCount:      stats.GetCounter("set.count"),
-		mSetRetry:      stats.GetCounter("set.retry"),
-		mSetFailed:     stats.GetCounter("set.failed.error"),
-		mSetSuccess:    stats.GetCounter("set.success"),
-		mSetLatency:    stats.GetTimer("set.latency"),
-		mAddCount:      stats.GetCounter("add.count"),
-		mAddDupe:       stats.GetCounter("add.failed.duplicate"),
-		mAddRetry:      stats.GetCounter("add.retry"),
-		mAddFailedDupe: stats.GetCounter("add.failed.duplicate"),
-		mAddFailedErr:  stats.GetCounter("add.failed.error"),
-		mAddSuccess:    stats.GetCounter("add.success"),
-		mAddLatency:    stats.GetTimer("add.latency"),
-		mDelCount:      stats.GetCounter("delete.count"),
-		mDelRetry:      stats.GetCounter("delete.retry"),
-		mDelFailedErr:  stats.GetCounter("delete.failed.error"),
-		mDelSuccess:    stats.GetCounter("delete.success"),
-		mDelLatency:    stats.GetTimer("delete.latency"),
+		mLatency:         stats.GetTimer("latency"),
+		mGetCount:        stats.GetCounter("get.count"),
+		mGetRetry:        stats.GetCounter("get.retry"),
+		mGetFailed:       stats.GetCounter("get.failed.error"),
+		mGetSuccess:      stats.GetCounter("get.success"),
+		mGetLatency:      stats.GetTimer("get.latency"),
+		mSetCount:        stats.GetCounter("set.count"),
+		mSetRetry:        stats.GetCounter("set.retry"),
+		mSetFailed:       stats.GetCounter("set.failed.error"),
+		mSetSuccess:      stats.GetCounter("set.success"),
+		mSetLatency:      stats.GetTimer("set.latency"),
+		mGetMultiCount:   stats.GetCounter("get_multi.count"),
+		mGetMultiRetry:   stats.GetCounter("get_multi.retry"),
+		mGetMultiFailed:  stats.GetCounter("get_multi.failed.error"),
+		mGetMultiSuccess: stats.GetCounter("get_multi.success"),
+		mGetMultiLatency: stats.GetTimer("get_multi.latency"),
+		mAddCount:        stats.GetCounter("add.count"),
+		mAddDupe:         stats.GetCounter("add.failed.duplicate"),
+		mAddRetry:        stats.GetCounter("add.retry"),
+		mAddFailedDupe:   stats.GetCounter("add.failed.duplicate"),
+		mAddFailedErr:    stats.GetCounter("add.failed.error"),
+		mAddSuccess:      stats.GetCounter("add.success"),
+		mAddLatency:      stats.GetTimer("add.latency"),
+		mDelCount:        stats.GetCounter("delete.count"),
+		mDelRetry:        stats.GetCounter("delete.retry"),
+		mDelFailedErr:    stats.GetCounter("delete.failed.error"),
+		mDelSuccess:      stats.GetCounter("delete.success"),
+		mDelLatency:      stats.GetTimer("delete.latency"),
+		mIncrCount:       stats.GetCounter("incr.count"),
+		mIncrFailed:      stats.GetCounter("incr.failed.error"),
+		mIncrSuccess:     stats.GetCounter("incr.success"),
+		mIncrLatency:     stats.GetTimer("incr.latency"),
+		mCASCount:        stats.GetCounter("cas.count"),
+		mCASRetry:        stats.GetCounter("cas.retry"),
+		mCASMismatch:     stats.GetCounter("cas.failed.mismatch"),
+		mCASFailed:       stats.GetCounter("cas.failed.error"),
+		mCASSuccess:      stats.GetCounter("cas.success"),
+		mCASLatency:      stats.GetTimer("cas.latency"),
 
 		retryPeriod: retryPeriod,
 		mc:          memcache.New(addresses...),
@@ -190,6 +222,45 @@ func (m *Memcached) Get(key string) ([]byte, error) {
 	return item.Value, err
 }
 
+// GetMulti attempts to locate and return the cached values of multiple keys
+// with a single request per server, keys that do not exist are omitted from the
+// result.
+func (m *Memcached) GetMulti(keys []string) (map[string][]byte, error) {
+	m.mGetMultiCount.Incr(1)
+	tStarted := time.Now()
+
+	prefixed := make([]string, len(keys))
+	for i, k := range keys {
+		prefixed[i] = m.conf.Memcached.Prefix + k
+	}
+
+	items, err := m.mc.GetMulti(prefixed)
+	for i := 0; i < m.conf.Memcached.Retries && err != nil; i++ {
+		m.log.Errorf("Get multi command failed: %v\n", err)
+		<-time.After(m.retryPeriod)
+		m.mGetMultiRetry.Incr(1)
+		items, err = m.mc.GetMulti(prefixed)
+	}
+
+	latency := int64(time.Since(tStarted))
+	m.mGetMultiLatency.Timing(latency)
+	m.mLatency.Timing(latency)
+
+	if err != nil {
+		m.mGetMultiFailed.Incr(1)
+		return nil, err
+	}
+	m.mGetMultiSuccess.Incr(1)
+
+	res := make(map[string][]byte, len(items))
+	for i, k := range prefixed {
+		if item, exists := items[k]; exists {
+			res[keys[i]] = item.Value
+		}
+	}
+	return res, nil
+}
+
 // SetWithTTL attempts to set the value of a key.
 func (m *Memcached) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
 	m.mSetCount.Incr(1)
@@ -323,6 +394,115 @@ func (m *Memcached) Delete(key string) error {
 	return err
 }
 
+// Incr atomically adds a delta to the integer value of a key and returns the
+// result. Memcached counters are unsigned and therefore decrementing a value
+// below zero results in zero.
+//
+// Incr is not idempotent and therefore failed attempts are not retried, as an
+// attempt may have been applied even though it resulted in an error.
+func (m *Memcached) Incr(key string, delta int64) (int64, error) {
+	m.mIncrCount.Incr(1)
+	tStarted := time.Now()
+
+	res, err := m.incr(key, delta)
+	if err != nil {
+		m.log.Errorf("Incr command failed: %v\n", err)
+		m.mIncrFailed.Incr(1)
+	} else {
+		m.mIncrSuccess.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	m.mIncrLatency.Timing(latency)
+	m.mLatency.Timing(latency)
+
+	return res, err
+}
+
+func (m *Memcached) incr(key string, delta int64) (int64, error) {
+	for {
+		var res uint64
+		var err error
+		if delta >= 0 {
+			res, err = m.mc.Increment(m.conf.Memcached.Prefix+key, uint64(delta))
+		} else {
+			res, err = m.mc.Decrement(m.conf.Memcached.Prefix+key, uint64(-delta))
+		}
+		if err != memcache.ErrCacheMiss {
+			return int64(res), err
+		}
+
+		// The key does not yet exist, and therefore we attempt to create it
+		// with the delta as its initial value, if another client beats us to
+		// it then we try the increment again.
+		initial := delta
+		if initial < 0 {
+			initial = 0
+		}
+		if err = m.mc.Add(m.getItemFor(key, []byte(strconv.FormatInt(initial, 10)), nil)); err != memcache.ErrNotStored {
+			return initial, err
+		}
+	}
+}
+
+// CompareAndSwap attempts to set the value of a key only if its current value
+// matches old, where a nil old value requires that the key does not exist.
+func (m *Memcached) CompareAndSwap(key string, old, value []byte, ttl *time.Duration) error {
+	m.mCASCount.Incr(1)
+	tStarted := time.Now()
+
+	err := m.compareAndSwap(key, old, value, ttl)
+	for i := 0; i < m.conf.Memcached.Retries && err != nil && err != types.ErrKeyValueMismatch; i++ {
+		m.log.Errorf("Compare and swap command failed: %v\n", err)
+		<-time.After(m.retryPeriod)
+		m.mCASRetry.Incr(1)
+		err = m.compareAndSwap(key, old, value, ttl)
+	}
+	if err == nil {
+		m.mCASSuccess.Incr(1)
+	} else if err == types.ErrKeyValueMismatch {
+		m.mCASMismatch.Incr(1)
+	} else {
+		m.mCASFailed.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	m.mCASLatency.Timing(latency)
+	m.mLatency.Timing(latency)
+
+	return err
+}
+
+func (m *Memcached) compareAndSwap(key string, old, value []byte, ttl *time.Duration) error {
+	newItem := m.getItemFor(key, value, ttl)
+	if old == nil {
+		err := m.mc.Add(newItem)
+		if err == memcache.ErrNotStored {
+			return types.ErrKeyValueMismatch
+		}
+		return err
+	}
+
+	item, err := m.mc.Get(newItem.Key)
+	if err != nil {
+		if errors.Is(err, memcache.ErrCacheMiss) {
+			return types.ErrKeyValueMismatch
+		}
+		return err
+	}
+	if !bytes.Equal(item.Value, old) {
+		return types.ErrKeyValueMismatch
+	}
+
+	// The item retains the CAS identifier of the Get call.
+	item.Value = newItem.Value
+	item.Expiration = newItem.Expiration
+	if err = m.mc.CompareAndSwap(item); err == memcache.ErrCASConflict || err == memcache.ErrNotStored {
+		return types.ErrKeyValueMismatch
+	}
+	return err
+}
+
 // CloseAsync shuts down the cache.
 func (m *Memcached) CloseAsync() {
 }
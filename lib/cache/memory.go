@@ -1,8 +1,12 @@
 package cache
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -218,6 +222,33 @@ func (m *memoryV2) Set(_ context.Context, key string, value []byte, _ *time.Dura
 	return nil
 }
 
+func (m *memoryV2) GetMulti(_ context.Context, keys []string) (map[string][]byte, error) {
+	res := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		shard := m.getShard(key)
+		shard.RLock()
+		k, exists := shard.items[key]
+		shard.RUnlock()
+		if exists && !shard.isExpired(k) {
+			res[key] = k.value
+		}
+	}
+	return res, nil
+}
+
+func (m *memoryV2) SetMulti(_ context.Context, items map[string]types.CacheTTLItem) error {
+	now := time.Now()
+	for key, v := range items {
+		shard := m.getShard(key)
+		shard.Lock()
+		shard.compaction()
+		shard.items[key] = item{value: v.Value, ts: now}
+		shard.mKeys.Set(int64(len(shard.items)))
+		shard.Unlock()
+	}
+	return nil
+}
+
 func (m *memoryV2) Add(_ context.Context, key string, value []byte, _ *time.Duration) error {
 	shard := m.getShard(key)
 	shard.Lock()
@@ -242,6 +273,72 @@ func (m *memoryV2) Delete(_ context.Context, key string) error {
 	return nil
 }
 
+func (m *memoryV2) Incr(_ context.Context, key string, delta int64) (int64, error) {
+	shard := m.getShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+
+	var current int64
+	if k, exists := shard.items[key]; exists && !shard.isExpired(k) {
+		var err error
+		if current, err = strconv.ParseInt(string(k.value), 10, 64); err != nil {
+			return 0, fmt.Errorf("value of key is not an integer: %w", err)
+		}
+	}
+	current += delta
+
+	shard.compaction()
+	shard.items[key] = item{value: []byte(strconv.FormatInt(current, 10)), ts: time.Now()}
+	shard.mKeys.Set(int64(len(shard.items)))
+	return current, nil
+}
+
+func (m *memoryV2) CompareAndSwap(_ context.Context, key string, old, value []byte, _ *time.Duration) error {
+	shard := m.getShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+
+	k, exists := shard.items[key]
+	if exists && shard.isExpired(k) {
+		exists = false
+	}
+	if old == nil {
+		if exists {
+			return types.ErrKeyValueMismatch
+		}
+	} else if !exists || !bytes.Equal(k.value, old) {
+		return types.ErrKeyValueMismatch
+	}
+
+	shard.compaction()
+	shard.items[key] = item{value: value, ts: time.Now()}
+	shard.mKeys.Set(int64(len(shard.items)))
+	return nil
+}
+
+func (m *memoryV2) Scan(_ context.Context, prefix string) ([]types.CacheScanItem, error) {
+	var items []types.CacheScanItem
+	for _, shard := range m.shards {
+		shard.RLock()
+		for k, v := range shard.items {
+			if !strings.HasPrefix(k, prefix) || shard.isExpired(v) {
+				continue
+			}
+			scanItem := types.CacheScanItem{Key: k}
+			if shard.compInterval != 0 && !v.ts.IsZero() {
+				ttl := shard.ttl - time.Since(v.ts)
+				scanItem.TTL = &ttl
+			}
+			items = append(items, scanItem)
+		}
+		shard.RUnlock()
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Key < items[j].Key
+	})
+	return items, nil
+}
+
 func (m *memoryV2) Close(context.Context) error {
 	return nil
 }
@@ -246,3 +246,114 @@ func BenchmarkMemoryShards100(b *testing.B) {
 		assert.Equal(b, value, res)
 	}
 }
+
+func TestMemoryCacheIncr(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "memory"
+	conf.Memory.InitValues = map[string]string{
+		"foo": "10",
+		"bar": "nope",
+	}
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	ic, ok := c.(types.CacheWithIncr)
+	require.True(t, ok)
+
+	v, err := ic.Incr("foo", 5)
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), v)
+
+	v, err = ic.Incr("baz", -3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-3), v)
+
+	_, err = ic.Incr("bar", 1)
+	require.Error(t, err)
+
+	b, err := c.Get("foo")
+	require.NoError(t, err)
+	assert.Equal(t, "15", string(b))
+}
+
+func TestMemoryCacheCAS(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "memory"
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	cc, ok := c.(types.CacheWithCAS)
+	require.True(t, ok)
+
+	require.NoError(t, cc.CompareAndSwap("foo", nil, []byte("1"), nil))
+	assert.Equal(t, types.ErrKeyValueMismatch, cc.CompareAndSwap("foo", nil, []byte("2"), nil))
+	assert.Equal(t, types.ErrKeyValueMismatch, cc.CompareAndSwap("foo", []byte("2"), []byte("3"), nil))
+	require.NoError(t, cc.CompareAndSwap("foo", []byte("1"), []byte("3"), nil))
+	assert.Equal(t, types.ErrKeyValueMismatch, cc.CompareAndSwap("bar", []byte("1"), []byte("3"), nil))
+
+	b, err := c.Get("foo")
+	require.NoError(t, err)
+	assert.Equal(t, "3", string(b))
+
+	_, err = c.Get("bar")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+}
+
+func TestMemoryCacheMulti(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "memory"
+	conf.Memory.Shards = 3
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	require.NoError(t, c.SetMulti(map[string][]byte{
+		"foo": []byte("1"),
+		"bar": []byte("2"),
+		"baz": []byte("3"),
+	}))
+
+	mc, ok := c.(types.CacheWithGetMulti)
+	require.True(t, ok)
+
+	res, err := mc.GetMulti([]string{"foo", "baz", "nope"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"foo": []byte("1"),
+		"baz": []byte("3"),
+	}, res)
+}
+
+func TestMemoryCacheScan(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "memory"
+	conf.Memory.Shards = 3
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	require.NoError(t, c.SetMulti(map[string][]byte{
+		"foo1": []byte("1"),
+		"foo2": []byte("2"),
+		"bar1": []byte("3"),
+	}))
+
+	sc, ok := c.(types.CacheWithScan)
+	require.True(t, ok)
+
+	items, err := sc.Scan("foo")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "foo1", items[0].Key)
+	assert.Equal(t, "foo2", items[1].Key)
+	for _, item := range items {
+		require.NotNil(t, item.TTL)
+		assert.True(t, *item.TTL > 0 && *item.TTL <= 300*time.Second)
+	}
+
+	items, err = sc.Scan("")
+	require.NoError(t, err)
+	assert.Len(t, items, 3)
+}
@@ -2,6 +2,9 @@ package cache
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
@@ -60,31 +63,56 @@ type Redis struct {
 	log   log.Modular
 	stats metrics.Type
 
-	mLatency       metrics.StatTimer
-	mGetCount      metrics.StatCounter
-	mGetRetry      metrics.StatCounter
-	mGetFailed     metrics.StatCounter
-	mGetSuccess    metrics.StatCounter
-	mGetLatency    metrics.StatTimer
-	mGetNotFound   metrics.StatCounter
-	mSetCount      metrics.StatCounter
-	mSetRetry      metrics.StatCounter
-	mSetFailed     metrics.StatCounter
-	mSetSuccess    metrics.StatCounter
-	mSetLatency    metrics.StatTimer
-	mAddCount      metrics.StatCounter
-	mAddDupe       metrics.StatCounter
-	mAddRetry      metrics.StatCounter
-	mAddFailedDupe metrics.StatCounter
-	mAddFailedErr  metrics.StatCounter
-	mAddSuccess    metrics.StatCounter
-	mAddLatency    metrics.StatTimer
-	mDelCount      metrics.StatCounter
-	mDelRetry      metrics.StatCounter
-	mDelFailedErr  metrics.StatCounter
-	mDelNotFound   metrics.StatCounter
-	mDelSuccess    metrics.StatCounter
-	mDelLatency    metrics.StatTimer
+	mLatency         metrics.StatTimer
+	mGetCount        metrics.StatCounter
+	mGetRetry        metrics.StatCounter
+	mGetFailed       metrics.StatCounter
+	mGetSuccess      metrics.StatCounter
+	mGetLatency      metrics.StatTimer
+	mGetNotFound     metrics.StatCounter
+	mSetCount        metrics.StatCounter
+	mSetRetry        metrics.StatCounter
+	mSetFailed       metrics.StatCounter
+	mSetSuccess      metrics.StatCounter
+	mSetLatency      metrics.StatTimer
+	mGetMultiCount   metrics.StatCounter
+	mGetMultiRetry   metrics.StatCounter
+	mGetMultiFailed  metrics.StatCounter
+	mGetMultiSuccess metrics.StatCounter
+	mGetMultiLatency metrics.StatTimer
+	mSetMultiCount   metrics.StatCounter
+	mSetMultiRetry   metrics.StatCounter
+	mSetMultiFailed  metrics.StatCounter
+	mSetMultiSuccess metrics.StatCounter
+	mSetMultiLatency metrics.StatTimer
+	mAddCount        metrics.StatCounter
+	mAddDupe         metrics.StatCounter
+	mAddRetry        metrics.StatCounter
+	mAddFailedDupe   metrics.StatCounter
+	mAddFailedErr    metrics.StatCounter
+	mAddSuccess      metrics.StatCounter
+	mAddLatency      metrics.StatTimer
+	mDelCount        metrics.StatCounter
+	mDelRetry        metrics.StatCounter
+	mDelFailedErr    metrics.StatCounter
+	mDelNotFound     metrics.StatCounter
+	mDelSuccess      metrics.StatCounter
+	mDelLatency      metrics.StatTimer
+	mIncrCount       metrics.StatCounter
+	mIncrFailed      metrics.StatCounter
+	mIncrSuccess     metrics.StatCounter
+	mIncrLatency     metrics.StatTimer
+	mCASCount        metrics.StatCounter
+	mCASRetry        metrics.StatCounter
+	mCASMismatch     metrics.StatCounter
+	mCASFailed       metrics.StatCounter
+	mCASSuccess      metrics.StatCounter
+	mCASLatency      metrics.StatTimer
+	mScanCount       metrics.StatCounter
+	mScanRetry       metrics.StatCounter
+	mScanFailed      metrics.StatCounter
+	mScanSuccess     metrics.StatCounter
+	mScanLatency     metrics.StatTimer
 
 	client      redis.UniversalClient
 	ttl         time.Duration
@@ -122,31 +150,56 @@ func NewRedis(
 		log:   log,
 		stats: stats,
 
-		mLatency:       stats.GetTimer("latency"),
-		mGetCount:      stats.GetCounter("get.count"),
-		mGetRetry:      stats.GetCounter("get.retry"),
-		mGetFailed:     stats.GetCounter("get.failed.error"),
-		mGetNotFound:   stats.GetCounter("get.failed.not_found"),
-		mGetSuccess:    stats.GetCounter("get.success"),
-		mGetLatency:    stats.GetTimer("get.latency"),
-		mSetCount:      stats.GetCounter("set.count"),
-		mSetRetry:      stats.GetCounter("set.retry"),
-		mSetFailed:     stats.GetCounter("set.failed.error"),
-		mSetSuccess:    stats.GetCounter("set.success"),
-		mSetLatency:    stats.GetTimer("set.latency"),
-		mAddCount:      stats.GetCounter("add.count"),
-		mAddDupe:       stats.GetCounter("add.failed.duplicate"),
-		mAddRetry:      stats.GetCounter("add.retry"),
-		mAddFailedDupe: stats.GetCounter("add.failed.duplicate"),
-		mAddFailedErr:  stats.GetCounter("add.failed.error"),
-		mAddSuccess:    stats.GetCounter("add.success"),
-		mAddLatency:    stats.GetTimer("add.latency"),
-		mDelCount:      stats.GetCounter("delete.count"),
-		mDelRetry:      stats.GetCounter("delete.retry"),
-		mDelFailedErr:  stats.GetCounter("delete.failed.error"),
-		mDelNotFound:   stats.GetCounter("delete.failed.not_found"),
-		mDelSuccess:    stats.GetCounter("delete.success"),
-		mDelLatency:    stats.GetTimer("delete.latency"),
+		mLatency:         stats.GetTimer("latency"),
+		mGetCount:        stats.GetCounter("get.count"),
+		mGetRetry:        stats.GetCounter("get.retry"),
+		mGetFailed:       stats.GetCounter("get.failed.error"),
+		mGetNotFound:     stats.GetCounter("get.failed.not_found"),
+		mGetSuccess:      stats.GetCounter("get.success"),
+		mGetLatency:      stats.GetTimer("get.latency"),
+		mSetCount:        stats.GetCounter("set.count"),
+		mSetRetry:        stats.GetCounter("set.retry"),
+		mSetFailed:       stats.GetCounter("set.failed.error"),
+		mSetSuccess:      stats.GetCounter("set.success"),
+		mSetLatency:      stats.GetTimer("set.latency"),
+		mGetMultiCount:   stats.GetCounter("get_multi.count"),
+		mGetMultiRetry:   stats.GetCounter("get_multi.retry"),
+		mGetMultiFailed:  stats.GetCounter("get_multi.failed.error"),
+		mGetMultiSuccess: stats.GetCounter("get_multi.success"),
+		mGetMultiLatency: stats.GetTimer("get_multi.latency"),
+		mSetMultiCount:   stats.GetCounter("set_multi.count"),
+		mSetMultiRetry:   stats.GetCounter("set_multi.retry"),
+		mSetMultiFailed:  stats.GetCounter("set_multi.failed.error"),
+		mSetMultiSuccess: stats.GetCounter("set_multi.success"),
+		mSetMultiLatency: stats.GetTimer("set_multi.latency"),
+		mAddCount:        stats.GetCounter("add.count"),
+		mAddDupe:         stats.GetCounter("add.failed.duplicate"),
+		mAddRetry:        stats.GetCounter("add.retry"),
+		mAddFailedDupe:   stats.GetCounter("add.failed.duplicate"),
+		mAddFailedErr:    stats.GetCounter("add.failed.error"),
+		mAddSuccess:      stats.GetCounter("add.success"),
+		mAddLatency:      stats.GetTimer("add.latency"),
+		mDelCount:        stats.GetCounter("delete.count"),
+		mDelRetry:        stats.GetCounter("delete.retry"),
+		mDelFailedErr:    stats.GetCounter("delete.failed.error"),
+		mDelNotFound:     stats.GetCounter("delete.failed.not_found"),
+		mDelSuccess:      stats.GetCounter("delete.success"),
+		mDelLatency:      stats.GetTimer("delete.latency"),
+		mIncrCount:       stats.GetCounter("incr.count"),
+		mIncrFailed:      stats.GetCounter("incr.failed.error"),
+		mIncrSuccess:     stats.GetCounter("incr.success"),
+		mIncrLatency:     stats.GetTimer("incr.latency"),
+		mCASCount:        stats.GetCounter("cas.count"),
+		mCASRetry:        stats.GetCounter("cas.retry"),
+		mCASMismatch:     stats.GetCounter("cas.failed.mismatch"),
+		mCASFailed:       stats.GetCounter("cas.failed.error"),
+		mCASSuccess:      stats.GetCounter("cas.success"),
+		mCASLatency:      stats.GetTimer("cas.latency"),
+		mScanCount:       stats.GetCounter("scan.count"),
+		mScanRetry:       stats.GetCounter("scan.retry"),
+		mScanFailed:      stats.GetCounter("scan.failed.error"),
+		mScanSuccess:     stats.GetCounter("scan.success"),
+		mScanLatency:     stats.GetTimer("scan.latency"),
 
 		retryPeriod: retryPeriod,
 		ttl:         ttl,
@@ -233,16 +286,94 @@ func (r *Redis) Set(key string, value []byte) error {
 	return r.SetWithTTL(key, value, nil)
 }
 
-// SetMultiWithTTL attempts to set the value of multiple keys, returns an error if any
-// keys fail.
+// GetMulti attempts to locate and return the cached values of multiple keys
+// with a single pipelined request, keys that do not exist are omitted from the
+// result.
+func (r *Redis) GetMulti(keys []string) (map[string][]byte, error) {
+	r.mGetMultiCount.Incr(1)
+	tStarted := time.Now()
+
+	res, err := r.getMulti(keys)
+	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
+		r.log.Errorf("Get multi command failed: %v\n", err)
+		<-time.After(r.retryPeriod)
+		r.mGetMultiRetry.Incr(1)
+		res, err = r.getMulti(keys)
+	}
+	if err != nil {
+		r.mGetMultiFailed.Incr(1)
+	} else {
+		r.mGetMultiSuccess.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	r.mGetMultiLatency.Timing(latency)
+	r.mLatency.Timing(latency)
+
+	return res, err
+}
+
+func (r *Redis) getMulti(keys []string) (map[string][]byte, error) {
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(keys))
+	for i, k := range keys {
+		cmds[i] = pipe.Get(r.prefix + k)
+	}
+	if _, err := pipe.Exec(); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	res := make(map[string][]byte, len(keys))
+	for i, cmd := range cmds {
+		v, err := cmd.Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		res[keys[i]] = v
+	}
+	return res, nil
+}
+
+// SetMultiWithTTL attempts to set the value of multiple keys with a single
+// pipelined request, returns an error if any keys fail.
 func (r *Redis) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
-	// TODO: Come back and optimise this.
+	r.mSetMultiCount.Incr(1)
+	tStarted := time.Now()
+
+	err := r.setMulti(items)
+	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
+		r.log.Errorf("Set multi command failed: %v\n", err)
+		<-time.After(r.retryPeriod)
+		r.mSetMultiRetry.Incr(1)
+		err = r.setMulti(items)
+	}
+	if err != nil {
+		r.mSetMultiFailed.Incr(1)
+	} else {
+		r.mSetMultiSuccess.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	r.mSetMultiLatency.Timing(latency)
+	r.mLatency.Timing(latency)
+
+	return err
+}
+
+func (r *Redis) setMulti(items map[string]types.CacheTTLItem) error {
+	pipe := r.client.Pipeline()
 	for k, v := range items {
-		if err := r.SetWithTTL(k, v.Value, v.TTL); err != nil {
-			return err
+		t := r.ttl
+		if v.TTL != nil {
+			t = *v.TTL
 		}
+		pipe.Set(r.prefix+k, v.Value, t)
 	}
-	return nil
+	_, err := pipe.Exec()
+	return err
 }
 
 // SetMulti attempts to set the value of multiple keys, returns an error if any
@@ -349,6 +480,195 @@ func (r *Redis) Delete(key string) error {
 	return err
 }
 
+// redisIncrScript increments the value of a key and, when the key does not
+// already have an expiry, sets the expiry to a TTL in milliseconds (when
+// positive). This is executed as a script so that both commands are applied
+// atomically.
+var redisIncrScript = redis.NewScript(`
+local v = redis.call("INCRBY", KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 and redis.call("PTTL", KEYS[1]) == -1 then
+  redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return v
+`)
+
+// Incr atomically adds a delta to the integer value of a key and returns the
+// result. Counters created by Incr expire after the configured expiration.
+//
+// Incr is not idempotent and therefore failed attempts are not retried, as an
+// attempt may have been applied even though it resulted in an error.
+func (r *Redis) Incr(key string, delta int64) (int64, error) {
+	r.mIncrCount.Incr(1)
+	tStarted := time.Now()
+
+	key = r.prefix + key
+
+	res, err := redisIncrScript.Run(r.client, []string{key}, delta, r.ttl.Milliseconds()).Int64()
+	if err != nil {
+		r.log.Errorf("Incr command failed: %v\n", err)
+		r.mIncrFailed.Incr(1)
+	} else {
+		r.mIncrSuccess.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	r.mIncrLatency.Timing(latency)
+	r.mLatency.Timing(latency)
+
+	return res, err
+}
+
+// redisCASScript sets a key to ARGV[3] only if its current value matches
+// ARGV[2], or if the key does not exist when ARGV[1] is 1. The key expires
+// after ARGV[4] milliseconds when greater than zero.
+var redisCASScript = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if ARGV[1] == "1" then
+  if current then return 0 end
+elseif current ~= ARGV[2] then
+  return 0
+end
+if tonumber(ARGV[4]) > 0 then
+  redis.call("SET", KEYS[1], ARGV[3], "PX", ARGV[4])
+else
+  redis.call("SET", KEYS[1], ARGV[3])
+end
+return 1
+`)
+
+// CompareAndSwap attempts to set the value of a key only if its current value
+// matches old, where a nil old value requires that the key does not exist.
+func (r *Redis) CompareAndSwap(key string, old, value []byte, ttl *time.Duration) error {
+	r.mCASCount.Incr(1)
+	tStarted := time.Now()
+
+	key = r.prefix + key
+
+	t := r.ttl
+	if ttl != nil {
+		t = *ttl
+	}
+	mustNotExist := "0"
+	if old == nil {
+		mustNotExist = "1"
+	}
+	args := []interface{}{mustNotExist, old, value, t.Milliseconds()}
+
+	swapped, err := redisCASScript.Run(r.client, []string{key}, args...).Int()
+	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
+		r.log.Errorf("Compare and swap command failed: %v\n", err)
+		<-time.After(r.retryPeriod)
+		r.mCASRetry.Incr(1)
+		swapped, err = redisCASScript.Run(r.client, []string{key}, args...).Int()
+	}
+	if err == nil && swapped == 0 {
+		err = types.ErrKeyValueMismatch
+	}
+	if err == nil {
+		r.mCASSuccess.Incr(1)
+	} else if err == types.ErrKeyValueMismatch {
+		r.mCASMismatch.Incr(1)
+	} else {
+		r.mCASFailed.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	r.mCASLatency.Timing(latency)
+	r.mLatency.Timing(latency)
+
+	return err
+}
+
+// Scan returns all keys that begin with a prefix along with their remaining
+// TTLs. Keys are enumerated with SCAN and therefore keys added or removed
+// during the operation may or may not be included.
+func (r *Redis) Scan(prefix string) ([]types.CacheScanItem, error) {
+	r.mScanCount.Incr(1)
+	tStarted := time.Now()
+
+	items, err := r.scan(prefix)
+	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
+		r.log.Errorf("Scan command failed: %v\n", err)
+		<-time.After(r.retryPeriod)
+		r.mScanRetry.Incr(1)
+		items, err = r.scan(prefix)
+	}
+	if err != nil {
+		r.mScanFailed.Incr(1)
+	} else {
+		r.mScanSuccess.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	r.mScanLatency.Timing(latency)
+	r.mLatency.Timing(latency)
+
+	return items, err
+}
+
+var redisGlobEscaper = strings.NewReplacer(
+	`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`,
+)
+
+func (r *Redis) scan(prefix string) ([]types.CacheScanItem, error) {
+	match := redisGlobEscaper.Replace(r.prefix+prefix) + "*"
+
+	var items []types.CacheScanItem
+	scanNode := func(client redis.Cmdable) error {
+		var cursor uint64
+		for {
+			keys, next, err := client.Scan(cursor, match, 1000).Result()
+			if err != nil {
+				return err
+			}
+			if len(keys) > 0 {
+				pipe := client.Pipeline()
+				cmds := make([]*redis.DurationCmd, len(keys))
+				for i, k := range keys {
+					cmds[i] = pipe.PTTL(k)
+				}
+				if _, err := pipe.Exec(); err != nil {
+					return err
+				}
+				for i, cmd := range cmds {
+					ttl := cmd.Val()
+					if ttl == -2 {
+						// The key expired since it was scanned.
+						continue
+					}
+					item := types.CacheScanItem{Key: strings.TrimPrefix(keys[i], r.prefix)}
+					if ttl >= 0 {
+						item.TTL = &ttl
+					}
+					items = append(items, item)
+				}
+			}
+			if cursor = next; cursor == 0 {
+				return nil
+			}
+		}
+	}
+
+	var err error
+	if cc, ok := r.client.(*redis.ClusterClient); ok {
+		var mut sync.Mutex
+		err = cc.ForEachMaster(func(c *redis.Client) error {
+			mut.Lock()
+			defer mut.Unlock()
+			return scanNode(c)
+		})
+	} else {
+		err = scanNode(r.client)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Key < items[j].Key
+	})
+	return items, nil
+}
+
 // CloseAsync shuts down the cache.
 func (r *Redis) CloseAsync() {
 }
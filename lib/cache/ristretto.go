@@ -3,6 +3,9 @@ package cache
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
@@ -10,6 +13,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/dgraph-io/ristretto"
+	"github.com/dgraph-io/ristretto/z"
 )
 
 //------------------------------------------------------------------------------
@@ -63,6 +67,18 @@ type Ristretto struct {
 
 	retries     int
 	retryPeriod time.Duration
+
+	// Ristretto does not support iterating its keys and therefore we track
+	// them ourselves in order to support Scan. Keys are tracked by their hash
+	// so that they can be removed when ristretto evicts, expires or rejects
+	// them.
+	keysMut sync.Mutex
+	keys    map[uint64]ristrettoKey
+}
+
+type ristrettoKey struct {
+	key      string
+	conflict uint64
 }
 
 // NewRistretto creates a new Ristretto cache type.
@@ -84,26 +100,51 @@ func NewRistretto(conf Config, mgr types.Manager, log log.Modular, stats metrics
 		}
 	}
 
-	cache, err := ristretto.NewCache(&ristretto.Config{
-		NumCounters: 1e7,     // number of keys to track frequency of (10M).
-		MaxCost:     1 << 30, // maximum cost of cache (1GB).
-		BufferItems: 64,      // number of keys per Get buffer.
-	})
-	if err != nil {
-		return nil, err
-	}
 	r := &Ristretto{
 		ttl:         ttl,
-		cache:       cache,
 		retries:     conf.Ristretto.Retries,
 		retryPeriod: retryPeriod,
+		keys:        map[uint64]ristrettoKey{},
 	}
 
+	if r.cache, err = ristretto.NewCache(&ristretto.Config{
+		NumCounters: 1e7,     // number of keys to track frequency of (10M).
+		MaxCost:     1 << 30, // maximum cost of cache (1GB).
+		BufferItems: 64,      // number of keys per Get buffer.
+		OnEvict:     r.forgetItem,
+		OnReject:    r.forgetItem,
+	}); err != nil {
+		return nil, err
+	}
 	return r, nil
 }
 
 //------------------------------------------------------------------------------
 
+func (r *Ristretto) trackKey(key string) {
+	hash, conflict := z.KeyToHash(key)
+	r.keysMut.Lock()
+	r.keys[hash] = ristrettoKey{key: key, conflict: conflict}
+	r.keysMut.Unlock()
+}
+
+func (r *Ristretto) forgetKey(key string) {
+	hash, _ := z.KeyToHash(key)
+	r.keysMut.Lock()
+	delete(r.keys, hash)
+	r.keysMut.Unlock()
+}
+
+// forgetItem stops tracking the key of an item that has been removed from the
+// cache by ristretto.
+func (r *Ristretto) forgetItem(item *ristretto.Item) {
+	r.keysMut.Lock()
+	if k, exists := r.keys[item.Key]; exists && (item.Conflict == 0 || k.conflict == item.Conflict) {
+		delete(r.keys, item.Key)
+	}
+	r.keysMut.Unlock()
+}
+
 // Get attempts to locate and return a cached value by its key, returns an error
 // if the key does not exist.
 func (r *Ristretto) Get(key string) ([]byte, error) {
@@ -129,6 +170,7 @@ func (r *Ristretto) SetWithTTL(key string, value []byte, ttl *time.Duration) err
 	if !r.cache.SetWithTTL(key, value, 1, t) {
 		return errors.New("set operation was dropped")
 	}
+	r.trackKey(key)
 	return nil
 }
 
@@ -150,6 +192,7 @@ func (r *Ristretto) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
 		if !r.cache.SetWithTTL(k, v.Value, 1, t) {
 			return errors.New("set operation was dropped")
 		}
+		r.trackKey(k)
 	}
 	return nil
 }
@@ -181,9 +224,42 @@ func (r *Ristretto) Add(key string, value []byte) error {
 // Delete attempts to remove a key.
 func (r *Ristretto) Delete(key string) error {
 	r.cache.Del(key)
+	r.forgetKey(key)
 	return nil
 }
 
+// Scan returns all keys that begin with a prefix along with their remaining
+// TTLs.
+func (r *Ristretto) Scan(prefix string) ([]types.CacheScanItem, error) {
+	// Wait for buffered sets to be applied so that recently set keys aren't
+	// pruned.
+	r.cache.Wait()
+
+	r.keysMut.Lock()
+	defer r.keysMut.Unlock()
+
+	var items []types.CacheScanItem
+	for hash, tk := range r.keys {
+		k := tk.key
+		if _, exists := r.cache.Get(k); !exists {
+			delete(r.keys, hash)
+			continue
+		}
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		item := types.CacheScanItem{Key: k}
+		if ttl, _ := r.cache.GetTTL(k); ttl > 0 {
+			item.TTL = &ttl
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Key < items[j].Key
+	})
+	return items, nil
+}
+
 // CloseAsync shuts down the cache.
 func (r *Ristretto) CloseAsync() {
 	r.cache.Close()
@@ -66,3 +66,57 @@ func TestRistrettoCacheWithTTL(t *testing.T) {
 		assert.Fail(t, "ristretto should implement CacheWithTTL interface")
 	}
 }
+
+func TestRistrettoCacheScan(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeRistretto
+	conf.Ristretto.Retries = 50
+	conf.Ristretto.RetryPeriod = "1ms"
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tc, ok := c.(types.CacheWithTTL)
+	require.True(t, ok)
+
+	ttl := time.Minute
+	require.NoError(t, tc.SetWithTTL("foo1", []byte("1"), &ttl))
+	require.NoError(t, tc.Set("foo2", []byte("2")))
+	require.NoError(t, tc.Set("bar1", []byte("3")))
+	require.NoError(t, tc.Delete("foo2"))
+
+	items, err := c.(types.CacheWithScan).Scan("foo")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "foo1", items[0].Key)
+	require.NotNil(t, items[0].TTL)
+	assert.True(t, *items[0].TTL > 0 && *items[0].TTL <= time.Minute)
+}
+
+func TestRistrettoCacheForgetsRemovedKeys(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeRistretto
+
+	c, err := NewRistretto(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	r, ok := c.(*Ristretto)
+	require.True(t, ok)
+
+	for _, k := range []string{"foo", "bar", "baz"} {
+		require.NoError(t, r.Set(k, []byte(k)))
+	}
+	require.NoError(t, r.Delete("foo"))
+	r.cache.Wait()
+
+	r.keysMut.Lock()
+	assert.Len(t, r.keys, 2)
+	r.keysMut.Unlock()
+
+	// Clearing the cache evicts all remaining items.
+	r.cache.Clear()
+
+	r.keysMut.Lock()
+	assert.Empty(t, r.keys)
+	r.keysMut.Unlock()
+}
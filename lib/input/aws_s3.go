@@ -14,6 +14,7 @@ import (
 
 	"github.com/Jeffail/benthos/v3/internal/codec"
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/lib/input/reader"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
@@ -32,7 +33,7 @@ func init() {
 		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
 			var r reader.Async
 			var err error
-			if r, err = newAmazonS3(conf.AWSS3, log, stats); err != nil {
+			if r, err = newAmazonS3(conf.AWSS3, mgr, log, stats); err != nil {
 				return nil, err
 			}
 			// If we're not pulling events directly from an SQS queue then
@@ -57,6 +58,19 @@ If your notification events are being routed to SQS via an SNS topic then the ev
 
 When using SQS please make sure you have sensible values for ` + "`sqs.max_messages`" + ` and also the visibility timeout of the queue itself. When Benthos consumes an S3 object the SQS message that triggered it is not deleted until the S3 object has been sent onwards. This ensures at-least-once crash resiliency, but also means that if the S3 object takes longer to process than the visibility timeout of your queue then the same objects might be processed multiple times.
 
+## Polling a Bucket
+
+When it isn't possible to configure event notifications for a bucket, Benthos can instead be configured to list the objects of the bucket periodically by setting ` + "`polling.enabled`" + ` to ` + "`true`" + `. Each listing is performed after ` + "`polling.poll_interval`" + ` has elapsed since the last one, and only objects that haven't already been consumed are downloaded. The object keys (and their ETags) consumed by the input are stored within the [cache resource](/docs/components/caches/about) ` + "`polling.cache`" + `, and therefore for the input to resume where it left off after a restart the cache must be persisted.
+
+The ` + "`polling.mode`" + ` field determines how listings are narrowed down to new objects:
+
+- ` + "`last_modified`" + ` lists the entire prefix on each poll and ignores objects with a last modified timestamp earlier than those already consumed. This is suitable for buckets where keys have no meaningful ordering.
+- ` + "`start_after`" + ` only lists keys that are lexically greater than those already consumed, which is far more efficient for large buckets where keys are written in ascending order, such as keys prefixed with a timestamp.
+
+In both modes objects that appear with a key or last modified timestamp earlier than objects already consumed will be ignored. The ` + "`polling.since` and `polling.until`" + ` fields can also be used in order to only consume objects last modified within a time range.
+
+Objects that fail to be consumed are downloaded again on the next poll, and the position of the listing does not advance beyond them until they have been consumed.
+
 ## Downloading Large Files
 
 When downloading large files it's often necessary to process it in streamed parts in order to avoid loading the entire file in memory at a given time. In order to do this a ` + "[`codec`](#codec)" + ` can be specified that determines how to break the input into smaller individual messages.
@@ -102,6 +116,14 @@ You can access these metadata fields using [function interpolation](/docs/config
 				),
 				docs.FieldAdvanced("max_messages", "The maximum number of SQS messages to consume from each request."),
 			),
+			docs.FieldAdvanced("polling", "Periodically list the bucket for new objects rather than walking it once, which is useful when notifications cannot be configured for a bucket. This mode cannot be used in combination with `sqs.url`.").WithChildren(
+				docs.FieldCommon("enabled", "Whether polling is enabled."),
+				docs.FieldCommon("poll_interval", "The period of time to wait between each listing of the bucket.", "30s", "5m"),
+				docs.FieldCommon("cache", "A [cache resource](/docs/components/caches/about) for storing the keys of objects already consumed, along with the current listing position."),
+				docs.FieldCommon("mode", "The method used for narrowing each listing down to new objects.").HasOptions("last_modified", "start_after"),
+				docs.FieldAdvanced("since", "An optional [RFC 3339](https://tools.ietf.org/html/rfc3339) timestamp, when set objects last modified before this time are ignored.", "2021-12-01T00:00:00Z"),
+				docs.FieldAdvanced("until", "An optional [RFC 3339](https://tools.ietf.org/html/rfc3339) timestamp, when set objects last modified at or after this time are ignored.", "2022-01-01T00:00:00Z"),
+			).AtVersion("3.60.0"),
 		),
 		Categories: []Category{
 			CategoryServices,
@@ -136,15 +158,39 @@ func NewAWSS3SQSConfig() AWSS3SQSConfig {
 	}
 }
 
+// AWSS3PollingConfig contains configuration for periodically listing the
+// objects of a bucket.
+type AWSS3PollingConfig struct {
+	Enabled      bool   `json:"enabled" yaml:"enabled"`
+	PollInterval string `json:"poll_interval" yaml:"poll_interval"`
+	Cache        string `json:"cache" yaml:"cache"`
+	Mode         string `json:"mode" yaml:"mode"`
+	Since        string `json:"since" yaml:"since"`
+	Until        string `json:"until" yaml:"until"`
+}
+
+// NewAWSS3PollingConfig creates a new AWSS3PollingConfig with default values.
+func NewAWSS3PollingConfig() AWSS3PollingConfig {
+	return AWSS3PollingConfig{
+		Enabled:      false,
+		PollInterval: "1m",
+		Cache:        "",
+		Mode:         "last_modified",
+		Since:        "",
+		Until:        "",
+	}
+}
+
 // AWSS3Config contains configuration values for the aws_s3 input type.
 type AWSS3Config struct {
 	sess.Config        `json:",inline" yaml:",inline"`
-	Bucket             string         `json:"bucket" yaml:"bucket"`
-	Codec              string         `json:"codec" yaml:"codec"`
-	Prefix             string         `json:"prefix" yaml:"prefix"`
-	ForcePathStyleURLs bool           `json:"force_path_style_urls" yaml:"force_path_style_urls"`
-	DeleteObjects      bool           `json:"delete_objects" yaml:"delete_objects"`
-	SQS                AWSS3SQSConfig `json:"sqs" yaml:"sqs"`
+	Bucket             string             `json:"bucket" yaml:"bucket"`
+	Codec              string             `json:"codec" yaml:"codec"`
+	Prefix             string             `json:"prefix" yaml:"prefix"`
+	ForcePathStyleURLs bool               `json:"force_path_style_urls" yaml:"force_path_style_urls"`
+	DeleteObjects      bool               `json:"delete_objects" yaml:"delete_objects"`
+	SQS                AWSS3SQSConfig     `json:"sqs" yaml:"sqs"`
+	Polling            AWSS3PollingConfig `json:"polling" yaml:"polling"`
 }
 
 // NewAWSS3Config creates a new AWSS3Config with default values.
@@ -157,6 +203,7 @@ func NewAWSS3Config() AWSS3Config {
 		ForcePathStyleURLs: false,
 		DeleteObjects:      false,
 		SQS:                NewAWSS3SQSConfig(),
+		Polling:            NewAWSS3PollingConfig(),
 	}
 }
 
@@ -542,6 +589,313 @@ func (s *sqsTargetReader) ackSQSMessage(ctx context.Context, msg *sqs.Message) e
 
 //------------------------------------------------------------------------------
 
+// s3PollingCursor tracks the position of a polled listing, where the cursor is
+// the greatest value (either a key or a formatted last modified timestamp) for
+// which all listed objects of an equal or lesser value have been consumed.
+type s3PollingCursor struct {
+	cursor   string
+	inFlight map[string]string
+	failed   map[string]struct{}
+	done     []string
+}
+
+func newS3PollingCursor(cursor string) *s3PollingCursor {
+	return &s3PollingCursor{
+		cursor:   cursor,
+		inFlight: map[string]string{},
+		failed:   map[string]struct{}{},
+	}
+}
+
+// add registers a listed object that is yet to be consumed.
+func (c *s3PollingCursor) add(key, value string) {
+	c.inFlight[key] = value
+	delete(c.failed, key)
+}
+
+// isInFlight returns whether an object key is listed and currently being
+// consumed, objects that failed are not in flight as they need listing again.
+func (c *s3PollingCursor) isInFlight(key string) bool {
+	if _, failed := c.failed[key]; failed {
+		return false
+	}
+	_, exists := c.inFlight[key]
+	return exists
+}
+
+// fail marks an object that failed to be consumed so that it is listed again,
+// the object continues to hold the cursor back until it has been consumed.
+func (c *s3PollingCursor) fail(key string) {
+	if _, exists := c.inFlight[key]; exists {
+		c.failed[key] = struct{}{}
+	}
+}
+
+// complete marks a listed object as consumed and returns the new cursor along
+// with whether it has changed.
+func (c *s3PollingCursor) complete(key, value string) (string, bool) {
+	delete(c.inFlight, key)
+	delete(c.failed, key)
+	if value > c.cursor {
+		c.done = append(c.done, value)
+	}
+
+	var lowestInFlight string
+	for _, v := range c.inFlight {
+		if lowestInFlight == "" || v < lowestInFlight {
+			lowestInFlight = v
+		}
+	}
+
+	prev := c.cursor
+	remaining := c.done[:0]
+	for _, v := range c.done {
+		if lowestInFlight != "" && v >= lowestInFlight {
+			remaining = append(remaining, v)
+		} else if v > c.cursor {
+			c.cursor = v
+		}
+	}
+	c.done = remaining
+	return c.cursor, c.cursor != prev
+}
+
+const s3PollingTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+type pollingTargetReader struct {
+	conf AWSS3Config
+	mgr  types.Manager
+	log  log.Modular
+	s3   *s3.S3
+
+	interval     time.Duration
+	since, until time.Time
+	cursorKey    string
+
+	nextPoll time.Time
+	pending  []*s3ObjectTarget
+
+	cursorMut sync.Mutex
+	cursor    *s3PollingCursor
+}
+
+func newPollingTargetReader(
+	ctx context.Context,
+	conf AWSS3Config,
+	mgr types.Manager,
+	log log.Modular,
+	s3Client *s3.S3,
+	interval time.Duration,
+	since, until time.Time,
+) (*pollingTargetReader, error) {
+	p := &pollingTargetReader{
+		conf:      conf,
+		mgr:       mgr,
+		log:       log,
+		s3:        s3Client,
+		interval:  interval,
+		since:     since,
+		until:     until,
+		cursorKey: conf.Polling.Mode + "_cursor:" + conf.Bucket + "/" + conf.Prefix,
+	}
+
+	var cursor []byte
+	var getErr error
+	if cerr := interop.AccessCache(ctx, mgr, conf.Polling.Cache, func(cache types.Cache) {
+		cursor, getErr = cache.Get(p.cursorKey)
+	}); cerr != nil {
+		return nil, fmt.Errorf("failed to access cache: %w", cerr)
+	}
+	if getErr != nil && !errors.Is(getErr, types.ErrKeyNotFound) {
+		return nil, fmt.Errorf("failed to get listing cursor from cache: %w", getErr)
+	}
+	p.cursor = newS3PollingCursor(string(cursor))
+	return p, nil
+}
+
+func (p *pollingTargetReader) objectValue(obj *s3.Object) string {
+	if p.conf.Polling.Mode == "start_after" {
+		return *obj.Key
+	}
+	return obj.LastModified.UTC().Format(s3PollingTimeFormat)
+}
+
+func (p *pollingTargetReader) listObjects(ctx context.Context, startAfter string) ([]*s3.Object, error) {
+	listInput := &s3.ListObjectsV2Input{
+		Bucket: aws.String(p.conf.Bucket),
+	}
+	if len(p.conf.Prefix) > 0 {
+		listInput.Prefix = aws.String(p.conf.Prefix)
+	}
+	if startAfter != "" {
+		listInput.StartAfter = aws.String(startAfter)
+	}
+
+	var objects []*s3.Object
+	for {
+		output, err := p.s3.ListObjectsV2WithContext(ctx, listInput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %v", err)
+		}
+		objects = append(objects, output.Contents...)
+		if output.IsTruncated == nil || !*output.IsTruncated || output.NextContinuationToken == nil {
+			return objects, nil
+		}
+		listInput.ContinuationToken = output.NextContinuationToken
+	}
+}
+
+func (p *pollingTargetReader) poll(ctx context.Context) ([]*s3ObjectTarget, error) {
+	p.cursorMut.Lock()
+	cursor := p.cursor.cursor
+	p.cursorMut.Unlock()
+
+	var startAfter string
+	if p.conf.Polling.Mode == "start_after" {
+		startAfter = cursor
+	}
+
+	objects, err := p.listObjects(ctx, startAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*s3.Object
+	for _, obj := range objects {
+		if obj.Key == nil || obj.LastModified == nil {
+			continue
+		}
+		if !p.since.IsZero() && obj.LastModified.Before(p.since) {
+			continue
+		}
+		if !p.until.IsZero() && !obj.LastModified.Before(p.until) {
+			continue
+		}
+		if p.objectValue(obj) < cursor {
+			continue
+		}
+		candidates = append(candidates, obj)
+	}
+
+	var targets []*s3ObjectTarget
+	var cacheErr error
+	if cerr := interop.AccessCache(ctx, p.mgr, p.conf.Polling.Cache, func(cache types.Cache) {
+		p.cursorMut.Lock()
+		defer p.cursorMut.Unlock()
+
+		for _, obj := range candidates {
+			key, value := *obj.Key, p.objectValue(obj)
+			if p.cursor.isInFlight(key) {
+				continue
+			}
+
+			etag := "@"
+			if obj.ETag != nil {
+				etag = *obj.ETag
+			}
+			cached, err := cache.Get(p.conf.Bucket + "/" + key)
+			if err == nil && string(cached) == etag {
+				// Already consumed, but may allow the cursor to advance.
+				p.cursor.add(key, value)
+				if newCursor, changed := p.cursor.complete(key, value); changed {
+					if cacheErr = cache.Set(p.cursorKey, []byte(newCursor)); cacheErr != nil {
+						return
+					}
+				}
+				continue
+			}
+			if err != nil && !errors.Is(err, types.ErrKeyNotFound) {
+				cacheErr = err
+				return
+			}
+
+			p.cursor.add(key, value)
+			targets = append(targets, newS3ObjectTarget(
+				key, p.conf.Bucket, time.Time{},
+				deleteS3ObjectAckFn(p.s3, p.conf.Bucket, key, p.conf.DeleteObjects, p.ackFn(key, value, etag)),
+			))
+		}
+	}); cerr != nil {
+		return nil, fmt.Errorf("failed to access cache: %w", cerr)
+	}
+	if cacheErr != nil {
+		// Fail the objects we registered so that they're listed again.
+		p.cursorMut.Lock()
+		for _, t := range targets {
+			p.cursor.fail(t.key)
+		}
+		p.cursorMut.Unlock()
+		return nil, fmt.Errorf("failed to check object keys against cache: %w", cacheErr)
+	}
+
+	p.log.Debugf("Listed %v new objects from bucket %v\n", len(targets), p.conf.Bucket)
+	return targets, nil
+}
+
+func (p *pollingTargetReader) ackFn(key, value, etag string) codec.ReaderAckFn {
+	return func(ctx context.Context, err error) error {
+		if err != nil {
+			p.cursorMut.Lock()
+			p.cursor.fail(key)
+			p.cursorMut.Unlock()
+			return nil
+		}
+
+		var setErr error
+		if cerr := interop.AccessCache(ctx, p.mgr, p.conf.Polling.Cache, func(cache types.Cache) {
+			if setErr = cache.Set(p.conf.Bucket+"/"+key, []byte(etag)); setErr != nil {
+				return
+			}
+
+			p.cursorMut.Lock()
+			defer p.cursorMut.Unlock()
+			if newCursor, changed := p.cursor.complete(key, value); changed {
+				setErr = cache.Set(p.cursorKey, []byte(newCursor))
+			}
+		}); cerr != nil {
+			return fmt.Errorf("failed to access cache: %w", cerr)
+		}
+		if setErr != nil {
+			return fmt.Errorf("failed to store consumed object in cache: %w", setErr)
+		}
+		return nil
+	}
+}
+
+func (p *pollingTargetReader) Pop(ctx context.Context) (*s3ObjectTarget, error) {
+	for len(p.pending) == 0 {
+		if until := time.Until(p.nextPoll); until > 0 {
+			select {
+			case <-time.After(until):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		p.nextPoll = time.Now().Add(p.interval)
+
+		var err error
+		if p.pending, err = p.poll(ctx); err != nil {
+			return nil, err
+		}
+	}
+	t := p.pending[0]
+	p.pending = p.pending[1:]
+	return t, nil
+}
+
+func (p *pollingTargetReader) Close(ctx context.Context) error {
+	var err error
+	for _, t := range p.pending {
+		if aerr := t.ackFn(ctx, errors.New("service shutting down")); aerr != nil {
+			err = aerr
+		}
+	}
+	p.pending = nil
+	return err
+}
+
+//------------------------------------------------------------------------------
+
 // AmazonS3 is a benthos reader.Type implementation that reads messages from an
 // Amazon S3 bucket.
 type awsS3 struct {
@@ -556,9 +910,13 @@ type awsS3 struct {
 
 	gracePeriod time.Duration
 
+	pollInterval         time.Duration
+	pollSince, pollUntil time.Time
+
 	objectMut sync.Mutex
 	object    *s3PendingObject
 
+	mgr   types.Manager
 	log   log.Modular
 	stats metrics.Type
 }
@@ -573,6 +931,7 @@ type s3PendingObject struct {
 // NewAmazonS3 creates a new Amazon S3 bucket reader.Type.
 func newAmazonS3(
 	conf AWSS3Config,
+	mgr types.Manager,
 	log log.Modular,
 	stats metrics.Type,
 ) (*awsS3, error) {
@@ -584,6 +943,7 @@ func newAmazonS3(
 	}
 	s := &awsS3{
 		conf:  conf,
+		mgr:   mgr,
 		log:   log,
 		stats: stats,
 	}
@@ -596,13 +956,55 @@ func newAmazonS3(
 			return nil, fmt.Errorf("failed to parse grace period: %w", err)
 		}
 	}
+	if conf.Polling.Enabled {
+		if err = s.initPolling(); err != nil {
+			return nil, err
+		}
+	}
 	return s, nil
 }
 
+func (a *awsS3) initPolling() error {
+	if a.conf.SQS.URL != "" {
+		return errors.New("cannot enable polling in combination with sqs.url")
+	}
+	switch a.conf.Polling.Mode {
+	case "last_modified", "start_after":
+	default:
+		return fmt.Errorf("unrecognised polling mode: %v", a.conf.Polling.Mode)
+	}
+
+	var err error
+	if a.pollInterval, err = time.ParseDuration(a.conf.Polling.PollInterval); err != nil {
+		return fmt.Errorf("failed to parse polling poll interval: %w", err)
+	}
+	if a.conf.Polling.Since != "" {
+		if a.pollSince, err = time.Parse(time.RFC3339, a.conf.Polling.Since); err != nil {
+			return fmt.Errorf("failed to parse polling since timestamp: %w", err)
+		}
+	}
+	if a.conf.Polling.Until != "" {
+		if a.pollUntil, err = time.Parse(time.RFC3339, a.conf.Polling.Until); err != nil {
+			return fmt.Errorf("failed to parse polling until timestamp: %w", err)
+		}
+	}
+
+	if a.conf.Polling.Cache == "" {
+		return errors.New("a cache must be specified when polling is enabled")
+	}
+	if err = interop.ProbeCache(context.Background(), a.mgr, a.conf.Polling.Cache); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (a *awsS3) getTargetReader(ctx context.Context) (s3ObjectTargetReader, error) {
 	if a.sqs != nil {
 		return newSQSTargetReader(a.conf, a.log, a.s3, a.sqs), nil
 	}
+	if a.conf.Polling.Enabled {
+		return newPollingTargetReader(ctx, a.conf, a.mgr, a.log, a.s3, a.pollInterval, a.pollSince, a.pollUntil)
+	}
 	return newStaticTargetReader(ctx, a.conf, a.log, a.s3)
 }
 
@@ -637,7 +1039,9 @@ func (a *awsS3) ConnectWithContext(ctx context.Context) error {
 		return err
 	}
 
-	if a.conf.SQS.URL == "" {
+	if a.conf.Polling.Enabled {
+		a.log.Infof("Polling S3 objects from bucket: %s\n", a.conf.Bucket)
+	} else if a.conf.SQS.URL == "" {
 		a.log.Infof("Downloading S3 objects from bucket: %s\n", a.conf.Bucket)
 	} else {
 		a.log.Infof("Downloading S3 objects found in messages from SQS: %s\n", a.conf.SQS.URL)
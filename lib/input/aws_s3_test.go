@@ -0,0 +1,164 @@
+package input
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3PollingCursor(t *testing.T) {
+	c := newS3PollingCursor("a")
+
+	c.add("b", "b")
+	c.add("c", "c")
+	c.add("d", "d")
+
+	// Completing objects out of order holds the cursor back until all prior
+	// objects are consumed.
+	cursor, changed := c.complete("c", "c")
+	assert.False(t, changed)
+	assert.Equal(t, "a", cursor)
+
+	cursor, changed = c.complete("b", "b")
+	assert.True(t, changed)
+	assert.Equal(t, "c", cursor)
+
+	// Objects that fail are listed again and hold the cursor back until they
+	// are consumed.
+	c.add("e", "e")
+	c.fail("d")
+	assert.False(t, c.isInFlight("d"))
+	assert.True(t, c.isInFlight("e"))
+
+	cursor, changed = c.complete("e", "e")
+	assert.False(t, changed)
+	assert.Equal(t, "c", cursor)
+
+	c.add("d", "d")
+	assert.True(t, c.isInFlight("d"))
+
+	cursor, changed = c.complete("d", "d")
+	assert.True(t, changed)
+	assert.Equal(t, "e", cursor)
+}
+
+func TestS3PollingCursorSharedValues(t *testing.T) {
+	c := newS3PollingCursor("")
+
+	c.add("foo", "2021-12-01T00:00:00.000000000Z")
+	c.add("bar", "2021-12-01T00:00:00.000000000Z")
+	c.add("baz", "2021-12-02T00:00:00.000000000Z")
+
+	cursor, changed := c.complete("baz", "2021-12-02T00:00:00.000000000Z")
+	assert.False(t, changed)
+	assert.Equal(t, "", cursor)
+
+	cursor, changed = c.complete("foo", "2021-12-01T00:00:00.000000000Z")
+	assert.False(t, changed)
+	assert.Equal(t, "", cursor)
+
+	cursor, changed = c.complete("bar", "2021-12-01T00:00:00.000000000Z")
+	assert.True(t, changed)
+	assert.Equal(t, "2021-12-02T00:00:00.000000000Z", cursor)
+}
+
+type s3PollingMgr struct {
+	types.Manager
+	cache types.Cache
+}
+
+func (m *s3PollingMgr) GetCache(name string) (types.Cache, error) {
+	return m.cache, nil
+}
+
+func TestS3PollingNackedObjectRelisted(t *testing.T) {
+	keys := []string{"a", "b", "c"}
+
+	var listings []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startAfter := r.URL.Query().Get("start-after")
+		listings = append(listings, startAfter)
+
+		var contents bytes.Buffer
+		for _, k := range keys {
+			if k <= startAfter {
+				continue
+			}
+			fmt.Fprintf(&contents, `<Contents><Key>%v</Key><LastModified>2021-12-01T00:00:00.000Z</LastModified><ETag>"%v"</ETag><Size>1</Size></Contents>`, k, k)
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>foo</Name><IsTruncated>false</IsTruncated>%v</ListBucketResult>`, contents.String())
+	}))
+	defer srv.Close()
+
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(srv.URL),
+		Region:           aws.String("us-east-1"),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+		S3ForcePathStyle: aws.Bool(true),
+	})
+	require.NoError(t, err)
+
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	mgr := &s3PollingMgr{Manager: types.NoopMgr(), cache: memCache}
+
+	conf := NewAWSS3Config()
+	conf.Bucket = "foo"
+	conf.Polling.Enabled = true
+	conf.Polling.Cache = "foocache"
+	conf.Polling.Mode = "start_after"
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	p, err := newPollingTargetReader(ctx, conf, mgr, log.Noop(), s3.New(sess), 0, time.Time{}, time.Time{})
+	require.NoError(t, err)
+
+	targets := map[string]*s3ObjectTarget{}
+	for i := 0; i < 3; i++ {
+		target, err := p.Pop(ctx)
+		require.NoError(t, err)
+		targets[target.key] = target
+	}
+	require.Len(t, targets, 3)
+
+	require.NoError(t, targets["a"].ackFn(ctx, nil))
+	require.NoError(t, targets["b"].ackFn(ctx, errors.New("nope")))
+	require.NoError(t, targets["c"].ackFn(ctx, nil))
+
+	// The nacked object holds the cursor back.
+	cursor, err := memCache.Get(p.cursorKey)
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(cursor))
+
+	// The nacked object is delivered again on the next poll, whereas the
+	// consumed objects are not.
+	target, err := p.Pop(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "b", target.key)
+	assert.Empty(t, p.pending)
+	assert.Equal(t, []string{"", "a"}, listings)
+
+	require.NoError(t, target.ackFn(ctx, nil))
+	cursor, err = memCache.Get(p.cursorKey)
+	require.NoError(t, err)
+	assert.Equal(t, "c", string(cursor))
+}
@@ -3,7 +3,9 @@ package input
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	nethttp "net/http"
 	"strings"
 	"sync"
 	"time"
@@ -23,7 +25,10 @@ import (
 func httpClientSpecs() docs.FieldSpecs {
 	codecDocs := codec.ReaderDocs.AtVersion("3.42.0")
 	codecDocs.Description = "The way in which the bytes of a continuous stream are converted into messages. It's possible to consume lines using a custom delimiter with the `delim:x` codec, where x is the character sequence custom delimiter. It's not necessary to add gzip in the codec when the response headers specify it as it will be decompressed automatically."
-	codecDocs.Examples = []interface{}{"lines", "delim:\t", "delim:foobar", "csv"}
+	codecDocs.Examples = []interface{}{"lines", "delim:\t", "delim:foobar", "csv", "sse"}
+	codecDocs.AnnotatedOptions = append(append([][2]string{}, codecDocs.AnnotatedOptions...), [2]string{
+		"sse", "Consume the stream as [server-sent events](#server-sent-events), where each event is a message. This codec cannot be chained with others.",
+	})
 
 	streamSpecs := docs.FieldSpecs{
 		docs.FieldBool("enabled", "Enables streaming mode."),
@@ -34,12 +39,38 @@ func httpClientSpecs() docs.FieldSpecs {
 		docs.FieldDeprecated("delimiter"),
 	}
 
+	paginationSpecs := docs.FieldSpecs{
+		docs.FieldBool("enabled", "Whether to follow the pages of a paginated API. Pagination cannot be used in streaming mode."),
+		docs.FieldBloblang(
+			"next_url",
+			"An optional [Bloblang mapping](/docs/guides/bloblang/about) executed on each page that should result in the URL of the next page, which can be relative to the URL of the page. When the mapping results in `null`, an empty string or deletes the root, pagination is complete. If the mapping fails the page is requested again. When empty the URL of the link with the relation type `next` within a `Link` header of the response is followed instead.",
+			`root = this.links.next`,
+			`root = if this.next_cursor != null { "https://api.example.com/items?cursor=" + this.next_cursor.escape_url_query() } else { null }`,
+			`root = if this.items.length() > 0 { "https://api.example.com/items?limit=100&offset=%v".format(meta("http_client_page").number() * 100) } else { null }`,
+		).HasDefault(""),
+		docs.FieldBloblang(
+			"stop_when",
+			"An optional [Bloblang query](/docs/guides/bloblang/about) executed on each page that should return a boolean, where `true` indicates that pagination is complete after the page. If the query fails the page is requested again.",
+			`this.items.length() == 0`,
+			`this.has_more == false`,
+		).HasDefault(""),
+		docs.FieldInt("max_pages", "The maximum number of pages to request before pagination is complete, where `0` means unlimited."),
+		docs.FieldString("rate_limit", "An optional [rate limit](/docs/components/rate_limits/about) to throttle each page request by."),
+		docs.FieldString(
+			"restart_interval", "An optional period to wait after pagination is complete before starting again from the first page. When empty the input closes once pagination is complete.",
+			"1m", "1h",
+		),
+	}
+
 	specs := append(client.FieldSpecs(),
 		docs.FieldCommon("payload", "An optional payload to deliver for each request."),
 		docs.FieldAdvanced("drop_empty_bodies", "Whether empty payloads received from the target server should be dropped."),
 		docs.FieldCommon(
 			"stream", "Allows you to set streaming mode, where requests are kept open and messages are processed line-by-line.",
 		).WithChildren(streamSpecs...),
+		docs.FieldAdvanced(
+			"pagination", "Allows you to follow the pages of a paginated API, where a request is made for each page until a stop condition is met.",
+		).WithChildren(paginationSpecs...).AtVersion("3.60.0"),
 	)
 	return specs
 }
@@ -57,9 +88,17 @@ interpolations described [here](/docs/configuration/interpolation#bloblang-queri
 
 If you enable streaming then Benthos will consume the body of the response as a continuous stream of data, breaking messages out following a chosen codec. This allows you to consume APIs that provide long lived streamed data feeds (such as Twitter).
 
+### Server-Sent Events
+
+When streaming with the codec ` + "`sse`" + ` the response body is consumed as a ` + "`text/event-stream`" + ` of [server-sent events](https://html.spec.whatwg.org/multipage/server-sent-events.html), where the data of each event becomes a message, and the metadata fields ` + "`sse_event`" + ` and ` + "`sse_id`" + ` are set to the name of the event (` + "`message`" + ` by default) and the last event ID respectively. Comments and events without data are skipped.
+
+When the connection is lost and ` + "`stream.reconnect`" + ` is enabled the input reconnects with a ` + "`Last-Event-ID`" + ` header set to the ID of the last event received, which allows servers to resume the stream, and waits for the reconnection time suggested by the server with a ` + "`retry`" + ` field, if any, before doing so.
+
 ### Pagination
 
-This input supports interpolation functions in the ` + "`url` and `headers`" + ` fields where data from the previous successfully consumed message (if there was one) can be referenced. This can be used in order to support basic levels of pagination. However, in cases where pagination depends on logic it is recommended that you use an ` + "[`http` processor](/docs/components/processors/http) instead, often combined with a [`generate` input](/docs/components/inputs/generate)" + ` in order to schedule the processor.`,
+This input supports interpolation functions in the ` + "`url` and `headers`" + ` fields where data from the previous successfully consumed message (if there was one) can be referenced. This can be used in order to support basic levels of pagination. However, in cases where pagination depends on logic it is recommended that you use an ` + "[`http` processor](/docs/components/processors/http) instead, often combined with a [`generate` input](/docs/components/inputs/generate)" + ` in order to schedule the processor.
+
+When ` + "`pagination.enabled`" + ` is set the first page is requested from ` + "`url`" + `, and each response determines the URL of the next page, either with the mapping ` + "`pagination.next_url`" + ` or by following the ` + "`next`" + ` link of a ` + "`Link`" + ` header. Cursors and offsets can be injected into the URL of the next page by the mapping, and each message of a page has the metadata field ` + "`http_client_page`" + ` set to the page number, starting from 1. Pagination is complete when there is no next page, the query ` + "`pagination.stop_when`" + ` returns ` + "`true`" + ` or ` + "`pagination.max_pages`" + ` have been requested, at which point the input closes unless ` + "`pagination.restart_interval`" + ` is set.`,
 		FieldSpecs: httpClientSpecs(),
 		Categories: []Category{
 			CategoryNetwork,
@@ -88,6 +127,35 @@ rate_limit_resources:
     local:
       count: 1
       interval: 30s
+`,
+			},
+			{
+				Title:   "Cursor Pagination",
+				Summary: "Pagination can be used in order to consume a full dataset from a REST API that returns a cursor to the next page within each response, here the dataset is consumed again each hour.",
+				Config: `
+input:
+  http_client:
+    url: https://api.example.com/items?limit=100
+    verb: GET
+    pagination:
+      enabled: true
+      next_url: |
+        root = if this.next_cursor != null {
+          "https://api.example.com/items?limit=100&cursor=" + this.next_cursor.escape_url_query()
+        } else { null }
+      stop_when: this.items.length() == 0
+      rate_limit: item_pages
+      restart_interval: 1h
+  processors:
+    - bloblang: root = this.items
+    - unarchive:
+        format: json_array
+
+rate_limit_resources:
+  - label: item_pages
+    local:
+      count: 10
+      interval: 1s
 `,
 			},
 		},
@@ -110,9 +178,10 @@ type StreamConfig struct {
 // HTTPClientConfig contains configuration for the HTTPClient output type.
 type HTTPClientConfig struct {
 	client.Config   `json:",inline" yaml:",inline"`
-	Payload         string       `json:"payload" yaml:"payload"`
-	DropEmptyBodies bool         `json:"drop_empty_bodies" yaml:"drop_empty_bodies"`
-	Stream          StreamConfig `json:"stream" yaml:"stream"`
+	Payload         string                     `json:"payload" yaml:"payload"`
+	DropEmptyBodies bool                       `json:"drop_empty_bodies" yaml:"drop_empty_bodies"`
+	Stream          StreamConfig               `json:"stream" yaml:"stream"`
+	Pagination      HTTPClientPaginationConfig `json:"pagination" yaml:"pagination"`
 }
 
 // NewHTTPClientConfig creates a new HTTPClientConfig with default values.
@@ -132,6 +201,7 @@ func NewHTTPClientConfig() HTTPClientConfig {
 			MaxBuffer: 1000000,
 			Delim:     "",
 		},
+		Pagination: NewHTTPClientPaginationConfig(),
 	}
 }
 
@@ -150,6 +220,15 @@ type HTTPClient struct {
 
 	codecMut sync.Mutex
 	codec    codec.Reader
+
+	sse          bool
+	sseConnected bool
+	lastEventID  string
+	sseRetry     time.Duration
+
+	paginator *httpClientPaginator
+
+	log log.Modular
 }
 
 // NewHTTPClient creates a new HTTPClient input type.
@@ -162,9 +241,19 @@ func NewHTTPClient(conf Config, mgr types.Manager, log log.Modular, stats metric
 }
 
 func newHTTPClient(conf HTTPClientConfig, mgr types.Manager, log log.Modular, stats metrics.Type) (*HTTPClient, error) {
-	var codecCtor codec.ReaderConstructor
+	h := &HTTPClient{
+		prevResponse: message.New(nil),
+		log:          log,
+	}
 
-	if conf.Stream.Enabled {
+	if conf.Stream.Enabled && conf.Stream.Codec == "sse" {
+		// Timeout should be left at zero if we are streaming.
+		conf.Timeout = ""
+		h.sse = true
+		h.codecCtor = func(_ string, r io.ReadCloser, fn codec.ReaderAckFn) (codec.Reader, error) {
+			return newSSEReader(r, conf.Stream.MaxBuffer, fn, &h.lastEventID, &h.sseRetry), nil
+		}
+	} else if conf.Stream.Enabled {
 		// Timeout should be left at zero if we are streaming.
 		conf.Timeout = ""
 		if len(conf.Stream.Delim) > 0 {
@@ -177,35 +266,58 @@ func newHTTPClient(conf HTTPClientConfig, mgr types.Manager, log log.Modular, st
 		codecConf.MaxScanTokenSize = conf.Stream.MaxBuffer
 
 		var err error
-		if codecCtor, err = codec.GetReader(conf.Stream.Codec, codecConf); err != nil {
+		if h.codecCtor, err = codec.GetReader(conf.Stream.Codec, codecConf); err != nil {
+			return nil, err
+		}
+	}
+	h.conf = conf
+
+	if conf.Stream.Enabled && conf.RetryWhen != "" {
+		return nil, errors.New("retry_when cannot be used in streaming mode")
+	}
+	if conf.Pagination.Enabled {
+		if conf.Stream.Enabled {
+			return nil, errors.New("pagination cannot be enabled in streaming mode")
+		}
+		var err error
+		if h.paginator, err = newHTTPClientPaginator(conf.Pagination, mgr); err != nil {
 			return nil, err
 		}
 	}
 
-	var payload types.Message = message.New(nil)
+	h.payload = message.New(nil)
 	if len(conf.Payload) > 0 {
-		payload = message.New([][]byte{[]byte(conf.Payload)})
+		h.payload = message.New([][]byte{[]byte(conf.Payload)})
 	}
 
 	cMgr, cLog, cStats := interop.LabelChild("client", mgr, log, stats)
-	client, err := http.NewClient(
-		conf.Config,
+	opts := []func(*http.Client){
 		http.OptSetManager(cMgr),
 		http.OptSetLogger(cLog),
 		http.OptSetStats(cStats),
-	)
-	if err != nil {
-		return nil, err
+	}
+	if h.sse {
+		opts = append(opts, http.OptSetRequestHook(h.sseRequestHook))
+	}
+	if h.paginator != nil {
+		opts = append(opts, http.OptSetRequestHook(h.paginator.requestHook))
 	}
 
-	return &HTTPClient{
-		conf:         conf,
-		payload:      payload,
-		prevResponse: message.New(nil),
-		client:       client,
+	var err error
+	if h.client, err = http.NewClient(conf.Config, opts...); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
 
-		codecCtor: codecCtor,
-	}, nil
+// sseRequestHook adds the headers of a server-sent events request, which is
+// only called whilst the codec mutex is held.
+func (h *HTTPClient) sseRequestHook(req *nethttp.Request) {
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if h.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", h.lastEventID)
+	}
 }
 
 //------------------------------------------------------------------------------
@@ -223,6 +335,14 @@ func (h *HTTPClient) ConnectWithContext(ctx context.Context) (err error) {
 		return nil
 	}
 
+	if h.sse && h.sseConnected && h.sseRetry > 0 {
+		select {
+		case <-time.After(h.sseRetry):
+		case <-ctx.Done():
+			return types.ErrTimeout
+		}
+	}
+
 	res, err := h.client.SendToResponse(context.Background(), h.payload, h.prevResponse)
 	if err != nil {
 		if strings.Contains(err.Error(), "(Client.Timeout exceeded while awaiting headers)") {
@@ -247,6 +367,7 @@ func (h *HTTPClient) ConnectWithContext(ctx context.Context) (err error) {
 		res.Body.Close()
 		return err
 	}
+	h.sseConnected = true
 	return nil
 }
 
@@ -313,6 +434,10 @@ func (h *HTTPClient) readStreamed(ctx context.Context) (types.Message, reader.As
 }
 
 func (h *HTTPClient) readNotStreamed(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	if h.paginator != nil {
+		return h.readPage(ctx)
+	}
+
 	msg, err := h.client.Send(ctx, h.payload, h.prevResponse)
 	if err != nil {
 		if strings.Contains(err.Error(), "(Client.Timeout exceeded while awaiting headers)") {
@@ -334,6 +459,48 @@ func (h *HTTPClient) readNotStreamed(ctx context.Context) (types.Message, reader
 	}, nil
 }
 
+// readPage requests the next page of a paginated API, determining the page
+// that follows from the response. When the next page cannot be determined the
+// page is discarded and requested again.
+func (h *HTTPClient) readPage(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	if err := h.paginator.waitForPage(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	res, err := h.client.SendToResponse(ctx, h.payload, h.prevResponse)
+	if err != nil {
+		if strings.Contains(err.Error(), "(Client.Timeout exceeded while awaiting headers)") {
+			err = types.ErrTimeout
+		}
+		return nil, nil, err
+	}
+	msg, err := h.client.ParseResponse(res)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page := h.paginator.pageMeta()
+	msg.Iter(func(i int, p types.Part) error {
+		p.Metadata().Set("http_client_page", page)
+		return nil
+	})
+	if err = h.paginator.advance(res, msg); err != nil {
+		return nil, nil, fmt.Errorf("failed to determine next page: %w", err)
+	}
+
+	if msg.Len() == 0 {
+		return nil, nil, types.ErrTimeout
+	}
+	if msg.Len() == 1 && msg.Get(0).IsEmpty() && h.conf.DropEmptyBodies {
+		return nil, nil, types.ErrTimeout
+	}
+
+	h.prevResponse = msg
+	return msg.Copy(), func(context.Context, types.Response) error {
+		return nil
+	}, nil
+}
+
 // CloseAsync shuts down the HTTPClient input and stops processing requests.
 func (h *HTTPClient) CloseAsync() {
 	h.client.Close(context.Background())
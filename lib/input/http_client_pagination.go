@@ -0,0 +1,256 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// HTTPClientPaginationConfig contains fields for following the pages of a
+// paginated API.
+type HTTPClientPaginationConfig struct {
+	Enabled         bool   `json:"enabled" yaml:"enabled"`
+	NextURL         string `json:"next_url" yaml:"next_url"`
+	StopWhen        string `json:"stop_when" yaml:"stop_when"`
+	MaxPages        int    `json:"max_pages" yaml:"max_pages"`
+	RateLimit       string `json:"rate_limit" yaml:"rate_limit"`
+	RestartInterval string `json:"restart_interval" yaml:"restart_interval"`
+}
+
+// NewHTTPClientPaginationConfig creates a new HTTPClientPaginationConfig with
+// default values.
+func NewHTTPClientPaginationConfig() HTTPClientPaginationConfig {
+	return HTTPClientPaginationConfig{
+		Enabled:         false,
+		NextURL:         "",
+		StopWhen:        "",
+		MaxPages:        0,
+		RateLimit:       "",
+		RestartInterval: "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// httpClientPaginator tracks the state of a paginated series of requests,
+// which begins with the configured URL of the input and follows the next page
+// of each response until pagination completes.
+type httpClientPaginator struct {
+	conf HTTPClientPaginationConfig
+	mgr  types.Manager
+
+	nextURLMapping  *mapping.Executor
+	stopWhen        *mapping.Executor
+	restartInterval time.Duration
+
+	page      int
+	nextURL   *url.URL
+	done      bool
+	restartAt time.Time
+}
+
+func newHTTPClientPaginator(conf HTTPClientPaginationConfig, mgr types.Manager) (*httpClientPaginator, error) {
+	p := &httpClientPaginator{
+		conf: conf,
+		mgr:  mgr,
+	}
+
+	var err error
+	if conf.NextURL != "" {
+		if p.nextURLMapping, err = interop.NewBloblangMapping(mgr, conf.NextURL); err != nil {
+			return nil, fmt.Errorf("failed to parse next_url mapping: %w", err)
+		}
+	}
+	if conf.StopWhen != "" {
+		if p.stopWhen, err = interop.NewBloblangMapping(mgr, conf.StopWhen); err != nil {
+			return nil, fmt.Errorf("failed to parse stop_when query: %w", err)
+		}
+	}
+	if conf.RestartInterval != "" {
+		if p.restartInterval, err = time.ParseDuration(conf.RestartInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse restart_interval string: %w", err)
+		}
+	}
+	if conf.RateLimit != "" {
+		if err = interop.ProbeRateLimit(context.Background(), mgr, conf.RateLimit); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// waitForPage blocks until the next page can be requested, which includes
+// waiting for a restart once pagination has completed and for access to the
+// rate limit. Returns types.ErrTypeClosed when pagination has completed and
+// will not be restarted.
+func (p *httpClientPaginator) waitForPage(ctx context.Context) error {
+	if p.done {
+		if p.restartInterval <= 0 {
+			return types.ErrTypeClosed
+		}
+		if p.restartAt.IsZero() {
+			p.restartAt = time.Now().Add(p.restartInterval)
+		}
+		select {
+		case <-time.After(time.Until(p.restartAt)):
+		case <-ctx.Done():
+			return types.ErrTimeout
+		}
+		p.page, p.nextURL, p.done, p.restartAt = 0, nil, false, time.Time{}
+	}
+
+	if p.conf.RateLimit == "" {
+		return nil
+	}
+	for {
+		var tUntil time.Duration
+		var err error
+		if rerr := interop.AccessRateLimit(ctx, p.mgr, p.conf.RateLimit, func(rl types.RateLimit) {
+			tUntil, err = rl.Access()
+		}); rerr != nil {
+			err = rerr
+		}
+		if err != nil {
+			return err
+		}
+		if tUntil <= 0 {
+			return nil
+		}
+		select {
+		case <-time.After(tUntil):
+		case <-ctx.Done():
+			return types.ErrTimeout
+		}
+	}
+}
+
+// requestHook points a request at the URL of the next page.
+func (p *httpClientPaginator) requestHook(req *http.Request) {
+	if p.nextURL == nil {
+		return
+	}
+	if req.Host == req.URL.Host {
+		req.Host = ""
+	}
+	req.URL = p.nextURL
+}
+
+// pageMeta returns the value of the http_client_page metadata field for the
+// messages of the page currently being consumed.
+func (p *httpClientPaginator) pageMeta() string {
+	return strconv.Itoa(p.page + 1)
+}
+
+// advance determines the next page from a response, where msg is the parsed
+// response with the page metadata added. Returns an error when the next page
+// could not be determined, in which case the paginator remains on the current
+// page so that it is requested again.
+func (p *httpClientPaginator) advance(res *http.Response, msg types.Message) error {
+	nextURL, err := p.nextPage(res, msg)
+	if err != nil {
+		return err
+	}
+	p.page++
+	p.nextURL = nextURL
+	p.done = nextURL == nil
+	return nil
+}
+
+// nextPage returns the URL of the page that follows a response, or nil when
+// pagination is complete.
+func (p *httpClientPaginator) nextPage(res *http.Response, msg types.Message) (*url.URL, error) {
+	if p.conf.MaxPages > 0 && p.page+1 >= p.conf.MaxPages {
+		return nil, nil
+	}
+	if p.stopWhen != nil && msg.Len() > 0 {
+		stop, err := p.stopWhen.QueryPart(0, msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute stop_when query: %w", err)
+		}
+		if stop {
+			return nil, nil
+		}
+	}
+
+	var next string
+	if p.nextURLMapping != nil {
+		if msg.Len() == 0 {
+			return nil, nil
+		}
+		part, err := p.nextURLMapping.MapPart(0, msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute next_url mapping: %w", err)
+		}
+		if part == nil {
+			return nil, nil
+		}
+		if next = string(part.Get()); next == "null" {
+			next = ""
+		}
+	} else {
+		for _, v := range res.Header.Values("Link") {
+			if next = parseLinkNext(v); next != "" {
+				break
+			}
+		}
+	}
+	if next == "" {
+		return nil, nil
+	}
+
+	nextURL, err := url.Parse(next)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse next page url: %w", err)
+	}
+	if res.Request != nil && res.Request.URL != nil {
+		nextURL = res.Request.URL.ResolveReference(nextURL)
+	}
+	if !nextURL.IsAbs() {
+		return nil, errors.New("next page url must be absolute")
+	}
+	return nextURL, nil
+}
+
+// parseLinkNext returns the target of the link with the relation type next
+// from the value of a Link header as described in RFC 8288, or an empty string
+// if there isn't one.
+func parseLinkNext(header string) string {
+	for len(header) > 0 {
+		start := strings.IndexByte(header, '<')
+		if start < 0 {
+			return ""
+		}
+		end := strings.IndexByte(header[start:], '>')
+		if end < 0 {
+			return ""
+		}
+		target := header[start+1 : start+end]
+		header = header[start+end+1:]
+
+		params := header
+		if next := strings.IndexByte(header, '<'); next >= 0 {
+			params = header[:next]
+		}
+		for _, param := range strings.Split(params, ";") {
+			kv := strings.SplitN(strings.TrimSpace(strings.TrimRight(strings.TrimSpace(param), ",")), "=", 2)
+			if len(kv) != 2 || !strings.EqualFold(strings.TrimSpace(kv[0]), "rel") {
+				continue
+			}
+			for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(kv[1]), `"`)) {
+				if strings.EqualFold(rel, "next") {
+					return target
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/codec"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// sseReader consumes a text/event-stream body as a stream of server-sent
+// events, where each event is a message with the metadata fields sse_event and
+// sse_id.
+type sseReader struct {
+	body  io.ReadCloser
+	buf   *bufio.Reader
+	ackFn codec.ReaderAckFn
+
+	// Shared with the input so that they survive reconnects.
+	lastEventID *string
+	retry       *time.Duration
+}
+
+func newSSEReader(body io.ReadCloser, maxBuffer int, ackFn codec.ReaderAckFn, lastEventID *string, retry *time.Duration) *sseReader {
+	return &sseReader{
+		body:        body,
+		buf:         bufio.NewReaderSize(body, maxBuffer),
+		ackFn:       ackFn,
+		lastEventID: lastEventID,
+		retry:       retry,
+	}
+}
+
+func (s *sseReader) readLine() (string, error) {
+	line, err := s.buf.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && line != "" {
+			// A partial line at the end of the stream is discarded as the
+			// event it belongs to is incomplete.
+			return "", io.EOF
+		}
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// Next reads fields until the end of an event, skipping comments and events
+// without data.
+func (s *sseReader) Next(ctx context.Context) ([]types.Part, codec.ReaderAckFn, error) {
+	var data bytes.Buffer
+	var hasData bool
+	event := ""
+
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if line == "" {
+			if !hasData {
+				event = ""
+				continue
+			}
+			if event == "" {
+				event = "message"
+			}
+			part := message.NewPart(bytes.TrimSuffix(data.Bytes(), []byte("\n")))
+			part.Metadata().Set("sse_event", event)
+			part.Metadata().Set("sse_id", *s.lastEventID)
+			return []types.Part{part}, func(context.Context, error) error {
+				return nil
+			}, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		name, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			name, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+
+		switch name {
+		case "event":
+			event = value
+		case "data":
+			hasData = true
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				*s.lastEventID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 64); err == nil {
+				*s.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+func (s *sseReader) Close(ctx context.Context) error {
+	err := s.body.Close()
+	_ = s.ackFn(ctx, nil)
+	return err
+}
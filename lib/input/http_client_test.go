@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/textproto"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -141,6 +142,159 @@ func TestHTTPClientPagination(t *testing.T) {
 	}
 }
 
+func TestHTTPClientPaginationStrategies(t *testing.T) {
+	t.Parallel()
+
+	var flakyMut sync.Mutex
+	flakyAttempts := map[int]int{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		switch r.URL.Path {
+		case "/flaky":
+			flakyMut.Lock()
+			flakyAttempts[page]++
+			attempt := flakyAttempts[page]
+			flakyMut.Unlock()
+			if page == 1 && attempt == 1 {
+				fmt.Fprint(w, `not json`)
+				return
+			}
+			fmt.Fprintf(w, `{"page":%v}`, page)
+		case "/cursor":
+			next := "null"
+			if page < 3 {
+				next = fmt.Sprintf(`"c%v"`, page+1)
+			}
+			fmt.Fprintf(w, `{"page":%v,"next":%v}`, page, next)
+		case "/link":
+			if page < 3 {
+				w.Header().Add("Link", `<https://example.com/other>; rel="prev"`)
+				w.Header().Add("Link", fmt.Sprintf(`</link?page=%v>; rel="last next"`, page+1))
+			}
+			fmt.Fprintf(w, `{"page":%v}`, page)
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		conf     func(c *HTTPClientConfig)
+		expected []string
+	}{
+		{
+			name: "next url mapping",
+			conf: func(c *HTTPClientConfig) {
+				c.URL = ts.URL + "/cursor"
+				c.Pagination.NextURL = `root = if this.next != null { "/cursor?page=" + this.next.slice(1) } else { null }`
+			},
+			expected: []string{
+				`1:{"page":0,"next":"c1"}`,
+				`2:{"page":1,"next":"c2"}`,
+				`3:{"page":2,"next":"c3"}`,
+				`4:{"page":3,"next":null}`,
+			},
+		},
+		{
+			name: "link header",
+			conf: func(c *HTTPClientConfig) {
+				c.URL = ts.URL + "/link?page=1"
+			},
+			expected: []string{
+				`1:{"page":1}`,
+				`2:{"page":2}`,
+				`3:{"page":3}`,
+			},
+		},
+		{
+			name: "stop when",
+			conf: func(c *HTTPClientConfig) {
+				c.URL = ts.URL + "/link"
+				c.Pagination.StopWhen = `this.page == 2`
+			},
+			expected: []string{
+				`1:{"page":0}`,
+				`2:{"page":1}`,
+				`3:{"page":2}`,
+			},
+		},
+		{
+			name: "next url mapping error",
+			conf: func(c *HTTPClientConfig) {
+				c.URL = ts.URL + "/flaky"
+				c.Pagination.NextURL = `root = if this.page < 2 { "/flaky?page=" + (this.page + 1).string() } else { null }`
+			},
+			expected: []string{
+				`1:{"page":0}`,
+				`2:{"page":1}`,
+				`3:{"page":2}`,
+			},
+		},
+		{
+			name: "max pages",
+			conf: func(c *HTTPClientConfig) {
+				c.URL = ts.URL + "/link"
+				c.Pagination.MaxPages = 2
+			},
+			expected: []string{
+				`1:{"page":0}`,
+				`2:{"page":1}`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf := NewConfig()
+			conf.HTTPClient.Retry = "1ms"
+			conf.HTTPClient.Pagination.Enabled = true
+			test.conf(&conf.HTTPClient)
+
+			h, err := NewHTTPClient(conf, nil, log.Noop(), metrics.Noop())
+			require.NoError(t, err)
+
+			var actual []string
+			for open := true; open; {
+				select {
+				case tr, ok := <-h.TransactionChan():
+					if open = ok; !ok {
+						break
+					}
+					require.Equal(t, 1, tr.Payload.Len())
+					p := tr.Payload.Get(0)
+					actual = append(actual, p.Metadata().Get("http_client_page")+":"+string(p.Get()))
+					select {
+					case tr.ResponseChan <- response.NewAck():
+					case <-time.After(time.Second):
+						t.Fatal("Action timed out")
+					}
+				case <-time.After(time.Second * 5):
+					t.Fatal("Action timed out")
+				}
+			}
+			assert.Equal(t, test.expected, actual)
+
+			h.CloseAsync()
+			assert.NoError(t, h.WaitForClose(time.Second))
+		})
+	}
+}
+
+func TestParseLinkNext(t *testing.T) {
+	tests := map[string]string{
+		``:                                    "",
+		`<http://a/b>; rel="prev"`:            "",
+		`<http://a/b>; rel="next"`:            "http://a/b",
+		`<http://a/b>; rel=next`:              "http://a/b",
+		`<http://a/b?x=1,2>; rel="last NEXT"`: "http://a/b?x=1,2",
+		`<http://a/p>; rel="prev", <http://a/n>; title="foo"; rel="next"`: "http://a/n",
+	}
+	for input, exp := range tests {
+		assert.Equal(t, exp, parseLinkNext(input), input)
+	}
+}
+
 func TestHTTPClientGETError(t *testing.T) {
 	t.Parallel()
 
@@ -900,3 +1054,83 @@ func BenchmarkHTTPClientGETMultipart(b *testing.B) {
 		b.Error(err)
 	}
 }
+
+func TestHTTPClientStreamSSE(t *testing.T) {
+	var reqMut sync.Mutex
+	var lastEventIDs []string
+
+	tserve := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+
+		reqMut.Lock()
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		reqMut.Unlock()
+
+		w.Header().Add("Content-Type", "text/event-stream")
+		if r.Header.Get("Last-Event-ID") == "" {
+			w.Write([]byte(`retry: 10
+: this is a comment
+
+id: 1
+data: first
+
+event: update
+id: 2
+data: second line 1
+data: second line 2
+
+id: 3
+
+`))
+		} else {
+			w.Write([]byte("id: 4\r\nevent: update\r\ndata:no space\r\n\r\ndata: incomplete"))
+		}
+	}))
+	defer tserve.Close()
+
+	conf := NewConfig()
+	conf.HTTPClient.URL = tserve.URL + "/testsse"
+	conf.HTTPClient.Retry = "1ms"
+	conf.HTTPClient.Stream.Enabled = true
+	conf.HTTPClient.Stream.Codec = "sse"
+
+	h, err := NewHTTPClient(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	type event struct {
+		data, name, id string
+	}
+	expected := []event{
+		{data: "first", name: "message", id: "1"},
+		{data: "second line 1\nsecond line 2", name: "update", id: "2"},
+		{data: "no space", name: "update", id: "4"},
+	}
+	for _, exp := range expected {
+		var ts types.Transaction
+		select {
+		case ts = <-h.TransactionChan():
+		case <-time.After(time.Second):
+			t.Fatal("Action timed out")
+		}
+		require.Equal(t, 1, ts.Payload.Len())
+		part := ts.Payload.Get(0)
+		assert.Equal(t, exp, event{
+			data: string(part.Get()),
+			name: part.Metadata().Get("sse_event"),
+			id:   part.Metadata().Get("sse_id"),
+		})
+
+		select {
+		case ts.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("Action timed out")
+		}
+	}
+
+	h.CloseAsync()
+	require.NoError(t, h.WaitForClose(time.Second))
+
+	reqMut.Lock()
+	assert.Equal(t, []string{"", "3"}, lastEventIDs[:2])
+	reqMut.Unlock()
+}
@@ -3,6 +3,7 @@ package input
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -31,6 +32,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 	"github.com/opentracing/opentracing-go"
+	jsonschema "github.com/xeipuuv/gojsonschema"
 )
 
 //------------------------------------------------------------------------------
@@ -87,6 +89,14 @@ established.
 It's also possible to specify a ` + "`ws_rate_limit_message`" + `, which is a
 static payload to be sent to clients that have triggered the servers rate limit.
 
+### Routes
+
+In order to serve multiple endpoints from a single input it's possible to specify a list of ` + "`routes`" + `, each with a path, a list of allowed methods and optionally its own ` + "`sync_response`" + `, where any fields that are not set are inherited from the top level ` + "`sync_response`" + `. Requests to a route are consumed in the same way as requests to ` + "`path`" + `, and path parameters of the form ` + "`/{foo}`" + ` are added to messages as metadata. The metadata field ` + "`http_server_route`" + ` is set to the path template of the route that received a request, which allows the pipeline to branch on it.
+
+Routes may also specify a ` + "`schema`" + ` or ` + "`schema_path`" + `, in which case the body of each request is parsed as JSON and validated against it. Requests that fail validation are rejected with a 400 response describing the violations, and are never consumed by the pipeline. The schema document is written in JSON or YAML and is either a [JSON Schema](https://json-schema.org/), which applies to all methods of the route, or an [OpenAPI 3](https://swagger.io/specification/) document, where the request body schema of the operation with the same path template and method as the route is used. The document must contain an operation for each method of the route, and operations without a JSON request body are not validated.
+
+Note that OpenAPI schemas are validated as JSON Schema, and therefore OpenAPI specific keywords such as ` + "`nullable`" + ` are ignored.
+
 ### Metadata
 
 This input adds the following metadata fields to each message:
@@ -95,6 +105,7 @@ This input adds the following metadata fields to each message:
 - http_server_user_agent
 - http_server_request_path
 - http_server_verb
+- http_server_route (only for requests to ` + "`routes`" + `)
 - All headers (only first values are taken)
 - All query parameters
 - All path parameters
@@ -110,6 +121,25 @@ You can access these metadata fields using
 			docs.FieldAdvanced("ws_welcome_message", "An optional message to deliver to fresh websocket connections."),
 			docs.FieldAdvanced("ws_rate_limit_message", "An optional message to delivery to websocket connections that are rate limited."),
 			docs.FieldCommon("allowed_verbs", "An array of verbs that are allowed for the `path` endpoint.").AtVersion("3.33.0").Array(),
+			docs.FieldAdvanced(
+				"routes", "A list of additional endpoints to consume requests from, each with their own allowed methods, optional request body validation and synchronous responses.",
+				[]interface{}{
+					map[string]interface{}{
+						"path":        "/orders/{id}",
+						"methods":     []interface{}{"PUT"},
+						"schema_path": "./openapi.yaml",
+					},
+				},
+			).Array().WithChildren(
+				docs.FieldString("path", "The path template of the endpoint, which may contain path parameters of the form `/{foo}`.", "/orders/{id}").HasDefault(""),
+				docs.FieldString("methods", "An array of methods that are allowed for the endpoint.").Array().HasDefault([]string{"POST"}),
+				docs.FieldString("schema", "An optional JSON Schema or OpenAPI 3 document to validate request bodies against. Use either this or the `schema_path` field.").HasDefault(""),
+				docs.FieldString("schema_path", "An optional path of a JSON Schema or OpenAPI 3 document to validate request bodies against. Use either this or the `schema` field.").HasDefault(""),
+				docs.FieldAdvanced("sync_response", "Customise messages returned via [synchronous responses](/docs/guides/sync_responses) from this endpoint. Fields that are not set are inherited from the top level `sync_response`.").WithChildren(
+					docs.FieldString("status", "Specify the status code to return with synchronous responses.", "201", `${! meta("status") }`).IsInterpolated().HasDefault(""),
+					docs.FieldString("headers", "Specify headers to return with synchronous responses, which are added to the top level headers.").IsInterpolated().Map().HasDefault(map[string]string{}),
+				),
+			).AtVersion("3.60.0"),
 			docs.FieldCommon("timeout", "Timeout for requests. If a consumed messages takes longer than this to be delivered the connection is closed, but the message may still be delivered."),
 			docs.FieldCommon("rate_limit", "An optional [rate limit](/docs/components/rate_limits/about) to throttle requests by."),
 			docs.FieldAdvanced("cert_file", "Only valid with a custom `address`."),
@@ -150,6 +180,59 @@ func NewHTTPServerResponseConfig() HTTPServerResponseConfig {
 	}
 }
 
+// HTTPServerRouteConfig contains configuration fields for an additional
+// endpoint of the HTTPServer input type.
+type HTTPServerRouteConfig struct {
+	Path       string                   `json:"path" yaml:"path"`
+	Methods    []string                 `json:"methods" yaml:"methods"`
+	Schema     string                   `json:"schema" yaml:"schema"`
+	SchemaPath string                   `json:"schema_path" yaml:"schema_path"`
+	Response   HTTPServerResponseConfig `json:"sync_response" yaml:"sync_response"`
+}
+
+// NewHTTPServerRouteConfig creates a new HTTPServerRouteConfig with default
+// values.
+func NewHTTPServerRouteConfig() HTTPServerRouteConfig {
+	return HTTPServerRouteConfig{
+		Path:       "",
+		Methods:    []string{"POST"},
+		Schema:     "",
+		SchemaPath: "",
+		Response: HTTPServerResponseConfig{
+			Status:  "",
+			Headers: map[string]string{},
+		},
+	}
+}
+
+// UnmarshalJSON ensures that when parsing configs that are in a slice the
+// default values are still applied.
+func (r *HTTPServerRouteConfig) UnmarshalJSON(bytes []byte) error {
+	type confAlias HTTPServerRouteConfig
+	aliased := confAlias(NewHTTPServerRouteConfig())
+
+	if err := json.Unmarshal(bytes, &aliased); err != nil {
+		return err
+	}
+
+	*r = HTTPServerRouteConfig(aliased)
+	return nil
+}
+
+// UnmarshalYAML ensures that when parsing configs that are in a slice the
+// default values are still applied.
+func (r *HTTPServerRouteConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type confAlias HTTPServerRouteConfig
+	aliased := confAlias(NewHTTPServerRouteConfig())
+
+	if err := unmarshal(&aliased); err != nil {
+		return err
+	}
+
+	*r = HTTPServerRouteConfig(aliased)
+	return nil
+}
+
 // HTTPServerConfig contains configuration for the HTTPServer input type.
 type HTTPServerConfig struct {
 	Address            string                   `json:"address" yaml:"address"`
@@ -158,6 +241,7 @@ type HTTPServerConfig struct {
 	WSWelcomeMessage   string                   `json:"ws_welcome_message" yaml:"ws_welcome_message"`
 	WSRateLimitMessage string                   `json:"ws_rate_limit_message" yaml:"ws_rate_limit_message"`
 	AllowedVerbs       []string                 `json:"allowed_verbs" yaml:"allowed_verbs"`
+	Routes             []HTTPServerRouteConfig  `json:"routes" yaml:"routes"`
 	Timeout            string                   `json:"timeout" yaml:"timeout"`
 	RateLimit          string                   `json:"rate_limit" yaml:"rate_limit"`
 	CertFile           string                   `json:"cert_file" yaml:"cert_file"`
@@ -176,6 +260,7 @@ func NewHTTPServerConfig() HTTPServerConfig {
 		AllowedVerbs: []string{
 			"POST",
 		},
+		Routes:    []HTTPServerRouteConfig{},
 		Timeout:   "5s",
 		RateLimit: "",
 		CertFile:  "",
@@ -186,6 +271,16 @@ func NewHTTPServerConfig() HTTPServerConfig {
 
 //------------------------------------------------------------------------------
 
+// httpServerRoute is an endpoint of the HTTPServer input along with the
+// methods it allows and how to respond to requests.
+type httpServerRoute struct {
+	path            string
+	methods         map[string]struct{}
+	schemas         map[string]*jsonschema.Schema
+	responseStatus  *field.Expression
+	responseHeaders map[string]*field.Expression
+}
+
 // HTTPServer is an input type that registers a range of HTTP endpoints where
 // requests can send messages through Benthos. The endpoints are registered on
 // the general Benthos HTTP server by default. It is also possible to specify a
@@ -198,19 +293,17 @@ type HTTPServer struct {
 	mgr   types.Manager
 
 	mux     *http.ServeMux
+	router  *mux.Router
 	server  *http.Server
 	timeout time.Duration
 
-	responseStatus  *field.Expression
-	responseHeaders map[string]*field.Expression
+	routePaths []string
 
 	handlerWG    sync.WaitGroup
 	transactions chan types.Transaction
 
 	shutSig *shutdown.Signaller
 
-	allowedVerbs map[string]struct{}
-
 	// TODO: V4 Reduce this way down
 	mCount         metrics.StatCounter
 	mLatency       metrics.StatTimer
@@ -230,12 +323,17 @@ type HTTPServer struct {
 
 // NewHTTPServer creates a new HTTPServer input type.
 func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	var mux *http.ServeMux
+	var serveMux *http.ServeMux
+	var router *mux.Router
 	var server *http.Server
 
 	if len(conf.HTTPServer.Address) > 0 {
-		mux = http.NewServeMux()
-		server = &http.Server{Addr: conf.HTTPServer.Address, Handler: mux}
+		// Routes are served by a router that supports path templates, and
+		// falls back to the legacy endpoints.
+		serveMux = http.NewServeMux()
+		router = mux.NewRouter()
+		router.NotFoundHandler = serveMux
+		server = &http.Server{Addr: conf.HTTPServer.Address, Handler: router}
 	}
 
 	var timeout time.Duration
@@ -255,18 +353,16 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 	}
 
 	h := HTTPServer{
-		shutSig:         shutdown.NewSignaller(),
-		conf:            conf.HTTPServer,
-		stats:           stats,
-		log:             log,
-		mgr:             mgr,
-		mux:             mux,
-		server:          server,
-		timeout:         timeout,
-		responseHeaders: map[string]*field.Expression{},
-		transactions:    make(chan types.Transaction),
-
-		allowedVerbs: verbs,
+		shutSig:      shutdown.NewSignaller(),
+		conf:         conf.HTTPServer,
+		stats:        stats,
+		log:          log,
+		mgr:          mgr,
+		mux:          serveMux,
+		router:       router,
+		server:       server,
+		timeout:      timeout,
+		transactions: make(chan types.Transaction),
 
 		mCount:         stats.GetCounter("count"),
 		mLatency:       stats.GetTimer("latency"),
@@ -284,24 +380,55 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 		mAsyncSucc:     stats.GetCounter("send.async_success"),
 	}
 
+	defaultRoute := &httpServerRoute{
+		path:            h.conf.Path,
+		methods:         verbs,
+		responseHeaders: map[string]*field.Expression{},
+	}
+
 	var err error
-	if h.responseStatus, err = interop.NewBloblangField(mgr, h.conf.Response.Status); err != nil {
+	if defaultRoute.responseStatus, err = interop.NewBloblangField(mgr, h.conf.Response.Status); err != nil {
 		return nil, fmt.Errorf("failed to parse response status expression: %v", err)
 	}
 	for k, v := range h.conf.Response.Headers {
-		if h.responseHeaders[k], err = interop.NewBloblangField(mgr, v); err != nil {
+		if defaultRoute.responseHeaders[k], err = interop.NewBloblangField(mgr, v); err != nil {
 			return nil, fmt.Errorf("failed to parse response header '%v' expression: %v", k, err)
 		}
 	}
 
-	postHdlr := httputil.GzipHandler(h.postHandler)
+	routesByPath := map[string][]*httpServerRoute{}
+	for i, rConf := range h.conf.Routes {
+		route, err := newHTTPServerRoute(mgr, rConf, defaultRoute)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create route %v: %w", i, err)
+		}
+		if route.path == h.conf.Path || route.path == h.conf.WSPath {
+			return nil, fmt.Errorf("route %v path '%v' collides with the path or ws_path of the input", i, route.path)
+		}
+		for _, existing := range routesByPath[route.path] {
+			for method := range route.methods {
+				if _, exists := existing.methods[method]; exists {
+					return nil, fmt.Errorf("route %v collides with another route for %v %v", i, method, route.path)
+				}
+			}
+		}
+		if _, exists := routesByPath[route.path]; !exists {
+			h.routePaths = append(h.routePaths, route.path)
+		}
+		routesByPath[route.path] = append(routesByPath[route.path], route)
+	}
+
+	postHdlr := httputil.GzipHandler(h.routeHandler([]*httpServerRoute{defaultRoute}))
 	wsHdlr := httputil.GzipHandler(h.wsHandler)
-	if mux != nil {
+	if serveMux != nil {
 		if len(h.conf.Path) > 0 {
-			mux.HandleFunc(h.conf.Path, postHdlr)
+			serveMux.HandleFunc(h.conf.Path, postHdlr)
 		}
 		if len(h.conf.WSPath) > 0 {
-			mux.HandleFunc(h.conf.WSPath, wsHdlr)
+			serveMux.HandleFunc(h.conf.WSPath, wsHdlr)
+		}
+		for _, path := range h.routePaths {
+			router.HandleFunc(path, httputil.GzipHandler(h.routeHandler(routesByPath[path])))
 		}
 	} else {
 		if len(h.conf.Path) > 0 {
@@ -314,6 +441,11 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 				h.conf.WSPath, "Post messages via websocket into Benthos.", wsHdlr,
 			)
 		}
+		for _, path := range h.routePaths {
+			mgr.RegisterEndpoint(
+				path, "Send a request into Benthos.", httputil.GzipHandler(h.routeHandler(routesByPath[path])),
+			)
+		}
 	}
 
 	if h.conf.RateLimit != "" {
@@ -326,6 +458,46 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 	return &h, nil
 }
 
+// newHTTPServerRoute creates a route from its config, where sync response
+// fields that are not set are inherited from a default route.
+func newHTTPServerRoute(mgr types.Manager, conf HTTPServerRouteConfig, defaultRoute *httpServerRoute) (*httpServerRoute, error) {
+	if conf.Path == "" {
+		return nil, errors.New("a path must be specified")
+	}
+	route := &httpServerRoute{
+		path:            conf.Path,
+		methods:         map[string]struct{}{},
+		responseStatus:  defaultRoute.responseStatus,
+		responseHeaders: map[string]*field.Expression{},
+	}
+	for _, m := range conf.Methods {
+		route.methods[m] = struct{}{}
+	}
+	if len(route.methods) == 0 {
+		return nil, errors.New("must provide at least one allowed method")
+	}
+
+	var err error
+	if route.schemas, err = loadRouteSchemas(conf); err != nil {
+		return nil, err
+	}
+
+	if conf.Response.Status != "" {
+		if route.responseStatus, err = interop.NewBloblangField(mgr, conf.Response.Status); err != nil {
+			return nil, fmt.Errorf("failed to parse response status expression: %v", err)
+		}
+	}
+	for k, v := range defaultRoute.responseHeaders {
+		route.responseHeaders[k] = v
+	}
+	for k, v := range conf.Response.Headers {
+		if route.responseHeaders[k], err = interop.NewBloblangField(mgr, v); err != nil {
+			return nil, fmt.Errorf("failed to parse response header '%v' expression: %v", k, err)
+		}
+	}
+	return route, nil
+}
+
 //------------------------------------------------------------------------------
 
 func (h *HTTPServer) extractMessageFromRequest(r *http.Request) (types.Message, error) {
@@ -398,16 +570,42 @@ func (h *HTTPServer) extractMessageFromRequest(r *http.Request) (types.Message,
 	return msg, nil
 }
 
-func (h *HTTPServer) postHandler(w http.ResponseWriter, r *http.Request) {
+// routeHandler returns a handler for a group of routes that share a path,
+// where requests are handled by the route that allows their method.
+func (h *HTTPServer) routeHandler(routes []*httpServerRoute) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, route := range routes {
+			if _, exists := route.methods[r.Method]; exists {
+				h.postHandler(route, w, r)
+				return
+			}
+		}
+		r.Body.Close()
+		http.Error(w, "Incorrect method", http.StatusMethodNotAllowed)
+	}
+}
+
+// validateMessage checks the parts of a message consumed by a route against
+// the request body schema of the method, if any.
+func (h *HTTPServer) validateMessage(route *httpServerRoute, method string, msg types.Message) error {
+	schema, exists := route.schemas[method]
+	if !exists {
+		return nil
+	}
+	return msg.Iter(func(i int, part types.Part) error {
+		jsonPart, err := part.JSON()
+		if err != nil {
+			return fmt.Errorf("failed to parse body as JSON: %v", err)
+		}
+		return validateAgainstSchema(schema, jsonPart)
+	})
+}
+
+func (h *HTTPServer) postHandler(route *httpServerRoute, w http.ResponseWriter, r *http.Request) {
 	h.handlerWG.Add(1)
 	defer h.handlerWG.Done()
 	defer r.Body.Close()
 
-	if _, exists := h.allowedVerbs[r.Method]; !exists {
-		http.Error(w, "Incorrect method", http.StatusMethodNotAllowed)
-		return
-	}
-
 	if h.conf.RateLimit != "" {
 		var tUntil time.Duration
 		var err error
@@ -438,13 +636,25 @@ func (h *HTTPServer) postHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tracing.FinishSpans(msg)
 
+	if route.path != h.conf.Path {
+		msg.Iter(func(i int, part types.Part) error {
+			part.Metadata().Set("http_server_route", route.path)
+			return nil
+		})
+	}
+	if err = h.validateMessage(route, r.Method, msg); err != nil {
+		http.Error(w, "Request body failed validation:\n"+err.Error(), http.StatusBadRequest)
+		h.log.Debugf("Request body failed validation: %v\n", err)
+		return
+	}
+
 	store := roundtrip.NewResultStore()
 	roundtrip.AddResultStore(msg, store)
 
 	h.mCount.Incr(1)
 	h.mPartsRcvd.Incr(int64(msg.Len()))
 	h.mRcvd.Incr(1)
-	h.log.Tracef("Consumed %v messages from POST to '%v'.\n", msg.Len(), h.conf.Path)
+	h.log.Tracef("Consumed %v messages from %v to '%v'.\n", msg.Len(), r.Method, r.URL.Path)
 
 	resChan := make(chan types.Response, 1)
 	select {
@@ -496,12 +706,12 @@ func (h *HTTPServer) postHandler(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 	if responseMsg.Len() > 0 {
-		for k, v := range h.responseHeaders {
+		for k, v := range route.responseHeaders {
 			w.Header().Set(k, v.String(0, responseMsg))
 		}
 
 		statusCode := 200
-		if statusCodeStr := h.responseStatus.String(0, responseMsg); statusCodeStr != "200" {
+		if statusCodeStr := route.responseStatus.String(0, responseMsg); statusCodeStr != "200" {
 			if statusCode, err = strconv.Atoi(statusCodeStr); err != nil {
 				h.log.Errorf("Failed to parse sync response status code expression: %v\n", err)
 				w.WriteHeader(http.StatusBadGateway)
@@ -517,7 +727,7 @@ func (h *HTTPServer) postHandler(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(statusCode)
 			w.Write(payload)
 		} else if plen > 1 {
-			customContentType, customContentTypeExists := h.responseHeaders["Content-Type"]
+			customContentType, customContentTypeExists := route.responseHeaders["Content-Type"]
 
 			var buf bytes.Buffer
 			writer := multipart.NewWriter(&buf)
@@ -694,6 +904,9 @@ func (h *HTTPServer) loop() {
 			if len(h.conf.WSPath) > 0 {
 				h.mgr.RegisterEndpoint(h.conf.WSPath, "Does nothing.", http.NotFound)
 			}
+			for _, path := range h.routePaths {
+				h.mgr.RegisterEndpoint(path, "Does nothing.", http.NotFound)
+			}
 		}
 
 		h.handlerWG.Wait()
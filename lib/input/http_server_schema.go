@@ -0,0 +1,210 @@
+package input
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	jsonschema "github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// normaliseSchemaDoc converts a document parsed from YAML into a structure that
+// can be serialised as JSON, where all map keys are strings.
+func normaliseSchemaDoc(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, v := range t {
+			t[k] = normaliseSchemaDoc(v)
+		}
+		return t
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[fmt.Sprintf("%v", k)] = normaliseSchemaDoc(v)
+		}
+		return m
+	case []interface{}:
+		for i, v := range t {
+			t[i] = normaliseSchemaDoc(v)
+		}
+		return t
+	}
+	return v
+}
+
+func escapeJSONPointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+// resolveJSONPointer returns the node of a document at a local JSON pointer
+// such as `#/components/requestBodies/Foo`.
+func resolveJSONPointer(doc interface{}, pointer string) (interface{}, error) {
+	if !strings.HasPrefix(pointer, "#/") {
+		return nil, fmt.Errorf("only local references are supported, got: %v", pointer)
+	}
+	node := doc
+	for _, seg := range strings.Split(strings.TrimPrefix(pointer, "#/"), "/") {
+		seg = strings.ReplaceAll(strings.ReplaceAll(seg, "~1", "/"), "~0", "~")
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("reference %v not found", pointer)
+		}
+		if node, ok = obj[seg]; !ok {
+			return nil, fmt.Errorf("reference %v not found", pointer)
+		}
+	}
+	return node, nil
+}
+
+// openAPIRequestBodyPointer returns a JSON pointer to the JSON schema of the
+// request body of an operation, or an empty string if the operation has no JSON
+// request body. An error is returned if the document does not contain the
+// operation.
+func openAPIRequestBodyPointer(doc map[string]interface{}, path, method string) (string, error) {
+	paths, _ := doc["paths"].(map[string]interface{})
+	pathItem, exists := paths[path].(map[string]interface{})
+	if !exists {
+		return "", fmt.Errorf("path %v not found in OpenAPI document", path)
+	}
+	operation, exists := pathItem[strings.ToLower(method)].(map[string]interface{})
+	if !exists {
+		return "", fmt.Errorf("method %v of path %v not found in OpenAPI document", method, path)
+	}
+	if _, exists := operation["requestBody"]; !exists {
+		return "", nil
+	}
+
+	pointer := "#/paths/" + escapeJSONPointer(path) + "/" + strings.ToLower(method) + "/requestBody"
+	node, err := resolveJSONPointer(doc, pointer)
+	if err != nil {
+		return "", err
+	}
+	body, _ := node.(map[string]interface{})
+	if ref, ok := body["$ref"].(string); ok {
+		pointer = ref
+		if node, err = resolveJSONPointer(doc, pointer); err != nil {
+			return "", err
+		}
+		body, _ = node.(map[string]interface{})
+	}
+
+	content, _ := body["content"].(map[string]interface{})
+	contentTypes := make([]string, 0, len(content))
+	for k := range content {
+		contentTypes = append(contentTypes, k)
+	}
+	sort.Strings(contentTypes)
+
+	var contentType string
+	if _, exists := content["application/json"]; exists {
+		contentType = "application/json"
+	} else {
+		for _, k := range contentTypes {
+			if strings.Contains(k, "json") {
+				contentType = k
+				break
+			}
+		}
+	}
+	if contentType == "" {
+		return "", nil
+	}
+	if media, _ := content[contentType].(map[string]interface{}); media == nil || media["schema"] == nil {
+		return "", nil
+	}
+	return pointer + "/content/" + escapeJSONPointer(contentType) + "/schema", nil
+}
+
+// loadRouteSchemas compiles the schemas used to validate request bodies of a
+// route for each of its methods. The document is either a JSON Schema, which
+// applies to all methods, or an OpenAPI 3 document, where the request body
+// schema of the operation that matches the path and method is used. Methods
+// without a schema are absent from the returned map.
+func loadRouteSchemas(conf HTTPServerRouteConfig) (map[string]*jsonschema.Schema, error) {
+	if conf.Schema != "" && conf.SchemaPath != "" {
+		return nil, errors.New("cannot specify both schema and schema_path")
+	}
+
+	docBytes, docURL := []byte(conf.Schema), "file:///benthos/routes"+conf.Path
+	if conf.SchemaPath != "" {
+		var err error
+		if docBytes, err = os.ReadFile(conf.SchemaPath); err != nil {
+			return nil, fmt.Errorf("failed to read schema_path: %w", err)
+		}
+		absPath, err := filepath.Abs(conf.SchemaPath)
+		if err != nil {
+			return nil, err
+		}
+		docURL = "file://" + filepath.ToSlash(absPath)
+	}
+
+	schemas := map[string]*jsonschema.Schema{}
+	if len(docBytes) == 0 {
+		return schemas, nil
+	}
+
+	var rawDoc interface{}
+	if err := yaml.Unmarshal(docBytes, &rawDoc); err != nil {
+		return nil, fmt.Errorf("failed to parse schema document: %w", err)
+	}
+	doc, ok := normaliseSchemaDoc(rawDoc).(map[string]interface{})
+	if !ok {
+		return nil, errors.New("expected schema document to be an object")
+	}
+
+	compile := func(pointer string) (*jsonschema.Schema, error) {
+		loader := jsonschema.NewSchemaLoader()
+		if err := loader.AddSchema(docURL, jsonschema.NewGoLoader(doc)); err != nil {
+			return nil, fmt.Errorf("failed to load schema document: %w", err)
+		}
+		return loader.Compile(jsonschema.NewGoLoader(map[string]interface{}{
+			"$ref": docURL + pointer,
+		}))
+	}
+
+	if _, isOpenAPI := doc["openapi"]; !isOpenAPI {
+		schema, err := compile("")
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile schema: %w", err)
+		}
+		for _, method := range conf.Methods {
+			schemas[method] = schema
+		}
+		return schemas, nil
+	}
+
+	for _, method := range conf.Methods {
+		pointer, err := openAPIRequestBodyPointer(doc, conf.Path, method)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find request body schema of %v %v: %w", method, conf.Path, err)
+		}
+		if pointer == "" {
+			continue
+		}
+		if schemas[method], err = compile(pointer); err != nil {
+			return nil, fmt.Errorf("failed to compile request body schema of %v %v: %w", method, conf.Path, err)
+		}
+	}
+	return schemas, nil
+}
+
+// validateAgainstSchema returns an error describing each violation of a schema
+// by a JSON document.
+func validateAgainstSchema(schema *jsonschema.Schema, doc interface{}) error {
+	result, err := schema.Validate(jsonschema.NewGoLoader(doc))
+	if err != nil {
+		return err
+	}
+	if result.Valid() {
+		return nil
+	}
+	errs := make([]string, 0, len(result.Errors()))
+	for _, desc := range result.Errors() {
+		errs = append(errs, desc.String())
+	}
+	return errors.New(strings.Join(errs, "\n"))
+}
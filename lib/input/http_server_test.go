@@ -13,6 +13,8 @@ import (
 	"net/http/httptest"
 	"net/textproto"
 	"net/url"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -1094,3 +1096,199 @@ func TestHTTPSyncResponseHeadersStatus(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestHTTPServerRoutes(t *testing.T) {
+	t.Parallel()
+
+	schemaPath := filepath.Join(t.TempDir(), "openapi.yaml")
+	require.NoError(t, os.WriteFile(schemaPath, []byte(`
+openapi: 3.0.0
+info:
+  title: Orders
+  version: 1.0.0
+paths:
+  /orders/{id}:
+    put:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Order'
+      responses:
+        200:
+          description: OK
+    delete:
+      responses:
+        200:
+          description: OK
+components:
+  schemas:
+    Order:
+      type: object
+      required: [ item ]
+      properties:
+        item:
+          type: string
+`), 0o644))
+
+	reg := apiRegGorillaMutWrapper{mut: mux.NewRouter()}
+	mgr, err := manager.New(manager.NewConfig(), reg, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	conf := input.NewConfig()
+	conf.HTTPServer.Path = "/testpost"
+	conf.HTTPServer.Response.Headers["foo"] = "bar"
+
+	putRoute := input.NewHTTPServerRouteConfig()
+	putRoute.Path = "/orders/{id}"
+	putRoute.Methods = []string{"PUT", "DELETE"}
+	putRoute.SchemaPath = schemaPath
+	putRoute.Response.Status = "201"
+
+	getRoute := input.NewHTTPServerRouteConfig()
+	getRoute.Path = "/orders/{id}"
+	getRoute.Methods = []string{"GET"}
+	getRoute.Response.Headers["foo"] = "baz"
+
+	conf.HTTPServer.Routes = append(conf.HTTPServer.Routes, putRoute, getRoute)
+
+	h, err := input.NewHTTPServer(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer func() {
+		h.CloseAsync()
+		assert.NoError(t, h.WaitForClose(time.Second))
+	}()
+
+	server := httptest.NewServer(reg.mut)
+	defer server.Close()
+
+	type result struct {
+		status int
+		body   string
+		foo    string
+	}
+	doRequest := func(method, path, body string) <-chan result {
+		resChan := make(chan result, 1)
+		go func() {
+			req, err := http.NewRequest(method, server.URL+path, bytes.NewReader([]byte(body)))
+			require.NoError(t, err)
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			resBytes, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			resChan <- result{status: res.StatusCode, body: string(resBytes), foo: res.Header.Get("foo")}
+		}()
+		return resChan
+	}
+
+	respond := func(method, route, id string) {
+		t.Helper()
+		select {
+		case ts := <-h.TransactionChan():
+			meta := ts.Payload.Get(0).Metadata()
+			assert.Equal(t, method, meta.Get("http_server_verb"))
+			assert.Equal(t, route, meta.Get("http_server_route"))
+			assert.Equal(t, id, meta.Get("id"))
+			roundtrip.SetAsResponse(ts.Payload)
+			select {
+			case ts.ResponseChan <- response.NewAck():
+			case <-time.After(time.Second):
+				t.Fatal("Timed out waiting for response")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for message")
+		}
+	}
+
+	resChan := doRequest("PUT", "/orders/foo", `{"item":"cup"}`)
+	respond("PUT", "/orders/{id}", "foo")
+	assert.Equal(t, result{status: 201, body: `{"item":"cup"}`, foo: "bar"}, <-resChan)
+
+	resChan = doRequest("GET", "/orders/bar", ``)
+	respond("GET", "/orders/{id}", "bar")
+	assert.Equal(t, result{status: 200, body: ``, foo: "baz"}, <-resChan)
+
+	// The DELETE operation has no request body in the OpenAPI document.
+	resChan = doRequest("DELETE", "/orders/baz", `not json`)
+	respond("DELETE", "/orders/{id}", "baz")
+	assert.Equal(t, 201, (<-resChan).status)
+
+	res := <-doRequest("PUT", "/orders/foo", `{"item":5}`)
+	assert.Equal(t, 400, res.status)
+	assert.Contains(t, res.body, "item: Invalid type. Expected: string, given: integer")
+
+	res = <-doRequest("PUT", "/orders/foo", `not json`)
+	assert.Equal(t, 400, res.status)
+
+	res = <-doRequest("POST", "/orders/foo", `{"item":"cup"}`)
+	assert.Equal(t, 405, res.status)
+
+	select {
+	case <-h.TransactionChan():
+		t.Error("Unexpected transaction for rejected request")
+	default:
+	}
+}
+
+func TestHTTPServerRoutesOpenAPIMissingOperation(t *testing.T) {
+	t.Parallel()
+
+	schemaPath := filepath.Join(t.TempDir(), "openapi.yaml")
+	require.NoError(t, os.WriteFile(schemaPath, []byte(`
+openapi: 3.0.0
+info:
+  title: Orders
+  version: 1.0.0
+paths:
+  /orders/{id}:
+    delete:
+      responses:
+        200:
+          description: OK
+`), 0o644))
+
+	conf := input.NewConfig()
+	conf.HTTPServer.Address = "localhost:0"
+
+	route := input.NewHTTPServerRouteConfig()
+	route.Path = "/orders/{id}"
+	route.Methods = []string{"PUT"}
+	route.SchemaPath = schemaPath
+	conf.HTTPServer.Routes = append(conf.HTTPServer.Routes, route)
+
+	_, err := input.NewHTTPServer(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "failed to create route 0: failed to find request body schema of PUT /orders/{id}: method PUT of path /orders/{id} not found in OpenAPI document")
+
+	route.Path = "/users/{id}"
+	conf.HTTPServer.Routes = []input.HTTPServerRouteConfig{route}
+	_, err = input.NewHTTPServer(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "failed to create route 0: failed to find request body schema of PUT /users/{id}: path /users/{id} not found in OpenAPI document")
+}
+
+func TestHTTPServerRoutesJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	conf := input.NewConfig()
+	conf.HTTPServer.Address = "localhost:0"
+
+	route := input.NewHTTPServerRouteConfig()
+	route.Path = "/events"
+	route.Schema = `{"type":"object","required":["type"]}`
+	conf.HTTPServer.Routes = append(conf.HTTPServer.Routes, route)
+
+	h, err := input.NewHTTPServer(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	h.CloseAsync()
+	require.NoError(t, h.WaitForClose(time.Second*5))
+
+	route.SchemaPath = "./foo.json"
+	conf.HTTPServer.Routes = []input.HTTPServerRouteConfig{route}
+	_, err = input.NewHTTPServer(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "failed to create route 0: cannot specify both schema and schema_path")
+
+	route.SchemaPath = ""
+	conf.HTTPServer.Routes = []input.HTTPServerRouteConfig{route, route}
+	_, err = input.NewHTTPServer(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "route 1 collides with another route for POST /events")
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/Jeffail/benthos/v3/internal/bundle"
 	imetrics "github.com/Jeffail/benthos/v3/internal/component/metrics"
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/api"
 	"github.com/Jeffail/benthos/v3/lib/buffer"
 	"github.com/Jeffail/benthos/v3/lib/cache"
 	"github.com/Jeffail/benthos/v3/lib/condition"
@@ -58,6 +59,10 @@ type Type struct {
 
 	apiReg APIReg
 
+	// Whether the cache endpoints registered with apiReg allow setting and
+	// deleting keys.
+	cacheWrites bool
+
 	inputs       map[string]types.Input
 	caches       map[string]types.Cache
 	processors   map[string]types.Processor
@@ -105,6 +110,15 @@ func OptSetBloblangEnvironment(env *bloblang.Environment) OptFunc {
 	}
 }
 
+// OptSetCacheWrites determines whether the HTTP endpoints registered by the
+// manager for cache resources allow setting and deleting keys, by default these
+// endpoints are read-only.
+func OptSetCacheWrites(allow bool) OptFunc {
+	return func(t *Type) {
+		t.cacheWrites = allow
+	}
+}
+
 // NewV2 returns an instance of manager.Type, which can be shared amongst
 // components and logical threads of a Benthos service.
 func NewV2(conf ResourceConfig, apiReg APIReg, log log.Modular, stats metrics.Type, opts ...OptFunc) (*Type, error) {
@@ -232,6 +246,18 @@ func NewV2(conf ResourceConfig, apiReg APIReg, log log.Modular, stats metrics.Ty
 		t.plugins[k] = newP
 	}
 
+	cacheAPI := api.NewCache(t.AccessCache, t.cacheWrites)
+	t.RegisterEndpoint(
+		"/resources/cache/{id}/keys",
+		"List the keys of a cache resource along with their TTLs.",
+		cacheAPI.HandleList,
+	)
+	t.RegisterEndpoint(
+		"/resources/cache/{id}/keys/{key:.+}",
+		"Get, set or delete a key of a cache resource, setting and deleting keys requires http.cache_writes.",
+		cacheAPI.HandleCRUD,
+	)
+
 	return t, nil
 }
 
@@ -305,6 +331,12 @@ func (t *Type) Label() string {
 	return t.component
 }
 
+// Stream returns the identifier of the stream that a manager is used by, which
+// is empty when the manager isn't used by a specific stream.
+func (t *Type) Stream() string {
+	return t.stream
+}
+
 //------------------------------------------------------------------------------
 
 // RegisterEndpoint registers a server wide HTTP endpoint.
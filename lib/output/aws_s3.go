@@ -90,6 +90,41 @@ output:
       processors:
         - archive:
             format: json_array
+` + "```" + `
+` + output.RollingDocs + `
+
+For example, in order to upload gzip compressed objects of newline delimited messages, with each object containing up to 100MB of data or an hour of messages, we could use the following config:
+
+` + "```yaml" + `
+output:
+  aws_s3:
+    bucket: TODO
+    path: logs/${!timestamp_unix_nano()}.log.gz
+    rolling:
+      enabled: true
+      max_in_flight: 1024
+      codec: gzip/lines
+      max_size: 104857600
+      max_age: 1h
+` + "```" + `
+` + output.PartitionCommitDocs + `
+
+For example, in order to upload rolling objects into hourly partitions and mark each partition as complete once messages of more than ten minutes past the hour have been written, we could use the following config:
+
+` + "```yaml" + `
+output:
+  aws_s3:
+    bucket: TODO
+    path: 'events/dt=${! json("created_at").format_timestamp(format: "2006-01-02", tz: "UTC") }/hour=${! json("created_at").format_timestamp(format: "15", tz: "UTC") }/${! uuid_v4() }.log'
+    rolling:
+      enabled: true
+      max_in_flight: 1024
+      max_age: 5m
+    partition_commit:
+      enabled: true
+      timestamp_mapping: root = this.created_at
+      period: 1h
+      allowed_lateness: 10m
 ` + "```" + ``,
 		Async: true,
 		FieldSpecs: docs.FieldSpecs{
@@ -122,6 +157,8 @@ output:
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
 			docs.FieldAdvanced("timeout", "The maximum period to wait on an upload before abandoning it and reattempting."),
 			batch.FieldSpec(),
+			docs.FieldAdvanced("rolling", "Optionally append messages to rolling objects that are streamed to the bucket with multipart uploads. Messages are only acknowledged once their object has been committed, and therefore whilst rolling is enabled `rolling.max_in_flight` is used instead of `max_in_flight` and also limits the number of messages (or batches) written to each object.").WithChildren(output.RollingFields()...).AtVersion("3.60.0"),
+			docs.FieldAdvanced("partition_commit", "Optionally track partitions of event time and commit them once complete.").WithChildren(output.PartitionCommitFields()...).AtVersion("3.60.0"),
 		}.Merge(session.FieldSpecs()),
 		Categories: []Category{
 			CategoryServices,
@@ -241,6 +278,8 @@ output:
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
 			docs.FieldAdvanced("timeout", "The maximum period to wait on an upload before abandoning it and reattempting."),
 			batch.FieldSpec(),
+			docs.FieldAdvanced("rolling", "Optionally append messages to rolling objects that are streamed to the bucket with multipart uploads. Messages are only acknowledged once their object has been committed, and therefore whilst rolling is enabled `rolling.max_in_flight` is used instead of `max_in_flight` and also limits the number of messages (or batches) written to each object.").WithChildren(output.RollingFields()...).AtVersion("3.60.0"),
+			docs.FieldAdvanced("partition_commit", "Optionally track partitions of event time and commit them once complete.").WithChildren(output.PartitionCommitFields()...).AtVersion("3.60.0"),
 		}.Merge(session.FieldSpecs()),
 		Categories: []Category{
 			CategoryServices,
@@ -267,7 +306,11 @@ func newAmazonS3(name string, conf writer.AmazonS3Config, mgr types.Manager, log
 		return nil, err
 	}
 
-	w, err := NewAsyncWriter(name, conf.MaxInFlight, sthree, log, stats)
+	maxInFlight := conf.MaxInFlight
+	if conf.Rolling.Enabled {
+		maxInFlight = conf.Rolling.MaxInFlight
+	}
+	w, err := NewAsyncWriter(name, maxInFlight, sthree, log, stats)
 	if err != nil {
 		return nil, err
 	}
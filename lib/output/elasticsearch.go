@@ -26,6 +26,43 @@ Both the ` + "`id` and `index`" + ` fields can be dynamically set using function
 interpolations described [here](/docs/configuration/interpolation#bloblang-queries). When
 sending batched messages these interpolations are performed per message part.
 
+### Error Handling
+
+The response of each document within a bulk request is checked individually.
+Documents that fail with a status that is likely to be temporary (` + "`429`" + `
+or ` + "`5xx`" + `) are retried according to the backoff fields, and documents
+that fail with any other status, such as a mapping error or a version
+conflict, are rejected without being retried.
+
+Rejected messages are given the metadata fields
+` + "`elasticsearch_error_status`, `elasticsearch_error_type` and `elasticsearch_error_reason`" + `
+and can be routed elsewhere with outputs such as
+[` + "`fallback`" + `](/docs/components/outputs/fallback), where only the
+rejected messages of a batch are passed on:
+
+` + "```yaml" + `
+output:
+  fallback:
+    - elasticsearch:
+        urls: [ http://localhost:9200 ]
+        index: foo
+        id: ${! json("id") }
+    - file:
+        path: ./rejected.jsonl
+      processors:
+        - bloblang: |
+            root.doc = this
+            root.reason = meta("elasticsearch_error_reason")
+` + "```" + `
+
+### Scripted Updates
+
+The ` + "`update` and `upsert`" + ` actions can execute a script on the
+existing document by setting ` + "`script.source`" + `, with parameters
+provided by the mapping ` + "`script.params_mapping`" + `. When a script is
+used with the ` + "`upsert`" + ` action the message is indexed as the
+document when it does not already exist.
+
 ### AWS
 
 It's possible to enable AWS connectivity with this output using the ` + "`aws`" + `
@@ -36,7 +73,12 @@ false for connections to succeed.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("urls", "A list of URLs to connect to. If an item of the list contains commas it will be expanded into multiple URLs.", []string{"http://localhost:9200"}).Array(),
 			docs.FieldCommon("index", "The index to place messages.").IsInterpolated(),
-			docs.FieldAdvanced("action", "The action to take on the document.").IsInterpolated().HasOptions("index", "update", "delete"),
+			docs.FieldAdvanced("action", "The action to take on the document.").IsInterpolated().HasOptions("index", "create", "update", "upsert", "delete"),
+			docs.FieldAdvanced("script", "An optional script to execute with the `update` and `upsert` actions instead of a partial document update.").WithChildren(
+				docs.FieldString("source", "The source of the script, when empty the message is used as a partial document.", "ctx._source.count += params.count"),
+				docs.FieldString("lang", "The language of the script."),
+				docs.FieldBloblang("params_mapping", "An optional [Bloblang mapping](/docs/guides/bloblang/about) executed on each message that should result in an object of parameters for the script.", "root.count = this.count"),
+			).AtVersion("3.60.0"),
 			docs.FieldAdvanced("pipeline", "An optional pipeline id to preprocess incoming documents.").IsInterpolated(),
 			docs.FieldCommon("id", "The ID for indexed messages. Interpolation should be used in order to create a unique ID for each message.").IsInterpolated(),
 			docs.FieldCommon("type", "The document type."),
@@ -1,16 +1,22 @@
 package output
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/broker"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/processor"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -98,3 +104,91 @@ func TestFallbackOutputBasic(t *testing.T) {
 		}
 	}
 }
+
+type fallbackTestSink struct {
+	mut      sync.Mutex
+	received [][]byte
+	reasons  []string
+	rejectFn func(content string) bool
+}
+
+func (f *fallbackTestSink) ConnectWithContext(ctx context.Context) error {
+	return nil
+}
+
+func (f *fallbackTestSink) WriteWithContext(ctx context.Context, msg types.Message) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	var bErr *batch.Error
+	errMsg := msg.Copy()
+	_ = msg.Iter(func(i int, p types.Part) error {
+		if f.rejectFn != nil && f.rejectFn(string(p.Get())) {
+			err := errors.New("rejected")
+			if bErr == nil {
+				bErr = batch.NewError(errMsg, err)
+			}
+			bErr.Failed(i, err)
+			errMsg.Get(i).Metadata().Set("reason", "rejected "+string(p.Get()))
+			return nil
+		}
+		f.received = append(f.received, p.Get())
+		f.reasons = append(f.reasons, p.Metadata().Get("reason"))
+		return nil
+	})
+	if bErr != nil {
+		return bErr
+	}
+	return nil
+}
+
+func (f *fallbackTestSink) CloseAsync() {}
+
+func (f *fallbackTestSink) WaitForClose(time.Duration) error {
+	return nil
+}
+
+func TestFallbackOutputBatchErrors(t *testing.T) {
+	first := &fallbackTestSink{
+		rejectFn: func(content string) bool {
+			return content == "b" || content == "d"
+		},
+	}
+	second := &fallbackTestSink{}
+
+	outOne, err := NewAsyncWriter("first", 1, first, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	outTwo, err := NewAsyncWriter("second", 1, second, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	s, err := broker.NewTry([]types.Output{outOne, outTwo}, metrics.Noop())
+	require.NoError(t, err)
+
+	sendChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	require.NoError(t, s.Consume(sendChan))
+
+	t.Cleanup(func() {
+		s.CloseAsync()
+		require.NoError(t, s.WaitForClose(time.Second))
+	})
+
+	select {
+	case sendChan <- types.NewTransaction(message.New([][]byte{
+		[]byte("a"), []byte("b"), []byte("c"), []byte("d"),
+	}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case res := <-resChan:
+		require.NoError(t, res.Error())
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("c")}, first.received)
+	assert.Equal(t, [][]byte{[]byte("b"), []byte("d")}, second.received)
+	assert.Equal(t, []string{"rejected b", "rejected d"}, second.reasons)
+}
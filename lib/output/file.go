@@ -10,6 +10,7 @@ import (
 
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/internal/codec"
+	"github.com/Jeffail/benthos/v3/internal/component/output"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/internal/shutdown"
@@ -27,7 +28,21 @@ func init() {
 		Summary: `
 Writes messages to files on disk based on a chosen codec.`,
 		Description: `
-Messages can be written to different files by using [interpolation functions](/docs/configuration/interpolation#bloblang-queries) in the path field. However, only one file is ever open at a given time, and therefore when the path changes the previously open file is closed.`,
+Messages can be written to different files by using [interpolation functions](/docs/configuration/interpolation#bloblang-queries) in the path field. However, only one file is ever open at a given time, and therefore when the path changes the previously open file is closed.
+` + output.PartitionCommitDocs + `
+
+For example, in order to write messages into daily partitions and mark each partition as complete once messages of more than an hour into the following day have been written, we could use the following config:
+
+` + "```yaml" + `
+output:
+  file:
+    path: '/data/events/dt=${! json("created_at").format_timestamp(format: "2006-01-02", tz: "UTC") }/events.jsonl'
+    partition_commit:
+      enabled: true
+      timestamp_mapping: root = this.created_at
+      period: 24h
+      allowed_lateness: 1h
+` + "```" + ``,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon(
 				"path", "The file to write to, if the file does not yet exist it will be created.",
@@ -37,6 +52,7 @@ Messages can be written to different files by using [interpolation functions](/d
 			).IsInterpolated().AtVersion("3.33.0"),
 			codec.WriterDocs.AtVersion("3.33.0"),
 			docs.FieldDeprecated("delimiter"),
+			docs.FieldAdvanced("partition_commit", "Optionally track partitions of event time and commit them once complete.").WithChildren(output.PartitionCommitFields()...).AtVersion("3.60.0"),
 		},
 		Categories: []Category{
 			CategoryLocal,
@@ -48,17 +64,19 @@ Messages can be written to different files by using [interpolation functions](/d
 
 // FileConfig contains configuration fields for the file based output type.
 type FileConfig struct {
-	Path  string `json:"path" yaml:"path"`
-	Codec string `json:"codec" yaml:"codec"`
-	Delim string `json:"delimiter" yaml:"delimiter"`
+	Path            string                       `json:"path" yaml:"path"`
+	Codec           string                       `json:"codec" yaml:"codec"`
+	Delim           string                       `json:"delimiter" yaml:"delimiter"`
+	PartitionCommit output.PartitionCommitConfig `json:"partition_commit" yaml:"partition_commit"`
 }
 
 // NewFileConfig creates a new FileConfig with default values.
 func NewFileConfig() FileConfig {
 	return FileConfig{
-		Path:  "",
-		Codec: "lines",
-		Delim: "",
+		Path:            "",
+		Codec:           "lines",
+		Delim:           "",
+		PartitionCommit: output.NewPartitionCommitConfig(),
 	}
 }
 
@@ -69,7 +87,7 @@ func NewFile(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type
 	if len(conf.File.Delim) > 0 {
 		conf.File.Codec = "delim:" + conf.File.Delim
 	}
-	f, err := newFileWriter(conf.File.Path, conf.File.Codec, mgr, log, stats)
+	f, err := newFileWriter(conf.File.Path, conf.File.Codec, conf.File.PartitionCommit, mgr, log, stats)
 	if err != nil {
 		return nil, err
 	}
@@ -93,14 +111,17 @@ type fileWriter struct {
 	codec     codec.WriterConstructor
 	codecConf codec.WriterConfig
 
-	handleMut  sync.Mutex
-	handlePath string
-	handle     codec.Writer
+	partitions *output.PartitionCommitter
+
+	handleMut       sync.Mutex
+	handlePath      string
+	handlePartition time.Time
+	handle          codec.Writer
 
 	shutSig *shutdown.Signaller
 }
 
-func newFileWriter(pathStr, codecStr string, mgr types.Manager, log log.Modular, stats metrics.Type) (*fileWriter, error) {
+func newFileWriter(pathStr, codecStr string, partitionConf output.PartitionCommitConfig, mgr types.Manager, log log.Modular, stats metrics.Type) (*fileWriter, error) {
 	codec, codecConf, err := codec.GetWriter(codecStr)
 	if err != nil {
 		return nil, err
@@ -109,14 +130,20 @@ func newFileWriter(pathStr, codecStr string, mgr types.Manager, log log.Modular,
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse path expression: %w", err)
 	}
-	return &fileWriter{
+	w := &fileWriter{
 		codec:     codec,
 		codecConf: codecConf,
 		path:      path,
 		log:       log,
 		stats:     stats,
 		shutSig:   shutdown.NewSignaller(),
-	}, nil
+	}
+	if partitionConf.Enabled {
+		if w.partitions, err = output.NewPartitionCommitter(partitionConf, w.writeMarker, mgr, log, stats); err != nil {
+			return nil, fmt.Errorf("failed to create partition committer: %w", err)
+		}
+	}
+	return w, nil
 }
 
 //------------------------------------------------------------------------------
@@ -126,6 +153,23 @@ func (w *fileWriter) ConnectWithContext(ctx context.Context) error {
 }
 
 func (w *fileWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	if w.partitions == nil {
+		return w.writeBatch(ctx, msg, nil)
+	}
+
+	pw := w.partitions.Begin(msg)
+	defer pw.End(ctx, w.flushPartition)
+
+	if err := w.writeBatch(ctx, msg, pw); err != nil {
+		return err
+	}
+	for i := 0; i < msg.Len(); i++ {
+		pw.Track(i, filepath.Clean(w.path.String(i, msg)))
+	}
+	return nil
+}
+
+func (w *fileWriter) writeBatch(ctx context.Context, msg types.Message, pw *output.PartitionWrite) error {
 	err := writer.IterateBatchedSend(msg, func(i int, p types.Part) error {
 		path := filepath.Clean(w.path.String(i, msg))
 
@@ -159,6 +203,9 @@ func (w *fileWriter) WriteWithContext(ctx context.Context, msg types.Message) er
 		}
 
 		w.handlePath = path
+		if pw != nil {
+			w.handlePartition = pw.Partition(i)
+		}
 		handle, err := w.codec(file)
 		if err != nil {
 			return err
@@ -190,6 +237,23 @@ func (w *fileWriter) WriteWithContext(ctx context.Context, msg types.Message) er
 	return nil
 }
 
+// flushPartition closes the open file if it belongs to a partition.
+func (w *fileWriter) flushPartition(partition time.Time) {
+	w.handleMut.Lock()
+	defer w.handleMut.Unlock()
+
+	if w.handle != nil && w.handlePartition.Equal(partition) {
+		if err := w.handle.Close(context.Background()); err != nil {
+			w.log.Errorf("Failed to close file: %v\n", err)
+		}
+		w.handle = nil
+	}
+}
+
+func (w *fileWriter) writeMarker(ctx context.Context, path string) error {
+	return os.WriteFile(path, nil, os.FileMode(0o666))
+}
+
 // CloseAsync shuts down the File output and stops processing messages.
 func (w *fileWriter) CloseAsync() {
 	go func() {
@@ -199,16 +263,26 @@ func (w *fileWriter) CloseAsync() {
 			w.handle = nil
 		}
 		w.handleMut.Unlock()
+		if w.partitions != nil {
+			// Partitions are committed once their files have been closed, and
+			// before the commit processors are closed.
+			w.partitions.CommitAll(context.Background(), w.flushPartition)
+			w.partitions.CloseAsync()
+		}
 		w.shutSig.ShutdownComplete()
 	}()
 }
 
 // WaitForClose blocks until the File output has closed down.
 func (w *fileWriter) WaitForClose(timeout time.Duration) error {
+	stopBy := time.Now().Add(timeout)
 	select {
 	case <-w.shutSig.HasClosedChan():
 	case <-time.After(timeout):
 		return types.ErrTimeout
 	}
+	if w.partitions != nil {
+		return w.partitions.WaitForClose(time.Until(stopBy))
+	}
 	return nil
 }
@@ -0,0 +1,73 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePartitionCommit(t *testing.T) {
+	dir := t.TempDir()
+
+	conf := NewConfig()
+	conf.Type = TypeFile
+	conf.File.Path = filepath.Join(dir, `dt=${! json("ts").format_timestamp(format: "2006-01-02", tz: "UTC") }/data.jsonl`)
+	conf.File.PartitionCommit.Enabled = true
+	conf.File.PartitionCommit.TimestampMapping = "root = this.ts"
+	conf.File.PartitionCommit.Period = "24h"
+
+	out, err := New(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, out.Consume(tChan))
+
+	resChan := make(chan types.Response)
+	send := func(doc string) {
+		t.Helper()
+		select {
+		case tChan <- types.NewTransaction(message.New([][]byte{[]byte(doc)}), resChan):
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+		select {
+		case res := <-resChan:
+			require.NoError(t, res.Error())
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+	}
+
+	send(`{"ts":"2021-12-01T03:00:00Z","id":1}`)
+	send(`{"ts":"2021-12-01T15:00:00Z","id":2}`)
+
+	_, err = os.Stat(filepath.Join(dir, "dt=2021-12-01", "_SUCCESS"))
+	require.True(t, os.IsNotExist(err))
+
+	send(`{"ts":"2021-12-02T01:00:00Z","id":3}`)
+
+	_, err = os.Stat(filepath.Join(dir, "dt=2021-12-01", "_SUCCESS"))
+	require.NoError(t, err)
+
+	b, err := os.ReadFile(filepath.Join(dir, "dt=2021-12-01", "data.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, "{\"ts\":\"2021-12-01T03:00:00Z\",\"id\":1}\n{\"ts\":\"2021-12-01T15:00:00Z\",\"id\":2}\n", string(b))
+
+	_, err = os.Stat(filepath.Join(dir, "dt=2021-12-02", "_SUCCESS"))
+	require.True(t, os.IsNotExist(err))
+
+	// The final partition is committed once the output is closed.
+	out.CloseAsync()
+	require.NoError(t, out.WaitForClose(time.Second*5))
+
+	_, err = os.Stat(filepath.Join(dir, "dt=2021-12-02", "_SUCCESS"))
+	require.NoError(t, err)
+}
@@ -1,6 +1,7 @@
 package output
 
 import (
+	"github.com/Jeffail/benthos/v3/internal/component/output"
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
 	"google.golang.org/api/googleapi"
 )
@@ -22,14 +23,16 @@ const (
 // GCPCloudStorageConfig contains configuration fields for the GCP Cloud Storage
 // output type.
 type GCPCloudStorageConfig struct {
-	Bucket          string             `json:"bucket" yaml:"bucket"`
-	Path            string             `json:"path" yaml:"path"`
-	ContentType     string             `json:"content_type" yaml:"content_type"`
-	ContentEncoding string             `json:"content_encoding" yaml:"content_encoding"`
-	ChunkSize       int                `json:"chunk_size" yaml:"chunk_size"`
-	MaxInFlight     int                `json:"max_in_flight" yaml:"max_in_flight"`
-	Batching        batch.PolicyConfig `json:"batching" yaml:"batching"`
-	CollisionMode   string             `json:"collision_mode" yaml:"collision_mode"`
+	Bucket          string                       `json:"bucket" yaml:"bucket"`
+	Path            string                       `json:"path" yaml:"path"`
+	ContentType     string                       `json:"content_type" yaml:"content_type"`
+	ContentEncoding string                       `json:"content_encoding" yaml:"content_encoding"`
+	ChunkSize       int                          `json:"chunk_size" yaml:"chunk_size"`
+	MaxInFlight     int                          `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching        batch.PolicyConfig           `json:"batching" yaml:"batching"`
+	CollisionMode   string                       `json:"collision_mode" yaml:"collision_mode"`
+	Rolling         output.RollingConfig         `json:"rolling" yaml:"rolling"`
+	PartitionCommit output.PartitionCommitConfig `json:"partition_commit" yaml:"partition_commit"`
 }
 
 // NewGCPCloudStorageConfig creates a new Config with default values.
@@ -43,5 +46,7 @@ func NewGCPCloudStorageConfig() GCPCloudStorageConfig {
 		MaxInFlight:     1,
 		Batching:        batch.NewPolicyConfig(),
 		CollisionMode:   GCPCloudStorageOverwriteCollisionMode,
+		Rolling:         output.NewRollingConfig(),
+		PartitionCommit: output.NewPartitionCommitConfig(),
 	}
 }
@@ -9,11 +9,14 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
+	"strconv"
 	"sync/atomic"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -44,12 +47,33 @@ websocket of messages for each request respectively.
 When messages are batched the ` + "`path`" + ` endpoint encodes the batch
 according to [RFC1341](https://www.w3.org/Protocols/rfc1341/7_2_Multipart.html).
 This behaviour can be overridden by
-[archiving your batches](/docs/configuration/batching#post-batch-processing).`,
+[archiving your batches](/docs/configuration/batching#post-batch-processing).
+
+### Server-Sent Events
+
+When an ` + "`sse_path`" + ` is specified messages are also served as a ` + "`text/event-stream`" + ` of [server-sent events](https://html.spec.whatwg.org/multipage/server-sent-events.html), where each message is an event with an incrementing ID and the lines of the message are the data of the event. The name of each event can be set with the interpolated field ` + "`sse_event`" + `, and a reconnection time can be suggested to clients with ` + "`sse_retry`" + `.
+
+Unlike the other endpoints, where each message is consumed by a single request, events are broadcast to all connected clients, and the most recent events are retained in a buffer of size ` + "`sse_buffer_size`" + `. Clients that reconnect with a ` + "`Last-Event-ID`" + ` header are sent all buffered events that follow that ID before receiving new events, and clients that fall further behind than the size of the buffer miss events.
+
+Since all messages are broadcast to SSE clients an ` + "`sse_path`" + ` cannot be combined with the other endpoints, and therefore the fields ` + "`path`, `stream_path` and `ws_path`" + ` must be set to empty strings. Messages are acknowledged once they are added to the buffer, regardless of whether any clients are connected.
+
+` + "```yaml" + `
+output:
+  http_server:
+    path: ""
+    stream_path: ""
+    ws_path: ""
+    sse_path: /get/sse
+` + "```" + ``,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("address", "An optional address to listen from. If left empty the service wide HTTP server is used."),
 			docs.FieldCommon("path", "The path from which discrete messages can be consumed."),
 			docs.FieldCommon("stream_path", "The path from which a continuous stream of messages can be consumed."),
 			docs.FieldCommon("ws_path", "The path from which websocket connections can be established."),
+			docs.FieldString("sse_path", "An optional path from which a stream of server-sent events can be consumed.", "/get/sse").AtVersion("3.60.0"),
+			docs.FieldString("sse_event", "An optional event name to set for each server-sent event.", `${! meta("event_type") }`).IsInterpolated().AtVersion("3.60.0").Advanced(),
+			docs.FieldString("sse_retry", "An optional duration to suggest to server-sent event clients as the time to wait before reconnecting.", "3s").AtVersion("3.60.0").Advanced(),
+			docs.FieldInt("sse_buffer_size", "The maximum number of recent server-sent events to retain for replaying to clients that reconnect.").AtVersion("3.60.0").Advanced(),
 			docs.FieldCommon("allowed_verbs", "An array of verbs that are allowed for the `path` and `stream_path` HTTP endpoint.").Array(),
 			docs.FieldAdvanced("timeout", "The maximum time to wait before a blocking, inactive connection is dropped (only applies to the `path` endpoint)."),
 			docs.FieldAdvanced("cert_file", "An optional certificate file to use for TLS connections. Only applicable when an `address` is specified."),
@@ -66,14 +90,18 @@ This behaviour can be overridden by
 // HTTPServerConfig contains configuration fields for the HTTPServer output
 // type.
 type HTTPServerConfig struct {
-	Address      string   `json:"address" yaml:"address"`
-	Path         string   `json:"path" yaml:"path"`
-	StreamPath   string   `json:"stream_path" yaml:"stream_path"`
-	WSPath       string   `json:"ws_path" yaml:"ws_path"`
-	AllowedVerbs []string `json:"allowed_verbs" yaml:"allowed_verbs"`
-	Timeout      string   `json:"timeout" yaml:"timeout"`
-	CertFile     string   `json:"cert_file" yaml:"cert_file"`
-	KeyFile      string   `json:"key_file" yaml:"key_file"`
+	Address       string   `json:"address" yaml:"address"`
+	Path          string   `json:"path" yaml:"path"`
+	StreamPath    string   `json:"stream_path" yaml:"stream_path"`
+	WSPath        string   `json:"ws_path" yaml:"ws_path"`
+	SSEPath       string   `json:"sse_path" yaml:"sse_path"`
+	SSEEvent      string   `json:"sse_event" yaml:"sse_event"`
+	SSERetry      string   `json:"sse_retry" yaml:"sse_retry"`
+	SSEBufferSize int      `json:"sse_buffer_size" yaml:"sse_buffer_size"`
+	AllowedVerbs  []string `json:"allowed_verbs" yaml:"allowed_verbs"`
+	Timeout       string   `json:"timeout" yaml:"timeout"`
+	CertFile      string   `json:"cert_file" yaml:"cert_file"`
+	KeyFile       string   `json:"key_file" yaml:"key_file"`
 }
 
 // NewHTTPServerConfig creates a new HTTPServerConfig with default values.
@@ -83,6 +111,11 @@ func NewHTTPServerConfig() HTTPServerConfig {
 		Path:       "/get",
 		StreamPath: "/get/stream",
 		WSPath:     "/get/ws",
+
+		SSEPath:       "",
+		SSEEvent:      "",
+		SSERetry:      "",
+		SSEBufferSize: 1000,
 		AllowedVerbs: []string{
 			"GET",
 		},
@@ -113,6 +146,10 @@ type HTTPServer struct {
 
 	allowedVerbs map[string]struct{}
 
+	sseEvent  *field.Expression
+	sseRetry  time.Duration
+	sseEvents *sseEventBuffer
+
 	mRunning       metrics.StatGauge
 	mCount         metrics.StatCounter
 	mPartsCount    metrics.StatCounter
@@ -137,6 +174,11 @@ type HTTPServer struct {
 	mStrmCount    metrics.StatCounter
 	mStrmErrWrite metrics.StatCounter
 	mStrmSndSucc  metrics.StatCounter
+
+	mSSEReqRcvd  metrics.StatCounter
+	mSSECount    metrics.StatCounter
+	mSSEErrWrite metrics.StatCounter
+	mSSESendSucc metrics.StatCounter
 }
 
 // NewHTTPServer creates a new HTTPServer output type.
@@ -190,6 +232,10 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 		mStrmCount:     stats.GetCounter("stream.count"),
 		mStrmErrWrite:  stats.GetCounter("stream.error.write"),
 		mStrmSndSucc:   stats.GetCounter("stream.send.success"),
+		mSSEReqRcvd:    stats.GetCounter("sse.request.received"),
+		mSSECount:      stats.GetCounter("sse.count"),
+		mSSEErrWrite:   stats.GetCounter("sse.error.write"),
+		mSSESendSucc:   stats.GetCounter("sse.send.success"),
 	}
 
 	if tout := conf.HTTPServer.Timeout; len(tout) > 0 {
@@ -199,6 +245,26 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 		}
 	}
 
+	if len(conf.HTTPServer.SSEPath) > 0 {
+		if len(conf.HTTPServer.Path) > 0 || len(conf.HTTPServer.StreamPath) > 0 || len(conf.HTTPServer.WSPath) > 0 {
+			return nil, errors.New("sse_path cannot be combined with path, stream_path or ws_path, as all messages are broadcast to server-sent event clients, set these fields to empty strings in order to use sse_path")
+		}
+		if conf.HTTPServer.SSEBufferSize <= 0 {
+			return nil, errors.New("sse_buffer_size must be greater than zero")
+		}
+		h.sseEvents = newSSEEventBuffer(conf.HTTPServer.SSEBufferSize)
+
+		var err error
+		if h.sseEvent, err = interop.NewBloblangField(mgr, conf.HTTPServer.SSEEvent); err != nil {
+			return nil, fmt.Errorf("failed to parse sse_event expression: %v", err)
+		}
+		if retry := conf.HTTPServer.SSERetry; len(retry) > 0 {
+			if h.sseRetry, err = time.ParseDuration(retry); err != nil {
+				return nil, fmt.Errorf("failed to parse sse_retry string: %v", err)
+			}
+		}
+	}
+
 	if mux != nil {
 		if len(h.conf.HTTPServer.Path) > 0 {
 			h.mux.HandleFunc(h.conf.HTTPServer.Path, h.getHandler)
@@ -209,6 +275,9 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 		if len(h.conf.HTTPServer.WSPath) > 0 {
 			h.mux.HandleFunc(h.conf.HTTPServer.WSPath, h.wsHandler)
 		}
+		if len(h.conf.HTTPServer.SSEPath) > 0 {
+			h.mux.HandleFunc(h.conf.HTTPServer.SSEPath, h.sseHandler)
+		}
 	} else {
 		if len(h.conf.HTTPServer.Path) > 0 {
 			mgr.RegisterEndpoint(
@@ -230,6 +299,13 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 				h.wsHandler,
 			)
 		}
+		if len(h.conf.HTTPServer.SSEPath) > 0 {
+			mgr.RegisterEndpoint(
+				h.conf.HTTPServer.SSEPath,
+				"Read messages from Benthos as server-sent events.",
+				h.sseHandler,
+			)
+		}
 	}
 
 	return &h, nil
@@ -430,6 +506,111 @@ func (h *HTTPServer) wsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *HTTPServer) sseHandler(w http.ResponseWriter, r *http.Request) {
+	h.mSSEReqRcvd.Incr(1)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		h.mStrmErrCast.Incr(1)
+		h.log.Errorln("Failed to cast response writer to flusher")
+		return
+	}
+
+	if _, exists := h.allowedVerbs[r.Method]; !exists {
+		http.Error(w, "Incorrect method", http.StatusMethodNotAllowed)
+		h.mStrmErrWrong.Incr(1)
+		return
+	}
+
+	// Clients that don't specify a last event ID only receive new events.
+	lastID := h.sseEvents.lastID()
+	if lastIDStr := r.Header.Get("Last-Event-ID"); lastIDStr != "" {
+		if id, err := strconv.ParseUint(lastIDStr, 10, 64); err == nil {
+			lastID = id
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if h.sseRetry > 0 {
+		fmt.Fprintf(w, "retry: %v\n\n", h.sseRetry.Milliseconds())
+	}
+	flusher.Flush()
+
+	for atomic.LoadInt32(&h.running) == 1 {
+		events, notify := h.sseEvents.since(lastID)
+		for _, e := range events {
+			if err := e.writeTo(w); err != nil {
+				h.mSSEErrWrite.Incr(1)
+				return
+			}
+			lastID = e.id
+			h.mSSESendSucc.Incr(1)
+		}
+		flusher.Flush()
+
+		select {
+		case <-notify:
+		case <-r.Context().Done():
+			h.mStrmClosed.Incr(1)
+			return
+		case <-h.closeChan:
+			return
+		case <-h.closedChan:
+			return
+		}
+	}
+}
+
+// sseLoop consumes all messages as events, which are added to the buffer and
+// broadcast to server-sent event clients.
+func (h *HTTPServer) sseLoop() {
+	for {
+		var ts types.Transaction
+		var open bool
+
+		select {
+		case ts, open = <-h.transactions:
+			if !open {
+				go h.CloseAsync()
+				return
+			}
+		case <-h.closeChan:
+			return
+		case <-h.closedChan:
+			return
+		}
+		h.mSSECount.Incr(1)
+		h.mCount.Incr(1)
+		h.mPartsCount.Incr(int64(ts.Payload.Len()))
+
+		events := make([]sseEvent, 0, ts.Payload.Len())
+		ts.Payload.Iter(func(i int, p types.Part) error {
+			events = append(events, sseEvent{
+				event: h.sseEvent.String(i, ts.Payload),
+				data:  p.Get(),
+			})
+			return nil
+		})
+		h.sseEvents.publish(events...)
+
+		h.mSendSucc.Incr(1)
+		h.mPartsSendSucc.Incr(int64(ts.Payload.Len()))
+		h.mSent.Incr(1)
+		h.mPartsSent.Incr(int64(batch.MessageCollapsedCount(ts.Payload)))
+
+		select {
+		case ts.ResponseChan <- response.NewAck():
+		case <-h.closeChan:
+			return
+		case <-h.closedChan:
+			return
+		}
+	}
+}
+
 //------------------------------------------------------------------------------
 
 // Consume assigns a messages channel for the output to read.
@@ -439,6 +620,10 @@ func (h *HTTPServer) Consume(ts <-chan types.Transaction) error {
 	}
 	h.transactions = ts
 
+	if h.sseEvents != nil {
+		go h.sseLoop()
+	}
+
 	if h.server != nil {
 		go func() {
 			h.mRunning.Incr(1)
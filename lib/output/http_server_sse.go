@@ -0,0 +1,108 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// sseEvent is a server-sent event that has been published to clients.
+type sseEvent struct {
+	id    uint64
+	event string
+	data  []byte
+}
+
+// sseFieldSanitiser removes line breaks from the value of an event field,
+// which would otherwise allow a value to inject arbitrary fields and events
+// into the stream.
+var sseFieldSanitiser = strings.NewReplacer("\r\n", "", "\r", "", "\n", "")
+
+// writeTo writes the event in the text/event-stream format, where each line of
+// the data is written as a separate data field.
+func (e sseEvent) writeTo(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString("id: ")
+	buf.WriteString(strconv.FormatUint(e.id, 10))
+	buf.WriteByte('\n')
+	if event := sseFieldSanitiser.Replace(e.event); event != "" {
+		buf.WriteString("event: ")
+		buf.WriteString(event)
+		buf.WriteByte('\n')
+	}
+	data := bytes.ReplaceAll(e.data, []byte("\r\n"), []byte("\n"))
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// sseEventBuffer retains a bounded number of the most recently published
+// events, allowing clients to replay events they missed whilst disconnected.
+type sseEventBuffer struct {
+	mut    sync.Mutex
+	events []sseEvent
+	size   int
+	nextID uint64
+
+	// Closed and replaced each time events are published.
+	notify chan struct{}
+}
+
+func newSSEEventBuffer(size int) *sseEventBuffer {
+	return &sseEventBuffer{
+		size:   size,
+		nextID: 1,
+		notify: make(chan struct{}),
+	}
+}
+
+// publish assigns IDs to new events, adds them to the buffer, evicting the
+// oldest events when full, and wakes up any waiting clients.
+func (b *sseEventBuffer) publish(events ...sseEvent) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	for _, e := range events {
+		e.id = b.nextID
+		b.nextID++
+		b.events = append(b.events, e)
+	}
+	if excess := len(b.events) - b.size; excess > 0 {
+		b.events = append([]sseEvent(nil), b.events[excess:]...)
+	}
+
+	close(b.notify)
+	b.notify = make(chan struct{})
+}
+
+// lastID returns the ID of the most recently published event, or zero if no
+// events have been published.
+func (b *sseEventBuffer) lastID() uint64 {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.nextID - 1
+}
+
+// since returns all buffered events with an ID greater than the provided ID,
+// along with a channel that is closed when further events are published. When
+// the ID is ahead of the latest event, which happens when a client reconnects
+// after a restart, all buffered events are returned.
+func (b *sseEventBuffer) since(id uint64) ([]sseEvent, <-chan struct{}) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if id >= b.nextID {
+		id = 0
+	}
+	i := 0
+	for ; i < len(b.events) && b.events[i].id <= id; i++ {
+	}
+	return b.events[i:], b.notify
+}
@@ -1,8 +1,11 @@
 package output
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,6 +13,8 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHTTPBasic(t *testing.T) {
@@ -145,3 +150,131 @@ func TestHTTPTimeout(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestHTTPServerSSE(t *testing.T) {
+	conf := NewConfig()
+	conf.HTTPServer.Address = "localhost:1238"
+	conf.HTTPServer.Path = ""
+	conf.HTTPServer.StreamPath = ""
+	conf.HTTPServer.WSPath = ""
+	conf.HTTPServer.SSEPath = "/testsse"
+	conf.HTTPServer.SSEEvent = `${! meta("event") }`
+	conf.HTTPServer.SSERetry = "2s"
+	conf.HTTPServer.SSEBufferSize = 2
+
+	h, err := NewHTTPServer(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	require.NoError(t, h.Consume(msgChan))
+	defer func() {
+		h.CloseAsync()
+		assert.NoError(t, h.WaitForClose(time.Second*5))
+	}()
+
+	<-time.After(time.Millisecond * 100)
+
+	sendMsg := func(event string, parts ...string) {
+		t.Helper()
+		msg := message.New(nil)
+		for _, p := range parts {
+			part := message.NewPart([]byte(p))
+			part.Metadata().Set("event", event)
+			msg.Append(part)
+		}
+		select {
+		case msgChan <- types.NewTransaction(msg, resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for message")
+		}
+		select {
+		case res := <-resChan:
+			require.NoError(t, res.Error())
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for response")
+		}
+	}
+
+	connect := func(lastEventID string) (*bufio.Reader, func()) {
+		t.Helper()
+		ctx, done := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, "GET", "http://localhost:1238/testsse", nil)
+		require.NoError(t, err)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, 200, res.StatusCode)
+		assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+		return bufio.NewReader(res.Body), func() {
+			done()
+			res.Body.Close()
+		}
+	}
+
+	readEvent := func(r *bufio.Reader) string {
+		t.Helper()
+		var lines []string
+		for {
+			line, err := r.ReadString('\n')
+			require.NoError(t, err)
+			if line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	r, closeFn := connect("")
+	assert.Equal(t, "retry: 2000\n", readEvent(r))
+
+	sendMsg("foo", "hello\nworld", "second")
+	assert.Equal(t, "id: 1\nevent: foo\ndata: hello\ndata: world\n", readEvent(r))
+	assert.Equal(t, "id: 2\nevent: foo\ndata: second\n", readEvent(r))
+	closeFn()
+
+	sendMsg("", "third")
+
+	// Only events following the last event ID are replayed, and the buffer
+	// only retains the two most recent events.
+	r, closeFn = connect("2")
+	assert.Equal(t, "retry: 2000\n", readEvent(r))
+	assert.Equal(t, "id: 3\ndata: third\n", readEvent(r))
+	closeFn()
+
+	r, closeFn = connect("0")
+	assert.Equal(t, "retry: 2000\n", readEvent(r))
+	assert.Equal(t, "id: 2\nevent: foo\ndata: second\n", readEvent(r))
+	assert.Equal(t, "id: 3\ndata: third\n", readEvent(r))
+
+	sendMsg("bar", "fourth")
+	assert.Equal(t, "id: 4\nevent: bar\ndata: fourth\n", readEvent(r))
+
+	// Line breaks within event names are removed so that they cannot inject
+	// fields.
+	sendMsg("baz\r\ndata: injected\n\nevent: buz", "fifth")
+	assert.Equal(t, "id: 5\nevent: bazdata: injectedevent: buz\ndata: fifth\n", readEvent(r))
+	closeFn()
+}
+
+func TestHTTPServerSSEWithOtherPaths(t *testing.T) {
+	conf := NewConfig()
+	conf.HTTPServer.Address = "localhost:1239"
+	conf.HTTPServer.SSEPath = "/testsse"
+
+	_, err := NewHTTPServer(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sse_path cannot be combined")
+
+	conf.HTTPServer.Path = ""
+	conf.HTTPServer.StreamPath = ""
+	_, err = NewHTTPServer(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	conf.HTTPServer.WSPath = ""
+	h, err := NewHTTPServer(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	h.CloseAsync()
+}
@@ -5,10 +5,13 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	ibatch "github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
 	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
@@ -34,24 +37,33 @@ type OptionalAWSConfig struct {
 
 //------------------------------------------------------------------------------
 
+// ElasticsearchScriptConfig contains configuration fields for a script executed
+// by update and upsert actions.
+type ElasticsearchScriptConfig struct {
+	Source        string `json:"source" yaml:"source"`
+	Lang          string `json:"lang" yaml:"lang"`
+	ParamsMapping string `json:"params_mapping" yaml:"params_mapping"`
+}
+
 // ElasticsearchConfig contains configuration fields for the Elasticsearch
 // output type.
 type ElasticsearchConfig struct {
-	URLs            []string             `json:"urls" yaml:"urls"`
-	Sniff           bool                 `json:"sniff" yaml:"sniff"`
-	Healthcheck     bool                 `json:"healthcheck" yaml:"healthcheck"`
-	ID              string               `json:"id" yaml:"id"`
-	Action          string               `json:"action" yaml:"action"`
-	Index           string               `json:"index" yaml:"index"`
-	Pipeline        string               `json:"pipeline" yaml:"pipeline"`
-	Routing         string               `json:"routing" yaml:"routing"`
-	Type            string               `json:"type" yaml:"type"`
-	Timeout         string               `json:"timeout" yaml:"timeout"`
-	TLS             btls.Config          `json:"tls" yaml:"tls"`
-	Auth            auth.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
-	AWS             OptionalAWSConfig    `json:"aws" yaml:"aws"`
-	GzipCompression bool                 `json:"gzip_compression" yaml:"gzip_compression"`
-	MaxInFlight     int                  `json:"max_in_flight" yaml:"max_in_flight"`
+	URLs            []string                  `json:"urls" yaml:"urls"`
+	Sniff           bool                      `json:"sniff" yaml:"sniff"`
+	Healthcheck     bool                      `json:"healthcheck" yaml:"healthcheck"`
+	ID              string                    `json:"id" yaml:"id"`
+	Action          string                    `json:"action" yaml:"action"`
+	Script          ElasticsearchScriptConfig `json:"script" yaml:"script"`
+	Index           string                    `json:"index" yaml:"index"`
+	Pipeline        string                    `json:"pipeline" yaml:"pipeline"`
+	Routing         string                    `json:"routing" yaml:"routing"`
+	Type            string                    `json:"type" yaml:"type"`
+	Timeout         string                    `json:"timeout" yaml:"timeout"`
+	TLS             btls.Config               `json:"tls" yaml:"tls"`
+	Auth            auth.BasicAuthConfig      `json:"basic_auth" yaml:"basic_auth"`
+	AWS             OptionalAWSConfig         `json:"aws" yaml:"aws"`
+	GzipCompression bool                      `json:"gzip_compression" yaml:"gzip_compression"`
+	MaxInFlight     int                       `json:"max_in_flight" yaml:"max_in_flight"`
 	retries.Config  `json:",inline" yaml:",inline"`
 	Batching        batch.PolicyConfig `json:"batching" yaml:"batching"`
 }
@@ -68,14 +80,19 @@ func NewElasticsearchConfig() ElasticsearchConfig {
 		Sniff:       true,
 		Healthcheck: true,
 		Action:      "index",
-		ID:          `${!count("elastic_ids")}-${!timestamp_unix()}`,
-		Index:       "benthos_index",
-		Pipeline:    "",
-		Type:        "doc",
-		Routing:     "",
-		Timeout:     "5s",
-		TLS:         btls.NewConfig(),
-		Auth:        auth.NewBasicAuthConfig(),
+		Script: ElasticsearchScriptConfig{
+			Source:        "",
+			Lang:          "painless",
+			ParamsMapping: "",
+		},
+		ID:       `${!count("elastic_ids")}-${!timestamp_unix()}`,
+		Index:    "benthos_index",
+		Pipeline: "",
+		Type:     "doc",
+		Routing:  "",
+		Timeout:  "5s",
+		TLS:      btls.NewConfig(),
+		Auth:     auth.NewBasicAuthConfig(),
 		AWS: OptionalAWSConfig{
 			Enabled: false,
 			Config:  sess.NewConfig(),
@@ -109,6 +126,8 @@ type Elasticsearch struct {
 	pipelineStr *field.Expression
 	routingStr  *field.Expression
 
+	scriptParams *mapping.Executor
+
 	eJSONErr metrics.StatCounter
 
 	client *elastic.Client
@@ -148,6 +167,11 @@ func NewElasticsearchV2(conf ElasticsearchConfig, mgr types.Manager, log log.Mod
 	if e.routingStr, err = interop.NewBloblangField(mgr, conf.Routing); err != nil {
 		return nil, fmt.Errorf("failed to parse routing key expression: %v", err)
 	}
+	if conf.Script.ParamsMapping != "" {
+		if e.scriptParams, err = interop.NewBloblangMapping(mgr, conf.Script.ParamsMapping); err != nil {
+			return nil, fmt.Errorf("failed to parse script params mapping: %v", err)
+		}
+	}
 
 	for _, u := range conf.URLs {
 		for _, splitURL := range strings.Split(u, ",") {
@@ -240,7 +264,12 @@ func (e *Elasticsearch) Connect() error {
 	return nil
 }
 
+// shouldRetry returns whether a bulk item that failed with a given status
+// should be attempted again.
 func shouldRetry(s int) bool {
+	if s == http.StatusTooManyRequests {
+		return true
+	}
 	if s >= 500 && s <= 599 {
 		return true
 	}
@@ -248,30 +277,34 @@ func shouldRetry(s int) bool {
 }
 
 type pendingBulkIndex struct {
-	Action   string
-	Index    string
-	Pipeline string
-	Routing  string
-	Type     string
-	Doc      interface{}
+	Action       string
+	ID           string
+	Index        string
+	Pipeline     string
+	Routing      string
+	Type         string
+	Doc          interface{}
+	ScriptParams map[string]interface{}
 }
 
-// WriteWithContext will attempt to write a message to Elasticsearch, wait for
-// acknowledgement, and returns an error if applicable.
-func (e *Elasticsearch) WriteWithContext(ctx context.Context, msg types.Message) error {
-	return e.Write(msg)
+// esItemFailure is the most recent failure of a document that is being
+// retried, which is given to the document if the retries are exhausted.
+type esItemFailure struct {
+	status  int
+	errType string
+	reason  string
 }
 
-// Write will attempt to write a message to Elasticsearch, wait for
+// WriteWithContext will attempt to write a message to Elasticsearch, wait for
 // acknowledgement, and returns an error if applicable.
-func (e *Elasticsearch) Write(msg types.Message) error {
+func (e *Elasticsearch) WriteWithContext(ctx context.Context, msg types.Message) error {
 	if e.client == nil {
 		return types.ErrNotConnected
 	}
 
 	boff := e.backoffCtor()
 
-	requests := map[string]*pendingBulkIndex{}
+	requests := make([]*pendingBulkIndex, msg.Len())
 	if err := msg.Iter(func(i int, part types.Part) error {
 		jObj, ierr := part.JSON()
 		if ierr != nil {
@@ -279,71 +312,140 @@ func (e *Elasticsearch) Write(msg types.Message) error {
 			e.log.Errorf("Failed to marshal message into JSON document: %v\n", ierr)
 			return fmt.Errorf("failed to marshal message into JSON document: %w", ierr)
 		}
-		requests[e.idStr.String(i, msg)] = &pendingBulkIndex{
+		req := &pendingBulkIndex{
 			Action:   e.actionStr.String(i, msg),
+			ID:       e.idStr.String(i, msg),
 			Index:    e.indexStr.String(i, msg),
 			Pipeline: e.pipelineStr.String(i, msg),
 			Routing:  e.routingStr.String(i, msg),
 			Type:     e.conf.Type,
 			Doc:      jObj,
 		}
+		if e.scriptParams != nil {
+			paramsPart, err := e.scriptParams.MapPart(i, msg)
+			if err != nil {
+				return fmt.Errorf("script params mapping failed: %w", err)
+			}
+			if paramsPart != nil {
+				paramsObj, err := paramsPart.JSON()
+				if err != nil {
+					return fmt.Errorf("failed to parse script params mapping result: %w", err)
+				}
+				params, ok := paramsObj.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("script params mapping returned non-object result: %T", paramsObj)
+				}
+				req.ScriptParams = params
+			}
+		}
+		requests[i] = req
 		return nil
 	}); err != nil {
 		return err
 	}
 
-	b := e.client.Bulk()
-	for k, v := range requests {
-		bulkReq, err := e.buildBulkableRequest(k, v)
-		if err != nil {
-			return err
+	// Rejected documents are given error metadata on a copy of the batch so
+	// that the messages owned by the caller are not modified.
+	var batchErr *ibatch.Error
+	var errMsg types.Message
+	failItem := func(i int, status int, errType, reason string) {
+		err := fmt.Errorf("elasticsearch rejected document '%v' with code [%v]: %v", requests[i].ID, status, reason)
+		if batchErr == nil {
+			errMsg = msg.Copy()
+			batchErr = ibatch.NewError(errMsg, err)
 		}
-		b.Add(bulkReq)
+		errMsg.Get(i).Metadata().
+			Set("elasticsearch_error_status", strconv.Itoa(status)).
+			Set("elasticsearch_error_type", errType).
+			Set("elasticsearch_error_reason", reason)
+		batchErr.Failed(i, err)
+	}
+
+	pending := make([]int, len(requests))
+	for i := range pending {
+		pending[i] = i
 	}
 
-	for b.NumberOfActions() != 0 {
-		result, err := b.Do(context.Background())
+	for len(pending) > 0 {
+		b := e.client.Bulk()
+		for _, i := range pending {
+			bulkReq, err := e.buildBulkableRequest(requests[i])
+			if err != nil {
+				return err
+			}
+			b.Add(bulkReq)
+		}
+
+		result, err := b.Do(ctx)
 		if err != nil {
+			if batchErr != nil {
+				for _, i := range pending {
+					batchErr.Failed(i, err)
+				}
+				return batchErr
+			}
 			return err
 		}
-
-		failed := result.Failed()
-		if len(failed) == 0 {
-			continue
+		if len(result.Items) != len(pending) {
+			return fmt.Errorf("expected %v items in bulk response, received %v", len(pending), len(result.Items))
 		}
 
-		wait := boff.NextBackOff()
-		for i := 0; i < len(failed); i++ {
-			reason := "no reason given"
-			if fErr := failed[i].Error; fErr != nil {
-				reason = fErr.Reason
+		var retry []int
+		retryFailures := map[int]esItemFailure{}
+		for j, item := range result.Items {
+			i := pending[j]
+			for _, res := range item {
+				if res.Status >= 200 && res.Status <= 299 {
+					continue
+				}
+
+				errType, reason := "", "no reason given"
+				if res.Error != nil {
+					errType, reason = res.Error.Type, res.Error.Reason
+				}
+				if !shouldRetry(res.Status) {
+					e.log.Errorf("Elasticsearch message '%v' rejected with code [%v]: %v\n", res.Id, res.Status, reason)
+					failItem(i, res.Status, errType, reason)
+					continue
+				}
+
+				e.log.Errorf("Elasticsearch message '%v' failed with code [%v]: %v\n", res.Id, res.Status, reason)
+				retry = append(retry, i)
+				retryFailures[i] = esItemFailure{status: res.Status, errType: errType, reason: reason}
 			}
-			if !shouldRetry(failed[i].Status) {
-				e.log.Errorf("Elasticsearch message '%v' rejected with code [%v]: %v\n", failed[i].Id, failed[i].Status, reason)
-				return fmt.Errorf("failed to send %v parts from message: [%v]: %v", len(failed), failed[i].Status, reason)
-			}
-			e.log.Errorf("Elasticsearch message '%v' failed with code [%v]: %v\n", failed[i].Id, failed[i].Status, reason)
-			id := failed[i].Id
-			req := requests[id]
-			bulkReq, err := e.buildBulkableRequest(id, req)
-			if err != nil {
-				return err
-			}
-			b.Add(bulkReq)
 		}
+		if len(retry) == 0 {
+			break
+		}
+
+		wait := boff.NextBackOff()
 		if wait == backoff.Stop {
-			reason := "no reason given"
-			if fErr := failed[0].Error; fErr != nil {
-				reason = fErr.Reason
+			for _, i := range retry {
+				f := retryFailures[i]
+				failItem(i, f.status, f.errType, f.reason)
 			}
-			return fmt.Errorf("failed to send %v parts from message: %v", len(failed), reason)
+			break
 		}
-		time.Sleep(wait)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		pending = retry
 	}
 
+	if batchErr != nil {
+		return batchErr
+	}
 	return nil
 }
 
+// Write will attempt to write a message to Elasticsearch, wait for
+// acknowledgement, and returns an error if applicable.
+func (e *Elasticsearch) Write(msg types.Message) error {
+	return e.WriteWithContext(context.Background(), msg)
+}
+
 // CloseAsync shuts down the Elasticsearch writer and stops processing messages.
 func (e *Elasticsearch) CloseAsync() {
 }
@@ -353,21 +455,45 @@ func (e *Elasticsearch) WaitForClose(timeout time.Duration) error {
 	return nil
 }
 
+func (e *Elasticsearch) getScript(p *pendingBulkIndex) *elastic.Script {
+	if e.conf.Script.Source == "" {
+		return nil
+	}
+	script := elastic.NewScriptInline(e.conf.Script.Source).Lang(e.conf.Script.Lang)
+	if p.ScriptParams != nil {
+		script = script.Params(p.ScriptParams)
+	}
+	return script
+}
+
 // Build a bulkable request for a given pending bulk index item.
-func (e *Elasticsearch) buildBulkableRequest(id string, p *pendingBulkIndex) (elastic.BulkableRequest, error) {
+func (e *Elasticsearch) buildBulkableRequest(p *pendingBulkIndex) (elastic.BulkableRequest, error) {
 	switch p.Action {
 	case "update":
-		return elastic.NewBulkUpdateRequest().
+		req := elastic.NewBulkUpdateRequest().
 			Index(p.Index).
 			Routing(p.Routing).
 			Type(p.Type).
-			Id(id).
-			Doc(p.Doc), nil
+			Id(p.ID)
+		if script := e.getScript(p); script != nil {
+			return req.Script(script), nil
+		}
+		return req.Doc(p.Doc), nil
+	case "upsert":
+		req := elastic.NewBulkUpdateRequest().
+			Index(p.Index).
+			Routing(p.Routing).
+			Type(p.Type).
+			Id(p.ID)
+		if script := e.getScript(p); script != nil {
+			return req.Script(script).Upsert(p.Doc), nil
+		}
+		return req.Doc(p.Doc).DocAsUpsert(true), nil
 	case "delete":
 		return elastic.NewBulkDeleteRequest().
 			Index(p.Index).
 			Routing(p.Routing).
-			Id(id).
+			Id(p.ID).
 			Type(p.Type), nil
 	case "index":
 		return elastic.NewBulkIndexRequest().
@@ -375,7 +501,15 @@ func (e *Elasticsearch) buildBulkableRequest(id string, p *pendingBulkIndex) (el
 			Pipeline(p.Pipeline).
 			Routing(p.Routing).
 			Type(p.Type).
-			Id(id).
+			Id(p.ID).
+			Doc(p.Doc), nil
+	case "create":
+		return elastic.NewBulkCreateRequest().
+			Index(p.Index).
+			Pipeline(p.Pipeline).
+			Routing(p.Routing).
+			Type(p.Type).
+			Id(p.ID).
 			Doc(p.Doc), nil
 	default:
 		return nil, fmt.Errorf("elasticsearch action '%s' is not allowed", p.Action)
@@ -0,0 +1,249 @@
+package writer
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	ibatch "github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bulkTestServer struct {
+	mut      sync.Mutex
+	attempts map[string]int
+	actions  []map[string]interface{}
+	docs     []map[string]interface{}
+	respond  func(id string, attempt int) (int, string)
+}
+
+func (b *bulkTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	var items []map[string]interface{}
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		b.actions = append(b.actions, action)
+
+		var actionName, id string
+		for k, v := range action {
+			actionName = k
+			id, _ = v.(map[string]interface{})["_id"].(string)
+		}
+		if actionName != "delete" && scanner.Scan() {
+			var doc map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			b.docs = append(b.docs, doc)
+		}
+
+		b.attempts[id]++
+		status, errType := b.respond(id, b.attempts[id])
+		item := map[string]interface{}{
+			"_index": "foo",
+			"_id":    id,
+			"status": status,
+		}
+		if errType != "" {
+			item["error"] = map[string]interface{}{
+				"type":   errType,
+				"reason": errType + " for " + id,
+			}
+		}
+		items = append(items, map[string]interface{}{actionName: item})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"took":   1,
+		"errors": true,
+		"items":  items,
+	})
+}
+
+func newBulkTestWriter(t *testing.T, srv *bulkTestServer, fn func(conf *ElasticsearchConfig)) *Elasticsearch {
+	t.Helper()
+
+	srv.attempts = map[string]int{}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	conf := NewElasticsearchConfig()
+	conf.URLs = []string{ts.URL}
+	conf.Sniff = false
+	conf.Healthcheck = false
+	conf.ID = `${! json("id") }`
+	conf.Index = "foo"
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+	conf.Backoff.MaxElapsedTime = "1s"
+	if fn != nil {
+		fn(&conf)
+	}
+
+	e, err := NewElasticsearchV2(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, e.Connect())
+	return e
+}
+
+func TestElasticsearchBulkPartialRetry(t *testing.T) {
+	srv := &bulkTestServer{
+		respond: func(id string, attempt int) (int, string) {
+			switch id {
+			case "1":
+				if attempt == 1 {
+					return http.StatusTooManyRequests, "es_rejected_execution_exception"
+				}
+			case "2":
+				if attempt == 1 {
+					return http.StatusServiceUnavailable, "unavailable_shards_exception"
+				}
+			case "3":
+				return http.StatusConflict, "version_conflict_engine_exception"
+			case "4":
+				return http.StatusBadRequest, "mapper_parsing_exception"
+			}
+			return http.StatusCreated, ""
+		},
+	}
+	e := newBulkTestWriter(t, srv, nil)
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":"0"}`),
+		[]byte(`{"id":"1"}`),
+		[]byte(`{"id":"2"}`),
+		[]byte(`{"id":"3"}`),
+		[]byte(`{"id":"4"}`),
+	})
+	err := e.Write(msg)
+	require.Error(t, err)
+
+	// Only the items that failed with a retryable status are retried.
+	assert.Equal(t, map[string]int{"0": 1, "1": 2, "2": 2, "3": 1, "4": 1}, srv.attempts)
+
+	bErr, ok := err.(*ibatch.Error)
+	require.True(t, ok, "%T", err)
+	assert.Equal(t, 2, bErr.IndexedErrors())
+
+	var failed []int
+	parts := map[int]types.Part{}
+	bErr.WalkParts(func(i int, p types.Part, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		parts[i] = p
+		return true
+	})
+	assert.Equal(t, []int{3, 4}, failed)
+
+	assert.Equal(t, "409", parts[3].Metadata().Get("elasticsearch_error_status"))
+	assert.Equal(t, "version_conflict_engine_exception", parts[3].Metadata().Get("elasticsearch_error_type"))
+	assert.Equal(t, "version_conflict_engine_exception for 3", parts[3].Metadata().Get("elasticsearch_error_reason"))
+	assert.Equal(t, "mapper_parsing_exception", parts[4].Metadata().Get("elasticsearch_error_type"))
+	assert.Equal(t, "", parts[0].Metadata().Get("elasticsearch_error_type"))
+
+	// The messages of the caller are not modified.
+	assert.Equal(t, "", msg.Get(3).Metadata().Get("elasticsearch_error_status"))
+	assert.Equal(t, "", msg.Get(4).Metadata().Get("elasticsearch_error_type"))
+}
+
+func TestElasticsearchBulkRetriesExhausted(t *testing.T) {
+	srv := &bulkTestServer{
+		respond: func(id string, attempt int) (int, string) {
+			switch id {
+			case "1":
+				return http.StatusTooManyRequests, "es_rejected_execution_exception"
+			case "2":
+				return http.StatusServiceUnavailable, "unavailable_shards_exception"
+			}
+			return http.StatusCreated, ""
+		},
+	}
+	e := newBulkTestWriter(t, srv, func(conf *ElasticsearchConfig) {
+		conf.MaxRetries = 2
+	})
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":"0"}`),
+		[]byte(`{"id":"1"}`),
+		[]byte(`{"id":"2"}`),
+	})
+	err := e.Write(msg)
+	require.Error(t, err)
+
+	bErr, ok := err.(*ibatch.Error)
+	require.True(t, ok, "%T", err)
+	assert.Equal(t, 2, bErr.IndexedErrors())
+	assert.Equal(t, map[string]int{"0": 1, "1": 3, "2": 3}, srv.attempts)
+
+	// Each document is failed with its own error.
+	parts := map[int]types.Part{}
+	errs := map[int]error{}
+	bErr.WalkParts(func(i int, p types.Part, err error) bool {
+		parts[i] = p
+		errs[i] = err
+		return true
+	})
+	assert.NoError(t, errs[0])
+	assert.Equal(t, "429", parts[1].Metadata().Get("elasticsearch_error_status"))
+	assert.Equal(t, "es_rejected_execution_exception", parts[1].Metadata().Get("elasticsearch_error_type"))
+	assert.Contains(t, errs[1].Error(), "[429]")
+	assert.Equal(t, "503", parts[2].Metadata().Get("elasticsearch_error_status"))
+	assert.Equal(t, "unavailable_shards_exception", parts[2].Metadata().Get("elasticsearch_error_type"))
+	assert.Equal(t, "unavailable_shards_exception for 2", parts[2].Metadata().Get("elasticsearch_error_reason"))
+	assert.Contains(t, errs[2].Error(), "[503]")
+}
+
+func TestElasticsearchBulkActions(t *testing.T) {
+	srv := &bulkTestServer{
+		respond: func(id string, attempt int) (int, string) {
+			return http.StatusOK, ""
+		},
+	}
+	e := newBulkTestWriter(t, srv, func(conf *ElasticsearchConfig) {
+		conf.Action = `${! json("action") }`
+		conf.Script.Source = "ctx._source.count += params.count"
+		conf.Script.ParamsMapping = `root.count = this.count | 1`
+	})
+
+	require.NoError(t, e.Write(message.New([][]byte{
+		[]byte(`{"id":"0","action":"create"}`),
+		[]byte(`{"id":"1","action":"upsert","count":5}`),
+		[]byte(`{"id":"2","action":"update","count":2}`),
+	})))
+
+	require.Len(t, srv.actions, 3)
+	assert.Contains(t, srv.actions[0], "create")
+	assert.Contains(t, srv.actions[1], "update")
+	assert.Contains(t, srv.actions[2], "update")
+
+	require.Len(t, srv.docs, 3)
+	assert.Equal(t, map[string]interface{}{"action": "create", "id": "0"}, srv.docs[0])
+
+	upsertDoc, _ := json.Marshal(srv.docs[1])
+	assert.True(t, strings.Contains(string(upsertDoc), `"upsert":{"action":"upsert","count":5,"id":"1"}`), string(upsertDoc))
+	assert.True(t, strings.Contains(string(upsertDoc), `"params":{"count":5}`), string(upsertDoc))
+	assert.True(t, strings.Contains(string(upsertDoc), `"source":"ctx._source.count += params.count"`), string(upsertDoc))
+
+	updateDoc, _ := json.Marshal(srv.docs[2])
+	assert.True(t, strings.Contains(string(updateDoc), `"params":{"count":2}`), string(updateDoc))
+	assert.False(t, strings.Contains(string(updateDoc), `"upsert"`), string(updateDoc))
+}
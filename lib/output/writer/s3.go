@@ -3,7 +3,9 @@ package writer
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"sort"
 	"strings"
@@ -27,22 +29,24 @@ import (
 // AmazonS3Config contains configuration fields for the AmazonS3 output type.
 type AmazonS3Config struct {
 	sess.Config             `json:",inline" yaml:",inline"`
-	Bucket                  string             `json:"bucket" yaml:"bucket"`
-	ForcePathStyleURLs      bool               `json:"force_path_style_urls" yaml:"force_path_style_urls"`
-	Path                    string             `json:"path" yaml:"path"`
-	Tags                    map[string]string  `json:"tags" yaml:"tags"`
-	ContentType             string             `json:"content_type" yaml:"content_type"`
-	ContentEncoding         string             `json:"content_encoding" yaml:"content_encoding"`
-	CacheControl            string             `json:"cache_control" yaml:"cache_control"`
-	ContentDisposition      string             `json:"content_disposition" yaml:"content_disposition"`
-	ContentLanguage         string             `json:"content_language" yaml:"content_language"`
-	WebsiteRedirectLocation string             `json:"website_redirect_location" yaml:"website_redirect_location"`
-	Metadata                output.Metadata    `json:"metadata" yaml:"metadata"`
-	StorageClass            string             `json:"storage_class" yaml:"storage_class"`
-	Timeout                 string             `json:"timeout" yaml:"timeout"`
-	KMSKeyID                string             `json:"kms_key_id" yaml:"kms_key_id"`
-	MaxInFlight             int                `json:"max_in_flight" yaml:"max_in_flight"`
-	Batching                batch.PolicyConfig `json:"batching" yaml:"batching"`
+	Bucket                  string                       `json:"bucket" yaml:"bucket"`
+	ForcePathStyleURLs      bool                         `json:"force_path_style_urls" yaml:"force_path_style_urls"`
+	Path                    string                       `json:"path" yaml:"path"`
+	Tags                    map[string]string            `json:"tags" yaml:"tags"`
+	ContentType             string                       `json:"content_type" yaml:"content_type"`
+	ContentEncoding         string                       `json:"content_encoding" yaml:"content_encoding"`
+	CacheControl            string                       `json:"cache_control" yaml:"cache_control"`
+	ContentDisposition      string                       `json:"content_disposition" yaml:"content_disposition"`
+	ContentLanguage         string                       `json:"content_language" yaml:"content_language"`
+	WebsiteRedirectLocation string                       `json:"website_redirect_location" yaml:"website_redirect_location"`
+	Metadata                output.Metadata              `json:"metadata" yaml:"metadata"`
+	StorageClass            string                       `json:"storage_class" yaml:"storage_class"`
+	Timeout                 string                       `json:"timeout" yaml:"timeout"`
+	KMSKeyID                string                       `json:"kms_key_id" yaml:"kms_key_id"`
+	MaxInFlight             int                          `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching                batch.PolicyConfig           `json:"batching" yaml:"batching"`
+	Rolling                 output.RollingConfig         `json:"rolling" yaml:"rolling"`
+	PartitionCommit         output.PartitionCommitConfig `json:"partition_commit" yaml:"partition_commit"`
 }
 
 // NewAmazonS3Config creates a new Config with default values.
@@ -65,6 +69,8 @@ func NewAmazonS3Config() AmazonS3Config {
 		KMSKeyID:                "",
 		MaxInFlight:             1,
 		Batching:                batch.NewPolicyConfig(),
+		Rolling:                 output.NewRollingConfig(),
+		PartitionCommit:         output.NewPartitionCommitConfig(),
 	}
 }
 
@@ -90,6 +96,8 @@ type AmazonS3 struct {
 	websiteRedirectLocation *field.Expression
 	storageClass            *field.Expression
 	metaFilter              *output.MetadataFilter
+	rolling                 *output.Rolling
+	partitions              *output.PartitionCommitter
 
 	session  *session.Session
 	uploader *s3manager.Uploader
@@ -175,6 +183,16 @@ func NewAmazonS3V2(
 		return a.tags[i].key < a.tags[j].key
 	})
 
+	if conf.Rolling.Enabled {
+		if a.rolling, err = output.NewRolling(conf.Rolling, a.openRollingObject, log); err != nil {
+			return nil, fmt.Errorf("failed to create rolling objects: %w", err)
+		}
+	}
+	if conf.PartitionCommit.Enabled {
+		if a.partitions, err = output.NewPartitionCommitter(conf.PartitionCommit, a.writeMarker, mgr, log, stats); err != nil {
+			return nil, fmt.Errorf("failed to create partition committer: %w", err)
+		}
+	}
 	return a, nil
 }
 
@@ -216,81 +234,201 @@ func (a *AmazonS3) WriteWithContext(wctx context.Context, msg types.Message) err
 		return types.ErrNotConnected
 	}
 
+	if a.partitions == nil {
+		return a.writeBatch(wctx, msg, nil)
+	}
+
+	pw := a.partitions.Begin(msg)
+	defer pw.End(wctx, a.flushPartition)
+
+	if err := a.writeBatch(wctx, msg, pw.Key); err != nil {
+		return err
+	}
+	for i := 0; i < msg.Len(); i++ {
+		pw.Track(i, a.path.String(i, msg))
+	}
+	return nil
+}
+
+func (a *AmazonS3) writeBatch(wctx context.Context, msg types.Message, keyFn func(int) string) error {
+	if a.rolling != nil {
+		// Writes block until their object is committed, which is subject to
+		// the rolling limits rather than the upload timeout.
+		return a.rolling.WriteKeyed(wctx, msg, keyFn)
+	}
+
 	ctx, cancel := context.WithTimeout(
 		wctx, a.timeout,
 	)
 	defer cancel()
 
 	return IterateBatchedSend(msg, func(i int, p types.Part) error {
-		metadata := map[string]*string{}
-		a.metaFilter.Iter(p.Metadata(), func(k, v string) error {
-			metadata[k] = aws.String(v)
-			return nil
-		})
-
-		var contentEncoding *string
-		if ce := a.contentEncoding.String(i, msg); len(ce) > 0 {
-			contentEncoding = aws.String(ce)
-		}
-		var cacheControl *string
-		if ce := a.cacheControl.String(i, msg); len(ce) > 0 {
-			cacheControl = aws.String(ce)
-		}
-		var contentDisposition *string
-		if ce := a.contentDisposition.String(i, msg); len(ce) > 0 {
-			contentDisposition = aws.String(ce)
-		}
-		var contentLanguage *string
-		if ce := a.contentLanguage.String(i, msg); len(ce) > 0 {
-			contentLanguage = aws.String(ce)
-		}
-		var websiteRedirectLocation *string
-		if ce := a.websiteRedirectLocation.String(i, msg); len(ce) > 0 {
-			websiteRedirectLocation = aws.String(ce)
+		if _, err := a.uploader.UploadWithContext(ctx, a.uploadInput(i, msg, bytes.NewReader(p.Get()))); err != nil {
+			return err
 		}
+		return nil
+	})
+}
 
-		uploadInput := &s3manager.UploadInput{
-			Bucket:                  &a.conf.Bucket,
-			Key:                     aws.String(a.path.String(i, msg)),
-			Body:                    bytes.NewReader(p.Get()),
-			ContentType:             aws.String(a.contentType.String(i, msg)),
-			ContentEncoding:         contentEncoding,
-			CacheControl:            cacheControl,
-			ContentDisposition:      contentDisposition,
-			ContentLanguage:         contentLanguage,
-			WebsiteRedirectLocation: websiteRedirectLocation,
-			StorageClass:            aws.String(a.storageClass.String(i, msg)),
-			Metadata:                metadata,
-		}
+func (a *AmazonS3) uploadInput(i int, msg types.Message, body io.Reader) *s3manager.UploadInput {
+	metadata := map[string]*string{}
+	a.metaFilter.Iter(msg.Get(i).Metadata(), func(k, v string) error {
+		metadata[k] = aws.String(v)
+		return nil
+	})
 
-		// Prepare tags, escaping keys and values to ensure they're valid query string parameters.
-		if len(a.tags) > 0 {
-			tags := make([]string, len(a.tags))
-			for j, pair := range a.tags {
-				tags[j] = url.QueryEscape(pair.key) + "=" + url.QueryEscape(pair.value.String(i, msg))
-			}
-			uploadInput.Tagging = aws.String(strings.Join(tags, "&"))
-		}
+	var contentEncoding *string
+	if ce := a.contentEncoding.String(i, msg); len(ce) > 0 {
+		contentEncoding = aws.String(ce)
+	}
+	var cacheControl *string
+	if ce := a.cacheControl.String(i, msg); len(ce) > 0 {
+		cacheControl = aws.String(ce)
+	}
+	var contentDisposition *string
+	if ce := a.contentDisposition.String(i, msg); len(ce) > 0 {
+		contentDisposition = aws.String(ce)
+	}
+	var contentLanguage *string
+	if ce := a.contentLanguage.String(i, msg); len(ce) > 0 {
+		contentLanguage = aws.String(ce)
+	}
+	var websiteRedirectLocation *string
+	if ce := a.websiteRedirectLocation.String(i, msg); len(ce) > 0 {
+		websiteRedirectLocation = aws.String(ce)
+	}
 
-		if a.conf.KMSKeyID != "" {
-			uploadInput.ServerSideEncryption = aws.String("aws:kms")
-			uploadInput.SSEKMSKeyId = &a.conf.KMSKeyID
+	uploadInput := &s3manager.UploadInput{
+		Bucket:                  &a.conf.Bucket,
+		Key:                     aws.String(a.path.String(i, msg)),
+		Body:                    body,
+		ContentType:             aws.String(a.contentType.String(i, msg)),
+		ContentEncoding:         contentEncoding,
+		CacheControl:            cacheControl,
+		ContentDisposition:      contentDisposition,
+		ContentLanguage:         contentLanguage,
+		WebsiteRedirectLocation: websiteRedirectLocation,
+		StorageClass:            aws.String(a.storageClass.String(i, msg)),
+		Metadata:                metadata,
+	}
+
+	// Prepare tags, escaping keys and values to ensure they're valid query string parameters.
+	if len(a.tags) > 0 {
+		tags := make([]string, len(a.tags))
+		for j, pair := range a.tags {
+			tags[j] = url.QueryEscape(pair.key) + "=" + url.QueryEscape(pair.value.String(i, msg))
 		}
+		uploadInput.Tagging = aws.String(strings.Join(tags, "&"))
+	}
 
-		if _, err := a.uploader.UploadWithContext(ctx, uploadInput); err != nil {
-			return err
+	if a.conf.KMSKeyID != "" {
+		uploadInput.ServerSideEncryption = aws.String("aws:kms")
+		uploadInput.SSEKMSKeyId = &a.conf.KMSKeyID
+	}
+	return uploadInput
+}
+
+// flushPartition commits the rolling objects of a partition.
+func (a *AmazonS3) flushPartition(partition time.Time) {
+	if a.rolling != nil {
+		a.rolling.Roll(output.PartitionKey(partition))
+	}
+}
+
+func (a *AmazonS3) writeMarker(ctx context.Context, path string) error {
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	uploadInput := &s3manager.UploadInput{
+		Bucket: &a.conf.Bucket,
+		Key:    aws.String(path),
+		Body:   bytes.NewReader(nil),
+	}
+	if a.conf.KMSKeyID != "" {
+		uploadInput.ServerSideEncryption = aws.String("aws:kms")
+		uploadInput.SSEKMSKeyId = &a.conf.KMSKeyID
+	}
+	_, err := a.uploader.UploadWithContext(ctx, uploadInput)
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+var errS3ObjectAborted = errors.New("object aborted")
+
+// s3RollingObject streams an object to S3 with a multipart upload that is
+// completed once the object is committed.
+type s3RollingObject struct {
+	pipe     *io.PipeWriter
+	uploaded chan error
+}
+
+func (a *AmazonS3) openRollingObject(ctx context.Context, msg types.Message, index int) (output.RollingObject, error) {
+	if a.session == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	pr, pw := io.Pipe()
+	o := &s3RollingObject{
+		pipe:     pw,
+		uploaded: make(chan error, 1),
+	}
+	uploadInput := a.uploadInput(index, msg, pr)
+	go func() {
+		_, err := a.uploader.UploadWithContext(ctx, uploadInput)
+		if err != nil {
+			// Unblock any pending writes to the object.
+			pr.CloseWithError(err)
 		}
-		return nil
-	})
+		o.uploaded <- err
+	}()
+	return o, nil
+}
+
+func (o *s3RollingObject) Write(b []byte) (int, error) {
+	return o.pipe.Write(b)
+}
+
+func (o *s3RollingObject) Commit(ctx context.Context) error {
+	o.pipe.Close()
+	select {
+	case err := <-o.uploaded:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
+func (o *s3RollingObject) Abort() {
+	o.pipe.CloseWithError(errS3ObjectAborted)
+}
+
+//------------------------------------------------------------------------------
+
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
 func (a *AmazonS3) CloseAsync() {
 }
 
 // WaitForClose will block until either the reader is closed or a specified
 // timeout occurs.
-func (a *AmazonS3) WaitForClose(time.Duration) error {
+func (a *AmazonS3) WaitForClose(timeout time.Duration) error {
+	stopBy := time.Now().Add(timeout)
+	if a.rolling != nil {
+		ctx, done := context.WithDeadline(context.Background(), stopBy)
+		defer done()
+		if err := a.rolling.Close(ctx); err != nil {
+			return types.ErrTimeout
+		}
+	}
+	if a.partitions != nil {
+		// Partitions are committed once their objects have been flushed, and
+		// before the commit processors are closed.
+		ctx, done := context.WithDeadline(context.Background(), stopBy)
+		defer done()
+		a.partitions.CommitAll(ctx, a.flushPartition)
+		a.partitions.CloseAsync()
+		return a.partitions.WaitForClose(time.Until(stopBy))
+	}
 	return nil
 }
 
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/internal/interop"
@@ -26,13 +28,16 @@ func init() {
 		Summary: `
 Performs operations against a [cache resource](/docs/components/caches/about) for each message, allowing you to store or retrieve data within message payloads.`,
 		Description: `
-This processor will interpolate functions within the ` + "`key` and `value`" + ` fields individually for each message. This allows you to specify dynamic keys and values based on the contents of the message payloads and metadata. You can find a list of functions [here](/docs/configuration/interpolation#bloblang-queries).`,
+This processor will interpolate functions within the ` + "`key` and `value`" + ` fields individually for each message. This allows you to specify dynamic keys and values based on the contents of the message payloads and metadata. You can find a list of functions [here](/docs/configuration/interpolation#bloblang-queries).
+
+When processing a batch of messages with the ` + "`get` or `set`" + ` operators the keys of all messages are read or written with a single multi-key operation where the cache supports it, which is the case for the ` + "`memory`, `redis`, `memcached` and `aws_dynamodb`" + ` caches.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("resource", "The [`cache` resource](/docs/components/caches/about) to target with this processor."),
 			docs.FieldDeprecated("cache"),
-			docs.FieldCommon("operator", "The [operation](#operators) to perform with the cache.").HasOptions("set", "add", "get", "delete"),
+			docs.FieldCommon("operator", "The [operation](#operators) to perform with the cache.").HasOptions("set", "add", "get", "delete", "incr", "decr", "cas"),
 			docs.FieldCommon("key", "A key to use with the cache.").IsInterpolated(),
 			docs.FieldCommon("value", "A value to use with the cache (when applicable).").IsInterpolated(),
+			docs.FieldAdvanced("old_value", "The value that a key is expected to currently hold when using the `cas` operator. When empty the operation only succeeds if the key does not exist.").IsInterpolated().AtVersion("3.60.0"),
 			docs.FieldAdvanced(
 				"ttl", "The TTL of each individual item as a duration string. After this period an item will be eligible for removal during the next compaction. Not all caches support per-key TTLs, and those that do not will fall back to their generally configured TTL setting.",
 				"60s", "5m", "36h",
@@ -87,6 +92,30 @@ cache_resources:
   - label: foocache
     memcached:
       addresses: [ "TODO:11211" ]
+`,
+			},
+			{
+				Title: "Counting",
+				Summary: `
+The incr operator atomically increments a counter and replaces the message
+payload with the result, which can be combined with the
+[` + "`branch`" + `](/docs/components/processors/branch) processor in order to
+add the running count of each user to their messages:`,
+				Config: `
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - cache:
+              resource: foocache
+              operator: incr
+              key: '${! json("user.id") }'
+        result_map: 'root.user.message_count = this'
+
+cache_resources:
+  - label: foocache
+    redis:
+      url: tcp://TODO:6379
 `,
 			},
 		},
@@ -113,7 +142,33 @@ can be detected with [processor error handling](/docs/configuration/error_handli
 ### ` + "`delete`" + `
 
 Delete a key and its contents from the cache.  If the key does not exist the
-action is a no-op and will not fail with an error.`,
+action is a no-op and will not fail with an error.
+
+### ` + "`incr`" + `
+
+Atomically increment the integer value of a key by the amount specified in the
+` + "`value`" + ` field, or by 1 when the value is empty, and replace the original
+message payload with the resulting number. If the key does not exist it is
+treated as having a value of 0. This operator is supported by the
+` + "`memory`, `redis`, `memcached` and `aws_dynamodb`" + ` caches.
+
+### ` + "`decr`" + `
+
+Atomically decrement the integer value of a key by the amount specified in the
+` + "`value`" + ` field, or by 1 when the value is empty, and replace the original
+message payload with the resulting number. This operator is supported by the
+same caches as ` + "`incr`" + `, note that ` + "`memcached`" + ` counters cannot be
+decremented below 0.
+
+### ` + "`cas`" + `
+
+Set a key in the cache to a value only if the key currently holds the value of
+the ` + "`old_value`" + ` field, or if the key does not exist when ` + "`old_value`" + `
+is empty. If the key holds any other value the action fails with a 'key value
+does not match' error, which can be detected with
+[processor error handling](/docs/configuration/error_handling). This operator
+is supported by the ` + "`memory`, `redis`, `memcached` and `aws_dynamodb`" + `
+caches.`,
 	}
 }
 
@@ -127,6 +182,7 @@ type CacheConfig struct {
 	Operator string `json:"operator" yaml:"operator"`
 	Key      string `json:"key" yaml:"key"`
 	Value    string `json:"value" yaml:"value"`
+	OldValue string `json:"old_value" yaml:"old_value"`
 	TTL      string `json:"ttl" yaml:"ttl"`
 }
 
@@ -139,6 +195,7 @@ func NewCacheConfig() CacheConfig {
 		Operator: "set",
 		Key:      "",
 		Value:    "",
+		OldValue: "",
 		TTL:      "",
 	}
 }
@@ -154,9 +211,10 @@ type Cache struct {
 
 	parts []int
 
-	key   *field.Expression
-	value *field.Expression
-	ttl   *field.Expression
+	key      *field.Expression
+	value    *field.Expression
+	oldValue *field.Expression
+	ttl      *field.Expression
 
 	mgr       types.Manager
 	cacheName string
@@ -196,6 +254,11 @@ func NewCache(
 		return nil, fmt.Errorf("failed to parse value expression: %v", err)
 	}
 
+	oldValue, err := interop.NewBloblangField(mgr, conf.Cache.OldValue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse old_value expression: %v", err)
+	}
+
 	ttl, err := interop.NewBloblangField(mgr, conf.Cache.TTL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse ttl expression: %v", err)
@@ -212,9 +275,10 @@ func NewCache(
 
 		parts: conf.Cache.Parts,
 
-		key:   key,
-		value: value,
-		ttl:   ttl,
+		key:      key,
+		value:    value,
+		oldValue: oldValue,
+		ttl:      ttl,
 
 		mgr:       mgr,
 		cacheName: cacheName,
@@ -230,10 +294,10 @@ func NewCache(
 
 //------------------------------------------------------------------------------
 
-type cacheOperator func(cache types.Cache, key string, value []byte, ttl *time.Duration) ([]byte, bool, error)
+type cacheOperator func(cache types.Cache, key string, value, oldValue []byte, ttl *time.Duration) ([]byte, bool, error)
 
 func newCacheSetOperator() cacheOperator {
-	return func(cache types.Cache, key string, value []byte, ttl *time.Duration) ([]byte, bool, error) {
+	return func(cache types.Cache, key string, value, _ []byte, ttl *time.Duration) ([]byte, bool, error) {
 		var err error
 		if cttl, ok := cache.(types.CacheWithTTL); ok {
 			err = cttl.SetWithTTL(key, value, ttl)
@@ -245,7 +309,7 @@ func newCacheSetOperator() cacheOperator {
 }
 
 func newCacheAddOperator() cacheOperator {
-	return func(cache types.Cache, key string, value []byte, ttl *time.Duration) ([]byte, bool, error) {
+	return func(cache types.Cache, key string, value, _ []byte, ttl *time.Duration) ([]byte, bool, error) {
 		var err error
 		if cttl, ok := cache.(types.CacheWithTTL); ok {
 			err = cttl.AddWithTTL(key, value, ttl)
@@ -257,19 +321,53 @@ func newCacheAddOperator() cacheOperator {
 }
 
 func newCacheGetOperator() cacheOperator {
-	return func(cache types.Cache, key string, _ []byte, _ *time.Duration) ([]byte, bool, error) {
+	return func(cache types.Cache, key string, _, _ []byte, _ *time.Duration) ([]byte, bool, error) {
 		result, err := cache.Get(key)
 		return result, true, err
 	}
 }
 
 func newCacheDeleteOperator() cacheOperator {
-	return func(cache types.Cache, key string, _ []byte, ttl *time.Duration) ([]byte, bool, error) {
+	return func(cache types.Cache, key string, _, _ []byte, _ *time.Duration) ([]byte, bool, error) {
 		err := cache.Delete(key)
 		return nil, false, err
 	}
 }
 
+func newCacheIncrOperator(sign int64) cacheOperator {
+	return func(cache types.Cache, key string, value, _ []byte, _ *time.Duration) ([]byte, bool, error) {
+		icache, ok := cache.(types.CacheWithIncr)
+		if !ok {
+			return nil, false, errors.New("cache does not support incr and decr operators")
+		}
+		delta := int64(1)
+		if len(value) > 0 {
+			var err error
+			if delta, err = strconv.ParseInt(string(value), 10, 64); err != nil {
+				return nil, false, fmt.Errorf("value must be an integer: %w", err)
+			}
+		}
+		result, err := icache.Incr(key, sign*delta)
+		if err != nil {
+			return nil, false, err
+		}
+		return []byte(strconv.FormatInt(result, 10)), true, nil
+	}
+}
+
+func newCacheCASOperator() cacheOperator {
+	return func(cache types.Cache, key string, value, oldValue []byte, ttl *time.Duration) ([]byte, bool, error) {
+		ccache, ok := cache.(types.CacheWithCAS)
+		if !ok {
+			return nil, false, errors.New("cache does not support the cas operator")
+		}
+		if len(oldValue) == 0 {
+			oldValue = nil
+		}
+		return nil, false, ccache.CompareAndSwap(key, oldValue, value, ttl)
+	}
+}
+
 func cacheOperatorFromString(operator string) (cacheOperator, error) {
 	switch operator {
 	case "set":
@@ -280,38 +378,181 @@ func cacheOperatorFromString(operator string) (cacheOperator, error) {
 		return newCacheGetOperator(), nil
 	case "delete":
 		return newCacheDeleteOperator(), nil
+	case "incr":
+		return newCacheIncrOperator(1), nil
+	case "decr":
+		return newCacheIncrOperator(-1), nil
+	case "cas":
+		return newCacheCASOperator(), nil
 	}
 	return nil, fmt.Errorf("operator not recognised: %v", operator)
 }
 
 //------------------------------------------------------------------------------
 
+func (c *Cache) parseTTL(index int, msg types.Message) (*time.Duration, error) {
+	ttls := c.ttl.String(index, msg)
+	if ttls == "" {
+		return nil, nil
+	}
+	td, err := time.ParseDuration(ttls)
+	if err != nil {
+		c.mErr.Incr(1)
+		c.log.Debugf("TTL must be a duration: %v\n", err)
+		return nil, err
+	}
+	return &td, nil
+}
+
+type cacheBatchItem struct {
+	key   string
+	value []byte
+	ttl   *time.Duration
+	err   error
+}
+
+// processBatch performs the get and set operators for all messages of a batch
+// with a single multi-key operation, where supported by the cache. Returns
+// false if the batch should instead be processed one message at a time.
+func (c *Cache) processBatch(msg, newMsg types.Message) bool {
+	op := c.conf.Cache.Operator
+	if (op != "get" && op != "set") || newMsg.Len() < 2 {
+		return false
+	}
+
+	indexes := c.parts
+	if len(indexes) == 0 {
+		indexes = make([]int, newMsg.Len())
+		for i := range indexes {
+			indexes[i] = i
+		}
+	}
+
+	items := make(map[int]cacheBatchItem, len(indexes))
+	for _, index := range indexes {
+		item := cacheBatchItem{key: c.key.String(index, msg)}
+		if op == "set" {
+			item.value = c.value.Bytes(index, msg)
+			item.ttl, item.err = c.parseTTL(index, msg)
+		}
+		items[index] = item
+	}
+
+	var handled bool
+	var results map[string][]byte
+	var batchErr error
+	if cerr := interop.AccessCache(context.Background(), c.mgr, c.cacheName, func(cache types.Cache) {
+		switch op {
+		case "get":
+			mcache, ok := cache.(types.CacheWithGetMulti)
+			if !ok {
+				return
+			}
+			handled = true
+			keys := make([]string, 0, len(items))
+			seen := make(map[string]struct{}, len(items))
+			for _, index := range indexes {
+				key := items[index].key
+				if _, exists := seen[key]; !exists {
+					seen[key] = struct{}{}
+					keys = append(keys, key)
+				}
+			}
+			results, batchErr = mcache.GetMulti(keys)
+		case "set":
+			handled = true
+			ttlItems := make(map[string]types.CacheTTLItem, len(items))
+			for _, index := range indexes {
+				if item := items[index]; item.err == nil {
+					ttlItems[item.key] = types.CacheTTLItem{Value: item.value, TTL: item.ttl}
+				}
+			}
+			if cttl, ok := cache.(types.CacheWithTTL); ok {
+				batchErr = cttl.SetMultiWithTTL(ttlItems)
+			} else {
+				values := make(map[string][]byte, len(ttlItems))
+				for k, v := range ttlItems {
+					values[k] = v.Value
+				}
+				batchErr = cache.SetMulti(values)
+			}
+		}
+	}); cerr != nil || !handled {
+		return false
+	}
+
+	// When the cache reports which keys failed only the messages of those keys
+	// are flagged, otherwise the error applies to all messages of the batch.
+	var failed map[int]error
+	if batchErr != nil {
+		bErr := batch.NewError(newMsg, batchErr)
+		var kErr *types.KeysError
+		if errors.As(batchErr, &kErr) && len(kErr.KeyErrors()) > 0 {
+			for _, index := range indexes {
+				if err, exists := kErr.KeyErrors()[items[index].key]; exists {
+					bErr.Failed(index, err)
+				}
+			}
+		}
+		failed = map[int]error{}
+		bErr.WalkParts(func(i int, _ types.Part, err error) bool {
+			if err != nil {
+				failed[i] = err
+			}
+			return true
+		})
+	}
+
+	IteratePartsWithSpan(TypeCache, c.parts, newMsg, func(index int, _ opentracing.Span, part types.Part) error {
+		item := items[index]
+		if item.err != nil {
+			return item.err
+		}
+		if err, isFailed := failed[index]; isFailed {
+			c.mErr.Incr(1)
+			c.log.Debugf("Operator failed for key '%s': %v\n", item.key, err)
+			return err
+		}
+		if op == "get" {
+			v, exists := results[item.key]
+			if !exists {
+				c.mErr.Incr(1)
+				c.log.Debugf("Operator failed for key '%s': %v\n", item.key, types.ErrKeyNotFound)
+				return types.ErrKeyNotFound
+			}
+			part.Set(v)
+		}
+		return nil
+	})
+	return true
+}
+
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (c *Cache) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
 	c.mCount.Incr(1)
 	newMsg := msg.Copy()
 
+	if c.processBatch(msg, newMsg) {
+		c.mBatchSent.Incr(1)
+		c.mSent.Incr(int64(newMsg.Len()))
+		return []types.Message{newMsg}, nil
+	}
+
 	proc := func(index int, span opentracing.Span, part types.Part) error {
 		key := c.key.String(index, msg)
 		value := c.value.Bytes(index, msg)
+		oldValue := c.oldValue.Bytes(index, msg)
 
-		var ttl *time.Duration
-		if ttls := c.ttl.String(index, msg); ttls != "" {
-			td, err := time.ParseDuration(ttls)
-			if err != nil {
-				c.mErr.Incr(1)
-				c.log.Debugf("TTL must be a duration: %v\n", err)
-				return err
-			}
-			ttl = &td
+		ttl, err := c.parseTTL(index, msg)
+		if err != nil {
+			return err
 		}
 
 		var result []byte
 		var useResult bool
-		var err error
 		if cerr := interop.AccessCache(context.Background(), c.mgr, c.cacheName, func(cache types.Cache) {
-			result, useResult, err = c.operator(cache, key, value, ttl)
+			result, useResult, err = c.operator(cache, key, value, oldValue, ttl)
 		}); cerr != nil {
 			err = cerr
 		}
@@ -1,6 +1,7 @@
 package processor
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 
@@ -382,3 +383,286 @@ func TestCacheDelete(t *testing.T) {
 		t.Errorf("Wrong result: %v != %v", err, types.ErrKeyNotFound)
 	}
 }
+
+func TestCacheIncrDecr(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Cache.Key = "${!json(\"key\")}"
+	conf.Cache.Value = "${!json(\"delta\").or(\"\")}"
+	conf.Cache.Resource = "foocache"
+	conf.Cache.Operator = "incr"
+	incr, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.Cache.Operator = "decr"
+	decr, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, res := incr.ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"foo"}`),
+		[]byte(`{"key":"foo","delta":"5"}`),
+		[]byte(`{"key":"bar","delta":"2"}`),
+		[]byte(`{"key":"foo","delta":"nope"}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(output) != 1 {
+		t.Fatalf("Wrong count of result messages: %v", len(output))
+	}
+
+	exp := [][]byte{
+		[]byte(`1`),
+		[]byte(`6`),
+		[]byte(`2`),
+		[]byte(`{"key":"foo","delta":"nope"}`),
+	}
+	if act := message.GetAllBytes(output[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result messages: %s != %s", act, exp)
+	}
+	if exp, act := true, HasFailed(output[0].Get(3)); exp != act {
+		t.Errorf("Wrong fail flag: %v != %v", act, exp)
+	}
+
+	output, res = decr.ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"foo","delta":"10"}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := [][]byte{[]byte(`-4`)}, message.GetAllBytes(output[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result messages: %s != %s", act, exp)
+	}
+
+	actBytes, err := memCache.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "-4", string(actBytes); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestCacheCAS(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Cache.Key = "${!json(\"key\")}"
+	conf.Cache.Value = "${!json(\"value\")}"
+	conf.Cache.OldValue = "${!json(\"old\").or(\"\")}"
+	conf.Cache.Resource = "foocache"
+	conf.Cache.Operator = "cas"
+	proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte(`{"key":"1","value":"foo 1"}`),
+		[]byte(`{"key":"1","value":"foo 2"}`),
+		[]byte(`{"key":"1","value":"foo 3","old":"foo 1"}`),
+		[]byte(`{"key":"1","value":"foo 4","old":"foo 1"}`),
+	})
+
+	output, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(output) != 1 {
+		t.Fatalf("Wrong count of result messages: %v", len(output))
+	}
+
+	if exp, act := message.GetAllBytes(input), message.GetAllBytes(output[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result messages: %s != %s", act, exp)
+	}
+	for i, exp := range []bool{false, true, false, true} {
+		if act := HasFailed(output[0].Get(i)); exp != act {
+			t.Errorf("Wrong fail flag for message %v: %v != %v", i, act, exp)
+		}
+	}
+
+	actBytes, err := memCache.Get("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo 3", string(actBytes); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+type multiCallCache struct {
+	types.CacheWithGetMulti
+	getMultiCalls int
+	setMultiCalls int
+	setMultiErr   error
+}
+
+func (m *multiCallCache) GetMulti(keys []string) (map[string][]byte, error) {
+	m.getMultiCalls++
+	return m.CacheWithGetMulti.GetMulti(keys)
+}
+
+func (m *multiCallCache) SetMulti(items map[string][]byte) error {
+	m.setMultiCalls++
+	if m.setMultiErr != nil {
+		return m.setMultiErr
+	}
+	return m.CacheWithGetMulti.SetMulti(items)
+}
+
+func TestCacheBatchedGetSet(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mCache := &multiCallCache{CacheWithGetMulti: memCache.(types.CacheWithGetMulti)}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": mCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Cache.Key = "${!json(\"key\")}"
+	conf.Cache.Value = "${!json(\"value\")}"
+	conf.Cache.Resource = "foocache"
+	conf.Cache.Operator = "set"
+	setProc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.Cache.Operator = "get"
+	getProc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, res := setProc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"1","value":"foo 1"}`),
+		[]byte(`{"key":"2","value":"foo 2"}`),
+		[]byte(`{"key":"1","value":"foo 3"}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := 1, mCache.setMultiCalls; exp != act {
+		t.Errorf("Wrong count of set multi calls: %v != %v", act, exp)
+	}
+	for i := 0; i < 3; i++ {
+		if HasFailed(output[0].Get(i)) {
+			t.Errorf("Unexpected failure of message %v", i)
+		}
+	}
+
+	output, res = getProc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"1"}`),
+		[]byte(`{"key":"2"}`),
+		[]byte(`{"key":"3"}`),
+		[]byte(`{"key":"1"}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := 1, mCache.getMultiCalls; exp != act {
+		t.Errorf("Wrong count of get multi calls: %v != %v", act, exp)
+	}
+
+	exp := [][]byte{
+		[]byte(`foo 3`),
+		[]byte(`foo 2`),
+		[]byte(`{"key":"3"}`),
+		[]byte(`foo 3`),
+	}
+	if act := message.GetAllBytes(output[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result messages: %s != %s", act, exp)
+	}
+	for i, exp := range []bool{false, false, true, false} {
+		if act := HasFailed(output[0].Get(i)); exp != act {
+			t.Errorf("Wrong fail flag for message %v: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestCacheBatchedSetErrors(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mCache := &multiCallCache{CacheWithGetMulti: memCache.(types.CacheWithGetMulti)}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": mCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Cache.Key = "${!json(\"key\")}"
+	conf.Cache.Value = "${!json(\"value\")}"
+	conf.Cache.Resource = "foocache"
+	conf.Cache.Operator = "set"
+	setProc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		err     error
+		expFail []bool
+	}{
+		{
+			name:    "general error",
+			err:     errors.New("nope"),
+			expFail: []bool{true, true, true},
+		},
+		{
+			name:    "keys error without keys",
+			err:     types.NewKeysError(errors.New("nope")),
+			expFail: []bool{true, true, true},
+		},
+		{
+			name:    "keys error",
+			err:     types.NewKeysError(errors.New("nope")).AddErrFor("2", errors.New("nope 2")),
+			expFail: []bool{false, true, false},
+		},
+	}
+
+	for _, test := range tests {
+		mCache.setMultiErr = test.err
+		output, res := setProc.ProcessMessage(message.New([][]byte{
+			[]byte(`{"key":"1","value":"foo 1"}`),
+			[]byte(`{"key":"2","value":"foo 2"}`),
+			[]byte(`{"key":"3","value":"foo 3"}`),
+		}))
+		if res != nil {
+			t.Fatal(res.Error())
+		}
+		for i, exp := range test.expFail {
+			if act := HasFailed(output[0].Get(i)); exp != act {
+				t.Errorf("%v: Wrong fail flag for message %v: %v != %v", test.name, i, act, exp)
+			}
+		}
+	}
+}
@@ -85,6 +85,24 @@ The previous meta object will also be preserved in the field ` + "`<meta_path>.p
 
 If a field ` + "`<meta_path>.apply`" + ` exists in the meta object for a message and is an array then it will be used as an explicit list of stages to apply, all other stages will be skipped.
 
+## Persisted State
+
+When the field ` + "`state.cache`" + ` is set the workflow processor persists the progress of each message within the referenced [cache resource][caches], identified by the interpolated ` + "`state.key`" + ` field. After each layer of the workflow is executed the results of all branches that succeeded are written to the cache, and when a message with the same key is processed again those branches are skipped and their stored results are applied to the message instead.
+
+This allows messages that are redelivered after a partial failure to resume from where they left off, without repeating expensive or non-idempotent branches. Since results are stored along with the progress of the workflow it is recommended to use a ` + "`state.ttl`" + ` (or a cache with a default TTL) in order to clean up the state of messages that have completed.
+
+Failing to read or write the state of a message does not fail the workflow, but is logged and the affected branches are executed as normal.
+
+## Visualising the DAG
+
+The DAG of workflows within a config, including those resolved automatically, can be printed with the subcommand ` + "`benthos -c ./config.yaml dag`" + `, which renders each DAG in the [Graphviz DOT language][graphviz_dot] by default or as a [Mermaid flowchart][mermaid_flowchart] with the flag ` + "`--format mermaid`" + `.
+
+When a workflow processor has a ` + "`label`" + ` its current DAG is also served by the HTTP server at the endpoint ` + "`/workflows/{label}/dag`" + `, which accepts the query parameter ` + "`format`" + ` with the same options.
+
+## Metrics
+
+For each branch the workflow processor emits the counters ` + "`<branch>.success` and `<branch>.error`" + `, which count the batches for which the branch succeeded or failed, the counter ` + "`<branch>.skipped`" + ` of messages that skipped the branch, and the timer ` + "`<branch>.latency`" + ` describing the time taken to execute the branch.
+
 ## Resources
 
 It's common to configure processors (and other components) [as resources][configuration.resources] in order to keep the pipeline configuration cleaner. With the workflow processor you can include branch processors configured as resources within your workflow either by specifying them by name in the field ` + "`order`" + `, if Benthos doesn't find a branch within the workflow configuration of that name it'll refer to the resources.
@@ -117,6 +135,9 @@ However, if structured metadata is disabled by setting the field ` + "`meta_path
 [configuration.pipelines]: /docs/configuration/processing_pipelines
 [configuration.error-handling]: /docs/configuration/error_handling
 [configuration.resources]: /docs/configuration/resources
+[caches]: /docs/components/caches/about
+[graphviz_dot]: https://graphviz.org/doc/info/lang.html
+[mermaid_flowchart]: https://mermaid-js.github.io/mermaid/#/flowchart
 `,
 		Examples: []docs.AnnotatedExample{
 			{
@@ -248,6 +269,10 @@ processor_resources:
 				"branch_resources",
 				"An optional list of [`branch` processor](/docs/components/processors/branch) names that are configured as [resources](#resources). These resources will be included in the workflow with any branches configured inline within the [`branches`](#branches) field. The order and parallelism in which branches are executed is automatically resolved based on the mappings of each branch. When using resources with an explicit order it is not necessary to list resources in this field.",
 			).AtVersion("3.38.0").Advanced().Array(),
+			docs.FieldAdvanced(
+				"state",
+				"Optionally persist the progress of the workflow for each message within a cache, allowing branches that succeeded during a previous attempt of the same message to be skipped and their results reused. For more information read [persisted state](#persisted-state).",
+			).WithChildren(workflowStateFields...).AtVersion("3.60.0"),
 			docs.FieldCommon(
 				"branches",
 				"An object of named [`branch` processors](/docs/components/processors/branch) that make up the workflow. The order and parallelism in which branches are executed can either be made explicit with the field `order`, or if omitted an attempt is made to automatically resolve an ordering based on the mappings of each branch.",
@@ -266,6 +291,7 @@ type WorkflowConfig struct {
 	BranchResources []string                       `json:"branch_resources" yaml:"branch_resources"`
 	Branches        map[string]BranchConfig        `json:"branches" yaml:"branches"`
 	Stages          map[string]DepProcessMapConfig `json:"stages" yaml:"stages"`
+	State           WorkflowStateConfig            `json:"state" yaml:"state"`
 }
 
 // NewWorkflowConfig returns a default WorkflowConfig.
@@ -276,6 +302,7 @@ func NewWorkflowConfig() WorkflowConfig {
 		BranchResources: []string{},
 		Branches:        map[string]BranchConfig{},
 		Stages:          map[string]DepProcessMapConfig{},
+		State:           NewWorkflowStateConfig(),
 	}
 }
 
@@ -291,6 +318,9 @@ type Workflow struct {
 	children  *workflowBranchMap
 	allStages map[string]struct{}
 	metaPath  []string
+	state     *workflowStateStore
+
+	dagEndpoint *workflowDAGEndpoint
 
 	mCount           metrics.StatCounter
 	mSent            metrics.StatCounter
@@ -300,8 +330,11 @@ type Workflow struct {
 	mErrJSON         metrics.StatCounter
 	mErrMeta         metrics.StatCounter
 	mErrOverlay      metrics.StatCounter
+	mErrState        metrics.StatCounter
 	mErrStages       map[string]metrics.StatCounter
 	mSuccStages      map[string]metrics.StatCounter
+	mSkippedStages   map[string]metrics.StatCounter
+	mLatencyStages   map[string]metrics.StatTimer
 	metricsMut       sync.RWMutex
 }
 
@@ -320,12 +353,14 @@ func NewWorkflow(
 	}
 
 	w := &Workflow{
-		log:         log,
-		stats:       stats,
-		mErrStages:  map[string]metrics.StatCounter{},
-		mSuccStages: map[string]metrics.StatCounter{},
-		metaPath:    nil,
-		allStages:   map[string]struct{}{},
+		log:            log,
+		stats:          stats,
+		mErrStages:     map[string]metrics.StatCounter{},
+		mSuccStages:    map[string]metrics.StatCounter{},
+		mSkippedStages: map[string]metrics.StatCounter{},
+		mLatencyStages: map[string]metrics.StatTimer{},
+		metaPath:       nil,
+		allStages:      map[string]struct{}{},
 	}
 	if len(conf.Workflow.MetaPath) > 0 {
 		w.metaPath = gabs.DotPathToSlice(conf.Workflow.MetaPath)
@@ -338,6 +373,9 @@ func NewWorkflow(
 	for k := range w.children.dynamicBranches {
 		w.allStages[k] = struct{}{}
 	}
+	if w.state, err = newWorkflowStateStore(conf.Workflow.State, mgr); err != nil {
+		return nil, err
+	}
 
 	w.mCount = stats.GetCounter("count")
 	w.mSent = stats.GetCounter("sent")
@@ -347,6 +385,11 @@ func NewWorkflow(
 	w.mErrJSON = stats.GetCounter("error.json_parse")
 	w.mErrMeta = stats.GetCounter("error.meta_set")
 	w.mErrOverlay = stats.GetCounter("error.overlay")
+	w.mErrState = stats.GetCounter("error.state")
+
+	if conf.Label != "" {
+		w.registerDAGEndpoint(mgr, conf.Label)
+	}
 
 	return w, nil
 }
@@ -387,6 +430,40 @@ func (w *Workflow) incrStageSucc(id string) {
 	w.mSuccStages[id] = ctr
 }
 
+func (w *Workflow) incrStageSkipped(id string, n int64) {
+	w.metricsMut.RLock()
+	ctr, exists := w.mSkippedStages[id]
+	w.metricsMut.RUnlock()
+	if exists {
+		ctr.Incr(n)
+		return
+	}
+
+	w.metricsMut.Lock()
+	defer w.metricsMut.Unlock()
+
+	ctr = w.stats.GetCounter(fmt.Sprintf("%v.skipped", id))
+	ctr.Incr(n)
+	w.mSkippedStages[id] = ctr
+}
+
+func (w *Workflow) timeStage(id string, d time.Duration) {
+	w.metricsMut.RLock()
+	tmr, exists := w.mLatencyStages[id]
+	w.metricsMut.RUnlock()
+	if exists {
+		tmr.Timing(d.Nanoseconds())
+		return
+	}
+
+	w.metricsMut.Lock()
+	defer w.metricsMut.Unlock()
+
+	tmr = w.stats.GetTimer(fmt.Sprintf("%v.latency", id))
+	tmr.Timing(d.Nanoseconds())
+	w.mLatencyStages[id] = tmr
+}
+
 //------------------------------------------------------------------------------
 
 type resultTracker struct {
@@ -427,6 +504,13 @@ func (r *resultTracker) Failed(k, why string) {
 	r.Unlock()
 }
 
+func (r *resultTracker) HasFailed(k string) bool {
+	r.Lock()
+	_, exists := r.failed[k]
+	r.Unlock()
+	return exists
+}
+
 func (r *resultTracker) ToObject() map[string]interface{} {
 	succeeded := make([]interface{}, 0, len(r.succeeded))
 	skipped := make([]interface{}, 0, len(r.skipped))
@@ -544,6 +628,17 @@ func (w *Workflow) ProcessMessage(msg types.Message) ([]types.Message, types.Res
 		return nil
 	})
 
+	// Obtain the persisted progress of each message from previous attempts.
+	var stateKeys []string
+	var states []*workflowState
+	if w.state != nil {
+		var err error
+		if stateKeys, states, err = w.state.load(payload); err != nil {
+			w.mErrState.Incr(1)
+			w.log.Errorf("Failed to load workflow state: %v\n", err)
+		}
+	}
+
 	propMsg, _ := tracing.WithChildSpans("workflow", payload)
 
 	records := make([]*resultTracker, payload.Len())
@@ -553,6 +648,7 @@ func (w *Workflow) ProcessMessage(msg types.Message) ([]types.Message, types.Res
 
 	for _, layer := range dag {
 		results := make([][]types.Part, len(layer))
+		restored := make([][]types.Part, len(layer))
 		errors := make([]error, len(layer))
 
 		wg := sync.WaitGroup{}
@@ -566,22 +662,45 @@ func (w *Workflow) ProcessMessage(msg types.Message) ([]types.Message, types.Res
 					// Remove errors so that they aren't propagated into the
 					// branch.
 					ClearFail(part)
-					if _, exists := skipOnMeta[partIndex][id]; !exists {
-						branchParts[partIndex] = part
+					if _, exists := skipOnMeta[partIndex][id]; exists {
+						return nil
 					}
+					if states != nil {
+						// Reuse the result of a previous successful attempt.
+						if res := states[partIndex].getResult(id); res != nil {
+							if restored[index] == nil {
+								restored[index] = make([]types.Part, branchMsg.Len())
+							}
+							restored[index][partIndex] = res
+							return nil
+						}
+					}
+					branchParts[partIndex] = part
 					return nil
 				})
 
 				var mapErrs []branchMapError
+				startedAt := time.Now()
 				results[index], mapErrs, errors[index] = children[id].createResult(branchParts, propMsg)
+				w.timeStage(id, time.Since(startedAt))
 				for _, s := range branchSpans {
 					s.Finish()
 				}
+				var skipped int64
 				for j, p := range results[index] {
-					if p == nil {
+					if p != nil {
+						continue
+					}
+					if restored[index] != nil && restored[index][j] != nil {
+						results[index][j] = restored[index][j]
+					} else {
 						records[j].Skipped(id)
+						skipped++
 					}
 				}
+				if skipped > 0 {
+					w.incrStageSkipped(id, skipped)
+				}
 				for _, e := range mapErrs {
 					records[e.index].Failed(id, e.err.Error())
 				}
@@ -612,6 +731,30 @@ func (w *Workflow) ProcessMessage(msg types.Message) ([]types.Message, types.Res
 			}
 			w.incrStageSucc(id)
 		}
+
+		// Persist the results of branches that succeeded during this layer.
+		if states != nil {
+			for j := range states {
+				updated := false
+				for i, id := range layer {
+					if errors[i] != nil || results[i][j] == nil || records[j].HasFailed(id) {
+						continue
+					}
+					if restored[i] != nil && restored[i][j] != nil {
+						continue
+					}
+					states[j].setResult(id, results[i][j])
+					updated = true
+				}
+				if !updated {
+					continue
+				}
+				if err := w.state.store(stateKeys[j], states[j]); err != nil {
+					w.mErrState.Incr(1)
+					w.log.Errorf("Failed to store workflow state: %v\n", err)
+				}
+			}
+		}
 	}
 
 	// Finally, set the meta records of each document.
@@ -661,6 +804,7 @@ func (w *Workflow) ProcessMessage(msg types.Message) ([]types.Message, types.Res
 
 // CloseAsync shuts down the processor and stops processing requests.
 func (w *Workflow) CloseAsync() {
+	w.deregisterDAGEndpoint()
 	w.children.CloseAsync()
 }
 
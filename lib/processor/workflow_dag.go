@@ -0,0 +1,220 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// WorkflowDAG describes the tiers in which the branches of a workflow are
+// executed along with the dependencies between them.
+type WorkflowDAG struct {
+	// Tiers of branches, where the branches of each tier are executed in
+	// parallel once all branches of the previous tier have completed.
+	Tiers [][]string `json:"tiers"`
+
+	// Dependencies maps each branch to the branches of earlier tiers that
+	// provide fields consumed by its request mapping.
+	Dependencies map[string][]string `json:"dependencies"`
+}
+
+func newWorkflowDAG(tiers [][]string, branches map[string]*Branch) WorkflowDAG {
+	tierOf := map[string]int{}
+	for i, tier := range tiers {
+		for _, id := range tier {
+			tierOf[id] = i
+		}
+	}
+
+	deps := map[string][]string{}
+	for id, b := range branches {
+		seen := map[string]struct{}{}
+		deps[id] = []string{}
+		for _, d := range getBranchDeps(id, b.targetsUsed(), branches) {
+			if _, exists := seen[d]; exists {
+				continue
+			}
+			seen[d] = struct{}{}
+			// Only dependencies that are honoured by the order are included,
+			// which can differ from the mappings when the order is explicit.
+			if t, exists := tierOf[d]; exists && t < tierOf[id] {
+				deps[id] = append(deps[id], d)
+			}
+		}
+		sort.Strings(deps[id])
+	}
+
+	sortedTiers := make([][]string, len(tiers))
+	for i, tier := range tiers {
+		sortedTiers[i] = append([]string{}, tier...)
+		sort.Strings(sortedTiers[i])
+	}
+
+	return WorkflowDAG{
+		Tiers:        sortedTiers,
+		Dependencies: deps,
+	}
+}
+
+// DOT renders the DAG in the Graphviz DOT language, where the branches of each
+// tier share the same rank.
+func (d WorkflowDAG) DOT() string {
+	var buf bytes.Buffer
+	buf.WriteString("digraph workflow {\n\trankdir=LR;\n")
+	for _, tier := range d.Tiers {
+		buf.WriteString("\t{ rank=same;")
+		for _, id := range tier {
+			fmt.Fprintf(&buf, " %v;", strconv.Quote(id))
+		}
+		buf.WriteString(" }\n")
+	}
+	for _, tier := range d.Tiers {
+		for _, id := range tier {
+			for _, dep := range d.Dependencies[id] {
+				fmt.Fprintf(&buf, "\t%v -> %v;\n", strconv.Quote(dep), strconv.Quote(id))
+			}
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// Mermaid renders the DAG as a Mermaid flowchart, where the branches of each
+// tier are grouped within a subgraph.
+func (d WorkflowDAG) Mermaid() string {
+	var buf bytes.Buffer
+	buf.WriteString("graph LR\n")
+	for i, tier := range d.Tiers {
+		fmt.Fprintf(&buf, "\tsubgraph tier_%v [tier %v]\n", i, i)
+		for _, id := range tier {
+			fmt.Fprintf(&buf, "\t\t%v\n", id)
+		}
+		buf.WriteString("\tend\n")
+	}
+	for _, tier := range d.Tiers {
+		for _, id := range tier {
+			for _, dep := range d.Dependencies[id] {
+				fmt.Fprintf(&buf, "\t%v --> %v\n", dep, id)
+			}
+		}
+	}
+	return buf.String()
+}
+
+// Render the DAG in a given format, which can be either dot or mermaid.
+func (d WorkflowDAG) Render(format string) (string, error) {
+	switch format {
+	case "dot", "":
+		return d.DOT(), nil
+	case "mermaid":
+		return d.Mermaid(), nil
+	}
+	return "", fmt.Errorf("format not recognised: %v", format)
+}
+
+//------------------------------------------------------------------------------
+
+// DAG returns the current DAG of the workflow, resolving it from the branches
+// of the workflow when an explicit order was not provided.
+func (w *Workflow) DAG() (WorkflowDAG, error) {
+	tiers, children, unlock, err := w.children.Lock()
+	if err != nil {
+		return WorkflowDAG{}, err
+	}
+	defer unlock()
+	return newWorkflowDAG(tiers, children), nil
+}
+
+func (w *Workflow) handleDAG(rw http.ResponseWriter, r *http.Request) {
+	dag, err := w.DAG()
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	res, err := dag.Render(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rw.Header().Set("Content-Type", "text/plain")
+	_, _ = rw.Write([]byte(res))
+}
+
+//------------------------------------------------------------------------------
+
+type workflowDAGEndpoint struct {
+	stream string
+	label  string
+}
+
+// workflowDAGEndpoints holds the open workflow processors of each DAG
+// endpoint. A workflow processor is created for each pipeline thread and so
+// the endpoint is only registered by the first to be opened, and serves the
+// DAG of the earliest that remains open. Workflows are removed once closed.
+var workflowDAGEndpoints = struct {
+	sync.Mutex
+	workflows map[workflowDAGEndpoint][]*Workflow
+}{workflows: map[workflowDAGEndpoint][]*Workflow{}}
+
+func (w *Workflow) registerDAGEndpoint(mgr types.Manager, label string) {
+	key := workflowDAGEndpoint{label: label}
+	if m, ok := mgr.(interface {
+		Stream() string
+	}); ok {
+		key.stream = m.Stream()
+	}
+	w.dagEndpoint = &key
+
+	workflowDAGEndpoints.Lock()
+	open := workflowDAGEndpoints.workflows[key]
+	workflowDAGEndpoints.workflows[key] = append(open, w)
+	workflowDAGEndpoints.Unlock()
+	if len(open) > 0 {
+		return
+	}
+
+	mgr.RegisterEndpoint(
+		path.Join("/workflows", label, "dag"),
+		"Returns the DAG of the workflow processor as Graphviz DOT, or as a Mermaid flowchart with the query parameter format=mermaid.",
+		func(rw http.ResponseWriter, r *http.Request) {
+			workflowDAGEndpoints.Lock()
+			open := workflowDAGEndpoints.workflows[key]
+			workflowDAGEndpoints.Unlock()
+			if len(open) == 0 {
+				http.Error(rw, "workflow is closed", http.StatusServiceUnavailable)
+				return
+			}
+			open[0].handleDAG(rw, r)
+		},
+	)
+}
+
+func (w *Workflow) deregisterDAGEndpoint() {
+	if w.dagEndpoint == nil {
+		return
+	}
+
+	workflowDAGEndpoints.Lock()
+	defer workflowDAGEndpoints.Unlock()
+
+	open := workflowDAGEndpoints.workflows[*w.dagEndpoint]
+	for i, o := range open {
+		if o == w {
+			open = append(open[:i:i], open[i+1:]...)
+			break
+		}
+	}
+	if len(open) == 0 {
+		delete(workflowDAGEndpoints.workflows, *w.dagEndpoint)
+	} else {
+		workflowDAGEndpoints.workflows[*w.dagEndpoint] = open
+	}
+}
@@ -0,0 +1,263 @@
+package processor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type endpointsMgr struct {
+	types.Manager
+	endpoints  map[string]http.HandlerFunc
+	registered map[string]int
+}
+
+func (e *endpointsMgr) RegisterEndpoint(path, desc string, h http.HandlerFunc) {
+	if e.registered == nil {
+		e.registered = map[string]int{}
+	}
+	e.registered[path]++
+	e.endpoints[path] = h
+}
+
+func testDAGWorkflowConfig(branches map[string][2]string) Config {
+	conf := NewConfig()
+	conf.Type = TypeWorkflow
+	for id, mappings := range branches {
+		branchConf := NewBranchConfig()
+		branchConf.RequestMap = mappings[0]
+		branchConf.ResultMap = mappings[1]
+		proc := NewConfig()
+		proc.Type = TypeBloblang
+		proc.Bloblang = "root = this"
+		branchConf.Processors = append(branchConf.Processors, proc)
+		conf.Workflow.Branches[id] = branchConf
+	}
+	return conf
+}
+
+func TestWorkflowDAG(t *testing.T) {
+	conf := testDAGWorkflowConfig(map[string][2]string{
+		"a": {"root = this.foo", "root.bar = this"},
+		"b": {"root = this.bar", "root.baz = this"},
+		"c": {"root = this.foo", "root.buz = this"},
+		"d": {"root = [ this.baz, this.buz ]", "root.qux = this"},
+	})
+
+	p, err := NewWorkflow(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	dag, err := p.(*Workflow).DAG()
+	require.NoError(t, err)
+
+	assert.Equal(t, WorkflowDAG{
+		Tiers: [][]string{{"a", "c"}, {"b"}, {"d"}},
+		Dependencies: map[string][]string{
+			"a": {},
+			"b": {"a"},
+			"c": {},
+			"d": {"b", "c"},
+		},
+	}, dag)
+
+	assert.Equal(t, `digraph workflow {
+	rankdir=LR;
+	{ rank=same; "a"; "c"; }
+	{ rank=same; "b"; }
+	{ rank=same; "d"; }
+	"a" -> "b";
+	"b" -> "d";
+	"c" -> "d";
+}
+`, dag.DOT())
+
+	assert.Equal(t, `graph LR
+	subgraph tier_0 [tier 0]
+		a
+		c
+	end
+	subgraph tier_1 [tier 1]
+		b
+	end
+	subgraph tier_2 [tier 2]
+		d
+	end
+	a --> b
+	b --> d
+	c --> d
+`, dag.Mermaid())
+
+	_, err = dag.Render("nope")
+	require.EqualError(t, err, "format not recognised: nope")
+
+	p.CloseAsync()
+	assert.NoError(t, p.WaitForClose(time.Second))
+}
+
+func TestWorkflowDAGExplicitOrder(t *testing.T) {
+	conf := testDAGWorkflowConfig(map[string][2]string{
+		"a": {"root = this.foo", "root.bar = this"},
+		"b": {"root = this.bar", "root.baz = this"},
+	})
+	conf.Workflow.Order = [][]string{{"b"}, {"a"}}
+
+	p, err := NewWorkflow(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	dag, err := p.(*Workflow).DAG()
+	require.NoError(t, err)
+
+	assert.Equal(t, WorkflowDAG{
+		Tiers: [][]string{{"b"}, {"a"}},
+		Dependencies: map[string][]string{
+			"a": {},
+			"b": {},
+		},
+	}, dag)
+
+	p.CloseAsync()
+	assert.NoError(t, p.WaitForClose(time.Second))
+}
+
+func TestWorkflowDAGEndpoint(t *testing.T) {
+	conf := testDAGWorkflowConfig(map[string][2]string{
+		"a": {"root = this.foo", "root.bar = this"},
+		"b": {"root = this.bar", "root.baz = this"},
+	})
+	conf.Label = "foo"
+
+	mgr := &endpointsMgr{
+		Manager:   types.NoopMgr(),
+		endpoints: map[string]http.HandlerFunc{},
+	}
+
+	p, err := NewWorkflow(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	h, exists := mgr.endpoints["/workflows/foo/dag"]
+	require.True(t, exists)
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest("GET", "/workflows/foo/dag", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `digraph workflow {
+	rankdir=LR;
+	{ rank=same; "a"; }
+	{ rank=same; "b"; }
+	"a" -> "b";
+}
+`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest("GET", "/workflows/foo/dag?format=mermaid", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `graph LR
+	subgraph tier_0 [tier 0]
+		a
+	end
+	subgraph tier_1 [tier 1]
+		b
+	end
+	a --> b
+`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest("GET", "/workflows/foo/dag?format=nope", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	p.CloseAsync()
+	assert.NoError(t, p.WaitForClose(time.Second))
+}
+
+func TestWorkflowBranchMetrics(t *testing.T) {
+	conf := testDAGWorkflowConfig(map[string][2]string{
+		"a": {
+			"root = if this.skip == true { deleted() } else { this }",
+			"root.bar = this.foo.number()",
+		},
+	})
+
+	stats := metrics.NewLocal()
+	p, err := NewWorkflow(conf, types.NoopMgr(), log.Noop(), stats)
+	require.NoError(t, err)
+
+	msgs, res := p.ProcessMessage(message.New([][]byte{
+		[]byte(`{"foo":"5"}`),
+		[]byte(`{"foo":"6"}`),
+		[]byte(`{"skip":true}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	counters := stats.GetCounters()
+	assert.Equal(t, int64(1), counters["a.success"])
+	assert.Equal(t, int64(1), counters["a.skipped"])
+	assert.Equal(t, int64(0), counters["a.error"])
+	assert.Contains(t, stats.GetTimings(), "a.latency")
+
+	p.CloseAsync()
+	assert.NoError(t, p.WaitForClose(time.Second))
+}
+
+func TestWorkflowDAGEndpointRegisteredOnce(t *testing.T) {
+	conf := testDAGWorkflowConfig(map[string][2]string{
+		"a": {"root = this.foo", "root.bar = this"},
+	})
+	conf.Label = "bar"
+
+	mgr := &endpointsMgr{
+		Manager:   types.NoopMgr(),
+		endpoints: map[string]http.HandlerFunc{},
+	}
+
+	// A workflow processor is created for each pipeline thread.
+	var procs []Type
+	for i := 0; i < 3; i++ {
+		p, err := NewWorkflow(conf, mgr, log.Noop(), metrics.Noop())
+		require.NoError(t, err)
+		procs = append(procs, p)
+	}
+	assert.Equal(t, 1, mgr.registered["/workflows/bar/dag"])
+
+	h, exists := mgr.endpoints["/workflows/bar/dag"]
+	require.True(t, exists)
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest("GET", "/workflows/bar/dag", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `digraph workflow {
+	rankdir=LR;
+	{ rank=same; "a"; }
+}
+`, rec.Body.String())
+
+	// The endpoint continues to serve the DAG whilst any of the workflows
+	// remain open.
+	procs[0].CloseAsync()
+	require.NoError(t, procs[0].WaitForClose(time.Second))
+
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest("GET", "/workflows/bar/dag", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	for _, p := range procs[1:] {
+		p.CloseAsync()
+		assert.NoError(t, p.WaitForClose(time.Second))
+	}
+
+	workflowDAGEndpoints.Lock()
+	assert.NotContains(t, workflowDAGEndpoints.workflows, workflowDAGEndpoint{label: "bar"})
+	workflowDAGEndpoints.Unlock()
+
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest("GET", "/workflows/bar/dag", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
@@ -0,0 +1,176 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+var workflowStateFields = docs.FieldSpecs{
+	docs.FieldString("cache", "A [`cache` resource](/docs/components/caches/about) to persist the progress of workflows within. When empty the progress of workflows is not persisted."),
+	docs.FieldInterpolatedString("key", "A key that uniquely identifies each message, used to store and look up its workflow progress. This must produce the same value when a message is redelivered.", `${! json("id") }`, `${! meta("kafka_topic") }-${! meta("kafka_partition") }-${! meta("kafka_offset") }`),
+	docs.FieldString("ttl", "An optional duration string describing the TTL of persisted workflow progress, otherwise the default TTL of the cache is used.", "1h", "24h"),
+}
+
+// WorkflowStateConfig contains configuration fields for persisting the
+// progress of workflows within a cache.
+type WorkflowStateConfig struct {
+	Cache string `json:"cache" yaml:"cache"`
+	Key   string `json:"key" yaml:"key"`
+	TTL   string `json:"ttl" yaml:"ttl"`
+}
+
+// NewWorkflowStateConfig returns a default WorkflowStateConfig.
+func NewWorkflowStateConfig() WorkflowStateConfig {
+	return WorkflowStateConfig{
+		Cache: "",
+		Key:   "",
+		TTL:   "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type workflowStateResult struct {
+	Content  []byte            `json:"content"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// workflowState is the form in which the progress of a workflow for a message
+// is persisted, containing the results of each branch that has succeeded.
+type workflowState struct {
+	Branches map[string]workflowStateResult `json:"branches"`
+}
+
+func (s *workflowState) setResult(id string, p types.Part) {
+	res := workflowStateResult{
+		Content: append([]byte(nil), p.Get()...),
+	}
+	_ = p.Metadata().Iter(func(k, v string) error {
+		if res.Metadata == nil {
+			res.Metadata = map[string]string{}
+		}
+		res.Metadata[k] = v
+		return nil
+	})
+	s.Branches[id] = res
+}
+
+func (s *workflowState) getResult(id string) types.Part {
+	res, exists := s.Branches[id]
+	if !exists {
+		return nil
+	}
+	p := message.NewPart(append([]byte(nil), res.Content...))
+	for k, v := range res.Metadata {
+		p.Metadata().Set(k, v)
+	}
+	return p
+}
+
+type workflowStateStore struct {
+	mgr   types.Manager
+	cache string
+	key   *field.Expression
+	ttl   *time.Duration
+}
+
+func newWorkflowStateStore(conf WorkflowStateConfig, mgr types.Manager) (*workflowStateStore, error) {
+	if conf.Cache == "" {
+		return nil, nil
+	}
+	if conf.Key == "" {
+		return nil, errors.New("a state key must be specified when a state cache is set")
+	}
+
+	s := &workflowStateStore{
+		mgr:   mgr,
+		cache: conf.Cache,
+	}
+
+	var err error
+	if s.key, err = interop.NewBloblangField(mgr, conf.Key); err != nil {
+		return nil, fmt.Errorf("failed to parse state key expression: %v", err)
+	}
+	if conf.TTL != "" {
+		ttl, err := time.ParseDuration(conf.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse state ttl: %v", err)
+		}
+		s.ttl = &ttl
+	}
+	if err := interop.ProbeCache(context.Background(), mgr, conf.Cache); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load attempts to obtain the persisted workflow state of each message part,
+// where a state is always returned for each part with a key.
+func (s *workflowStateStore) load(msg types.Message) (keys []string, states []*workflowState, err error) {
+	keys = make([]string, msg.Len())
+	states = make([]*workflowState, msg.Len())
+	for i := range keys {
+		keys[i] = s.key.String(i, msg)
+	}
+
+	if cerr := interop.AccessCache(context.Background(), s.mgr, s.cache, func(c types.Cache) {
+		for i, k := range keys {
+			state := &workflowState{Branches: map[string]workflowStateResult{}}
+			states[i] = state
+			if k == "" {
+				continue
+			}
+
+			stateBytes, gerr := c.Get(k)
+			if gerr != nil {
+				if !errors.Is(gerr, types.ErrKeyNotFound) {
+					err = gerr
+				}
+				continue
+			}
+			if jerr := json.Unmarshal(stateBytes, state); jerr != nil {
+				err = fmt.Errorf("failed to parse workflow state: %w", jerr)
+			}
+			if state.Branches == nil {
+				state.Branches = map[string]workflowStateResult{}
+			}
+		}
+	}); cerr != nil {
+		err = cerr
+	}
+	return
+}
+
+// store persists the workflow state of a message part.
+func (s *workflowStateStore) store(key string, state *workflowState) (err error) {
+	if key == "" {
+		return nil
+	}
+
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	if cerr := interop.AccessCache(context.Background(), s.mgr, s.cache, func(c types.Cache) {
+		if cttl, ok := c.(types.CacheWithTTL); ok {
+			err = cttl.SetWithTTL(key, stateBytes, s.ttl)
+		} else {
+			err = c.Set(key, stateBytes)
+		}
+	}); cerr != nil {
+		err = cerr
+	}
+	return
+}
@@ -1,12 +1,14 @@
 package processor
 
 import (
+	"fmt"
 	"sort"
 	"strconv"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/lib/cache"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -917,3 +919,87 @@ func TestWorkflowsWithOrderResources(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkflowsWithState(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	counterName := fmt.Sprintf("workflow_state_%v", time.Now().UnixNano())
+
+	conf := NewConfig()
+	conf.Workflow.State.Cache = "foocache"
+	conf.Workflow.State.Key = `${! json("id") }`
+
+	for id, mappings := range map[string][3]string{
+		"a": {
+			"root = this",
+			fmt.Sprintf(`root.runs = count("%v")`, counterName),
+			"root.a = this.runs",
+		},
+		"b": {
+			"root.v = this.b_input.not_null()",
+			"root = this",
+			"root.b = this.v",
+		},
+	} {
+		branchConf := NewBranchConfig()
+		branchConf.RequestMap = mappings[0]
+		branchConf.ResultMap = mappings[2]
+		proc := NewConfig()
+		proc.Type = TypeBloblang
+		proc.Bloblang = BloblangConfig(mappings[1])
+		branchConf.Processors = append(branchConf.Processors, proc)
+		conf.Workflow.Branches[id] = branchConf
+	}
+
+	p, err := NewWorkflow(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := p.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"foo"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"a":1,"id":"foo","meta":{"workflow":{"failed":{"b":"request mapping failed: failed assignment (line 1): field `+"`this.b_input`"+`: value is null"},"succeeded":["a"]}}}`, string(msgs[0].Get(0).Get()))
+
+	stateBytes, err := memCache.Get("foo")
+	require.NoError(t, err)
+	assert.Contains(t, string(stateBytes), `"a"`)
+
+	// Redelivery of the same message, branch a should not be executed again.
+	msgs, res = p.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"foo","b_input":"bar"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"a":1,"b":"bar","b_input":"bar","id":"foo","meta":{"workflow":{"succeeded":["a","b"]}}}`, string(msgs[0].Get(0).Get()))
+
+	// A different message is processed from scratch.
+	msgs, res = p.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"bar","b_input":"baz"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"a":2,"b":"baz","b_input":"baz","id":"bar","meta":{"workflow":{"succeeded":["a","b"]}}}`, string(msgs[0].Get(0).Get()))
+
+	p.CloseAsync()
+	assert.NoError(t, p.WaitForClose(time.Second))
+}
+
+func TestWorkflowsStateMissingCache(t *testing.T) {
+	conf := NewConfig()
+	conf.Workflow.State.Cache = "nope"
+	conf.Workflow.State.Key = `${! json("id") }`
+
+	_, err := NewWorkflow(conf, &fakeMgr{}, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	conf.Workflow.State.Key = ""
+	_, err = NewWorkflow(conf, &fakeMgr{}, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "a state key must be specified when a state cache is set")
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+type workflowConfig struct {
+	path string
+	conf processor.Config
+}
+
+// findWorkflows walks a sanitised config and returns the configs of all
+// workflow processors found within it, including those nested within other
+// processors.
+func findWorkflows(path string, node *yaml.Node) (workflows []workflowConfig) {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			workflows = append(workflows, findWorkflows(joinDAGPath(path, strconv.Itoa(i)), child)...)
+		}
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content)-1; i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+			if key == processor.TypeWorkflow && value.Kind == yaml.MappingNode {
+				pConf := processor.NewConfig()
+				if err := node.Decode(&pConf); err == nil && pConf.Type == processor.TypeWorkflow {
+					workflows = append(workflows, workflowConfig{path: path, conf: pConf})
+				}
+			}
+			workflows = append(workflows, findWorkflows(joinDAGPath(path, key), value)...)
+		}
+	}
+	return
+}
+
+func joinDAGPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func renderWorkflowDAG(mgr *manager.Type, wConf workflowConfig, format string) (string, error) {
+	p, err := processor.NewWorkflow(wConf.conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		p.CloseAsync()
+		_ = p.WaitForClose(time.Second)
+	}()
+
+	w, ok := p.(*processor.Workflow)
+	if !ok {
+		return "", errors.New("workflows using the deprecated field stages are not supported")
+	}
+	dag, err := w.DAG()
+	if err != nil {
+		return "", err
+	}
+	return dag.Render(format)
+}
+
+func dagCliCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "dag",
+		Usage: "Render the DAG of workflow processors within a config",
+		Description: `
+   Parses a Benthos config and prints the DAG of each workflow processor found
+   within it, including those configured as resources, where the DAG is
+   resolved automatically from the mappings of branches when an explicit order
+   is not provided:
+
+   benthos -c ./config.yaml dag
+   benthos -c ./config.yaml dag --format mermaid
+
+   Branch processors configured as resources are created in order to resolve
+   the DAG, but inputs and outputs are not.`[4:],
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Value: "dot",
+				Usage: "The format in which to render each DAG. Options are dot or mermaid.",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			conf := config.New()
+			confReader := readConfig(c.String("config"), false, c.StringSlice("resources"), nil, c.StringSlice("set"))
+			if _, err := confReader.Read(&conf); err != nil {
+				fmt.Fprintf(os.Stderr, "Configuration file read error: %v\n", err)
+				os.Exit(1)
+			}
+
+			var node yaml.Node
+			err := node.Encode(conf)
+			if err == nil {
+				err = config.Spec().SanitiseYAML(&node, docs.SanitiseConfig{
+					RemoveTypeField: true,
+				})
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Configuration parse error: %v\n", err)
+				os.Exit(1)
+			}
+
+			workflows := findWorkflows("", &node)
+			if len(workflows) == 0 {
+				fmt.Fprintln(os.Stderr, "No workflow processors were found within the config")
+				os.Exit(1)
+			}
+
+			// Only processors and the resources they might depend upon are
+			// required in order to resolve DAGs.
+			rConf := conf.ResourceConfig
+			rConf.Manager.Inputs = map[string]input.Config{}
+			rConf.Manager.Outputs = map[string]output.Config{}
+			rConf.ResourceInputs = nil
+			rConf.ResourceOutputs = nil
+
+			mgr, err := manager.NewV2(rConf, nil, log.Noop(), metrics.Noop())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to create resources: %v\n", err)
+				os.Exit(1)
+			}
+
+			comment := "//"
+			if c.String("format") == "mermaid" {
+				comment = "%%"
+			}
+
+			failed := false
+			for i, w := range workflows {
+				name := w.path
+				if w.conf.Label != "" {
+					name = w.conf.Label
+				}
+				res, err := renderWorkflowDAG(mgr, w, c.String("format"))
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%v: %v\n", name, red(err))
+					failed = true
+					continue
+				}
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("%v %v\n%v", comment, name, res)
+			}
+
+			mgr.CloseAsync()
+			_ = mgr.WaitForClose(time.Second)
+			if failed {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+}
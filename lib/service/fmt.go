@@ -0,0 +1,165 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// resolveYAMLTargets expands a list of paths into a list of files, where paths
+// ending with '...' are walked for files with a .yaml or .yml extension.
+func resolveYAMLTargets(paths []string) ([]string, error) {
+	var targets []string
+	for _, p := range paths {
+		var recurse bool
+		if p, recurse = resolveLintPath(p); !recurse {
+			targets = append(targets, p)
+			continue
+		}
+		if err := filepath.Walk(p, func(path string, info os.FileInfo, werr error) error {
+			if werr != nil {
+				return werr
+			}
+			if info.IsDir() {
+				return nil
+			}
+			if strings.HasSuffix(path, ".yaml") ||
+				strings.HasSuffix(path, ".yml") {
+				targets = append(targets, path)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return targets, nil
+}
+
+// readYAMLDocuments parses all documents of a YAML stream into nodes.
+func readYAMLDocuments(rawBytes []byte) ([]*yaml.Node, error) {
+	var nodes []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(rawBytes))
+	for {
+		var node yaml.Node
+		if err := dec.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		nodes = append(nodes, &node)
+	}
+	return nodes, nil
+}
+
+// writeYAMLDocuments emits YAML documents in a canonical format, preserving
+// comments.
+func writeYAMLDocuments(nodes []*yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, node := range nodes {
+		if err := enc.Encode(node); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatYAML re-emits a YAML stream in a canonical format.
+func formatYAML(rawBytes []byte) ([]byte, error) {
+	nodes, err := readYAMLDocuments(rawBytes)
+	if err != nil {
+		return nil, err
+	}
+	return writeYAMLDocuments(nodes)
+}
+
+func fmtCliCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "fmt",
+		Usage: "Format Benthos configs into a canonical form",
+		Description: `
+   Parses Benthos configs and prints them in a canonical form, where comments
+   are preserved and indentation is normalised:
+
+   benthos fmt ./config.yaml
+   benthos fmt -w ./configs/*.yaml
+   benthos fmt -l ./configs/...
+
+   If a path ends with '...' then Benthos will walk the target and format any
+   files with the .yaml or .yml extension.`[4:],
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "write",
+				Aliases: []string{"w"},
+				Value:   false,
+				Usage:   "Write the formatted result back to the source file instead of stdout.",
+			},
+			&cli.BoolFlag{
+				Name:    "list",
+				Aliases: []string{"l"},
+				Value:   false,
+				Usage:   "List files whose formatting differs from the canonical form instead of printing them.",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			targets, err := resolveYAMLTargets(c.Args().Slice())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Filesystem walk error: %v\n", err)
+				os.Exit(1)
+			}
+			if conf := c.String("config"); len(conf) > 0 {
+				targets = append(targets, conf)
+			}
+
+			failed := false
+			for _, target := range targets {
+				rawBytes, err := os.ReadFile(target)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%v: %v\n", target, red(err))
+					failed = true
+					continue
+				}
+				formatted, err := formatYAML(rawBytes)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%v: %v\n", target, red(err))
+					failed = true
+					continue
+				}
+				changed := !bytes.Equal(rawBytes, formatted)
+				if c.Bool("list") {
+					if changed {
+						fmt.Println(target)
+					}
+					continue
+				}
+				if c.Bool("write") {
+					if changed {
+						if err := os.WriteFile(target, formatted, 0o644); err != nil {
+							fmt.Fprintf(os.Stderr, "%v: %v\n", target, red(err))
+							failed = true
+						}
+					}
+					continue
+				}
+				os.Stdout.Write(formatted)
+			}
+			if failed {
+				os.Exit(1)
+			}
+			os.Exit(0)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatYAML(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		output      string
+		errContains string
+	}{
+		{
+			name: "indentation normalised",
+			input: `input:
+    generate:
+        mapping: 'root = "hello world"'
+        interval: 1s
+output:
+      drop: {}
+`,
+			output: `input:
+  generate:
+    mapping: 'root = "hello world"'
+    interval: 1s
+output:
+  drop: {}
+`,
+		},
+		{
+			name: "comments preserved",
+			input: `# The input of the pipeline.
+input:
+    kafka:
+        addresses: [ localhost:9092 ] # brokers
+        topics: [ foo ]
+
+pipeline:
+    processors:
+        # Drop documents without a body.
+        - bloblang: 'root = if this.body == null { deleted() }'
+        # Trailing comment of the processors.
+`,
+			output: `# The input of the pipeline.
+input:
+  kafka:
+    addresses: ['localhost:9092'] # brokers
+    topics: [foo]
+pipeline:
+  processors:
+    # Drop documents without a body.
+    - bloblang: 'root = if this.body == null { deleted() }'
+  # Trailing comment of the processors.
+`,
+		},
+		{
+			name: "multiple documents",
+			input: `# First document
+foo:   bar
+---
+# Second document
+baz:
+      - buz
+`,
+			output: `# First document
+foo: bar
+---
+# Second document
+baz:
+  - buz
+`,
+		},
+		{
+			name: "canonical config unchanged",
+			input: `input:
+  stdin: {} # read from stdin
+output:
+  stdout: {}
+`,
+			output: `input:
+  stdin: {} # read from stdin
+output:
+  stdout: {}
+`,
+		},
+		{
+			name:        "invalid yaml",
+			input:       "foo: [bar\n",
+			errContains: "did not find expected",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			res, err := formatYAML([]byte(test.input))
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.output, string(res))
+		})
+	}
+}
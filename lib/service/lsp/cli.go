@@ -0,0 +1,31 @@
+package lsp
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+// CliCommand is a cli.Command definition for running a language server.
+func CliCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "lsp",
+		Usage: "EXPERIMENTAL: Run a language server for Benthos config files over stdio",
+		Description: `
+   Runs a language server that communicates with an editor over stdin and
+   stdout using the language server protocol. The server provides completion
+   of component types and fields, hover documentation, diagnostics from the
+   config linter, and Bloblang completion within mapping fields.
+
+   benthos lsp`[4:],
+		Action: func(c *cli.Context) error {
+			if err := NewServer(os.Stdout).Serve(os.Stdin); err != nil {
+				fmt.Fprintf(os.Stderr, "Language server error: %v\n", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+			return nil
+		},
+	}
+}
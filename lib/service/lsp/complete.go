@@ -0,0 +1,343 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+)
+
+func markdown(value string) *markupContent {
+	if value == "" {
+		return nil
+	}
+	return &markupContent{Kind: "markdown", Value: value}
+}
+
+func fieldDetail(f docs.FieldSpec) string {
+	if f.Kind == docs.KindScalar || f.Kind == "" {
+		return string(f.Type)
+	}
+	return fmt.Sprintf("%v of %v", f.Kind, f.Type)
+}
+
+func fieldMarkdown(f docs.FieldSpec) string {
+	md := fmt.Sprintf("**%v** `%v`", f.Name, fieldDetail(f))
+	if f.IsDeprecated {
+		md += " (deprecated)"
+	}
+	if f.Description != "" {
+		md += "\n\n" + f.Description
+	}
+	return md
+}
+
+func componentMarkdown(spec docs.ComponentSpec) string {
+	md := fmt.Sprintf("**%v** `%v`", spec.Name, spec.Type)
+	if spec.Status != "" && spec.Status != docs.StatusStable {
+		md += fmt.Sprintf(" (%v)", spec.Status)
+	}
+	if spec.Summary != "" {
+		md += "\n\n" + spec.Summary
+	}
+	if spec.Description != "" {
+		md += "\n\n" + spec.Description
+	}
+	return md
+}
+
+func paramsSignature(name string, params query.Params) string {
+	var names []string
+	for _, p := range params.Definitions {
+		names = append(names, p.Name)
+	}
+	if params.Variadic {
+		names = append(names, "...")
+	}
+	return fmt.Sprintf("%v(%v)", name, strings.Join(names, ", "))
+}
+
+//------------------------------------------------------------------------------
+
+func componentCompletions(t docs.Type) []completionItem {
+	var items []completionItem
+	for _, spec := range componentSpecs(t) {
+		if spec.Status == docs.StatusDeprecated {
+			continue
+		}
+		items = append(items, completionItem{
+			Label:         spec.Name,
+			Kind:          completionKindClass,
+			Detail:        string(t),
+			Documentation: markdown(componentMarkdown(spec)),
+		})
+	}
+	return items
+}
+
+func keyCompletions(f docs.FieldSpec) []completionItem {
+	var items []completionItem
+	addField := func(child docs.FieldSpec) {
+		if child.IsDeprecated {
+			return
+		}
+		items = append(items, completionItem{
+			Label:         child.Name,
+			Kind:          completionKindField,
+			Detail:        fieldDetail(child),
+			Documentation: markdown(child.Description),
+			InsertText:    child.Name + ": ",
+		})
+	}
+
+	if coreType, isCore := componentTypeOf(f); isCore {
+		items = componentCompletions(coreType)
+		for i := range items {
+			items[i].InsertText = items[i].Label + ":"
+		}
+		reserved := docs.ReservedFieldsByType(coreType)
+		names := make([]string, 0, len(reserved))
+		for k := range reserved {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			addField(reserved[k])
+		}
+		return items
+	}
+
+	if f.Kind == docs.KindScalar || f.Kind == "" {
+		for _, child := range f.Children {
+			addField(child)
+		}
+	}
+	return items
+}
+
+func valueCompletions(parent, f docs.FieldSpec, key, valuePrefix string) []completionItem {
+	if coreType, isCore := componentTypeOf(parent); isCore && key == "type" {
+		return componentCompletions(coreType)
+	}
+
+	var items []completionItem
+	switch {
+	case len(f.AnnotatedOptions) > 0:
+		for _, opt := range f.AnnotatedOptions {
+			items = append(items, completionItem{
+				Label:         opt[0],
+				Kind:          completionKindEnumMember,
+				Documentation: markdown(opt[1]),
+			})
+		}
+	case len(f.Options) > 0:
+		for _, opt := range f.Options {
+			items = append(items, completionItem{
+				Label: opt,
+				Kind:  completionKindEnumMember,
+			})
+		}
+	case f.Type == docs.FieldTypeBool:
+		for _, v := range []string{"true", "false"} {
+			items = append(items, completionItem{
+				Label: v,
+				Kind:  completionKindValue,
+			})
+		}
+	case f.Bloblang, f.Interpolated:
+		items = bloblangCompletions(f, valuePrefix)
+	}
+	return items
+}
+
+// isInterpolating returns true if the end of a string is within an
+// interpolation function.
+func isInterpolating(s string) bool {
+	return strings.LastIndex(s, "${!") > strings.LastIndex(s, "}")
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// precedingIdentStart returns the byte offset of the start of an identifier
+// that ends at the end of a string.
+func precedingIdentStart(s string) int {
+	i := len(s)
+	for i > 0 && isIdentChar(s[i-1]) {
+		i--
+	}
+	return i
+}
+
+// bloblangCompletions returns Bloblang methods when the text preceding the
+// cursor ends with a path, and functions otherwise.
+func bloblangCompletions(f docs.FieldSpec, prefix string) []completionItem {
+	if f.Interpolated && !f.Bloblang && !isInterpolating(prefix) {
+		return nil
+	}
+
+	var items []completionItem
+	start := precedingIdentStart(prefix)
+	if start > 0 && prefix[start-1] == '.' {
+		bloblang.GlobalEnvironment().WalkMethods(func(name string, spec query.MethodSpec) {
+			if spec.Status == query.StatusDeprecated {
+				return
+			}
+			items = append(items, completionItem{
+				Label:         name,
+				Kind:          completionKindMethod,
+				Detail:        paramsSignature(name, spec.Params),
+				Documentation: markdown(spec.Description),
+			})
+		})
+	} else {
+		bloblang.GlobalEnvironment().WalkFunctions(func(name string, spec query.FunctionSpec) {
+			if spec.Status == query.StatusDeprecated {
+				return
+			}
+			items = append(items, completionItem{
+				Label:         name,
+				Kind:          completionKindFunction,
+				Detail:        paramsSignature(name, spec.Params),
+				Documentation: markdown(spec.Description),
+			})
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+	return items
+}
+
+// complete returns completion items for a position within a config document.
+func complete(root docs.FieldSpecs, text string, pos position) []completionItem {
+	lines := splitLines(text)
+	if pos.Line < 0 || pos.Line >= len(lines) {
+		return nil
+	}
+	line := lines[pos.Line]
+	col := byteOffset(line, pos.Character)
+	before := line[:col]
+
+	path, inBlock := scopeAt(lines, pos.Line, col)
+	if inBlock {
+		f, ok := specAt(root, path)
+		if !ok || !(f.Bloblang || f.Interpolated) {
+			return nil
+		}
+		return bloblangCompletions(f, before)
+	}
+
+	parent, ok := specAt(root, path)
+	if !ok {
+		return nil
+	}
+
+	t := tokenizeLine(before)
+	if t.keyCol == -1 {
+		return keyCompletions(parent)
+	}
+
+	f, ok := childSpec(parent, t.key)
+	if !ok {
+		return nil
+	}
+	return valueCompletions(parent, f, t.key, before[t.keyCol:])
+}
+
+//------------------------------------------------------------------------------
+
+func bloblangHover(word string, isMethod bool) *hover {
+	var md string
+	if isMethod {
+		bloblang.GlobalEnvironment().WalkMethods(func(name string, spec query.MethodSpec) {
+			if name == word {
+				md = fmt.Sprintf("```coffee\n%v\n```\n\n%v", paramsSignature(name, spec.Params), spec.Description)
+			}
+		})
+	} else {
+		bloblang.GlobalEnvironment().WalkFunctions(func(name string, spec query.FunctionSpec) {
+			if name == word {
+				md = fmt.Sprintf("```coffee\n%v\n```\n\n%v", paramsSignature(name, spec.Params), spec.Description)
+			}
+		})
+	}
+	if md == "" {
+		return nil
+	}
+	return &hover{Contents: markupContent{Kind: "markdown", Value: md}}
+}
+
+// hoverAt returns documentation for the field, component or Bloblang function
+// at a position within a config document.
+func hoverAt(root docs.FieldSpecs, text string, pos position) *hover {
+	lines := splitLines(text)
+	if pos.Line < 0 || pos.Line >= len(lines) {
+		return nil
+	}
+	line := lines[pos.Line]
+	col := byteOffset(line, pos.Character)
+
+	start, end := col, col
+	for start > 0 && isIdentChar(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isIdentChar(line[end]) {
+		end++
+	}
+	if start == end {
+		return nil
+	}
+	word := line[start:end]
+
+	path, inBlock := scopeAt(lines, pos.Line, start)
+	if inBlock {
+		if f, ok := specAt(root, path); ok && (f.Bloblang || f.Interpolated) {
+			return bloblangHover(word, start > 0 && line[start-1] == '.')
+		}
+		return nil
+	}
+
+	parent, ok := specAt(root, path)
+	if !ok {
+		return nil
+	}
+
+	t := tokenizeLine(line)
+	if t.keyCol == -1 {
+		return nil
+	}
+
+	// Hovering over the key itself.
+	if start <= t.keyCol+len(t.key) && end > t.keyCol {
+		if coreType, isCore := componentTypeOf(parent); isCore {
+			if _, reserved := docs.ReservedFieldsByType(coreType)[t.key]; !reserved {
+				if spec, exists := docs.GetDocs(nil, t.key, coreType); exists {
+					return &hover{Contents: markupContent{Kind: "markdown", Value: componentMarkdown(spec)}}
+				}
+				return nil
+			}
+		}
+		f, ok := childSpec(parent, t.key)
+		if !ok {
+			return nil
+		}
+		return &hover{Contents: markupContent{Kind: "markdown", Value: fieldMarkdown(f)}}
+	}
+
+	// Hovering over a value.
+	if coreType, isCore := componentTypeOf(parent); isCore && t.key == "type" {
+		if spec, exists := docs.GetDocs(nil, word, coreType); exists {
+			return &hover{Contents: markupContent{Kind: "markdown", Value: componentMarkdown(spec)}}
+		}
+		return nil
+	}
+	if f, ok := childSpec(parent, t.key); ok && (f.Bloblang || f.Interpolated) {
+		return bloblangHover(word, start > 0 && line[start-1] == '.')
+	}
+	return nil
+}
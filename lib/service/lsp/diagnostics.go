@@ -0,0 +1,156 @@
+package lsp
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"gopkg.in/yaml.v3"
+)
+
+var yamlErrLineRegexp = regexp.MustCompile(`line (\d+):`)
+
+// lineRange returns a range that spans a line from its first non-whitespace
+// character, or from a byte offset when one is provided.
+func lineRange(lines []string, lineIdx, offset int) lspRange {
+	if lineIdx < 0 {
+		lineIdx = 0
+	}
+	if lineIdx >= len(lines) {
+		lineIdx = len(lines) - 1
+	}
+	line := lines[lineIdx]
+	if offset < 0 || offset > len(line) {
+		offset = len(line) - len(strings.TrimLeft(line, " \t"))
+	}
+	return lspRange{
+		Start: position{Line: lineIdx, Character: characterOf(line, offset)},
+		End:   position{Line: lineIdx, Character: characterOf(line, len(line))},
+	}
+}
+
+// runeOffset converts a one-based rune column of a line into a byte offset.
+func runeOffset(line string, column int) int {
+	n := 0
+	for i := range line {
+		if n++; n == column {
+			return i
+		}
+	}
+	return len(line)
+}
+
+// scalarNodes returns all scalar nodes of a document.
+func scalarNodes(node *yaml.Node) []*yaml.Node {
+	if node.Kind == yaml.ScalarNode {
+		return []*yaml.Node{node}
+	}
+	var nodes []*yaml.Node
+	for _, child := range node.Content {
+		nodes = append(nodes, scalarNodes(child)...)
+	}
+	return nodes
+}
+
+// lintOffset converts the column of a lint into a byte offset of its line.
+//
+// Lints of scalar values, such as Bloblang mappings, report a column that is
+// the column of the scalar node plus the column within the scalar value, and
+// therefore the owning scalar is used in order to find the exact offset of the
+// column within the line, which for block scalars is relative to the
+// indentation of the block rather than the position of the block indicator.
+func lintOffset(lines []string, scalars []*yaml.Node, lint docs.Lint) int {
+	lineIdx := lint.Line - 1
+	if lint.Column < 1 || lineIdx < 0 || lineIdx >= len(lines) {
+		return -1
+	}
+	line := lines[lineIdx]
+
+	var owner *yaml.Node
+	for _, n := range scalars {
+		if n.Column >= lint.Column {
+			continue
+		}
+		if n.Style&yaml.LiteralStyle != 0 {
+			if lint.Line > n.Line && lint.Line <= n.Line+strings.Count(n.Value, "\n")+1 {
+				owner = n
+				break
+			}
+			continue
+		}
+		if n.Line == lint.Line && (owner == nil || n.Column > owner.Column) {
+			owner = n
+		}
+	}
+	if owner == nil {
+		return runeOffset(line, lint.Column)
+	}
+
+	valueCol := lint.Column - owner.Column
+	if owner.Style&yaml.LiteralStyle != 0 {
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		for i := owner.Line; i < len(lines); i++ {
+			if trimmed := strings.TrimLeft(lines[i], " "); trimmed != "" {
+				indent = len(lines[i]) - len(trimmed)
+				break
+			}
+		}
+		if indent > len(line) {
+			indent = len(line)
+		}
+		return indent + runeOffset(line[indent:], valueCol)
+	}
+
+	start := runeOffset(line, owner.Column)
+	if owner.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		start++
+	}
+	if start > len(line) {
+		return -1
+	}
+	return start + runeOffset(line[start:], valueCol)
+}
+
+// diagnose lints a config document and returns the results as diagnostics.
+func diagnose(root docs.FieldSpecs, text string) []diagnostic {
+	diagnostics := []diagnostic{}
+	if strings.HasPrefix(text, "# BENTHOS LINT DISABLE") {
+		return diagnostics
+	}
+
+	lines := splitLines(text)
+
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &node); err != nil {
+		lineIdx := 0
+		if m := yamlErrLineRegexp.FindStringSubmatch(err.Error()); m != nil {
+			lineIdx, _ = strconv.Atoi(m[1])
+			lineIdx--
+		}
+		return append(diagnostics, diagnostic{
+			Range:    lineRange(lines, lineIdx, -1),
+			Severity: severityError,
+			Source:   "benthos",
+			Message:  err.Error(),
+		})
+	}
+	if len(node.Content) == 0 {
+		return diagnostics
+	}
+
+	scalars := scalarNodes(&node)
+	for _, lint := range root.LintYAML(docs.NewLintContext(), &node) {
+		severity := severityError
+		if lint.Level == docs.LintWarning {
+			severity = severityWarning
+		}
+		diagnostics = append(diagnostics, diagnostic{
+			Range:    lineRange(lines, lint.Line-1, lintOffset(lines, scalars, lint)),
+			Severity: severity,
+			Source:   "benthos",
+			Message:  lint.What,
+		})
+	}
+	return diagnostics
+}
@@ -0,0 +1,126 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+)
+
+// itemSegment is the path segment used for an element of a sequence.
+const itemSegment = "-"
+
+// lineTokens describes a single line of a YAML document in terms of sequence
+// item dashes and a mapping key, which is enough to infer the path of a
+// position within a document even when the document isn't valid YAML, which is
+// common whilst it is being edited.
+type lineTokens struct {
+	// Byte offsets of each sequence item dash.
+	dashes []int
+
+	// The text following any dashes and its offset.
+	rest    string
+	restCol int
+
+	// The mapping key of the line, if any, and the value that follows it.
+	key    string
+	keyCol int
+	value  string
+}
+
+var keyRegexp = regexp.MustCompile(`^("[^"]*"|'[^']*'|[^\s:#'"{}\[\],&*!|>%@` + "`" + `][^\s:#]*)[ \t]*:(\s|$)`)
+
+func tokenizeLine(line string) lineTokens {
+	t := lineTokens{keyCol: -1}
+
+	i := 0
+	for {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		if i < len(line) && line[i] == '-' && (i+1 == len(line) || line[i+1] == ' ') {
+			t.dashes = append(t.dashes, i)
+			i++
+			continue
+		}
+		break
+	}
+
+	t.restCol, t.rest = i, line[i:]
+	if m := keyRegexp.FindStringSubmatch(t.rest); m != nil {
+		t.key = strings.Trim(m[1], `"'`)
+		t.keyCol = i
+		t.value = strings.TrimSpace(stripComment(t.rest[len(m[0]):]))
+	}
+	return t
+}
+
+func (t lineTokens) isBlank() bool {
+	return len(t.dashes) == 0 && (strings.TrimSpace(t.rest) == "" || strings.HasPrefix(t.rest, "#"))
+}
+
+func stripComment(s string) string {
+	if strings.HasPrefix(s, "#") {
+		return ""
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+func isBlockIndicator(value string) bool {
+	return strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">")
+}
+
+// scopeAt returns the path of the mapping or sequence containing a byte offset
+// of a line, by walking up through the lines above it and comparing their
+// indentation. If the offset is within a block scalar then the path of the
+// field that owns the block is returned and inBlock is true.
+func scopeAt(lines []string, lineIdx, col int) (path []string, inBlock bool) {
+	var reversed []string
+
+	cur := tokenizeLine(lines[lineIdx][:col])
+	c, cIsDash := cur.restCol, false
+	for i := len(cur.dashes) - 1; i >= 0; i-- {
+		reversed = append(reversed, itemSegment)
+		c, cIsDash = cur.dashes[i], true
+	}
+
+	for i := lineIdx - 1; i >= 0 && (c > 0 || cIsDash); i-- {
+		t := tokenizeLine(lines[i])
+		if t.isBlank() {
+			continue
+		}
+		if t.keyCol >= 0 && (t.keyCol < c || (cIsDash && t.keyCol == c)) {
+			switch {
+			case t.value == "":
+				reversed = append(reversed, t.key)
+				c, cIsDash = t.keyCol, false
+			case isBlockIndicator(t.value) && len(reversed) == 0:
+				reversed = append(reversed, t.key)
+				c, cIsDash = t.keyCol, false
+				inBlock = true
+			}
+		}
+		for j := len(t.dashes) - 1; j >= 0; j-- {
+			if t.dashes[j] < c {
+				reversed = append(reversed, itemSegment)
+				c, cIsDash = t.dashes[j], true
+			}
+		}
+	}
+
+	path = make([]string, 0, len(reversed))
+	for i := len(reversed) - 1; i >= 0; i-- {
+		path = append(path, reversed[i])
+	}
+	return path, inBlock
+}
+
+// splitLines splits a document into lines without their line endings.
+func splitLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSuffix(l, "\r")
+	}
+	return lines
+}
@@ -0,0 +1,192 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/config"
+)
+
+// Server is a language server for Benthos config files that communicates with
+// a client using the language server protocol. Diagnostics are provided by the
+// config linter, and completion and hover documentation are provided from the
+// specs of config fields, components and Bloblang functions and methods.
+type Server struct {
+	spec      docs.FieldSpecs
+	out       io.Writer
+	documents map[string]string
+	shutdown  bool
+}
+
+// NewServer creates a language server that writes messages to a writer.
+func NewServer(out io.Writer) *Server {
+	return &Server{
+		spec:      config.Spec(),
+		out:       out,
+		documents: map[string]string{},
+	}
+}
+
+// Serve reads and handles messages from a reader until it is closed or an exit
+// notification is received. An error is returned if the client exits without
+// first requesting a shutdown.
+func (s *Server) Serve(r io.Reader) error {
+	br := bufio.NewReader(r)
+	for {
+		body, err := readMessage(br)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err = s.respond(nil, nil, &responseError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit notification received before a shutdown request")
+			}
+			return nil
+		}
+
+		result, rErr := s.handle(req)
+		if req.ID == nil {
+			continue
+		}
+		if err = s.respond(req.ID, result, rErr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) respond(id *json.RawMessage, result interface{}, rErr *responseError) error {
+	res := response{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   rErr,
+	}
+	if rErr == nil {
+		resultBytes, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		res.Result = resultBytes
+	}
+	return writeMessage(s.out, res)
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return writeMessage(s.out, notification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+func (s *Server) publishDiagnostics(uri string) *responseError {
+	params := publishDiagnosticsParams{URI: uri, Diagnostics: []diagnostic{}}
+	if text, exists := s.documents[uri]; exists {
+		params.Diagnostics = diagnose(s.spec, text)
+	}
+	if err := s.notify("textDocument/publishDiagnostics", params); err != nil {
+		return &responseError{Code: codeInvalidRequest, Message: err.Error()}
+	}
+	return nil
+}
+
+func decodeParams(req request, v interface{}) *responseError {
+	if err := json.Unmarshal(req.Params, v); err != nil {
+		return &responseError{
+			Code:    codeInvalidParams,
+			Message: fmt.Sprintf("failed to parse %v params: %v", req.Method, err),
+		}
+	}
+	return nil
+}
+
+func (s *Server) handle(req request) (interface{}, *responseError) {
+	if s.shutdown {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shutting down"}
+	}
+
+	switch req.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync": 1,
+				"completionProvider": map[string]interface{}{
+					"triggerCharacters": []string{".", ":", " ", "-"},
+				},
+				"hoverProvider": true,
+			},
+			"serverInfo": map[string]interface{}{
+				"name": "benthos",
+			},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		s.documents[params.TextDocument.URI] = params.TextDocument.Text
+		return nil, s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			s.documents[params.TextDocument.URI] = params.ContentChanges[n-1].Text
+		}
+		return nil, s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		delete(s.documents, params.TextDocument.URI)
+		return nil, s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		items := complete(s.spec, s.documents[params.TextDocument.URI], params.Position)
+		if items == nil {
+			items = []completionItem{}
+		}
+		return completionList{Items: items}, nil
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		if h := hoverAt(s.spec, s.documents[params.TextDocument.URI], params.Position); h != nil {
+			return h, nil
+		}
+		return nil, nil
+	}
+
+	if req.ID == nil {
+		// Unsupported notifications, such as initialized, are ignored.
+		return nil, nil
+	}
+	return nil, &responseError{
+		Code:    codeMethodNotFound,
+		Message: fmt.Sprintf("method %v not supported", req.Method),
+	}
+}
@@ -0,0 +1,389 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/Jeffail/benthos/v3/public/components/legacy"
+)
+
+// cursorDoc removes a cursor marker from a document and returns its position.
+func cursorDoc(t *testing.T, doc string) (string, position) {
+	t.Helper()
+	i := strings.Index(doc, "|^")
+	require.True(t, i >= 0, "document has no cursor")
+	lines := strings.Split(doc[:i], "\n")
+	return doc[:i] + doc[i+2:], position{
+		Line:      len(lines) - 1,
+		Character: len(lines[len(lines)-1]),
+	}
+}
+
+func TestScopeAt(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		path    []string
+		inBlock bool
+	}{
+		{
+			name: "root",
+			doc:  `inp|^`,
+			path: []string{},
+		},
+		{
+			name: "nested key",
+			doc: `
+input:
+  kafka:
+    addr|^`,
+			path: []string{"input", "kafka"},
+		},
+		{
+			name: "sibling after nested",
+			doc: `
+input:
+  kafka:
+    addresses: [ foo ]
+  proc|^`,
+			path: []string{"input"},
+		},
+		{
+			name: "sequence item",
+			doc: `
+pipeline:
+  processors:
+    - bloblang: 'root = this'
+    - |^`,
+			path: []string{"pipeline", "processors", "-"},
+		},
+		{
+			name: "compact sequence",
+			doc: `
+pipeline:
+  processors:
+  - log:
+      level: INFO
+      mess|^`,
+			path: []string{"pipeline", "processors", "-", "log"},
+		},
+		{
+			name: "key of sequence item",
+			doc: `
+pipeline:
+  processors:
+    - label: foo
+      blob|^`,
+			path: []string{"pipeline", "processors", "-"},
+		},
+		{
+			name: "block scalar",
+			doc: `
+pipeline:
+  processors:
+    - bloblang: |
+        root = this
+        root.foo = this.bar.|^`,
+			path:    []string{"pipeline", "processors", "-", "bloblang"},
+			inBlock: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			doc, pos := cursorDoc(t, test.doc)
+			lines := splitLines(doc)
+			path, inBlock := scopeAt(lines, pos.Line, byteOffset(lines[pos.Line], pos.Character))
+			assert.Equal(t, test.path, path)
+			assert.Equal(t, test.inBlock, inBlock)
+		})
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	doc := `input:
+  generate:
+    mapping: 'root = this.foo.bar('
+    nope: true
+
+pipeline:
+  processors:
+    - bloblang: |
+        root = this
+        root.foo = this.
+
+output:
+  drop: {}
+`
+
+	diags := diagnose(config.Spec(), doc)
+	require.Len(t, diags, 3)
+
+	assert.Equal(t, severityError, diags[0].Severity)
+	assert.Equal(t, position{Line: 2, Character: 34}, diags[0].Range.Start)
+
+	assert.Equal(t, "field nope not recognised", diags[1].Message)
+	assert.Equal(t, position{Line: 3, Character: 4}, diags[1].Range.Start)
+	assert.Equal(t, position{Line: 3, Character: 14}, diags[1].Range.End)
+
+	assert.Equal(t, 9, diags[2].Range.Start.Line)
+	assert.Equal(t, 24, diags[2].Range.Start.Character)
+}
+
+func TestDiagnosticsYAMLError(t *testing.T) {
+	doc := `input:
+  generate:
+    mapping: foo
+   - bar
+`
+
+	diags := diagnose(config.Spec(), doc)
+	require.Len(t, diags, 1)
+	assert.Equal(t, severityError, diags[0].Severity)
+	assert.Contains(t, diags[0].Message, "yaml:")
+}
+
+func completionLabels(items []completionItem) []string {
+	labels := make([]string, 0, len(items))
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+	return labels
+}
+
+func TestCompletion(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "root fields",
+			doc:      `inp|^`,
+			contains: []string{"input", "pipeline", "output", "resources"},
+		},
+		{
+			name: "component types",
+			doc: `
+input:
+  |^`,
+			contains: []string{"kafka", "generate", "label", "processors"},
+			excludes: []string{"bloblang"},
+		},
+		{
+			name: "component fields",
+			doc: `
+input:
+  generate:
+    |^`,
+			contains: []string{"mapping", "interval", "count"},
+		},
+		{
+			name: "processor types in sequence",
+			doc: `
+pipeline:
+  processors:
+    - |^`,
+			contains: []string{"bloblang", "branch", "label"},
+		},
+		{
+			name: "field options",
+			doc: `
+pipeline:
+  processors:
+    - log:
+        level: |^`,
+			contains: []string{"INFO", "DEBUG"},
+		},
+		{
+			name: "bloblang functions",
+			doc: `
+pipeline:
+  processors:
+    - bloblang: |
+        root.id = uu|^`,
+			contains: []string{"uuid_v4", "now"},
+			excludes: []string{"uppercase"},
+		},
+		{
+			name: "bloblang methods",
+			doc: `
+pipeline:
+  processors:
+    - bloblang: 'root = this.foo.up|^'`,
+			contains: []string{"uppercase"},
+			excludes: []string{"uuid_v4"},
+		},
+		{
+			name: "interpolation functions",
+			doc: `
+output:
+  file:
+    path: '${! meta|^`,
+			contains: []string{"meta", "count"},
+		},
+		{
+			name: "no interpolation",
+			doc: `
+output:
+  file:
+    path: 'foo|^`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			doc, pos := cursorDoc(t, test.doc)
+			labels := completionLabels(complete(config.Spec(), doc, pos))
+			for _, exp := range test.contains {
+				assert.Contains(t, labels, exp)
+			}
+			for _, exp := range test.excludes {
+				assert.NotContains(t, labels, exp)
+			}
+			if len(test.contains) == 0 {
+				assert.Empty(t, labels)
+			}
+		})
+	}
+}
+
+func TestHover(t *testing.T) {
+	doc := `
+input:
+  generate:
+    mapping: 'root = now()'
+pipeline:
+  processors:
+    - type: bloblang
+      bloblang: |
+        root = this.uppercase()
+`
+
+	hoverAtLine := func(line, char int) string {
+		t.Helper()
+		h := hoverAt(config.Spec(), doc, position{Line: line, Character: char})
+		if h == nil {
+			return ""
+		}
+		return h.Contents.Value
+	}
+
+	assert.Contains(t, hoverAtLine(2, 4), "**generate** `input`")
+	assert.Contains(t, hoverAtLine(3, 6), "**mapping** `string`")
+	assert.Contains(t, hoverAtLine(3, 22), "now()")
+	assert.Contains(t, hoverAtLine(6, 15), "**bloblang** `processor`")
+	assert.Contains(t, hoverAtLine(8, 23), "uppercase()")
+	assert.Contains(t, hoverAtLine(4, 2), "**pipeline** `object`")
+	assert.Equal(t, "", hoverAtLine(7, 0))
+}
+
+func TestServerLifecycle(t *testing.T) {
+	var in bytes.Buffer
+	writeReq := func(id int, method string, params interface{}) {
+		msg := map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  method,
+			"params":  params,
+		}
+		if id > 0 {
+			msg["id"] = id
+		}
+		require.NoError(t, writeMessage(&in, msg))
+	}
+
+	uri := "file:///tmp/config.yaml"
+	writeReq(1, "initialize", map[string]interface{}{})
+	writeReq(0, "initialized", map[string]interface{}{})
+	writeReq(0, "textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{
+			"uri":     uri,
+			"version": 1,
+			"text":    "input:\n  generate:\n    nope: true\n",
+		},
+	})
+	writeReq(0, "textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri},
+		"contentChanges": []interface{}{map[string]interface{}{"text": "input:\n  gen"}},
+	})
+	writeReq(2, "textDocument/completion", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"position":     map[string]interface{}{"line": 1, "character": 5},
+	})
+	writeReq(3, "unknown/method", map[string]interface{}{})
+	writeReq(4, "shutdown", nil)
+	writeReq(0, "exit", nil)
+
+	var out bytes.Buffer
+	require.NoError(t, NewServer(&out).Serve(&in))
+
+	var msgs []map[string]interface{}
+	outReader := bufio.NewReader(&out)
+	for {
+		body, err := readMessage(outReader)
+		if err != nil {
+			break
+		}
+		var msg map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &msg))
+		msgs = append(msgs, msg)
+	}
+	require.Len(t, msgs, 6)
+
+	assert.Equal(t, float64(1), msgs[0]["id"])
+	assert.Equal(t, true, msgs[0]["result"].(map[string]interface{})["capabilities"].(map[string]interface{})["hoverProvider"])
+
+	assert.Equal(t, "textDocument/publishDiagnostics", msgs[1]["method"])
+	diags := msgs[1]["params"].(map[string]interface{})["diagnostics"].([]interface{})
+	require.Len(t, diags, 1)
+	assert.Equal(t, "field nope not recognised", diags[0].(map[string]interface{})["message"])
+
+	assert.Equal(t, "textDocument/publishDiagnostics", msgs[2]["method"])
+
+	assert.Equal(t, float64(2), msgs[3]["id"])
+	items := msgs[3]["result"].(map[string]interface{})["items"].([]interface{})
+	var labels []string
+	for _, item := range items {
+		labels = append(labels, item.(map[string]interface{})["label"].(string))
+	}
+	assert.Contains(t, labels, "generate")
+
+	assert.Equal(t, float64(3), msgs[4]["id"])
+	assert.Equal(t, float64(codeMethodNotFound), msgs[4]["error"].(map[string]interface{})["code"])
+
+	assert.Equal(t, float64(4), msgs[5]["id"])
+	assert.Contains(t, msgs[5], "result")
+	assert.Nil(t, msgs[5]["result"])
+}
+
+func TestServerExitWithoutShutdown(t *testing.T) {
+	var in bytes.Buffer
+	require.NoError(t, writeMessage(&in, map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "exit",
+	}))
+
+	var out bytes.Buffer
+	assert.EqualError(t, NewServer(&out).Serve(&in), "exit notification received before a shutdown request")
+}
+
+func TestReadMessageContentLength(t *testing.T) {
+	body, err := readMessage(bufio.NewReader(strings.NewReader("Content-Length: 2\r\n\r\n{}")))
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(body))
+
+	for _, length := range []string{"-1", "1099511627776", "nope"} {
+		_, err := readMessage(bufio.NewReader(strings.NewReader("Content-Length: " + length + "\r\n\r\n{}")))
+		assert.Error(t, err, length)
+	}
+}
@@ -0,0 +1,93 @@
+package lsp
+
+import (
+	"github.com/Jeffail/benthos/v3/internal/bundle"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+)
+
+// componentSpecs returns the specs of all registered components of a type.
+func componentSpecs(t docs.Type) []docs.ComponentSpec {
+	switch t {
+	case docs.TypeBuffer:
+		return bundle.AllBuffers.Docs()
+	case docs.TypeCache:
+		return bundle.AllCaches.Docs()
+	case docs.TypeInput:
+		return bundle.AllInputs.Docs()
+	case docs.TypeMetrics:
+		return bundle.AllMetrics.Docs()
+	case docs.TypeOutput:
+		return bundle.AllOutputs.Docs()
+	case docs.TypeProcessor:
+		return bundle.AllProcessors.Docs()
+	case docs.TypeRateLimit:
+		return bundle.AllRateLimits.Docs()
+	case docs.TypeTracer:
+		return bundle.AllTracers.Docs()
+	}
+	return nil
+}
+
+// childSpec returns the spec of a child of a field identified by a path
+// segment, which is either a key or an item of a sequence.
+func childSpec(f docs.FieldSpec, seg string) (docs.FieldSpec, bool) {
+	switch f.Kind {
+	case docs.Kind2DArray:
+		if seg == itemSegment {
+			return f.Array(), true
+		}
+		return docs.FieldSpec{}, false
+	case docs.KindArray:
+		if seg == itemSegment {
+			return f.Scalar(), true
+		}
+		return docs.FieldSpec{}, false
+	case docs.KindMap:
+		return f.Scalar(), true
+	}
+
+	if coreType, isCore := f.Type.IsCoreComponent(); isCore {
+		if reserved, exists := docs.ReservedFieldsByType(coreType)[seg]; exists {
+			return reserved, true
+		}
+		if cSpec, exists := docs.GetDocs(nil, seg, coreType); exists {
+			conf := cSpec.Config
+			conf.Name = seg
+			return conf, true
+		}
+		return docs.FieldSpec{}, false
+	}
+
+	for _, child := range f.Children {
+		if child.Name == seg {
+			return child, true
+		}
+	}
+	return docs.FieldSpec{}, false
+}
+
+// specAt walks a path from the root of a config and returns the spec of the
+// field found at the end of it.
+func specAt(root docs.FieldSpecs, path []string) (docs.FieldSpec, bool) {
+	current := docs.FieldCommon("", "").WithChildren(root...)
+	for _, seg := range path {
+		var ok bool
+		if current, ok = childSpec(current, seg); !ok {
+			return docs.FieldSpec{}, false
+		}
+	}
+	return current, true
+}
+
+// componentTypeOf returns the component type of a field when its value is a
+// component config.
+func componentTypeOf(f docs.FieldSpec) (docs.Type, bool) {
+	if f.Kind != docs.KindScalar && f.Kind != "" {
+		return "", false
+	}
+	t, isCore := f.Type.IsCoreComponent()
+	if !isCore || t == "condition" {
+		return "", false
+	}
+	return t, true
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/urfave/cli/v2"
+)
+
+// migrateFile applies migrations to the deprecated components and fields of a
+// config file, returning the formatted original and migrated configs.
+func migrateFile(path string) (before, after []byte, migrations []docs.Migration, err error) {
+	var rawBytes []byte
+	if rawBytes, err = os.ReadFile(path); err != nil {
+		return
+	}
+	if before, err = formatYAML(rawBytes); err != nil {
+		return
+	}
+
+	nodes, err := readYAMLDocuments(rawBytes)
+	if err != nil {
+		return
+	}
+	spec := config.Spec()
+	for _, node := range nodes {
+		migrations = append(migrations, spec.MigrateYAML(nil, node)...)
+	}
+	after, err = writeYAMLDocuments(nodes)
+	return
+}
+
+func migrateCliCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "Migrate deprecated components and fields of Benthos configs",
+		Description: `
+   Rewrites deprecated components and fields of Benthos configs to their modern
+   equivalents where possible. By default a diff of the changes is printed
+   without modifying any files, use the --write flag in order to apply them:
+
+   benthos migrate ./config.yaml
+   benthos migrate -w ./configs/...
+
+   Deprecated components and fields that cannot be migrated automatically are
+   reported and must be migrated manually. The resulting configs are formatted
+   as they would be with the fmt subcommand.
+
+   If a path ends with '...' then Benthos will walk the target and migrate any
+   files with the .yaml or .yml extension.`[4:],
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "write",
+				Aliases: []string{"w"},
+				Value:   false,
+				Usage:   "Write migrated configs back to their source files instead of printing a diff.",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			targets, err := resolveYAMLTargets(c.Args().Slice())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Filesystem walk error: %v\n", err)
+				os.Exit(1)
+			}
+			if conf := c.String("config"); len(conf) > 0 {
+				targets = append(targets, conf)
+			}
+
+			failed := false
+			for _, target := range targets {
+				before, after, migrations, err := migrateFile(target)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%v: %v\n", target, red(err))
+					failed = true
+					continue
+				}
+				for _, m := range migrations {
+					message := m.What
+					if m.Skipped {
+						message = yellow(message)
+					}
+					fmt.Fprintf(os.Stderr, "%v: line %v: %v\n", target, m.Line, message)
+				}
+				if bytes.Equal(before, after) {
+					continue
+				}
+				if c.Bool("write") {
+					if err := os.WriteFile(target, after, 0o644); err != nil {
+						fmt.Fprintf(os.Stderr, "%v: %v\n", target, red(err))
+						failed = true
+					}
+					continue
+				}
+				diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+					A:        difflib.SplitLines(string(before)),
+					B:        difflib.SplitLines(string(after)),
+					FromFile: target,
+					ToFile:   target + " (migrated)",
+					Context:  3,
+				})
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%v: %v\n", target, red(err))
+					failed = true
+					continue
+				}
+				fmt.Print(diff)
+			}
+			if failed {
+				os.Exit(1)
+			}
+			os.Exit(0)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		before     string
+		after      string
+		migrations []docs.Migration
+	}{
+		{
+			name: "deprecated fields",
+			input: `# The input of the pipeline.
+input:
+    kafka:
+        addresses: [ localhost:9092 ] # brokers
+        topic: foo
+        max_batch_count: 1
+`,
+			before: `# The input of the pipeline.
+input:
+  kafka:
+    addresses: ['localhost:9092'] # brokers
+    topic: foo
+    max_batch_count: 1
+`,
+			after: `# The input of the pipeline.
+input:
+  kafka:
+    addresses: ['localhost:9092'] # brokers
+    topics:
+      - foo:0
+`,
+			migrations: []docs.Migration{
+				{Line: 5, What: "fields topic and partition replaced with topics"},
+				{Line: 6, What: "deprecated field max_batch_count removed as it is set to its default value"},
+			},
+		},
+		{
+			name: "deprecated processor",
+			input: `pipeline:
+  processors:
+    # Drop documents without a body.
+    - filter_parts:
+        bloblang: 'this.body != null'
+    - bloblang: 'root = this.body' # extract the body
+`,
+			before: `pipeline:
+  processors:
+    # Drop documents without a body.
+    - filter_parts:
+        bloblang: 'this.body != null'
+    - bloblang: 'root = this.body' # extract the body
+`,
+			after: `pipeline:
+  processors:
+    # Drop documents without a body.
+    - bloblang: root = if !(this.body != null) { deleted() }
+    - bloblang: 'root = this.body' # extract the body
+`,
+			migrations: []docs.Migration{
+				{Line: 4, What: "deprecated processor filter_parts replaced with bloblang, the resulting mapping deletes individual messages rather than entire batches"},
+			},
+		},
+		{
+			name: "nothing deprecated",
+			input: `input:
+    stdin: {} # read from stdin
+output:
+    stdout: {}
+`,
+			before: `input:
+  stdin: {} # read from stdin
+output:
+  stdout: {}
+`,
+			after: `input:
+  stdin: {} # read from stdin
+output:
+  stdout: {}
+`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(test.input), 0o644))
+
+			before, after, migrations, err := migrateFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, test.before, string(before))
+			assert.Equal(t, test.after, string(after))
+			assert.Equal(t, test.migrations, migrations)
+
+			// The source file is never modified.
+			rawBytes, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, test.input, string(rawBytes))
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/Jeffail/benthos/v3/internal/template"
 	"github.com/Jeffail/benthos/v3/lib/config"
 	"github.com/Jeffail/benthos/v3/lib/service/blobl"
+	"github.com/Jeffail/benthos/v3/lib/service/lsp"
 	"github.com/Jeffail/benthos/v3/lib/service/test"
 	uconfig "github.com/Jeffail/benthos/v3/lib/util/config"
 	"github.com/urfave/cli/v2"
@@ -264,6 +265,9 @@ func Run() {
 				},
 			},
 			lintCliCommand(),
+			fmtCliCommand(),
+			migrateCliCommand(),
+			dagCliCommand(),
 			{
 				Name:  "streams",
 				Usage: "Run Benthos in streams mode",
@@ -324,6 +328,7 @@ func Run() {
 			test.CliCommand(testSuffix),
 			clitemplate.CliCommand(),
 			blobl.CliCommand(),
+			lsp.CliCommand(),
 		},
 	}
 
@@ -376,7 +376,10 @@ func cmdService(
 	}
 
 	// Create resource manager.
-	manager, err := manager.NewV2(conf.ResourceConfig, httpServer, logger, stats)
+	manager, err := manager.NewV2(
+		conf.ResourceConfig, httpServer, logger, stats,
+		manager.OptSetCacheWrites(conf.HTTP.CacheWrites),
+	)
 	if err != nil {
 		logger.Errorf("Failed to create resource: %v\n", err)
 		return 1
@@ -75,8 +75,11 @@ type Case struct {
 	Mocks            map[string]yaml.Node `yaml:"mocks"`
 	InputBatch       []InputPart          `yaml:"input_batch"`
 	OutputBatches    [][]ConditionsMap    `yaml:"output_batches"`
+	Fuzz             *FuzzConfig          `yaml:"fuzz,omitempty"`
 
-	line int
+	line            int
+	updateSnapshots bool
+	runFuzz         bool
 }
 
 // AtLine returns a test case at a given line.
@@ -143,6 +146,11 @@ func (c *Case) Execute(provider ProcProvider) (failures []CaseFailure, err error
 }
 
 func (c *Case) executeFrom(dir string, provider ProcProvider) (failures []CaseFailure, err error) {
+	if c.Fuzz != nil && !c.runFuzz {
+		// Fuzz cases are only executed when fuzzing is enabled.
+		return nil, nil
+	}
+
 	var procSet []types.Processor
 	if c.TargetMapping != "" {
 		if procSet, err = provider.ProvideBloblang(c.TargetMapping); err != nil {
@@ -156,6 +164,10 @@ func (c *Case) executeFrom(dir string, provider ProcProvider) (failures []CaseFa
 		return nil, fmt.Errorf("failed to initialise processors '%v': %v", c.TargetProcessors, err)
 	}
 
+	if c.Fuzz != nil {
+		return c.executeFuzzFrom(dir, procSet)
+	}
+
 	reportFailure := func(reason string) {
 		failures = append(failures, CaseFailure{
 			Name:     c.Name,
@@ -209,7 +221,7 @@ func (c *Case) executeFrom(dir string, provider ProcProvider) (failures []CaseFa
 				reportFailure(fmt.Sprintf("unexpected message from batch %v: %s", i, part.Get()))
 				return nil
 			}
-			condErrs := expectedBatch[i2].checkAllFrom(dir, part)
+			condErrs := expectedBatch[i2].checkAllFrom(dir, c.updateSnapshots, part)
 			for _, condErr := range condErrs {
 				reportFailure(fmt.Sprintf("batch %v message %v: %v", i, i2, condErr))
 			}
@@ -336,3 +336,44 @@ output_batches:
 		},
 	}, fails)
 }
+
+func TestCaseUpdateSnapshots(t *testing.T) {
+	color.NoColor = true
+
+	provider := mockProvider{}
+	procConf := processor.NewConfig()
+
+	procConf.Type = processor.TypeBloblang
+	procConf.Bloblang = processor.BloblangConfig(`root.value = content().string()`)
+	proc, err := processor.New(procConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	provider["/pipeline/processors"] = []types.Processor{proc}
+
+	tmpDir := t.TempDir()
+
+	c := NewCase()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+name: snapshots
+input_batch:
+  - content: foo
+output_batches:
+-
+  - snapshot: ./snapshots/foo.json
+`), &c))
+
+	fails, err := c.executeFrom(tmpDir, provider)
+	require.NoError(t, err)
+	require.Len(t, fails, 1)
+	assert.Contains(t, fails[0].Reason, "does not exist")
+
+	c.updateSnapshots = true
+	fails, err = c.executeFrom(tmpDir, provider)
+	require.NoError(t, err)
+	assert.Empty(t, fails)
+
+	c.updateSnapshots = false
+	fails, err = c.executeFrom(tmpDir, provider)
+	require.NoError(t, err)
+	assert.Empty(t, fails)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
 	"github.com/Jeffail/benthos/v3/internal/filepath"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/urfave/cli/v2"
@@ -30,6 +31,26 @@ func CliCommand(testSuffix string) *cli.Command {
 				Value: false,
 				Usage: "instead of testing, detect untested Benthos configs and generate test definitions for them.",
 			},
+			&cli.BoolFlag{
+				Name:  "fuzz",
+				Value: false,
+				Usage: "execute test cases that define a fuzz section, which are otherwise skipped.",
+			},
+			&cli.BoolFlag{
+				Name:  "update-snapshots",
+				Value: false,
+				Usage: "instead of comparing messages against snapshot conditions, (re)write the snapshot files from the actual messages.",
+			},
+			&cli.BoolFlag{
+				Name:  "coverage",
+				Value: false,
+				Usage: "record which statements, branches and maps of Bloblang mappings are executed by tests, print a summary and write an lcov report.",
+			},
+			&cli.StringFlag{
+				Name:  "coverage-out",
+				Value: "coverage.lcov",
+				Usage: "the path to write an lcov coverage report to when --coverage is set.",
+			},
 			&cli.StringFlag{
 				Name:  "log",
 				Value: "",
@@ -56,14 +77,24 @@ func CliCommand(testSuffix string) *cli.Command {
 				fmt.Printf("Failed to resolve resource glob pattern: %v\n", err)
 				os.Exit(1)
 			}
+			logger := log.Noop()
 			if logLevel := c.String("log"); len(logLevel) > 0 {
 				logConf := log.NewConfig()
 				logConf.LogLevel = logLevel
-				logger := log.New(os.Stdout, logConf)
-				if runAll(c.Args().Slice(), testSuffix, true, logger, resourcesPaths) {
-					os.Exit(0)
+				logger = log.New(os.Stdout, logConf)
+			}
+			var coverage *mapping.Coverage
+			if c.Bool("coverage") {
+				coverage = mapping.NewCoverage()
+			}
+			success := runAll(c.Args().Slice(), testSuffix, true, logger, resourcesPaths, coverage, c.Bool("update-snapshots"), c.Bool("fuzz"))
+			if coverage != nil {
+				if err := reportCoverage(coverage, c.String("coverage-out")); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to write coverage report: %v\n", err)
+					os.Exit(1)
 				}
-			} else if runAll(c.Args().Slice(), testSuffix, true, log.Noop(), resourcesPaths) {
+			}
+			if success {
 				os.Exit(0)
 			}
 			os.Exit(1)
@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
 	"github.com/Jeffail/benthos/v3/lib/config"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/fatih/color"
@@ -157,17 +158,17 @@ func Run(path, testSuffix string, lint bool) bool {
 // a config file, a config files test definition file, a directory, or the
 // wildcard pattern './...'.
 func RunAll(paths []string, testSuffix string, lint bool) bool {
-	return runAll(paths, testSuffix, lint, log.Noop(), nil)
+	return runAll(paths, testSuffix, lint, log.Noop(), nil, nil, false, false)
 }
 
 // RunAllWithLogger executes the test command for a slice of paths. The path can
 // either be a config file, a config files test definition file, a directory, or
 // the wildcard pattern './...'.
 func RunAllWithLogger(paths []string, testSuffix string, lint bool, logger log.Modular) bool {
-	return runAll(paths, testSuffix, lint, logger, nil)
+	return runAll(paths, testSuffix, lint, logger, nil, nil, false, false)
 }
 
-func runAll(paths []string, testSuffix string, lint bool, logger log.Modular, resourcesPaths []string) bool {
+func runAll(paths []string, testSuffix string, lint bool, logger log.Modular, resourcesPaths []string, coverage *mapping.Coverage, updateSnapshots, runFuzz bool) bool {
 	targets := map[string]Definition{}
 
 	for _, path := range paths {
@@ -211,7 +212,7 @@ func runAll(paths []string, testSuffix string, lint bool, logger log.Modular, re
 				return false
 			}
 		}
-		if failCases, err = targets[target].execute(target, resourcesPaths, logger); err != nil {
+		if failCases, err = targets[target].execute(target, resourcesPaths, logger, coverage, updateSnapshots, runFuzz); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to execute test target '%v': %v\n", target, err)
 			return false
 		}
@@ -222,6 +223,8 @@ func runAll(paths []string, testSuffix string, lint bool, logger log.Modular, re
 				cases:  failCases,
 			})
 			fmt.Printf("Test '%v' %v\n", target, red("failed"))
+		} else if skipped := targets[target].fuzzCases(); !runFuzz && skipped > 0 {
+			fmt.Printf("Test '%v' %v %v\n", target, green("succeeded"), yellow(fmt.Sprintf("(%v fuzz cases skipped, use --fuzz to execute them)", skipped)))
 		} else {
 			fmt.Printf("Test '%v' %v\n", target, green("succeeded"))
 		}
@@ -84,6 +84,12 @@ func (c *ConditionsMap) UnmarshalYAML(value *yaml.Node) error {
 				return fmt.Errorf("line %v: %v", v.Line, err)
 			}
 			cond = val
+		case "snapshot":
+			val := SnapshotCondition("")
+			if err := v.Decode(&val); err != nil {
+				return fmt.Errorf("line %v: %v", v.Line, err)
+			}
+			cond = val
 		default:
 			return fmt.Errorf("line %v: message part condition type not recognised: %v", v.Line, k)
 		}
@@ -95,17 +101,21 @@ func (c *ConditionsMap) UnmarshalYAML(value *yaml.Node) error {
 // CheckAll checks all conditions against a message part. Conditions are
 // executed in alphabetical order.
 func (c ConditionsMap) CheckAll(part types.Part) (errs []error) {
-	return c.checkAllFrom("", part)
+	return c.checkAllFrom("", false, part)
 }
 
-func (c ConditionsMap) checkAllFrom(dir string, part types.Part) (errs []error) {
+func (c ConditionsMap) checkAllFrom(dir string, updateSnapshots bool, part types.Part) (errs []error) {
 	condTypes := []string{}
 	for k := range c {
 		condTypes = append(condTypes, k)
 	}
 	sort.Strings(condTypes)
 	for _, k := range condTypes {
-		if relCheck, ok := c[k].(interface {
+		if snapshot, ok := c[k].(SnapshotCondition); ok && updateSnapshots {
+			if err := snapshot.updateFrom(dir, part); err != nil {
+				errs = append(errs, fmt.Errorf("%v: %v", k, err))
+			}
+		} else if relCheck, ok := c[k].(interface {
 			checkFrom(string, types.Part) error
 		}); ok {
 			if err := relCheck.checkFrom(dir, part); err != nil {
@@ -259,6 +269,90 @@ func (m MetadataEqualsCondition) Check(p types.Part) error {
 
 //------------------------------------------------------------------------------
 
+// SnapshotCondition is a string condition that reads a snapshot (golden) file
+// at the string path and compares it against both the contents and metadata of
+// a message. Snapshot files can be (re)written from the actual messages by
+// running tests with the flag --update-snapshots.
+type SnapshotCondition string
+
+// Check this condition against a message part.
+func (s SnapshotCondition) Check(p types.Part) error {
+	return s.checkFrom("", p)
+}
+
+// snapshotBytes returns a JSON document describing the contents and metadata
+// of a message part. Contents that are valid JSON are stored under the key
+// json_content in their structured form in order to produce readable diffs,
+// otherwise they're stored as a string under the key content.
+func snapshotBytes(p types.Part) ([]byte, error) {
+	meta := map[string]string{}
+	_ = p.Metadata().Iter(func(k, v string) error {
+		meta[k] = v
+		return nil
+	})
+
+	doc := map[string]interface{}{
+		"metadata": meta,
+	}
+	var jContent interface{}
+	if err := json.Unmarshal(p.Get(), &jContent); err == nil {
+		doc["json_content"] = jContent
+	} else {
+		doc["content"] = string(p.Get())
+	}
+
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+func (s SnapshotCondition) checkFrom(dir string, p types.Part) error {
+	relPath := filepath.Join(dir, string(s))
+
+	expBytes, err := os.ReadFile(relPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("snapshot file '%v' does not exist, run the tests with --update-snapshots in order to create it", string(s))
+		}
+		return fmt.Errorf("failed to read snapshot file: %w", err)
+	}
+
+	actBytes, err := snapshotBytes(p)
+	if err != nil {
+		return fmt.Errorf("failed to serialise message: %w", err)
+	}
+
+	jdopts := jsondiff.DefaultConsoleOptions()
+	diff, explanation := jsondiff.Compare(actBytes, expBytes, &jdopts)
+	if diff != jsondiff.FullMatch {
+		if diff == jsondiff.SecondArgIsInvalidJson {
+			return fmt.Errorf("snapshot file '%v' is not valid JSON", string(s))
+		}
+		return fmt.Errorf("snapshot mismatch\n%v", explanation)
+	}
+	return nil
+}
+
+func (s SnapshotCondition) updateFrom(dir string, p types.Part) error {
+	relPath := filepath.Join(dir, string(s))
+
+	actBytes, err := snapshotBytes(p)
+	if err != nil {
+		return fmt.Errorf("failed to serialise message: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(relPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	if err := os.WriteFile(relPath, actBytes, 0o644); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
 // Helper function for converting yaml.Node to a string
 // simple nodes are converted to their string equivalents
 // complex nodes are converted to a JSON representation
@@ -480,3 +480,65 @@ func TestFileEqualsCondition(t *testing.T) {
 		})
 	}
 }
+
+func TestSnapshotCondition(t *testing.T) {
+	color.NoColor = true
+
+	tmpDir := t.TempDir()
+
+	jsonPart := message.NewPart([]byte(`{"foo":"bar","baz":[1,2]}`))
+	jsonPart.Metadata().Set("key1", "value1")
+
+	rawPart := message.NewPart([]byte(`hello world`))
+
+	cond := SnapshotCondition("./snapshots/foo.json")
+
+	err := cond.checkFrom(tmpDir, jsonPart)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--update-snapshots")
+
+	require.NoError(t, cond.updateFrom(tmpDir, jsonPart))
+
+	snapBytes, err := os.ReadFile(filepath.Join(tmpDir, "snapshots", "foo.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "json_content": {
+    "baz": [
+      1,
+      2
+    ],
+    "foo": "bar"
+  },
+  "metadata": {
+    "key1": "value1"
+  }
+}
+`, string(snapBytes))
+
+	assert.NoError(t, cond.checkFrom(tmpDir, jsonPart))
+
+	modifiedPart := message.NewPart([]byte(`{"foo":"buz","baz":[1,2]}`))
+	modifiedPart.Metadata().Set("key1", "value1")
+	err = cond.checkFrom(tmpDir, modifiedPart)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "snapshot mismatch")
+	assert.Contains(t, err.Error(), `"buz"`)
+
+	err = cond.checkFrom(tmpDir, message.NewPart([]byte(`{"foo":"bar","baz":[1,2]}`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "snapshot mismatch")
+
+	rawCond := SnapshotCondition("raw.json")
+	require.NoError(t, rawCond.updateFrom(tmpDir, rawPart))
+
+	snapBytes, err = os.ReadFile(filepath.Join(tmpDir, "raw.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "content": "hello world",
+  "metadata": {}
+}
+`, string(snapBytes))
+
+	assert.NoError(t, rawCond.checkFrom(tmpDir, rawPart))
+	assert.Error(t, rawCond.checkFrom(tmpDir, message.NewPart([]byte(`hello there`))))
+}
@@ -0,0 +1,224 @@
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+)
+
+//------------------------------------------------------------------------------
+
+// mappingLineOffset attempts to locate a mapping within the contents of the
+// file it originates from, and returns the number of lines that precede it.
+// Mappings within config files are usually block scalars and are therefore
+// indented, so lines are compared with surrounding whitespace trimmed. If the
+// mapping cannot be located then zero is returned.
+func mappingLineOffset(fileContents, mappingStr string) int {
+	fileLines := strings.Split(fileContents, "\n")
+	mappingLines := strings.Split(mappingStr, "\n")
+
+	first := -1
+	for i, l := range mappingLines {
+		if strings.TrimSpace(l) != "" {
+			first = i
+			break
+		}
+	}
+	if first == -1 {
+		return 0
+	}
+
+	for i := range fileLines {
+		if len(fileLines)-i < len(mappingLines)-first {
+			break
+		}
+		matched := true
+		for j := first; j < len(mappingLines); j++ {
+			if strings.TrimSpace(fileLines[i+j-first]) != strings.TrimSpace(mappingLines[j]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i - first
+		}
+	}
+	return 0
+}
+
+type coverageFile struct {
+	name    string
+	results []mapping.CoverageResult
+	offsets []int
+}
+
+func groupCoverageResults(results []mapping.CoverageResult) []coverageFile {
+	var files []coverageFile
+	for _, res := range results {
+		if len(files) == 0 || files[len(files)-1].name != res.Name {
+			files = append(files, coverageFile{name: res.Name})
+		}
+		f := &files[len(files)-1]
+
+		var offset int
+		if contents, err := os.ReadFile(res.Name); err == nil {
+			offset = mappingLineOffset(string(contents), res.Mapping)
+		}
+		f.results = append(f.results, res)
+		f.offsets = append(f.offsets, offset)
+	}
+	return files
+}
+
+// mapNameAt extracts the name of a map definition from a given line of a
+// mapping.
+func mapNameAt(mappingStr string, line int) string {
+	lines := strings.Split(mappingStr, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	name := strings.TrimSpace(lines[line-1])
+	name = strings.TrimPrefix(name, "map")
+	if i := strings.Index(name, "{"); i >= 0 {
+		name = name[:i]
+	}
+	return strings.Trim(strings.TrimSpace(name), `"`)
+}
+
+func percentage(covered, total int) string {
+	if total == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%%", float64(covered)/float64(total)*100)
+}
+
+//------------------------------------------------------------------------------
+
+func printCoverageSummary(w io.Writer, files []coverageFile) {
+	if len(files) == 0 {
+		fmt.Fprintf(w, "\nCoverage: %v\n", yellow("no Bloblang mappings were executed"))
+		return
+	}
+
+	fmt.Fprintf(w, "\nCoverage:\n\n")
+	for _, f := range files {
+		var stmts, stmtsTotal, branches, branchesTotal, maps, mapsTotal int
+		var uncovered []string
+		for i, res := range f.results {
+			c, t := res.Covered(query.CoverageStatement)
+			stmts, stmtsTotal = stmts+c, stmtsTotal+t
+			c, t = res.Covered(query.CoverageMatchCase, query.CoverageIfBranch, query.CoverageElseBranch)
+			branches, branchesTotal = branches+c, branchesTotal+t
+			c, t = res.Covered(query.CoverageMap)
+			maps, mapsTotal = maps+c, mapsTotal+t
+
+			for _, p := range res.Points {
+				if p.Hits == 0 {
+					uncovered = append(uncovered, fmt.Sprintf("line %v: %v", p.Line+f.offsets[i], p.Kind))
+				}
+			}
+		}
+		fmt.Fprintf(
+			w, "%v: statements %v/%v (%v), branches %v/%v (%v), maps %v/%v (%v)\n", f.name,
+			stmts, stmtsTotal, percentage(stmts, stmtsTotal),
+			branches, branchesTotal, percentage(branches, branchesTotal),
+			maps, mapsTotal, percentage(maps, mapsTotal),
+		)
+		for _, u := range uncovered {
+			fmt.Fprintf(w, "  %v %v\n", red("not covered"), u)
+		}
+	}
+}
+
+// writeLCOV writes coverage results as an lcov tracefile, where statements are
+// reported as lines, branches of match and if expressions as branches, and
+// named maps as functions.
+func writeLCOV(w io.Writer, files []coverageFile) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range files {
+		fmt.Fprintf(bw, "TN:\nSF:%v\n", f.name)
+
+		var fnFound, fnHit int
+		for i, res := range f.results {
+			for _, p := range res.Points {
+				if p.Kind != query.CoverageMap {
+					continue
+				}
+				name := mapNameAt(res.Mapping, p.Line)
+				fmt.Fprintf(bw, "FN:%v,%v\nFNDA:%v,%v\n", p.Line+f.offsets[i], name, p.Hits, name)
+				if fnFound++; p.Hits > 0 {
+					fnHit++
+				}
+			}
+		}
+		fmt.Fprintf(bw, "FNF:%v\nFNH:%v\n", fnFound, fnHit)
+
+		var brFound, brHit int
+		for i, res := range f.results {
+			branch := 0
+			for _, p := range res.Points {
+				if !p.Kind.IsBranch() {
+					continue
+				}
+				fmt.Fprintf(bw, "BRDA:%v,%v,%v,%v\n", p.Line+f.offsets[i], i, branch, p.Hits)
+				branch++
+				if brFound++; p.Hits > 0 {
+					brHit++
+				}
+			}
+		}
+		fmt.Fprintf(bw, "BRF:%v\nBRH:%v\n", brFound, brHit)
+
+		lineHits := map[int]int64{}
+		var lines []int
+		for i, res := range f.results {
+			for _, p := range res.Points {
+				if p.Kind != query.CoverageStatement {
+					continue
+				}
+				line := p.Line + f.offsets[i]
+				if _, exists := lineHits[line]; !exists {
+					lines = append(lines, line)
+				}
+				lineHits[line] += p.Hits
+			}
+		}
+		sort.Ints(lines)
+
+		var linesHit int
+		for _, l := range lines {
+			fmt.Fprintf(bw, "DA:%v,%v\n", l, lineHits[l])
+			if lineHits[l] > 0 {
+				linesHit++
+			}
+		}
+		fmt.Fprintf(bw, "LF:%v\nLH:%v\nend_of_record\n", len(lines), linesHit)
+	}
+	return bw.Flush()
+}
+
+func reportCoverage(coverage *mapping.Coverage, lcovPath string) error {
+	files := groupCoverageResults(coverage.Results())
+	printCoverageSummary(os.Stdout, files)
+	if lcovPath == "" {
+		return nil
+	}
+
+	f, err := os.Create(lcovPath)
+	if err != nil {
+		return err
+	}
+	if err = writeLCOV(f, files); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//------------------------------------------------------------------------------
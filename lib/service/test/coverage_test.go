@@ -0,0 +1,78 @@
+package test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappingLineOffset(t *testing.T) {
+	fileContents := `pipeline:
+  processors:
+    - bloblang: |
+        root.foo = this.bar
+        root.baz = "buz"
+`
+	assert.Equal(t, 3, mappingLineOffset(fileContents, "root.foo = this.bar\nroot.baz = \"buz\"\n"))
+	assert.Equal(t, 0, mappingLineOffset(fileContents, "root = this"))
+}
+
+func TestCoverageLCOV(t *testing.T) {
+	dir := t.TempDir()
+	confPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(confPath, []byte(`pipeline:
+  processors:
+    - bloblang: |
+        root.a = match this.v {
+          "x" => "was x"
+          _ => "was not x"
+        }
+`), 0o644))
+
+	def := Definition{
+		Cases: []Case{
+			{
+				Name:             "foo",
+				TargetProcessors: "/pipeline/processors",
+				InputBatch: []InputPart{
+					{Content: `{"v":"x"}`},
+				},
+				OutputBatches: [][]ConditionsMap{
+					{
+						{"json_equals": ContentJSONEqualsCondition(`{"a":"was x"}`)},
+					},
+				},
+			},
+		},
+	}
+
+	cov := mapping.NewCoverage()
+	fails, err := def.execute(confPath, nil, log.Noop(), cov, false, false)
+	require.NoError(t, err)
+	require.Empty(t, fails)
+
+	files := groupCoverageResults(cov.Results())
+	require.Len(t, files, 1)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeLCOV(&buf, files))
+	assert.Equal(t, `TN:
+SF:`+confPath+`
+FNF:0
+FNH:0
+BRDA:5,0,0,1
+BRDA:6,0,1,0
+BRF:2
+BRH:1
+DA:4,1
+LF:1
+LH:1
+end_of_record
+`, buf.String())
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"golang.org/x/sync/errgroup"
 )
@@ -45,23 +46,36 @@ func ExampleDefinition() Definition {
 
 //------------------------------------------------------------------------------
 
+// fuzzCases returns the number of cases of the definition that define a fuzz
+// section, which are skipped unless fuzzing is enabled.
+func (d Definition) fuzzCases() int {
+	n := 0
+	for _, c := range d.Cases {
+		if c.Fuzz != nil {
+			n++
+		}
+	}
+	return n
+}
+
 // ExecuteWithLogger attempts to run a test definition on a target config file,
 // with a logger. Returns an array of test failures or an error.
 func (d Definition) ExecuteWithLogger(filepath string, logger log.Modular) ([]CaseFailure, error) {
-	return d.execute(filepath, nil, logger)
+	return d.execute(filepath, nil, logger, nil, false, false)
 }
 
 // Execute attempts to run a test definition on a target config file. Returns
 // an array of test failures or an error.
 func (d Definition) Execute(filepath string) ([]CaseFailure, error) {
-	return d.execute(filepath, nil, log.Noop())
+	return d.execute(filepath, nil, log.Noop(), nil, false, false)
 }
 
-func (d Definition) execute(testFilePath string, resourcesPaths []string, logger log.Modular) ([]CaseFailure, error) {
+func (d Definition) execute(testFilePath string, resourcesPaths []string, logger log.Modular, coverage *mapping.Coverage, updateSnapshots, runFuzz bool) ([]CaseFailure, error) {
 	procsProvider := NewProcessorsProvider(
 		testFilePath,
 		OptAddResourcesPaths(resourcesPaths),
 		OptProcessorsProviderSetLogger(logger),
+		OptProcessorsProviderSetCoverage(coverage),
 	)
 	if d.Parallel {
 		// Warm the cache of processor configs.
@@ -77,6 +91,7 @@ func (d Definition) execute(testFilePath string, resourcesPaths []string, logger
 	var totalFailures []CaseFailure
 	if !d.Parallel {
 		for i, c := range d.Cases {
+			c.updateSnapshots, c.runFuzz = updateSnapshots, runFuzz
 			cleanupEnv := setEnvironment(c.Environment)
 			failures, err := c.executeFrom(dir, procsProvider)
 			if err != nil {
@@ -93,6 +108,7 @@ func (d Definition) execute(testFilePath string, resourcesPaths []string, logger
 		for i, c := range d.Cases {
 			i := i
 			c := c
+			c.updateSnapshots, c.runFuzz = updateSnapshots, runFuzz
 			g.Go(func() error {
 				failures, err := c.executeFrom(dir, procsProvider)
 				if err != nil {
@@ -0,0 +1,410 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/metadata"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	yaml "gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// FuzzAssertions describes invariants that must hold for all output messages
+// resulting from fuzzed inputs.
+type FuzzAssertions struct {
+	NoErrors     bool   `yaml:"no_errors"`
+	OutputSchema string `yaml:"output_schema"`
+	Bloblang     string `yaml:"bloblang"`
+}
+
+// FuzzConfig describes how random inputs of a test case are generated, either
+// from a JSON Schema document or a Bloblang mapping, and the invariants that
+// must hold for the resulting outputs.
+type FuzzConfig struct {
+	Iterations   int            `yaml:"iterations"`
+	Seed         int64          `yaml:"seed"`
+	InputSchema  string         `yaml:"input_schema"`
+	InputMapping string         `yaml:"input_mapping"`
+	Assertions   FuzzAssertions `yaml:"assertions"`
+}
+
+// NewFuzzConfig returns a FuzzConfig with default values.
+func NewFuzzConfig() FuzzConfig {
+	return FuzzConfig{
+		Iterations: 100,
+		Assertions: FuzzAssertions{
+			NoErrors: true,
+		},
+	}
+}
+
+// UnmarshalYAML extracts a FuzzConfig from a YAML node.
+func (f *FuzzConfig) UnmarshalYAML(value *yaml.Node) error {
+	type confAlias FuzzConfig
+	aliased := confAlias(NewFuzzConfig())
+
+	if err := value.Decode(&aliased); err != nil {
+		return fmt.Errorf("line %v: %v", value.Line, err)
+	}
+
+	*f = FuzzConfig(aliased)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type fuzzer struct {
+	conf FuzzConfig
+	rand *rand.Rand
+
+	inputSchema  *fuzzSchema
+	inputMapping *mapping.Executor
+	outputSchema *fuzzSchema
+	predicate    *mapping.Executor
+
+	procs []types.Processor
+}
+
+func newFuzzer(dir string, conf FuzzConfig, procs []types.Processor) (*fuzzer, error) {
+	f := &fuzzer{
+		conf:  conf,
+		rand:  rand.New(rand.NewSource(conf.Seed)),
+		procs: procs,
+	}
+
+	var err error
+	switch {
+	case conf.InputSchema != "" && conf.InputMapping != "":
+		return nil, errors.New("fields input_schema and input_mapping cannot both be set")
+	case conf.InputSchema != "":
+		if f.inputSchema, err = loadFuzzSchema(filepath.Join(dir, conf.InputSchema)); err != nil {
+			return nil, fmt.Errorf("input_schema: %w", err)
+		}
+	case conf.InputMapping != "":
+		if f.inputMapping, err = bloblang.GlobalEnvironment().NewMapping(conf.InputMapping); err != nil {
+			return nil, fmt.Errorf("input_mapping: %w", err)
+		}
+	default:
+		return nil, errors.New("either input_schema or input_mapping must be set")
+	}
+
+	if conf.Assertions.OutputSchema != "" {
+		if f.outputSchema, err = loadFuzzSchema(filepath.Join(dir, conf.Assertions.OutputSchema)); err != nil {
+			return nil, fmt.Errorf("output_schema: %w", err)
+		}
+	}
+	if conf.Assertions.Bloblang != "" {
+		if f.predicate, err = bloblang.GlobalEnvironment().NewMapping(conf.Assertions.Bloblang); err != nil {
+			return nil, fmt.Errorf("bloblang: %w", err)
+		}
+	}
+	return f, nil
+}
+
+// fuzzInput is a generated input message, where the structured form of the
+// contents is retained (when applicable) in order to shrink it.
+type fuzzInput struct {
+	raw        []byte
+	structured interface{}
+	isJSON     bool
+	meta       map[string]string
+}
+
+func (i fuzzInput) withValue(v interface{}) fuzzInput {
+	i.structured = v
+	i.raw, _ = json.Marshal(v)
+	return i
+}
+
+func (i fuzzInput) part() types.Part {
+	part := message.NewPart(i.raw)
+	if len(i.meta) > 0 {
+		part.SetMetadata(metadata.New(i.meta))
+	}
+	return part
+}
+
+func (i fuzzInput) String() string {
+	if len(i.meta) == 0 {
+		return string(i.raw)
+	}
+	metaKeys := make([]string, 0, len(i.meta))
+	for k := range i.meta {
+		metaKeys = append(metaKeys, k)
+	}
+	sort.Strings(metaKeys)
+	str := string(i.raw)
+	for _, k := range metaKeys {
+		str += fmt.Sprintf("\n    %v: %v", k, i.meta[k])
+	}
+	return str
+}
+
+// generate creates an input for an iteration. Inputs of a schema are generated
+// from the seeded source of the fuzzer, whereas an input mapping is executed on
+// a document containing the seed and iteration, and must derive any randomness
+// from the seed, e.g. with random_int(this.seed), in order to be reproducible.
+func (f *fuzzer) generate(iteration int) (fuzzInput, error) {
+	if f.inputSchema != nil {
+		v, err := f.inputSchema.generate(f.rand)
+		if err != nil {
+			return fuzzInput{}, err
+		}
+		return fuzzInput{isJSON: true}.withValue(v), nil
+	}
+
+	seedDoc, err := json.Marshal(map[string]interface{}{
+		"seed":      f.conf.Seed,
+		"iteration": iteration,
+	})
+	if err != nil {
+		return fuzzInput{}, err
+	}
+	part, err := f.inputMapping.MapPart(0, message.New([][]byte{seedDoc}))
+	if err != nil {
+		return fuzzInput{}, fmt.Errorf("failed to execute input_mapping: %w", err)
+	}
+	if part == nil {
+		return fuzzInput{}, errors.New("input_mapping resulted in a deleted message")
+	}
+
+	input := fuzzInput{
+		raw:  part.Get(),
+		meta: map[string]string{},
+	}
+	_ = part.Metadata().Iter(func(k, v string) error {
+		input.meta[k] = v
+		return nil
+	})
+	if v, err := part.JSON(); err == nil {
+		input.structured = v
+		input.isJSON = true
+	}
+	return input, nil
+}
+
+// fuzzFailure describes an invariant that failed for an input.
+type fuzzFailure struct {
+	invariant string
+	reason    string
+}
+
+// check runs an input through the target processors and returns the first
+// invariant that failed, or nil if none failed.
+func (f *fuzzer) check(input fuzzInput) *fuzzFailure {
+	msg := message.New(nil)
+	msg.Append(input.part())
+
+	outputBatches, result := processor.ExecuteAll(f.procs, msg)
+	if result != nil && result.Error() != nil && f.conf.Assertions.NoErrors {
+		return &fuzzFailure{"no_errors", fmt.Sprintf("processors resulted in error: %v", result.Error())}
+	}
+
+	for i, batch := range outputBatches {
+		var failure *fuzzFailure
+		_ = batch.Iter(func(j int, part types.Part) error {
+			if f.conf.Assertions.NoErrors {
+				if procErr := processor.GetFail(part); len(procErr) > 0 {
+					failure = &fuzzFailure{"no_errors", fmt.Sprintf("batch %v message %v: processor error: %v", i, j, procErr)}
+					return errors.New(procErr)
+				}
+			}
+			if f.outputSchema != nil {
+				v, err := part.JSON()
+				if err == nil {
+					err = f.outputSchema.validate(v)
+				}
+				if err != nil {
+					failure = &fuzzFailure{"output_schema", fmt.Sprintf("batch %v message %v: output_schema: %v", i, j, err)}
+					return err
+				}
+			}
+			if f.predicate != nil {
+				res, err := f.predicate.QueryPart(j, batch)
+				if err == nil && !res {
+					err = errors.New("bloblang expression was false")
+				}
+				if err != nil {
+					failure = &fuzzFailure{"bloblang", fmt.Sprintf("batch %v message %v: bloblang: %v", i, j, err)}
+					return err
+				}
+			}
+			return nil
+		})
+		if failure != nil {
+			return failure
+		}
+	}
+	return nil
+}
+
+const fuzzMaxShrinks = 1000
+
+// shrink attempts to find a smaller structured input that still causes the
+// same invariant to fail by greedily applying reductions to the input until
+// none of them continue to fail.
+func (f *fuzzer) shrink(input fuzzInput, failure *fuzzFailure) (fuzzInput, *fuzzFailure) {
+	if !input.isJSON {
+		return input, failure
+	}
+
+	attempts := 0
+	for {
+		shrunk := false
+		for _, candidate := range shrinkCandidates(input.structured) {
+			if attempts++; attempts > fuzzMaxShrinks {
+				return input, failure
+			}
+			if f.inputSchema != nil && f.inputSchema.validate(candidate) != nil {
+				continue
+			}
+			next := input.withValue(candidate)
+			if nextFailure := f.check(next); nextFailure != nil && nextFailure.invariant == failure.invariant {
+				input, failure = next, nextFailure
+				shrunk = true
+				break
+			}
+		}
+		if !shrunk {
+			return input, failure
+		}
+	}
+}
+
+// shrinkCandidates returns a list of values that are smaller than the provided
+// value, ordered from the most to least aggressive reduction.
+func shrinkCandidates(v interface{}) []interface{} {
+	var candidates []interface{}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			without := make(map[string]interface{}, len(t)-1)
+			for k2, v2 := range t {
+				if k2 != k {
+					without[k2] = v2
+				}
+			}
+			candidates = append(candidates, without)
+		}
+		for _, k := range keys {
+			for _, c := range shrinkCandidates(t[k]) {
+				replaced := make(map[string]interface{}, len(t))
+				for k2, v2 := range t {
+					replaced[k2] = v2
+				}
+				replaced[k] = c
+				candidates = append(candidates, replaced)
+			}
+		}
+	case []interface{}:
+		if len(t) > 1 {
+			candidates = append(candidates, t[:len(t)/2])
+		}
+		for i := range t {
+			without := make([]interface{}, 0, len(t)-1)
+			without = append(without, t[:i]...)
+			without = append(without, t[i+1:]...)
+			candidates = append(candidates, without)
+		}
+		for i := range t {
+			for _, c := range shrinkCandidates(t[i]) {
+				replaced := make([]interface{}, len(t))
+				copy(replaced, t)
+				replaced[i] = c
+				candidates = append(candidates, replaced)
+			}
+		}
+	case string:
+		if len(t) > 0 {
+			candidates = append(candidates, "")
+		}
+		if len(t) > 1 {
+			candidates = append(candidates, t[:len(t)/2], t[:len(t)-1])
+		}
+	case int64:
+		if t != 0 {
+			candidates = append(candidates, int64(0))
+		}
+		if t/2 != 0 && t/2 != t {
+			candidates = append(candidates, t/2)
+		}
+	case float64:
+		if t != 0 {
+			candidates = append(candidates, float64(0))
+		}
+		if h := float64(int64(t / 2)); h != 0 && h != t {
+			candidates = append(candidates, h)
+		}
+	case json.Number:
+		if f, err := t.Float64(); err == nil {
+			return shrinkCandidates(f)
+		}
+	case bool:
+		if t {
+			candidates = append(candidates, false)
+		}
+	}
+	return candidates
+}
+
+// run executes all iterations of the fuzzer and returns a failure for the
+// first input that fails an invariant, after shrinking it.
+func (f *fuzzer) run() (string, error) {
+	for i := 0; i < f.conf.Iterations; i++ {
+		input, err := f.generate(i)
+		if err != nil {
+			return "", err
+		}
+		if failure := f.check(input); failure != nil {
+			input, failure = f.shrink(input, failure)
+			return fmt.Sprintf(
+				"fuzz iteration %v (seed %v) failed: %v\n  input: %v",
+				i, f.conf.Seed, failure.reason, input,
+			), nil
+		}
+	}
+	return "", nil
+}
+
+//------------------------------------------------------------------------------
+
+func (c *Case) executeFuzzFrom(dir string, procSet []types.Processor) (failures []CaseFailure, err error) {
+	conf := *c.Fuzz
+	if conf.Seed == 0 {
+		conf.Seed = time.Now().UnixNano()
+	}
+
+	var f *fuzzer
+	if f, err = newFuzzer(dir, conf, procSet); err != nil {
+		return nil, fmt.Errorf("failed to initialise fuzzer: %w", err)
+	}
+
+	var failure string
+	if failure, err = f.run(); err != nil {
+		return nil, fmt.Errorf("failed to generate fuzz input: %w", err)
+	}
+	if failure != "" {
+		failures = append(failures, CaseFailure{
+			Name:     c.Name,
+			TestLine: c.line,
+			Reason:   failure,
+		})
+	}
+	return
+}
@@ -0,0 +1,354 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	jsonschema "github.com/xeipuuv/gojsonschema"
+)
+
+//------------------------------------------------------------------------------
+
+// fuzzSchema wraps a JSON Schema document that is used both in order to
+// generate random documents and to validate them.
+type fuzzSchema struct {
+	root      map[string]interface{}
+	validator *jsonschema.Schema
+}
+
+func loadFuzzSchema(path string) (*fuzzSchema, error) {
+	schemaBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	var root map[string]interface{}
+	if err = json.Unmarshal(schemaBytes, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	validator, err := jsonschema.NewSchema(jsonschema.NewReferenceLoader("file://" + filepath.ToSlash(absPath)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema: %w", err)
+	}
+
+	return &fuzzSchema{root: root, validator: validator}, nil
+}
+
+// validate returns an error describing why a document does not satisfy the
+// schema, or nil if it does.
+func (s *fuzzSchema) validate(v interface{}) error {
+	result, err := s.validator.Validate(jsonschema.NewGoLoader(v))
+	if err != nil {
+		return err
+	}
+	if result.Valid() {
+		return nil
+	}
+	var errStrs []string
+	for _, desc := range result.Errors() {
+		errStrs = append(errStrs, desc.String())
+	}
+	return errors.New(strings.Join(errStrs, ", "))
+}
+
+const fuzzSchemaAttempts = 100
+
+// generate attempts to create a random document that satisfies the schema.
+// Keywords that cannot be used for generation (such as pattern) are ignored,
+// and therefore documents are validated and regenerated until a valid one is
+// created or the attempts are exhausted.
+func (s *fuzzSchema) generate(r *rand.Rand) (interface{}, error) {
+	var lastErr error
+	for i := 0; i < fuzzSchemaAttempts; i++ {
+		v, err := s.generateFrom(r, s.root, 0)
+		if err != nil {
+			return nil, err
+		}
+		if lastErr = s.validate(v); lastErr == nil {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to generate a document that satisfies the schema: %v", lastErr)
+}
+
+const fuzzMaxDepth = 16
+
+func (s *fuzzSchema) resolveRef(ref string) (map[string]interface{}, error) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("schema reference '%v' is not supported, only local references can be used for generating documents", ref)
+	}
+	var current interface{} = s.root
+	for _, seg := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		seg = strings.ReplaceAll(strings.ReplaceAll(seg, "~1", "/"), "~0", "~")
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("schema reference '%v' not found", ref)
+		}
+		if current, ok = obj[seg]; !ok {
+			return nil, fmt.Errorf("schema reference '%v' not found", ref)
+		}
+	}
+	obj, ok := current.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema reference '%v' is not an object", ref)
+	}
+	return obj, nil
+}
+
+func numberField(schema map[string]interface{}, key string) (float64, bool) {
+	f, ok := schema[key].(float64)
+	return f, ok
+}
+
+func schemaTypes(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	if _, exists := schema["properties"]; exists {
+		return []string{"object"}
+	}
+	if _, exists := schema["items"]; exists {
+		return []string{"array"}
+	}
+	return []string{"string", "number", "boolean", "null"}
+}
+
+func (s *fuzzSchema) generateFrom(r *rand.Rand, schema map[string]interface{}, depth int) (interface{}, error) {
+	if depth > fuzzMaxDepth {
+		return nil, nil
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		refSchema, err := s.resolveRef(ref)
+		if err != nil {
+			return nil, err
+		}
+		return s.generateFrom(r, refSchema, depth+1)
+	}
+	if c, exists := schema["const"]; exists {
+		return c, nil
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[r.Intn(len(enum))], nil
+	}
+	for _, k := range []string{"oneOf", "anyOf"} {
+		if options, ok := schema[k].([]interface{}); ok && len(options) > 0 {
+			option, _ := options[r.Intn(len(options))].(map[string]interface{})
+			return s.generateFrom(r, option, depth+1)
+		}
+	}
+	if all, ok := schema["allOf"].([]interface{}); ok && len(all) > 0 {
+		merged := map[string]interface{}{}
+		for k, v := range schema {
+			if k != "allOf" {
+				merged[k] = v
+			}
+		}
+		for _, sub := range all {
+			subObj, _ := sub.(map[string]interface{})
+			for k, v := range subObj {
+				existing, isObj := merged[k].(map[string]interface{})
+				if subProps, isSubObj := v.(map[string]interface{}); isObj && isSubObj && k == "properties" {
+					props := map[string]interface{}{}
+					for pk, pv := range existing {
+						props[pk] = pv
+					}
+					for pk, pv := range subProps {
+						props[pk] = pv
+					}
+					v = props
+				}
+				merged[k] = v
+			}
+		}
+		return s.generateFrom(r, merged, depth+1)
+	}
+
+	types := schemaTypes(schema)
+	switch types[r.Intn(len(types))] {
+	case "object":
+		return s.generateObject(r, schema, depth)
+	case "array":
+		return s.generateArray(r, schema, depth)
+	case "string":
+		return generateString(r, schema), nil
+	case "integer":
+		return int64(math.Round(generateNumber(r, schema, true))), nil
+	case "number":
+		return generateNumber(r, schema, false), nil
+	case "boolean":
+		return r.Intn(2) == 1, nil
+	}
+	return nil, nil
+}
+
+func (s *fuzzSchema) generateObject(r *rand.Rand, schema map[string]interface{}, depth int) (interface{}, error) {
+	required := map[string]bool{}
+	if req, ok := schema["required"].([]interface{}); ok {
+		for _, k := range req {
+			if ks, ok := k.(string); ok {
+				required[ks] = true
+			}
+		}
+	}
+
+	obj := map[string]interface{}{}
+	props, _ := schema["properties"].(map[string]interface{})
+
+	// Keys are sorted so that documents are deterministic for a given seed.
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if !required[k] && r.Intn(2) == 0 {
+			continue
+		}
+		propSchema, _ := props[k].(map[string]interface{})
+		pv, err := s.generateFrom(r, propSchema, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		obj[k] = pv
+	}
+	return obj, nil
+}
+
+func (s *fuzzSchema) generateArray(r *rand.Rand, schema map[string]interface{}, depth int) (interface{}, error) {
+	if tuple, ok := schema["items"].([]interface{}); ok {
+		arr := make([]interface{}, 0, len(tuple))
+		for _, v := range tuple {
+			itemSchema, _ := v.(map[string]interface{})
+			iv, err := s.generateFrom(r, itemSchema, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, iv)
+		}
+		return arr, nil
+	}
+
+	minItems, maxItems := 0, 5
+	if f, ok := numberField(schema, "minItems"); ok {
+		minItems = int(f)
+		maxItems = minItems + 5
+	}
+	if f, ok := numberField(schema, "maxItems"); ok {
+		maxItems = int(f)
+	}
+	if maxItems < minItems {
+		maxItems = minItems
+	}
+
+	itemSchema, _ := schema["items"].(map[string]interface{})
+	arr := make([]interface{}, minItems+r.Intn(maxItems-minItems+1))
+	for i := range arr {
+		iv, err := s.generateFrom(r, itemSchema, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = iv
+	}
+	return arr, nil
+}
+
+const fuzzAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 _-"
+
+func generateString(r *rand.Rand, schema map[string]interface{}) string {
+	switch schema["format"] {
+	case "date-time":
+		return time.Unix(r.Int63n(4102444800), 0).UTC().Format(time.RFC3339)
+	case "date":
+		return time.Unix(r.Int63n(4102444800), 0).UTC().Format("2006-01-02")
+	case "email":
+		return randomString(r, fuzzAlphabet[:26], 1+r.Intn(10)) + "@example.com"
+	case "uuid":
+		b := make([]byte, 16)
+		_, _ = r.Read(b)
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	case "uri":
+		return "https://example.com/" + randomString(r, fuzzAlphabet[:36], r.Intn(16))
+	}
+
+	minLen, maxLen := 0, 16
+	if f, ok := numberField(schema, "minLength"); ok {
+		minLen = int(f)
+		maxLen = minLen + 16
+	}
+	if f, ok := numberField(schema, "maxLength"); ok {
+		maxLen = int(f)
+	}
+	if maxLen < minLen {
+		maxLen = minLen
+	}
+	return randomString(r, fuzzAlphabet, minLen+r.Intn(maxLen-minLen+1))
+}
+
+func randomString(r *rand.Rand, alphabet string, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphabet[r.Intn(len(alphabet))]
+	}
+	return string(b)
+}
+
+func generateNumber(r *rand.Rand, schema map[string]interface{}, integer bool) float64 {
+	minV, maxV := -1000.0, 1000.0
+	if f, ok := numberField(schema, "minimum"); ok {
+		minV = f
+		if _, hasMax := numberField(schema, "maximum"); !hasMax {
+			maxV = minV + 2000
+		}
+	}
+	if f, ok := numberField(schema, "exclusiveMinimum"); ok {
+		minV = f + 1
+		if integer {
+			minV = math.Floor(f) + 1
+		}
+	}
+	if f, ok := numberField(schema, "maximum"); ok {
+		maxV = f
+		if _, hasMin := numberField(schema, "minimum"); !hasMin {
+			minV = maxV - 2000
+		}
+	}
+	if f, ok := numberField(schema, "exclusiveMaximum"); ok {
+		maxV = f - 1
+		if integer {
+			maxV = math.Ceil(f) - 1
+		}
+	}
+	if maxV < minV {
+		maxV = minV
+	}
+	if integer {
+		if minV, maxV = math.Ceil(minV), math.Floor(maxV); maxV < minV {
+			return minV
+		}
+		return minV + float64(r.Int63n(int64(maxV-minV)+1))
+	}
+	return minV + r.Float64()*(maxV-minV)
+}
@@ -0,0 +1,181 @@
+package test
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestFuzzSchemaGenerate(t *testing.T) {
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "schema.json")
+	require.NoError(t, os.WriteFile(schemaPath, []byte(`{
+  "type": "object",
+  "definitions": {
+    "tag": { "type": "string", "enum": [ "a", "b", "c" ] }
+  },
+  "properties": {
+    "id": { "type": "integer", "minimum": 1, "maximum": 10 },
+    "name": { "type": "string", "minLength": 2, "maxLength": 5 },
+    "score": { "type": "number", "exclusiveMaximum": 0 },
+    "tags": { "type": "array", "items": { "$ref": "#/definitions/tag" }, "maxItems": 3 },
+    "created": { "type": "string", "format": "date-time" },
+    "nested": {
+      "type": "object",
+      "properties": { "ok": { "type": "boolean" } },
+      "required": [ "ok" ]
+    }
+  },
+  "required": [ "id", "name" ]
+}`), 0o644))
+
+	schema, err := loadFuzzSchema(schemaPath)
+	require.NoError(t, err)
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		v, err := schema.generate(r)
+		require.NoError(t, err)
+		require.NoError(t, schema.validate(v))
+
+		obj, ok := v.(map[string]interface{})
+		require.True(t, ok)
+		assert.Contains(t, obj, "id")
+		assert.Contains(t, obj, "name")
+	}
+
+	// Same seed, same documents
+	a, err := schema.generate(rand.New(rand.NewSource(5)))
+	require.NoError(t, err)
+	b, err := schema.generate(rand.New(rand.NewSource(5)))
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+}
+
+func TestShrinkCandidates(t *testing.T) {
+	assert.Equal(t, []interface{}{"", "fo", "foo"}, shrinkCandidates("food"))
+	assert.Equal(t, []interface{}{int64(0), int64(5)}, shrinkCandidates(int64(10)))
+	assert.Equal(t, []interface{}{false}, shrinkCandidates(true))
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"b": true},
+		map[string]interface{}{"a": true},
+		map[string]interface{}{"a": false, "b": true},
+		map[string]interface{}{"a": true, "b": false},
+	}, shrinkCandidates(map[string]interface{}{"a": true, "b": true}))
+	assert.Empty(t, shrinkCandidates(nil))
+}
+
+func TestFuzzCase(t *testing.T) {
+	color.NoColor = true
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "schema.json"), []byte(`{
+  "type": "object",
+  "properties": {
+    "name": { "type": "string", "minLength": 1, "maxLength": 20 },
+    "items": { "type": "array", "items": { "type": "integer", "minimum": 0, "maximum": 100 } }
+  },
+  "required": [ "name", "items" ]
+}`), 0o644))
+
+	procConf := processor.NewConfig()
+	procConf.Type = processor.TypeBloblang
+	procConf.Bloblang = processor.BloblangConfig(`
+root.name = this.name.uppercase()
+root.total = this.items.sum()
+root.first = this.items.index(0)
+`)
+	proc, err := processor.New(procConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	provider := mockProvider{}
+	provider["/pipeline/processors"] = []types.Processor{proc}
+
+	c := NewCase()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+name: fuzzy
+fuzz:
+  iterations: 50
+  seed: 10
+  input_schema: ./schema.json
+`), &c))
+
+	// Fuzz cases are skipped unless fuzzing is enabled
+	fails, err := c.executeFrom(tmpDir, provider)
+	require.NoError(t, err)
+	assert.Empty(t, fails)
+
+	c.runFuzz = true
+	fails, err = c.executeFrom(tmpDir, provider)
+	require.NoError(t, err)
+	require.Len(t, fails, 1)
+	assert.Contains(t, fails[0].Reason, "(seed 10) failed: batch 0 message 0: processor error")
+	assert.Contains(t, fails[0].Reason, `input: {"items":[],"name":"`)
+
+	c = NewCase()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+name: fuzzy predicate
+fuzz:
+  iterations: 50
+  seed: 10
+  input_mapping: |
+    root.name = "foo"
+    root.items = [ random_int(this.seed) % 101 ]
+  assertions:
+    bloblang: 'this.total < 50'
+`), &c))
+
+	c.runFuzz = true
+	fails, err = c.executeFrom(tmpDir, provider)
+	require.NoError(t, err)
+	require.Len(t, fails, 1)
+	assert.Contains(t, fails[0].Reason, "bloblang: bloblang expression was false")
+	assert.Contains(t, fails[0].Reason, `input: {"items":[`)
+
+	// The same seed reproduces the same failure.
+	c.runFuzz = true
+	refails, err := c.executeFrom(tmpDir, provider)
+	require.NoError(t, err)
+	assert.Equal(t, fails, refails)
+
+	c = NewCase()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+name: fuzzy passes
+fuzz:
+  iterations: 20
+  input_mapping: |
+    root.name = "foo"
+    root.items = [ random_int() % 101 ]
+  assertions:
+    bloblang: 'this.total <= 100 && this.name == "FOO"'
+`), &c))
+
+	c.runFuzz = true
+	fails, err = c.executeFrom(tmpDir, provider)
+	require.NoError(t, err)
+	assert.Empty(t, fails)
+}
+
+func TestFuzzCasesSkipped(t *testing.T) {
+	var d Definition
+	require.NoError(t, yaml.Unmarshal([]byte(`
+tests:
+  - name: fuzzy
+    fuzz:
+      input_mapping: 'root = random_int(this.seed)'
+  - name: not fuzzy
+    input_batch:
+      - content: foo
+`), &d))
+	assert.Equal(t, 1, d.fuzzCases())
+}
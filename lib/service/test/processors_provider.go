@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/parser"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/config"
@@ -22,6 +24,7 @@ import (
 //------------------------------------------------------------------------------
 
 type cachedConfig struct {
+	path  string
 	mgr   manager.ResourceConfig
 	procs []processor.Config
 }
@@ -33,7 +36,8 @@ type ProcessorsProvider struct {
 	resourcesPaths []string
 	cachedConfigs  map[string]cachedConfig
 
-	logger log.Modular
+	logger   log.Modular
+	coverage *mapping.Coverage
 }
 
 // NewProcessorsProvider returns a new processors provider aimed at a filepath.
@@ -65,6 +69,14 @@ func OptProcessorsProviderSetLogger(logger log.Modular) func(*ProcessorsProvider
 	}
 }
 
+// OptProcessorsProviderSetCoverage sets a coverage tracker that Bloblang
+// mappings of provided processors are registered with.
+func OptProcessorsProviderSetCoverage(coverage *mapping.Coverage) func(*ProcessorsProvider) {
+	return func(p *ProcessorsProvider) {
+		p.coverage = coverage
+	}
+}
+
 //------------------------------------------------------------------------------
 
 // Provide attempts to extract an array of processors from a Benthos config. If
@@ -103,6 +115,9 @@ func (p *ProcessorsProvider) ProvideBloblang(pathStr string) ([]types.Processor,
 	if mapErr != nil {
 		return nil, mapErr
 	}
+	if p.coverage != nil {
+		p.coverage.Register(pathStr, exec)
+	}
 
 	return []types.Processor{
 		processor.NewBloblangFromExecutor(exec, p.logger, metrics.Noop()),
@@ -112,7 +127,14 @@ func (p *ProcessorsProvider) ProvideBloblang(pathStr string) ([]types.Processor,
 //------------------------------------------------------------------------------
 
 func (p *ProcessorsProvider) initProcs(confs cachedConfig) ([]types.Processor, error) {
-	mgr, err := manager.NewV2(confs.mgr, types.NoopMgr(), p.logger, metrics.Noop())
+	var mgrOpts []manager.OptFunc
+	if p.coverage != nil {
+		mgrOpts = append(mgrOpts, manager.OptSetBloblangEnvironment(
+			bloblang.GlobalEnvironment().WithCoverage(p.coverage, confs.path),
+		))
+	}
+
+	mgr, err := manager.NewV2(confs.mgr, types.NoopMgr(), p.logger, metrics.Noop(), mgrOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialise resources: %v", err)
 	}
@@ -235,6 +257,7 @@ func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]str
 		}
 	}
 
+	confs.path = targetPath
 	confs.mgr = mgrWrapper
 
 	root := &yaml.Node{}
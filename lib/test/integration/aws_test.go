@@ -276,6 +276,57 @@ input:
 		)
 	})
 
+	t.Run("s3_polling", func(t *testing.T) {
+		template := `
+output:
+  aws_s3:
+    bucket: bucket-$ID
+    endpoint: http://localhost:$PORT
+    force_path_style_urls: true
+    region: eu-west-1
+    path: ${!count("$ID")}.txt
+    credentials:
+      id: xxxxx
+      secret: xxxxx
+      token: xxxxx
+    batching:
+      count: $OUTPUT_BATCH_COUNT
+
+input:
+  aws_s3:
+    bucket: bucket-$ID
+    endpoint: http://localhost:$PORT
+    force_path_style_urls: true
+    region: eu-west-1
+    polling:
+      enabled: true
+      poll_interval: 100ms
+      cache: objects-memory
+      mode: last_modified
+    credentials:
+      id: xxxxx
+      secret: xxxxx
+      token: xxxxx
+
+resources:
+  caches:
+    objects-memory:
+      memory:
+        ttl: 900
+`
+		integration.StreamTests(
+			integration.StreamTestOpenClose(),
+			integration.StreamTestStreamSequential(10),
+			integration.StreamTestStreamParallelLossyThroughReconnect(10),
+		).Run(
+			t, template,
+			integration.StreamTestOptPreTest(func(t testing.TB, ctx context.Context, testID string, vars *integration.StreamTestConfigVars) {
+				require.NoError(t, createBucketQueue(servicePort, "", testID))
+			}),
+			integration.StreamTestOptPort(servicePort),
+		)
+	})
+
 	t.Run("sqs", func(t *testing.T) {
 		template := `
 output:
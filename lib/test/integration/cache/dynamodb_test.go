@@ -112,6 +112,9 @@ cache_resources:
 		integration.CacheTestDoubleAdd(),
 		integration.CacheTestDelete(),
 		integration.CacheTestGetAndSet(50),
+		integration.CacheTestGetAndSetMulti(50),
+		integration.CacheTestIncr(),
+		integration.CacheTestCompareAndSwap(),
 	)
 	suite.Run(
 		t, template,
@@ -9,6 +9,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/cache"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/ory/dockertest/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -55,9 +56,39 @@ cache_resources:
 		integration.CacheTestDoubleAdd(),
 		integration.CacheTestDelete(),
 		integration.CacheTestGetAndSet(50),
+		integration.CacheTestGetAndSetMulti(50),
+		integration.CacheTestIncr(),
+		integration.CacheTestCompareAndSwap(),
 	)
 	suite.Run(
 		t, template,
 		integration.CacheTestOptPort(resource.GetPort("6379/tcp")),
 	)
+
+	t.Run("incr applies expiration", func(t *testing.T) {
+		conf := cache.NewConfig()
+		conf.Redis.URL = fmt.Sprintf("tcp://localhost:%v/1", resource.GetPort("6379/tcp"))
+		conf.Redis.Prefix = "incr_expiration_"
+		conf.Redis.Expiration = "1m"
+
+		r, err := cache.NewRedis(conf, nil, log.Noop(), metrics.Noop())
+		require.NoError(t, err)
+
+		ic, ok := r.(types.CacheWithIncr)
+		require.True(t, ok)
+
+		v, err := ic.Incr("foo", 2)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), v)
+
+		v, err = ic.Incr("foo", 3)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), v)
+
+		items, err := r.(types.CacheWithScan).Scan("foo")
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		require.NotNil(t, items[0].TTL)
+		assert.True(t, *items[0].TTL > 0 && *items[0].TTL <= time.Minute)
+	})
 })
@@ -83,18 +83,63 @@ func (b *BatchError) Unwrap() error {
 
 //------------------------------------------------------------------------------
 
+// KeysError represents an error from a multiple key cache operation that can
+// optionally be broken down into errors for the individual keys that failed.
+type KeysError struct {
+	err       error
+	keyErrors map[string]error
+}
+
+// NewKeysError creates a fresh keys error from a general error message. Once a
+// keys error is initialized it is possible to add key specific errors with
+// AddErrFor. If no key specific errors are added then all keys of the
+// operation are assumed to have failed.
+func NewKeysError(err error) *KeysError {
+	return &KeysError{
+		err:       err,
+		keyErrors: map[string]error{},
+	}
+}
+
+// AddErrFor adds an error for a specific key. If an error for the given key
+// already exists it is overridden. A reference to the KeysError is returned
+// for convenient chaining.
+func (k *KeysError) AddErrFor(key string, err error) *KeysError {
+	k.keyErrors[key] = err
+	return k
+}
+
+// KeyErrors returns a map of keys to their individual errors.
+func (k *KeysError) KeyErrors() map[string]error {
+	return k.keyErrors
+}
+
+// Error implements the common error interface.
+func (k *KeysError) Error() string {
+	return k.err.Error()
+}
+
+// Unwrap returns the underlying common error.
+func (k *KeysError) Unwrap() error {
+	return k.err
+}
+
+//------------------------------------------------------------------------------
+
 // Manager errors
 var (
-	ErrInputNotFound     = errors.New("input not found")
-	ErrCacheNotFound     = errors.New("cache not found")
-	ErrConditionNotFound = errors.New("condition not found")
-	ErrProcessorNotFound = errors.New("processor not found")
-	ErrRateLimitNotFound = errors.New("rate limit not found")
-	ErrOutputNotFound    = errors.New("output not found")
-	ErrPluginNotFound    = errors.New("plugin not found")
-	ErrKeyAlreadyExists  = errors.New("key already exists")
-	ErrKeyNotFound       = errors.New("key does not exist")
-	ErrPipeNotFound      = errors.New("pipe was not found")
+	ErrInputNotFound       = errors.New("input not found")
+	ErrCacheNotFound       = errors.New("cache not found")
+	ErrConditionNotFound   = errors.New("condition not found")
+	ErrProcessorNotFound   = errors.New("processor not found")
+	ErrRateLimitNotFound   = errors.New("rate limit not found")
+	ErrOutputNotFound      = errors.New("output not found")
+	ErrPluginNotFound      = errors.New("plugin not found")
+	ErrKeyAlreadyExists    = errors.New("key already exists")
+	ErrKeyNotFound         = errors.New("key does not exist")
+	ErrKeyValueMismatch    = errors.New("key value does not match")
+	ErrCacheOpNotSupported = errors.New("operation not supported by cache")
+	ErrPipeNotFound        = errors.New("pipe was not found")
 )
 
 //------------------------------------------------------------------------------
@@ -59,6 +59,61 @@ type CacheWithTTL interface {
 	Cache
 }
 
+// CacheWithGetMulti is a key/value store that supports retrieving the values
+// of multiple keys in a single operation.
+type CacheWithGetMulti interface {
+	// GetMulti attempts to locate and return the cached values of multiple
+	// keys, where keys that do not exist are omitted from the result. Returns
+	// an error if the command fails.
+	GetMulti(keys []string) (map[string][]byte, error)
+
+	Cache
+}
+
+// CacheWithIncr is a key/value store that supports atomically incrementing
+// integer values.
+type CacheWithIncr interface {
+	// Incr atomically adds a delta to the integer value of a key and returns
+	// the result. A key that does not exist is treated as having a value of
+	// zero. Returns ErrCacheOpNotSupported if the cache is unable to increment
+	// values or an error if the existing value is not an integer or if the
+	// command fails.
+	Incr(key string, delta int64) (int64, error)
+
+	Cache
+}
+
+// CacheWithCAS is a key/value store that supports atomic compare-and-swap
+// operations.
+type CacheWithCAS interface {
+	// CompareAndSwap attempts to set the value of a key only if its current
+	// value matches old, where a nil old value requires that the key does not
+	// exist. Returns ErrKeyValueMismatch if the current value does not match,
+	// ErrCacheOpNotSupported if the cache is unable to perform the operation
+	// or an error if the command fails.
+	CompareAndSwap(key string, old, value []byte, ttl *time.Duration) error
+
+	Cache
+}
+
+// CacheScanItem describes a key of a cache along with its remaining TTL, where
+// a nil TTL indicates that the key does not expire or that its expiry is
+// unknown.
+type CacheScanItem struct {
+	Key string
+	TTL *time.Duration
+}
+
+// CacheWithScan is a key/value store that supports enumerating its keys.
+type CacheWithScan interface {
+	// Scan returns all keys that begin with a prefix along with their remaining
+	// TTLs. Returns ErrCacheOpNotSupported if the cache is unable to enumerate
+	// its keys or an error if the command fails.
+	Scan(prefix string) ([]CacheScanItem, error)
+
+	Cache
+}
+
 //------------------------------------------------------------------------------
 
 // RateLimit is a strategy for limiting access to a shared resource, this
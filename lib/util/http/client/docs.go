@@ -19,6 +19,26 @@ func FieldSpecs() docs.FieldSpecs {
 	}
 	httpSpecs = append(httpSpecs, auth.FieldSpecsExpanded()...)
 	httpSpecs = append(httpSpecs, tls.FieldSpec(),
+		docs.FieldAdvanced(
+			"host_tls", "A list of custom TLS settings for requests to specific hosts, which override the `tls` field for those hosts. This allows, for example, a different client certificate to be presented to each host.",
+			[]interface{}{
+				map[string]interface{}{
+					"host": "api.example.com",
+					"tls": map[string]interface{}{
+						"enabled": true,
+						"client_certs": []interface{}{
+							map[string]interface{}{
+								"cert_file": "./api.pem",
+								"key_file":  "./api.key",
+							},
+						},
+					},
+				},
+			},
+		).Array().WithChildren(
+			docs.FieldString("host", "The host to apply the TLS settings to, either a hostname, which matches any port, or a hostname and port.", "api.example.com", "api.example.com:8443").HasDefault(""),
+			tls.FieldSpec(),
+		).HasDefault([]interface{}{}).AtVersion("3.60.0"),
 		docs.FieldBool("copy_response_headers", "Sets whether to copy the headers from the response to the resulting payload.").Advanced(),
 		docs.FieldString("rate_limit", "An optional [rate limit](/docs/components/rate_limits/about) to throttle requests by."),
 		docs.FieldString("timeout", "A static timeout to apply to requests."),
@@ -29,6 +49,35 @@ func FieldSpecs() docs.FieldSpecs {
 		docs.FieldInt("drop_on", "A list of status codes whereby the request should be considered to have failed but retries should not be attempted. This is useful for preventing wasted retries for requests that will never succeed. Note that with these status codes the _request_ is dropped, but _message_ that caused the request will not be dropped.").Array().Advanced(),
 		docs.FieldInt("successful_on", "A list of status codes whereby the attempt should be considered successful, this is useful for dropping requests that return non-2XX codes indicating that the message has been dealt with, such as a 303 See Other or a 409 Conflict. All 2XX codes are considered successful unless they are present within `backoff_on` or `drop_on`, regardless of this field.").Array().Advanced(),
 		docs.FieldString("proxy_url", "An optional HTTP proxy URL.").Advanced(),
+		docs.FieldAdvanced(
+			"proxy", "Allows you to route requests through an HTTP proxy that is determined for each request, which cannot be combined with `proxy_url`.",
+		).WithChildren(
+			docs.FieldString(
+				"url", "An optional HTTP proxy URL to route each request through, which is evaluated for each request and can reference the message being sent. When the result is empty the request is sent without a proxy.",
+				"http://proxy.example.com:3128", `${! meta("proxy") }`,
+			).IsInterpolated(),
+			docs.FieldString("username", "An optional username to authenticate with the proxy."),
+			docs.FieldString("password", "An optional password to authenticate with the proxy."),
+		).AtVersion("3.60.0"),
+		docs.FieldAdvanced(
+			"http2", "Allows you to configure the use of HTTP/2.",
+		).WithChildren(
+			docs.FieldBool("enabled", "Whether to always attempt to negotiate HTTP/2 with servers that support it over TLS, falling back to HTTP/1.1 otherwise, including when a custom `proxy_url` is configured. When disabled the default negotiation of the client is used."),
+			docs.FieldBool("h2c", "Whether to send requests to `http` URLs with HTTP/2 over cleartext (h2c) with prior knowledge, in which case the server must support h2c. This cannot be combined with a proxy."),
+		).AtVersion("3.60.0"),
+		docs.FieldBloblang(
+			"retry_when",
+			"An optional [Bloblang query](/docs/guides/bloblang/about) executed on each response that should return a boolean, where `true` indicates that the request failed and should be retried with the same backoff as `backoff_on`, regardless of the status code. The query is executed on a message containing the response body with the metadata field `http_status_code` and the response headers, in lower case, as metadata fields. When the query returns `false` the status code fields determine the outcome. The response body is read in full before the query is executed, and therefore this field cannot be used when streaming.",
+			`meta("http_status_code") == "503" || meta("x-throttled") == "true"`,
+			`this.error.code.or("") == "RATE_LIMITED"`,
+		).HasDefault("").AtVersion("3.60.0").Advanced(),
+		docs.FieldBool("respect_retry_after", "Whether to wait for the period specified by the `Retry-After` header of a failed response before retrying the request, instead of the period determined by `retry_period`, up to a maximum of `max_retry_backoff`.").AtVersion("3.60.0").Advanced(),
+		docs.FieldAdvanced(
+			"hedging", "Allows you to send hedged requests, where when a response is not received within a delay an identical request is sent, and the first response received is used whilst the others are cancelled. This reduces tail latency at the cost of extra requests and is only permitted for the idempotent verbs `GET`, `HEAD`, `OPTIONS`, `TRACE`, `PUT` and `DELETE`.",
+		).WithChildren(
+			docs.FieldString("delay", "The period to wait for a response before sending each hedged request, hedging is disabled when empty.", "50ms", "1s"),
+			docs.FieldInt("max_requests", "The maximum number of requests to send for each attempt, including the original request."),
+		).AtVersion("3.60.0"),
 	)
 
 	return httpSpecs
@@ -46,11 +46,76 @@ type Config struct {
 	DropOn              []int             `json:"drop_on" yaml:"drop_on"`
 	SuccessfulOn        []int             `json:"successful_on" yaml:"successful_on"`
 	TLS                 tls.Config        `json:"tls" yaml:"tls"`
+	HostTLS             []HostTLSConfig   `json:"host_tls" yaml:"host_tls"`
 	ProxyURL            string            `json:"proxy_url" yaml:"proxy_url"`
+	Proxy               ProxyConfig       `json:"proxy" yaml:"proxy"`
+	HTTP2               HTTP2Config       `json:"http2" yaml:"http2"`
+	RetryWhen           string            `json:"retry_when" yaml:"retry_when"`
+	RespectRetryAfter   bool              `json:"respect_retry_after" yaml:"respect_retry_after"`
+	Hedging             HedgingConfig     `json:"hedging" yaml:"hedging"`
 	auth.Config         `json:",inline" yaml:",inline"`
 	OAuth2              auth.OAuth2Config `json:"oauth2" yaml:"oauth2"`
 }
 
+// ProxyConfig contains fields for routing requests through an HTTP proxy.
+type ProxyConfig struct {
+	URL      string `json:"url" yaml:"url"`
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+}
+
+// NewProxyConfig creates a new ProxyConfig with default values.
+func NewProxyConfig() ProxyConfig {
+	return ProxyConfig{
+		URL:      "",
+		Username: "",
+		Password: "",
+	}
+}
+
+// HostTLSConfig contains custom TLS settings for requests to a host.
+type HostTLSConfig struct {
+	Host string     `json:"host" yaml:"host"`
+	TLS  tls.Config `json:"tls" yaml:"tls"`
+}
+
+// NewHostTLSConfig creates a new HostTLSConfig with default values.
+func NewHostTLSConfig() HostTLSConfig {
+	return HostTLSConfig{
+		Host: "",
+		TLS:  tls.NewConfig(),
+	}
+}
+
+// HTTP2Config contains fields for configuring the use of HTTP/2.
+type HTTP2Config struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	H2C     bool `json:"h2c" yaml:"h2c"`
+}
+
+// NewHTTP2Config creates a new HTTP2Config with default values.
+func NewHTTP2Config() HTTP2Config {
+	return HTTP2Config{
+		Enabled: false,
+		H2C:     false,
+	}
+}
+
+// HedgingConfig contains fields for sending hedged requests, where duplicate
+// requests are sent when the original is slow to respond.
+type HedgingConfig struct {
+	Delay       string `json:"delay" yaml:"delay"`
+	MaxRequests int    `json:"max_requests" yaml:"max_requests"`
+}
+
+// NewHedgingConfig creates a new HedgingConfig with default values.
+func NewHedgingConfig() HedgingConfig {
+	return HedgingConfig{
+		Delay:       "",
+		MaxRequests: 2,
+	}
+}
+
 // NewConfig creates a new Config with default values.
 func NewConfig() Config {
 	return Config{
@@ -69,6 +134,13 @@ func NewConfig() Config {
 		DropOn:              []int{},
 		SuccessfulOn:        []int{},
 		TLS:                 tls.NewConfig(),
+		HostTLS:             []HostTLSConfig{},
+		ProxyURL:            "",
+		Proxy:               NewProxyConfig(),
+		HTTP2:               NewHTTP2Config(),
+		RetryWhen:           "",
+		RespectRetryAfter:   false,
+		Hedging:             NewHedgingConfig(),
 		Config:              auth.NewConfig(),
 		OAuth2:              auth.NewOAuth2Config(),
 	}
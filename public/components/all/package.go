@@ -10,6 +10,7 @@ import (
 	// Import new service packages.
 	_ "github.com/Jeffail/benthos/v3/internal/impl/aws"
 	_ "github.com/Jeffail/benthos/v3/internal/impl/confluent"
+	_ "github.com/Jeffail/benthos/v3/internal/impl/elasticsearch"
 	_ "github.com/Jeffail/benthos/v3/internal/impl/gcp"
 	_ "github.com/Jeffail/benthos/v3/internal/impl/generic"
 	_ "github.com/Jeffail/benthos/v3/internal/impl/mongodb"
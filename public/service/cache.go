@@ -11,10 +11,11 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
-// Errors returned by cache types.
+// Errors returned by cache types. These are the same errors that are checked
+// for by components that use caches, such as the dedupe processor.
 var (
-	ErrKeyAlreadyExists = errors.New("key already exists")
-	ErrKeyNotFound      = errors.New("key does not exist")
+	ErrKeyAlreadyExists = types.ErrKeyAlreadyExists
+	ErrKeyNotFound      = types.ErrKeyNotFound
 )
 
 // Cache is an interface implemented by Benthos caches.
@@ -482,3 +482,18 @@ func TestCacheReverseAirGapDelete(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, map[string]testCacheItem{}, rl.m)
 }
+
+func TestCacheAirGapErrorsMatchTypes(t *testing.T) {
+	rl := &closableCache{
+		m: map[string]testCacheItem{
+			"foo": {b: []byte("bar")},
+		},
+	}
+	agrl := newAirGapCache(rl, metrics.Noop())
+
+	_, err := agrl.Get("nope")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	err = agrl.Add("foo", []byte("baz"))
+	assert.Equal(t, types.ErrKeyAlreadyExists, err)
+}
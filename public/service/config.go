@@ -121,6 +121,15 @@ func NewObjectField(name string, fields ...*ConfigField) *ConfigField {
 	}
 }
 
+// NewObjectListField describes a new list type config field consisting of
+// objects with one or more child fields.
+func NewObjectListField(name string, fields ...*ConfigField) *ConfigField {
+	objField := NewObjectField(name, fields...)
+	return &ConfigField{
+		field: objField.field.Array(),
+	}
+}
+
 // NewInternalField returns a ConfigField derived from an internal package field
 // spec. This function is for internal use only.
 func NewInternalField(ifield docs.FieldSpec) *ConfigField {
@@ -642,3 +651,33 @@ func (p *ParsedConfig) FieldBool(path ...string) (bool, error) {
 	}
 	return b, nil
 }
+
+// FieldObjectList accesses a field that is a list of objects from the parsed
+// config by its name and returns the value as an array of *ParsedConfig types,
+// where each one represents an object in the list. Returns an error if the
+// field is not found, or is not a list of objects.
+//
+// This method is not valid when the configuration spec was built around a
+// config constructor.
+func (p *ParsedConfig) FieldObjectList(path ...string) ([]*ParsedConfig, error) {
+	v, exists := p.field(path...)
+	if !exists {
+		return nil, fmt.Errorf("field '%v' was not found in the config", p.fullDotPath(path...))
+	}
+	iList, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected field '%v' to be a list, got %T", p.fullDotPath(path...), v)
+	}
+	sList := make([]*ParsedConfig, len(iList))
+	for i, ev := range iList {
+		obj, ok := ev.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected field '%v' to be a list of objects, got %T", p.fullDotPath(path...), ev)
+		}
+		sList[i] = &ParsedConfig{
+			mgr:     p.mgr,
+			generic: obj,
+		}
+	}
+	return sList, nil
+}
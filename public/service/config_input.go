@@ -0,0 +1,46 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"gopkg.in/yaml.v3"
+)
+
+// NewInputField defines a new config field that describes a child input. It is
+// then possible to extract an *OwnedInput from the resulting parsed config with
+// the method FieldInput.
+func NewInputField(name string) *ConfigField {
+	return &ConfigField{
+		field: docs.FieldCommon(name, "").HasType(docs.FieldTypeInput),
+	}
+}
+
+// FieldInput accesses a field from a parsed config that was defined with
+// NewInputField and returns an *OwnedInput, or an error if the configuration
+// was invalid. The input returned is owned by the caller, and must be closed
+// when it is no longer needed.
+func (p *ParsedConfig) FieldInput(path ...string) (*OwnedInput, error) {
+	v, exists := p.field(path...)
+	if !exists {
+		return nil, fmt.Errorf("field '%v' was not found in the config", strings.Join(path, "."))
+	}
+
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return nil, err
+	}
+
+	conf := input.NewConfig()
+	if err := node.Decode(&conf); err != nil {
+		return nil, fmt.Errorf("field '%v': %w", strings.Join(path, "."), err)
+	}
+
+	i, err := p.mgr.NewInput(conf, false)
+	if err != nil {
+		return nil, fmt.Errorf("field '%v': %w", strings.Join(path, "."), err)
+	}
+	return &OwnedInput{i: i}, nil
+}
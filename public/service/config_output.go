@@ -0,0 +1,53 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"gopkg.in/yaml.v3"
+)
+
+// NewOutputField defines a new config field that describes a child output. It
+// is then possible to extract an *OwnedOutput from the resulting parsed config
+// with the method FieldOutput.
+func NewOutputField(name string) *ConfigField {
+	return &ConfigField{
+		field: docs.FieldCommon(name, "").HasType(docs.FieldTypeOutput),
+	}
+}
+
+// FieldOutput accesses a field from a parsed config that was defined with
+// NewOutputField and returns an *OwnedOutput, or an error if the configuration
+// was invalid. The output returned is owned by the caller, and must be closed
+// when it is no longer needed.
+func (p *ParsedConfig) FieldOutput(path ...string) (*OwnedOutput, error) {
+	v, exists := p.field(path...)
+	if !exists {
+		return nil, fmt.Errorf("field '%v' was not found in the config", strings.Join(path, "."))
+	}
+
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return nil, err
+	}
+
+	conf := output.NewConfig()
+	if err := node.Decode(&conf); err != nil {
+		return nil, fmt.Errorf("field '%v': %w", strings.Join(path, "."), err)
+	}
+
+	o, err := p.mgr.NewOutput(conf)
+	if err != nil {
+		return nil, fmt.Errorf("field '%v': %w", strings.Join(path, "."), err)
+	}
+
+	tChan := make(chan types.Transaction)
+	if err := o.Consume(tChan); err != nil {
+		o.CloseAsync()
+		return nil, fmt.Errorf("field '%v': %w", strings.Join(path, "."), err)
+	}
+	return &OwnedOutput{o: o, tChan: tChan}, nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"gopkg.in/yaml.v3"
+)
+
+// NewProcessorListField defines a new config field that describes a list of
+// child processors. It is then possible to extract a slice of *OwnedProcessor
+// from the resulting parsed config with the method FieldProcessorList.
+func NewProcessorListField(name string) *ConfigField {
+	return &ConfigField{
+		field: docs.FieldCommon(name, "").Array().HasType(docs.FieldTypeProcessor),
+	}
+}
+
+// FieldProcessorList accesses a field from a parsed config that was defined
+// with NewProcessorListField and returns a slice of *OwnedProcessor, or an
+// error if the configuration was invalid. The processors returned are owned by
+// the caller, and must be closed when they are no longer needed.
+func (p *ParsedConfig) FieldProcessorList(path ...string) ([]*OwnedProcessor, error) {
+	v, exists := p.field(path...)
+	if !exists {
+		return nil, fmt.Errorf("field '%v' was not found in the config", strings.Join(path, "."))
+	}
+
+	iList, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected field '%v' to be an array, got %T", strings.Join(path, "."), v)
+	}
+
+	procs := make([]*OwnedProcessor, 0, len(iList))
+	for i, iConf := range iList {
+		var node yaml.Node
+		if err := node.Encode(iConf); err != nil {
+			return nil, err
+		}
+
+		conf := processor.NewConfig()
+		if err := node.Decode(&conf); err != nil {
+			return nil, fmt.Errorf("field '%v' index %v: %w", strings.Join(path, "."), i, err)
+		}
+
+		proc, err := p.mgr.NewProcessor(conf)
+		if err != nil {
+			return nil, fmt.Errorf("field '%v' index %v: %w", strings.Join(path, "."), i, err)
+		}
+		procs = append(procs, &OwnedProcessor{p: proc})
+	}
+	return procs, nil
+}
@@ -331,6 +331,45 @@ c:
 	assert.Equal(t, 23.1, f)
 }
 
+func TestConfigObjectList(t *testing.T) {
+	spec := NewConfigSpec().
+		Field(NewObjectListField("a",
+			NewStringField("b"),
+			NewIntField("c").Default(5),
+		))
+
+	parsedConfig, err := spec.ParseYAML(`
+a:
+  - b: first
+  - b: second
+    c: 10
+`, nil)
+	require.NoError(t, err)
+
+	objs, err := parsedConfig.FieldObjectList("a")
+	require.NoError(t, err)
+	require.Len(t, objs, 2)
+
+	s, err := objs[0].FieldString("b")
+	require.NoError(t, err)
+	assert.Equal(t, "first", s)
+
+	i, err := objs[0].FieldInt("c")
+	require.NoError(t, err)
+	assert.Equal(t, 5, i)
+
+	s, err = objs[1].FieldString("b")
+	require.NoError(t, err)
+	assert.Equal(t, "second", s)
+
+	i, err = objs[1].FieldInt("c")
+	require.NoError(t, err)
+	assert.Equal(t, 10, i)
+
+	_, err = parsedConfig.FieldObjectList("z")
+	assert.Error(t, err)
+}
+
 func TestConfigBatching(t *testing.T) {
 	spec := NewConfigSpec().
 		Field(NewBatchPolicyField("a"))
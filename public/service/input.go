@@ -8,6 +8,7 @@ import (
 	"github.com/Jeffail/benthos/v3/internal/shutdown"
 	"github.com/Jeffail/benthos/v3/lib/input/reader"
 	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
@@ -208,3 +209,65 @@ func (a *airGapBatchReader) WaitForClose(tout time.Duration) error {
 	}
 	return nil
 }
+
+//------------------------------------------------------------------------------
+
+// OwnedInput provides direct ownership of an input extracted from a plugin
+// config. Connectivity of the input is handled internally, and so the consumer
+// of this type should only be concerned with reading messages and eventually
+// calling Close to terminate the input.
+type OwnedInput struct {
+	i types.Input
+}
+
+// ReadBatch attempts to read a message batch from the input, along with a
+// function to be called once the entire batch can be either acked
+// (successfully sent or intentionally filtered) or nacked (failed to be
+// processed or dispatched to the output).
+//
+// If this method returns ErrEndOfInput then that indicates that the input has
+// finished and will no longer yield new messages.
+func (o *OwnedInput) ReadBatch(ctx context.Context) (MessageBatch, AckFunc, error) {
+	var tran types.Transaction
+	var open bool
+	select {
+	case tran, open = <-o.i.TransactionChan():
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	if !open {
+		return nil, nil, ErrEndOfInput
+	}
+
+	var b MessageBatch
+	_ = tran.Payload.Iter(func(_ int, part types.Part) error {
+		b = append(b, newMessageFromPart(part))
+		return nil
+	})
+
+	return b, func(actx context.Context, err error) error {
+		select {
+		case tran.ResponseChan <- response.NewError(err):
+		case <-actx.Done():
+			return actx.Err()
+		}
+		return nil
+	}, nil
+}
+
+// Close the input, allowing it to clean up resources. Blocks until either the
+// input has closed or the context is cancelled.
+func (o *OwnedInput) Close(ctx context.Context) error {
+	o.i.CloseAsync()
+	for {
+		// Gross but will do for now until we replace these with context params.
+		if err := o.i.WaitForClose(time.Millisecond * 100); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+	}
+}
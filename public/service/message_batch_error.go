@@ -0,0 +1,90 @@
+package service
+
+import (
+	"errors"
+	"sort"
+
+	ibatch "github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// BatchError is an error type that can be returned by batched outputs in order
+// to indicate that only specific messages of a batch failed, allowing the
+// remaining messages to be acknowledged successfully.
+type BatchError struct {
+	err         error
+	indexedErrs map[int]error
+}
+
+// NewBatchError creates a new batch-wide error, where it's possible to add
+// granular errors for individual messages of the batch with Failed.
+func NewBatchError(headline error) *BatchError {
+	return &BatchError{
+		err: headline,
+	}
+}
+
+// Failed stores an error state for a particular message of the batch and
+// returns the same BatchError, allowing calls to be chained.
+//
+// If Failed is never called then all messages of the batch are assumed to have
+// failed. If it is called at least once then all messages that aren't
+// explicitly failed are assumed to have been delivered successfully.
+func (err *BatchError) Failed(i int, merr error) *BatchError {
+	if err.indexedErrs == nil {
+		err.indexedErrs = map[int]error{}
+	}
+	err.indexedErrs[i] = merr
+	return err
+}
+
+// IndexedErrors returns the number of messages that have been explicitly
+// failed.
+func (err *BatchError) IndexedErrors() int {
+	return len(err.indexedErrs)
+}
+
+// WalkIndexedErrors applies a closure to each message that has been explicitly
+// failed, in order of their index within the batch, along with its error. The
+// closure returns a bool which indicates whether the iteration should be
+// continued.
+func (err *BatchError) WalkIndexedErrors(fn func(int, error) bool) {
+	indexes := make([]int, 0, len(err.indexedErrs))
+	for i := range err.indexedErrs {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		if !fn(i, err.indexedErrs[i]) {
+			return
+		}
+	}
+}
+
+// Error implements the common error interface.
+func (err *BatchError) Error() string {
+	return err.err.Error()
+}
+
+// Unwrap returns the underlying headline error.
+func (err *BatchError) Unwrap() error {
+	return err.err
+}
+
+// toInternal converts the error into a batch error that refers to the
+// messages of an internal message batch.
+func (err *BatchError) toInternal(msg types.Message) error {
+	bErr := ibatch.NewError(msg, err.err)
+	for i, merr := range err.indexedErrs {
+		bErr.Failed(i, merr)
+	}
+	return bErr
+}
+
+func toInternalBatchError(msg types.Message, err error) error {
+	var bErr *BatchError
+	if errors.As(err, &bErr) {
+		return bErr.toInternal(msg)
+	}
+	return err
+}
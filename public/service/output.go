@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/shutdown"
+	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/output"
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
@@ -63,7 +64,9 @@ type BatchOutput interface {
 	// not possible.
 	//
 	// If this method returns ErrNotConnected then write will not be called
-	// again until Connect has returned a nil error.
+	// again until Connect has returned a nil error. If only a subset of the
+	// messages could not be delivered then a *BatchError can be returned
+	// in order to identify them.
 	WriteBatch(context.Context, MessageBatch) error
 
 	Closer
@@ -138,7 +141,7 @@ func (a *airGapBatchWriter) WriteWithContext(ctx context.Context, msg types.Mess
 	if err != nil && errors.Is(err, ErrNotConnected) {
 		err = types.ErrNotConnected
 	}
-	return err
+	return toInternalBatchError(msg, err)
 }
 
 func (a *airGapBatchWriter) CloseAsync() {
@@ -157,3 +160,60 @@ func (a *airGapBatchWriter) WaitForClose(tout time.Duration) error {
 	}
 	return nil
 }
+
+//------------------------------------------------------------------------------
+
+// OwnedOutput provides direct ownership of an output extracted from a plugin
+// config. Connectivity of the output is handled internally, and so the owner
+// of this type should only be concerned with writing messages and eventually
+// calling Close to terminate the output.
+type OwnedOutput struct {
+	o     types.Output
+	tChan chan types.Transaction
+}
+
+// Write a message to the output, blocking until either the message has been
+// delivered or the context is cancelled.
+func (o *OwnedOutput) Write(ctx context.Context, m *Message) error {
+	return o.WriteBatch(ctx, MessageBatch{m})
+}
+
+// WriteBatch attempts to write a message batch to the output, blocking until
+// either the batch has been delivered or the context is cancelled.
+func (o *OwnedOutput) WriteBatch(ctx context.Context, b MessageBatch) error {
+	msg := message.New(nil)
+	for _, m := range b {
+		msg.Append(m.part)
+	}
+
+	resChan := make(chan types.Response)
+	select {
+	case o.tChan <- types.NewTransaction(msg, resChan):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case res := <-resChan:
+		return res.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close the output, allowing it to clean up resources. Blocks until either the
+// output has closed or the context is cancelled.
+func (o *OwnedOutput) Close(ctx context.Context) error {
+	o.o.CloseAsync()
+	for {
+		// Gross but will do for now until we replace these with context params.
+		if err := o.o.WaitForClose(time.Millisecond * 100); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"testing"
 	"time"
 
+	ibatch "github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type fnOutput struct {
@@ -176,3 +178,33 @@ func TestBatchOutputAirGapHappy(t *testing.T) {
 
 	assert.Equal(t, "hello world", wroteMsg)
 }
+
+func TestBatchOutputAirGapBatchError(t *testing.T) {
+	o := &fnBatchOutput{
+		connect: func() error {
+			return nil
+		},
+		writeBatch: func(m MessageBatch) error {
+			return NewBatchError(errors.New("some failed")).Failed(1, errors.New("second failed"))
+		},
+	}
+	agi := newAirGapBatchWriter(o)
+
+	inMsg := message.New([][]byte{[]byte("first"), []byte("second"), []byte("third")})
+
+	err := agi.WriteWithContext(context.Background(), inMsg)
+	assert.EqualError(t, err, "some failed")
+
+	walkable, ok := err.(ibatch.WalkableError)
+	require.True(t, ok, "%T", err)
+	assert.Equal(t, 1, walkable.IndexedErrors())
+
+	var failed []string
+	walkable.WalkParts(func(i int, p types.Part, err error) bool {
+		if err != nil {
+			failed = append(failed, string(p.Get())+": "+err.Error())
+		}
+		return true
+	})
+	assert.Equal(t, []string{"second: second failed"}, failed)
+}
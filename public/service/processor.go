@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/component/processor"
+	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
@@ -124,3 +126,72 @@ func (a *airGapBatchProcessor) ProcessBatch(ctx context.Context, msgs []types.Pa
 func (a *airGapBatchProcessor) Close(ctx context.Context) error {
 	return a.p.Close(context.Background())
 }
+
+//------------------------------------------------------------------------------
+
+// OwnedProcessor provides direct ownership of a processor extracted from a
+// plugin config.
+type OwnedProcessor struct {
+	p types.Processor
+}
+
+// Process a single message, returns either a batch of zero or more resulting
+// messages or an error if the message could not be processed.
+func (o *OwnedProcessor) Process(ctx context.Context, msg *Message) (MessageBatch, error) {
+	outBatches, err := o.ProcessBatch(ctx, MessageBatch{msg})
+	if err != nil {
+		return nil, err
+	}
+
+	var outMsgs MessageBatch
+	for _, b := range outBatches {
+		outMsgs = append(outMsgs, b...)
+	}
+	return outMsgs, nil
+}
+
+// ProcessBatch attempts to process a batch of messages, returns zero or more
+// batches of resulting messages or an error if the messages could not be
+// processed.
+func (o *OwnedProcessor) ProcessBatch(ctx context.Context, batch MessageBatch) ([]MessageBatch, error) {
+	parts := make([]types.Part, len(batch))
+	for i, m := range batch {
+		parts[i] = m.part
+	}
+
+	msg := message.New(nil)
+	msg.SetAll(parts)
+
+	outMsgs, res := o.p.ProcessMessage(msg)
+	if res != nil && res.Error() != nil {
+		return nil, res.Error()
+	}
+
+	outBatches := make([]MessageBatch, 0, len(outMsgs))
+	for _, m := range outMsgs {
+		var b MessageBatch
+		_ = m.Iter(func(_ int, part types.Part) error {
+			b = append(b, newMessageFromPart(part))
+			return nil
+		})
+		outBatches = append(outBatches, b)
+	}
+	return outBatches, nil
+}
+
+// Close the processor, allowing it to clean up resources. Blocks until either
+// the processor has closed or the context is cancelled.
+func (o *OwnedProcessor) Close(ctx context.Context) error {
+	o.p.CloseAsync()
+	for {
+		// Gross but will do for now until we replace these with context params.
+		if err := o.p.WaitForClose(time.Millisecond * 100); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+	}
+}
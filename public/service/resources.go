@@ -42,6 +42,15 @@ func (r *Resources) AccessCache(ctx context.Context, name string, fn func(c Cach
 	})
 }
 
+// HasCache confirms whether a cache with a given name has been registered as a
+// resource. Unlike AccessCache this method does not block on CRUD operations,
+// and is therefore safe to call whilst a component is being initialised as a
+// resource.
+func (r *Resources) HasCache(name string) bool {
+	_, err := r.mgr.GetCache(name)
+	return err == nil
+}
+
 // AccessRateLimit attempts to access a rate limit resource by name. This action
 // can block if CRUD operations are being actively performed on the resource.
 func (r *Resources) AccessRateLimit(ctx context.Context, name string, fn func(r RateLimit)) error {
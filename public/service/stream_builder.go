@@ -714,6 +714,7 @@ func (s *StreamBuilder) Build() (*Stream, error) {
 		conf.ResourceConfig, apiMut, logger, stats,
 		manager.OptSetEnvironment(s.env.internal),
 		manager.OptSetBloblangEnvironment(s.env.getBloblangParserEnv()),
+		manager.OptSetCacheWrites(s.http.CacheWrites),
 	)
 	if err != nil {
 		return nil, err